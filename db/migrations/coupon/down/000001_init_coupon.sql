@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS discounts;
+DROP TABLE IF EXISTS promotion_codes;
+DROP TABLE IF EXISTS coupons;
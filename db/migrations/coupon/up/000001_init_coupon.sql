@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS coupons (
+    id BIGINT PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    code TEXT NOT NULL,
+    name TEXT,
+    percent_off DOUBLE PRECISION NOT NULL DEFAULT 0,
+    amount_off_cents BIGINT NOT NULL DEFAULT 0,
+    currency TEXT,
+    duration TEXT NOT NULL,
+    duration_in_periods INTEGER NOT NULL DEFAULT 0,
+    max_redemptions INTEGER NOT NULL DEFAULT 0,
+    times_redeemed INTEGER NOT NULL DEFAULT 0,
+    redeem_by TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    metadata JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coupons_tenant_code ON coupons (tenant_id, code);
+
+CREATE TABLE IF NOT EXISTS promotion_codes (
+    id BIGINT PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    coupon_id BIGINT NOT NULL REFERENCES coupons(id),
+    code TEXT NOT NULL,
+    customer_id BIGINT,
+    max_redemptions INTEGER NOT NULL DEFAULT 0,
+    times_redeemed INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promotion_codes_tenant_code ON promotion_codes (tenant_id, code);
+
+-- A discount attaches a redeemed coupon to a customer or a subscription.
+CREATE TABLE IF NOT EXISTS discounts (
+    id BIGINT PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    coupon_id BIGINT NOT NULL REFERENCES coupons(id),
+    promotion_code_id BIGINT REFERENCES promotion_codes(id),
+    customer_id BIGINT,
+    subscription_id BIGINT,
+    periods_applied INTEGER NOT NULL DEFAULT 0,
+    start_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (customer_id IS NOT NULL OR subscription_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_discounts_customer ON discounts (tenant_id, customer_id) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_discounts_subscription ON discounts (tenant_id, subscription_id) WHERE ended_at IS NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS dunning_cases;
+DROP TABLE IF EXISTS dunning_policies;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_dunning_cases_final_action_pending;
+ALTER TABLE dunning_cases DROP COLUMN IF EXISTS final_action_at;
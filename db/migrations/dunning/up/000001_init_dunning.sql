@@ -0,0 +1,31 @@
+-- One policy per tenant; tenants without a row use the built-in default.
+-- retry_schedule_seconds holds the wait before each retry.
+CREATE TABLE IF NOT EXISTS dunning_policies (
+    tenant_id BIGINT PRIMARY KEY,
+    retry_schedule_seconds BIGINT[] NOT NULL DEFAULT '{}',
+    final_action TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- A dunning case follows one invoice from its first failed payment until it
+-- is recovered (status 2) or exhausted (status 3).
+CREATE TABLE IF NOT EXISTS dunning_cases (
+    id BIGINT PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    invoice_id BIGINT NOT NULL,
+    customer_id BIGINT,
+    subscription_id BIGINT,
+    status SMALLINT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_reason TEXT,
+    next_retry_at TIMESTAMPTZ,
+    final_action TEXT,
+    closed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_dunning_cases_open_invoice ON dunning_cases (tenant_id, invoice_id) WHERE status = 1;
+CREATE INDEX IF NOT EXISTS idx_dunning_cases_subscription ON dunning_cases (tenant_id, subscription_id) WHERE status = 1;
+CREATE INDEX IF NOT EXISTS idx_dunning_cases_next_retry ON dunning_cases (next_retry_at) WHERE status = 1;
@@ -0,0 +1,8 @@
+-- final_action_at is set once the final action of an exhausted case has been
+-- applied; exhausted cases without it are retried by the dunning worker.
+-- Cases exhausted before the column existed are taken as applied.
+ALTER TABLE dunning_cases ADD COLUMN IF NOT EXISTS final_action_at TIMESTAMPTZ;
+
+UPDATE dunning_cases SET final_action_at = closed_at WHERE status = 3 AND final_action_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_dunning_cases_final_action_pending ON dunning_cases (closed_at) WHERE status = 3 AND final_action_at IS NULL;
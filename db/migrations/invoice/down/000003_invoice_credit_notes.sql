@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_credit_note_lines_note;
+DROP TABLE IF EXISTS credit_note_lines;
+DROP INDEX IF EXISTS idx_credit_notes_invoice;
+DROP TABLE IF EXISTS credit_notes;
+ALTER TABLE invoices DROP COLUMN IF EXISTS credited_cents;
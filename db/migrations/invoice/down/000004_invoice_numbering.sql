@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS invoice_number_sequences;
+DROP TABLE IF EXISTS invoice_numbering_schemes;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_invoices_past_due;
+ALTER TABLE invoices DROP COLUMN IF EXISTS overdue_at;
+DROP TABLE IF EXISTS invoice_payment_terms;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_invoice_ledger_postings_pending;
+DROP TABLE IF EXISTS invoice_ledger_postings;
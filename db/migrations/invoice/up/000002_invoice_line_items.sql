@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS invoice_line_items (
+    id BIGINT PRIMARY KEY,
+    invoice_id BIGINT NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    tenant_id BIGINT NOT NULL,
+    line_type TEXT NOT NULL,
+    description TEXT NOT NULL,
+    price_id BIGINT,
+    meter_code TEXT,
+    quantity DOUBLE PRECISION NOT NULL DEFAULT 0,
+    unit_amount_cents BIGINT NOT NULL DEFAULT 0,
+    amount_cents BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    period_start TIMESTAMPTZ,
+    period_end TIMESTAMPTZ,
+    metadata JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_line_items_invoice ON invoice_line_items (invoice_id);
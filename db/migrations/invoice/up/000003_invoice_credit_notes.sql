@@ -0,0 +1,29 @@
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS credited_cents BIGINT NOT NULL DEFAULT 0;
+
+-- Credit notes reduce what is left to collect on an invoice; the part of a
+-- note exceeding the amount due becomes customer credit.
+CREATE TABLE IF NOT EXISTS credit_notes (
+    id BIGINT PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    invoice_id BIGINT NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    customer_id BIGINT,
+    reason TEXT NOT NULL,
+    memo TEXT,
+    currency TEXT NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    balance_cents BIGINT NOT NULL DEFAULT 0,
+    customer_credit_cents BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_notes_invoice ON credit_notes (invoice_id, created_at);
+
+CREATE TABLE IF NOT EXISTS credit_note_lines (
+    id BIGINT PRIMARY KEY,
+    credit_note_id BIGINT NOT NULL REFERENCES credit_notes(id) ON DELETE CASCADE,
+    line_item_id BIGINT NOT NULL REFERENCES invoice_line_items(id),
+    description TEXT,
+    amount_cents BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_note_lines_note ON credit_note_lines (credit_note_id);
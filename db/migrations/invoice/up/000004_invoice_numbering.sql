@@ -0,0 +1,19 @@
+-- Tenants choose how invoice numbers are rendered; see NumberingScheme.
+CREATE TABLE IF NOT EXISTS invoice_numbering_schemes (
+    tenant_id BIGINT PRIMARY KEY,
+    prefix TEXT NOT NULL,
+    template TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One gap-free counter per tenant and series. Numbers are drawn by
+-- incrementing the row inside the transaction that stores the invoice.
+CREATE TABLE IF NOT EXISTS invoice_number_sequences (
+    tenant_id BIGINT NOT NULL,
+    series TEXT NOT NULL,
+    last_value BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, series)
+);
+
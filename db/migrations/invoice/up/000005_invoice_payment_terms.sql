@@ -0,0 +1,15 @@
+-- Payment terms per tenant, with customer_id 0, or per customer; see
+-- PaymentTermsSetting.
+CREATE TABLE IF NOT EXISTS invoice_payment_terms (
+    tenant_id BIGINT NOT NULL,
+    customer_id BIGINT NOT NULL DEFAULT 0,
+    terms TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, customer_id)
+);
+
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS overdue_at TIMESTAMPTZ;
+
+-- The overdue scheduler scans open invoices not marked overdue by due date.
+CREATE INDEX IF NOT EXISTS idx_invoices_past_due ON invoices (due_at) WHERE overdue_at IS NULL;
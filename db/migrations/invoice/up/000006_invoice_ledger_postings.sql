@@ -0,0 +1,21 @@
+-- Ledger postings queued with the invoice change they record and posted by
+-- the ledger worker; see LedgerPosting. The id is reused as the ledger
+-- journal id so a retried posting is applied once.
+CREATE TABLE IF NOT EXISTS invoice_ledger_postings (
+    id BIGINT PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    reference_type TEXT NOT NULL,
+    reference_id TEXT NOT NULL,
+    description TEXT NOT NULL,
+    debit_account TEXT NOT NULL,
+    credit_account TEXT NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT,
+    posted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_ledger_postings_pending ON invoice_ledger_postings (next_attempt_at) WHERE posted_at IS NULL;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_invoice_engine_runs_subscription_period;
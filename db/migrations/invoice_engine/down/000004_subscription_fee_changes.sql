@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscription_fee_changes_period;
+DROP INDEX IF EXISTS idx_subscription_fee_changes_source;
+DROP TABLE IF EXISTS subscription_fee_changes;
@@ -0,0 +1,24 @@
+-- Pending items are charges and credits, such as prorations, waiting to be
+-- picked up by the next invoice for the subscription.
+CREATE TABLE IF NOT EXISTS pending_invoice_items (
+    id BIGINT PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    customer_id BIGINT,
+    subscription_id BIGINT NOT NULL,
+    source_key TEXT NOT NULL,
+    line_type TEXT NOT NULL,
+    description TEXT NOT NULL,
+    price_id BIGINT,
+    quantity DOUBLE PRECISION NOT NULL DEFAULT 0,
+    unit_amount_cents BIGINT NOT NULL DEFAULT 0,
+    amount_cents BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    period_start TIMESTAMPTZ,
+    period_end TIMESTAMPTZ,
+    metadata JSONB,
+    invoice_id BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pending_invoice_items_source ON pending_invoice_items (tenant_id, source_key);
+CREATE INDEX IF NOT EXISTS idx_pending_invoice_items_open ON pending_invoice_items (subscription_id) WHERE invoice_id IS NULL;
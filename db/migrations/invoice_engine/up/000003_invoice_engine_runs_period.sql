@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_invoice_engine_runs_subscription_period ON invoice_engine_runs (subscription_id, period_start, period_end);
@@ -0,0 +1,16 @@
+-- Price and quantity changes made within a period billed in arrears. The
+-- period-end invoice splits the recurring fee at these changes; see FeeChange.
+CREATE TABLE IF NOT EXISTS subscription_fee_changes (
+    id BIGINT PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    subscription_id BIGINT NOT NULL,
+    item_id BIGINT,
+    price_id BIGINT NOT NULL,
+    quantity BIGINT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL,
+    source_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_fee_changes_source ON subscription_fee_changes (tenant_id, source_key);
+CREATE INDEX IF NOT EXISTS idx_subscription_fee_changes_period ON subscription_fee_changes (subscription_id, changed_at);
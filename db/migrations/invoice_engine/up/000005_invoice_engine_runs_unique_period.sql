@@ -0,0 +1,9 @@
+DELETE FROM invoice_engine_runs r
+USING invoice_engine_runs newer
+WHERE r.subscription_id = newer.subscription_id
+  AND r.period_start = newer.period_start
+  AND r.period_end = newer.period_end
+  AND (r.created_at, r.id) < (newer.created_at, newer.id);
+
+DROP INDEX IF EXISTS idx_invoice_engine_runs_subscription_period;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoice_engine_runs_subscription_period ON invoice_engine_runs (subscription_id, period_start, period_end);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_ledger_accounts_name;
+CREATE INDEX IF NOT EXISTS idx_ledger_accounts_name ON ledger_accounts (tenant_id, name, currency);
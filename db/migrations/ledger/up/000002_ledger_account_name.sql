@@ -0,0 +1,2 @@
+-- Billing accounts are looked up by name per tenant and currency.
+CREATE INDEX IF NOT EXISTS idx_ledger_accounts_name ON ledger_accounts (tenant_id, name, currency);
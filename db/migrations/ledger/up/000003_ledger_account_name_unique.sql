@@ -0,0 +1,5 @@
+-- A tenant has one account of a name per currency, so billing accounts can be
+-- created with an upsert. Duplicates left by concurrent creation must be
+-- merged before this runs.
+DROP INDEX IF EXISTS idx_ledger_accounts_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_accounts_name ON ledger_accounts (tenant_id, name, currency);
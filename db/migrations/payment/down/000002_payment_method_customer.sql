@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_payment_methods_customer_default;
+DROP INDEX IF EXISTS idx_payment_methods_customer;
+ALTER TABLE payment_methods DROP COLUMN IF EXISTS customer_id;
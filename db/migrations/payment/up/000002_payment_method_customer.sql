@@ -0,0 +1,6 @@
+-- Payment methods belong to a customer; at most one is the customer's default.
+ALTER TABLE payment_methods ADD COLUMN IF NOT EXISTS customer_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_payment_methods_customer ON payment_methods (tenant_id, customer_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_methods_customer_default
+    ON payment_methods (tenant_id, customer_id) WHERE is_default;
@@ -0,0 +1,11 @@
+-- A provider transaction is recorded once per tenant, so a re-sent payment
+-- webhook does not count as another attempt. Duplicates already stored keep
+-- their first attempt.
+DELETE FROM payment_attempts a
+USING payment_attempts older
+WHERE a.tenant_id = older.tenant_id
+  AND a.provider_transaction_id = older.provider_transaction_id
+  AND (a.created_at, a.id) > (older.created_at, older.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_attempts_transaction
+    ON payment_attempts (tenant_id, provider_transaction_id) WHERE provider_transaction_id IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE price_tiers DROP COLUMN IF EXISTS flat_amount_cents;
+ALTER TABLE prices DROP COLUMN IF EXISTS tier_mode;
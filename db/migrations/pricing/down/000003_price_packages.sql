@@ -0,0 +1,2 @@
+ALTER TABLE prices DROP COLUMN IF EXISTS package_rounding;
+ALTER TABLE prices DROP COLUMN IF EXISTS package_size;
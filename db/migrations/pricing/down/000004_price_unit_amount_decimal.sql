@@ -0,0 +1,2 @@
+ALTER TABLE price_tiers DROP COLUMN IF EXISTS unit_amount_decimal;
+ALTER TABLE prices DROP COLUMN IF EXISTS unit_amount_decimal;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_prices_root_version;
+DROP INDEX IF EXISTS idx_prices_tenant_code_version;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prices_tenant_code ON prices (tenant_id, code);
+
+ALTER TABLE prices DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE prices DROP COLUMN IF EXISTS migration_policy;
+ALTER TABLE prices DROP COLUMN IF EXISTS effective_from;
+ALTER TABLE prices DROP COLUMN IF EXISTS version;
+ALTER TABLE prices DROP COLUMN IF EXISTS root_price_id;
@@ -0,0 +1,3 @@
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS tier_mode TEXT NOT NULL DEFAULT 'graduated';
+
+ALTER TABLE price_tiers ADD COLUMN IF NOT EXISTS flat_amount_cents BIGINT NOT NULL DEFAULT 0;
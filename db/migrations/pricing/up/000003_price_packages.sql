@@ -0,0 +1,2 @@
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS package_size DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS package_rounding TEXT NOT NULL DEFAULT 'up';
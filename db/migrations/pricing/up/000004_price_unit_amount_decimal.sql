@@ -0,0 +1,8 @@
+-- Unit prices in minor units with sub-cent precision (e.g. 0.04 cents per token).
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS unit_amount_decimal NUMERIC(38, 6);
+UPDATE prices SET unit_amount_decimal = unit_amount_cents WHERE unit_amount_decimal IS NULL;
+ALTER TABLE prices ALTER COLUMN unit_amount_decimal SET NOT NULL;
+
+ALTER TABLE price_tiers ADD COLUMN IF NOT EXISTS unit_amount_decimal NUMERIC(38, 6);
+UPDATE price_tiers SET unit_amount_decimal = unit_amount_cents WHERE unit_amount_decimal IS NULL;
+ALTER TABLE price_tiers ALTER COLUMN unit_amount_decimal SET NOT NULL;
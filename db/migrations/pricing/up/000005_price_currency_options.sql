@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS price_currency_options (
+    price_id BIGINT NOT NULL REFERENCES prices(id) ON DELETE CASCADE,
+    currency TEXT NOT NULL,
+    unit_amount_cents BIGINT NOT NULL,
+    unit_amount_decimal NUMERIC(38, 6) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (price_id, currency)
+);
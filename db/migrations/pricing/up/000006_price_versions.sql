@@ -0,0 +1,16 @@
+-- Prices are immutable; a change creates a new version in the same family.
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS root_price_id BIGINT;
+UPDATE prices SET root_price_id = id WHERE root_price_id IS NULL;
+ALTER TABLE prices ALTER COLUMN root_price_id SET NOT NULL;
+
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS effective_from TIMESTAMPTZ;
+UPDATE prices SET effective_from = created_at WHERE effective_from IS NULL;
+ALTER TABLE prices ALTER COLUMN effective_from SET NOT NULL;
+
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS migration_policy TEXT NOT NULL DEFAULT 'grandfather';
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS idx_prices_tenant_code;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prices_tenant_code_version ON prices (tenant_id, code, version);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prices_root_version ON prices (root_price_id, version);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS uidx_rating_usage_price;
+DROP INDEX IF EXISTS idx_rating_subscription;
+ALTER TABLE rating_results DROP COLUMN IF EXISTS quantity;
+ALTER TABLE rating_results DROP COLUMN IF EXISTS meter_code;
+ALTER TABLE rating_results DROP COLUMN IF EXISTS subscription_id;
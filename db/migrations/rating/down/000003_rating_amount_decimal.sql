@@ -0,0 +1 @@
+ALTER TABLE rating_results DROP COLUMN IF EXISTS amount;
@@ -0,0 +1,6 @@
+ALTER TABLE rating_results ADD COLUMN IF NOT EXISTS subscription_id BIGINT;
+ALTER TABLE rating_results ADD COLUMN IF NOT EXISTS meter_code TEXT;
+ALTER TABLE rating_results ADD COLUMN IF NOT EXISTS quantity DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_rating_subscription ON rating_results (subscription_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uidx_rating_usage_price ON rating_results (usage_id, price_id);
@@ -0,0 +1,4 @@
+-- Unrounded rated amount in minor units; amount_cents keeps the rounded value.
+ALTER TABLE rating_results ADD COLUMN IF NOT EXISTS amount NUMERIC(38, 6);
+UPDATE rating_results SET amount = amount_cents WHERE amount IS NULL;
+ALTER TABLE rating_results ALTER COLUMN amount SET NOT NULL;
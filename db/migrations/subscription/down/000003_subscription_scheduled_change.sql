@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscriptions_scheduled_change;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS scheduled_change_at;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS scheduled_price_id;
@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS billing_anchor_day;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS billing_anchor;
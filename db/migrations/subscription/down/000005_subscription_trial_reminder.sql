@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_subscriptions_trial_end;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS trial_reminder_sent_at;
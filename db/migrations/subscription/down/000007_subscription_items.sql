@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscription_items_price;
+DROP INDEX IF EXISTS idx_subscription_items_subscription;
+DROP TABLE IF EXISTS subscription_items;
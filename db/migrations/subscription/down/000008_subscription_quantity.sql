@@ -0,0 +1,3 @@
+ALTER TABLE subscription_items DROP COLUMN IF EXISTS scheduled_quantity;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS scheduled_quantity;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS quantity;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscription_schedules_active;
+DROP INDEX IF EXISTS idx_subscription_schedules_tenant;
+DROP TABLE IF EXISTS subscription_schedules;
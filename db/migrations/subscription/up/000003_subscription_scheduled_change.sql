@@ -0,0 +1,6 @@
+-- A scheduled change swaps the subscription price at the end of the current
+-- period, e.g. for downgrades.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS scheduled_price_id BIGINT;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS scheduled_change_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_scheduled_change ON subscriptions (scheduled_change_at) WHERE scheduled_change_at IS NOT NULL;
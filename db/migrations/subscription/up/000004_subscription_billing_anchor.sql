@@ -0,0 +1,4 @@
+-- billing_anchor is 'anniversary' or 'calendar'; billing_anchor_day is the day
+-- of month periods end on, clamped to the length of short months.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS billing_anchor TEXT NOT NULL DEFAULT 'anniversary';
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS billing_anchor_day SMALLINT NOT NULL DEFAULT 0;
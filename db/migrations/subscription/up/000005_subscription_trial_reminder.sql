@@ -0,0 +1,5 @@
+-- trial_reminder_sent_at records when subscription.trial_will_end was emitted
+-- so the reminder goes out once per trial.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS trial_reminder_sent_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_trial_end ON subscriptions (trial_end_at) WHERE trial_end_at IS NOT NULL;
@@ -0,0 +1,7 @@
+-- A paused subscription has paused_at set. pause_behavior is 'void' or 'keep';
+-- resumes_at is the optional automatic resume date.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS paused_at TIMESTAMPTZ;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS pause_behavior TEXT;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS resumes_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_resumes_at ON subscriptions (resumes_at) WHERE paused_at IS NOT NULL;
@@ -0,0 +1,15 @@
+-- Prices billed on a subscription next to its primary price_id. Removed items
+-- keep their row so the period they were removed in is still invoiced.
+CREATE TABLE IF NOT EXISTS subscription_items (
+    id BIGINT PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    subscription_id BIGINT NOT NULL,
+    price_id BIGINT NOT NULL,
+    quantity BIGINT NOT NULL DEFAULT 1,
+    removed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_items_subscription ON subscription_items (subscription_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_items_price ON subscription_items (subscription_id, price_id) WHERE removed_at IS NULL;
@@ -0,0 +1,5 @@
+-- quantity is the number of units, e.g. seats, billed for the primary price.
+-- scheduled_quantity is a decrease taking effect at the end of the current period.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS quantity BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS scheduled_quantity BIGINT;
+ALTER TABLE subscription_items ADD COLUMN IF NOT EXISTS scheduled_quantity BIGINT;
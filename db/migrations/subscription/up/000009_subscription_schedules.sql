@@ -0,0 +1,19 @@
+-- Ordered phases a subscription moves through at its renewal boundaries.
+-- phases holds [{price_id, quantity, coupon_code, iterations}]; the current
+-- phase's coupon is redeemed as discount_id.
+CREATE TABLE IF NOT EXISTS subscription_schedules (
+    id BIGINT PRIMARY KEY,
+    tenant_id BIGINT NOT NULL,
+    subscription_id BIGINT NOT NULL,
+    phases JSONB NOT NULL,
+    end_behavior TEXT NOT NULL DEFAULT 'release',
+    status TEXT NOT NULL DEFAULT 'active',
+    current_phase INTEGER NOT NULL DEFAULT 0,
+    periods_in_phase INTEGER NOT NULL DEFAULT 0,
+    discount_id BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscription_schedules_tenant ON subscription_schedules (tenant_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_schedules_active ON subscription_schedules (subscription_id) WHERE status = 'active';
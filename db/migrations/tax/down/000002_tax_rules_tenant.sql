@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tax_rules_tenant_region;
+CREATE INDEX IF NOT EXISTS idx_tax_rules_region ON tax_rules (region_code);
+ALTER TABLE tax_rules DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,6 @@
+-- Tax rules belong to a tenant. Rules created before tenant_id existed have
+-- no tenant and no longer apply until they are assigned to one.
+ALTER TABLE tax_rules ADD COLUMN IF NOT EXISTS tenant_id BIGINT;
+
+DROP INDEX IF EXISTS idx_tax_rules_region;
+CREATE INDEX IF NOT EXISTS idx_tax_rules_tenant_region ON tax_rules (tenant_id, region_code);
@@ -9,14 +9,17 @@ import (
 	"github.com/smallbiznis/corebilling/internal/billing_event"
 	"github.com/smallbiznis/corebilling/internal/billingcycle"
 	"github.com/smallbiznis/corebilling/internal/config"
+	"github.com/smallbiznis/corebilling/internal/coupon"
 	"github.com/smallbiznis/corebilling/internal/customer"
 	"github.com/smallbiznis/corebilling/internal/db"
+	"github.com/smallbiznis/corebilling/internal/dunning"
 	"github.com/smallbiznis/corebilling/internal/eventfx"
 	"github.com/smallbiznis/corebilling/internal/invoice"
 	"github.com/smallbiznis/corebilling/internal/invoice_engine"
 	"github.com/smallbiznis/corebilling/internal/ledger"
 	"github.com/smallbiznis/corebilling/internal/log"
 	"github.com/smallbiznis/corebilling/internal/meter"
+	"github.com/smallbiznis/corebilling/internal/payment"
 	"github.com/smallbiznis/corebilling/internal/pricing"
 	"github.com/smallbiznis/corebilling/internal/quota"
 	"github.com/smallbiznis/corebilling/internal/rating"
@@ -55,6 +58,8 @@ func Billing() *fx.App {
 		audit.Module,
 		customer.Module,
 		pricing.Module,
+		coupon.Module,
+		payment.Module,
 		meter.Module,
 		invoice_engine.Module,
 		billingcycle.Module,
@@ -64,6 +69,7 @@ func Billing() *fx.App {
 		rating.Module,
 		ledger.Module,
 		invoice.Module,
+		dunning.Module,
 		grpcserver.Module,
 		httpserver.Module,
 		webhook.Module,
@@ -2,12 +2,18 @@ package billingcycle
 
 import (
 	"context"
+	"time"
 
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 
 	"github.com/smallbiznis/corebilling/internal/billingcycle/repository"
+	coupondomain "github.com/smallbiznis/corebilling/internal/coupon/domain"
 	"github.com/smallbiznis/corebilling/internal/invoice_engine/domain"
+	paymentdomain "github.com/smallbiznis/corebilling/internal/payment/domain"
+	subdomain "github.com/smallbiznis/corebilling/internal/subscription/domain"
+	invoiceenginev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice_engine/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // Module wires billing cycle services and scheduler.
@@ -16,8 +22,20 @@ var Module = fx.Options(
 	fx.Provide(func(engine *domain.Service) InvoiceGenerator {
 		return &engineAdapter{engine: engine}
 	}),
+	fx.Provide(func(engine *domain.Service) SubscriptionInvoicer {
+		return &engineAdapter{engine: engine}
+	}),
 	fx.Provide(NewService),
 	fx.Provide(NewScheduler),
+	fx.Provide(func(coupons *coupondomain.Service) DiscountManager {
+		return coupons
+	}),
+	fx.Provide(NewRenewalScheduler),
+	fx.Provide(func(payments *paymentdomain.Service) PaymentMethodChecker {
+		return payments
+	}),
+	fx.Provide(NewTrialScheduler),
+	fx.Provide(NewResumeScheduler),
 	fx.Invoke(startScheduler),
 )
 
@@ -30,10 +48,27 @@ func (e *engineAdapter) GenerateForTenant(ctx context.Context, tenantID string)
 	return nil
 }
 
-func startScheduler(lc fx.Lifecycle, scheduler *Scheduler, logger *zap.Logger) {
+func (e *engineAdapter) InvoicePeriod(ctx context.Context, sub subdomain.Subscription, start, end time.Time) (string, error) {
+	resp, err := e.engine.GenerateInvoice(ctx, &invoiceenginev1.GenerateInvoiceRequest{
+		TenantId:       sub.TenantID,
+		CustomerId:     sub.CustomerID,
+		SubscriptionId: sub.ID,
+		PeriodStart:    timestamppb.New(start),
+		PeriodEnd:      timestamppb.New(end),
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.GetInvoiceId(), nil
+}
+
+func startScheduler(lc fx.Lifecycle, scheduler *Scheduler, renewal *RenewalScheduler, trials *TrialScheduler, resumes *ResumeScheduler, logger *zap.Logger) {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
 			go scheduler.Run(ctx)
+			go renewal.Run(ctx)
+			go trials.Run(ctx)
+			go resumes.Run(ctx)
 			logger.Info("billing cycle scheduler started")
 			return nil
 		},
@@ -0,0 +1,59 @@
+package billingcycle
+
+import (
+	"context"
+	"time"
+
+	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	subdomain "github.com/smallbiznis/corebilling/internal/subscription/domain"
+	"go.uber.org/zap"
+)
+
+// ResumeScheduler resumes paused subscriptions once their resume date passes.
+type ResumeScheduler struct {
+	subscriptions *subdomain.Service
+	outbox        outbox.OutboxRepository
+	logger        *zap.Logger
+}
+
+// NewResumeScheduler constructs a resume scheduler.
+func NewResumeScheduler(subscriptions *subdomain.Service, outboxRepo outbox.OutboxRepository, logger *zap.Logger) *ResumeScheduler {
+	return &ResumeScheduler{
+		subscriptions: subscriptions,
+		outbox:        outboxRepo,
+		logger:        logger.Named("billingcycle.resume"),
+	}
+}
+
+// Run starts the periodic worker.
+func (s *ResumeScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.process(ctx, time.Now().UTC())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *ResumeScheduler) process(ctx context.Context, now time.Time) {
+	subs, err := s.subscriptions.ListResumesDue(ctx, subdomain.ResumeFilter{At: now, Limit: renewalBatchSize})
+	if err != nil {
+		s.logger.Error("failed to list subscriptions due to resume", zap.Error(err))
+		return
+	}
+	for _, sub := range subs {
+		// Resume as of the scheduled date so void pauses credit exactly the paused time.
+		resumed, evt, err := s.subscriptions.Resume(ctx, sub, *sub.Pause.ResumesAt)
+		if err != nil {
+			s.logger.Error("failed to resume subscription", zap.Error(err), zap.String("subscription_id", sub.ID))
+			continue
+		}
+		insertEvent(ctx, s.outbox, s.logger, evt)
+		s.logger.Info("subscription resumed automatically", zap.String("subscription_id", resumed.ID))
+	}
+}
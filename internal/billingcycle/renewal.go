@@ -0,0 +1,226 @@
+package billingcycle
+
+import (
+	"context"
+	"time"
+
+	coupondomain "github.com/smallbiznis/corebilling/internal/coupon/domain"
+	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	subdomain "github.com/smallbiznis/corebilling/internal/subscription/domain"
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	renewalBatchSize = 100
+	// maxCatchUpPeriods bounds how many missed periods one subscription is
+	// advanced through in a single pass.
+	maxCatchUpPeriods = 12
+)
+
+// SubscriptionInvoicer bills a single subscription period.
+type SubscriptionInvoicer interface {
+	InvoicePeriod(ctx context.Context, sub subdomain.Subscription, start, end time.Time) (string, error)
+}
+
+// DiscountManager redeems and ends the coupons of subscription schedule phases.
+type DiscountManager interface {
+	Redeem(ctx context.Context, req coupondomain.RedeemRequest) (coupondomain.Discount, error)
+	EndDiscount(ctx context.Context, tenantID, discountID string, at time.Time) error
+}
+
+// RenewalScheduler renews subscriptions on their own anniversary: every
+// auto-renewing subscription whose period ended is invoiced for that period
+// and advanced by its price's billing interval.
+type RenewalScheduler struct {
+	subscriptions *subdomain.Service
+	invoicer      SubscriptionInvoicer
+	discounts     DiscountManager
+	outbox        outbox.OutboxRepository
+	logger        *zap.Logger
+}
+
+// NewRenewalScheduler constructs a renewal scheduler.
+func NewRenewalScheduler(subscriptions *subdomain.Service, invoicer SubscriptionInvoicer, discounts DiscountManager, outboxRepo outbox.OutboxRepository, logger *zap.Logger) *RenewalScheduler {
+	return &RenewalScheduler{
+		subscriptions: subscriptions,
+		invoicer:      invoicer,
+		discounts:     discounts,
+		outbox:        outboxRepo,
+		logger:        logger.Named("billingcycle.renewal"),
+	}
+}
+
+// Run starts the periodic worker.
+func (s *RenewalScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.process(ctx, time.Now().UTC())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *RenewalScheduler) process(ctx context.Context, now time.Time) {
+	subs, err := s.subscriptions.ListRenewalsDue(ctx, subdomain.RenewalFilter{
+		At: now,
+		Statuses: []int32{
+			int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE),
+			int32(subdomain.SubscriptionStatusPastDue),
+		},
+		Limit: renewalBatchSize,
+	})
+	if err != nil {
+		s.logger.Error("failed to list subscriptions due for renewal", zap.Error(err))
+		return
+	}
+	for _, sub := range subs {
+		s.renew(ctx, sub, now)
+	}
+	s.cancelDue(ctx, now)
+}
+
+// cancelDue ends subscriptions whose cancellation at period end has come,
+// invoicing their final period first.
+func (s *RenewalScheduler) cancelDue(ctx context.Context, now time.Time) {
+	subs, err := s.subscriptions.ListCancellationsDue(ctx, subdomain.CancellationFilter{
+		At: now,
+		Statuses: []int32{
+			int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE),
+			int32(subdomain.SubscriptionStatusPaused),
+			int32(subdomain.SubscriptionStatusPastDue),
+			int32(subdomain.SubscriptionStatusUnpaid),
+		},
+		Limit: renewalBatchSize,
+	})
+	if err != nil {
+		s.logger.Error("failed to list subscriptions due for cancellation", zap.Error(err))
+		return
+	}
+	for _, sub := range subs {
+		cancelAt := *sub.CancelAt
+		var invoiceID string
+		if sub.CurrentPeriodEnd.After(sub.CurrentPeriodStart) && !sub.CurrentPeriodEnd.After(cancelAt) {
+			invoiceID, err = s.invoicer.InvoicePeriod(ctx, sub, sub.CurrentPeriodStart, sub.CurrentPeriodEnd)
+			if err != nil {
+				s.logger.Error("failed to invoice final period", zap.Error(err), zap.String("subscription_id", sub.ID))
+				continue
+			}
+		}
+		canceled, evt, err := s.subscriptions.Cancel(ctx, sub, cancelAt)
+		if err != nil {
+			s.logger.Error("failed to cancel subscription", zap.Error(err), zap.String("subscription_id", sub.ID))
+			continue
+		}
+		insertEvent(ctx, s.outbox, s.logger, evt)
+		s.emit(ctx, "subscription.canceled", canceled.TenantID, map[string]*structpb.Value{
+			"subscription_id": structpb.NewStringValue(canceled.ID),
+			"invoice_id":      structpb.NewStringValue(invoiceID),
+			"canceled_at":     structpb.NewStringValue(cancelAt.Format(time.RFC3339)),
+		})
+	}
+}
+
+// renew invoices and advances each ended period until the subscription's
+// current period contains now.
+func (s *RenewalScheduler) renew(ctx context.Context, sub subdomain.Subscription, now time.Time) {
+	for i := 0; i < maxCatchUpPeriods && !sub.CurrentPeriodEnd.After(now); i++ {
+		start, end := sub.CurrentPeriodStart, sub.CurrentPeriodEnd
+		invoiceID, err := s.invoicer.InvoicePeriod(ctx, sub, start, end)
+		if err != nil {
+			s.logger.Error("failed to invoice subscription period", zap.Error(err), zap.String("subscription_id", sub.ID))
+			return
+		}
+
+		renewal, err := s.subscriptions.Renew(ctx, sub)
+		if err != nil {
+			s.logger.Error("failed to renew subscription", zap.Error(err), zap.String("subscription_id", sub.ID))
+			return
+		}
+		sub = renewal.Subscription
+
+		if renewal.Downgraded {
+			s.emit(ctx, "subscription.downgraded", sub.TenantID, map[string]*structpb.Value{
+				"subscription_id":   structpb.NewStringValue(sub.ID),
+				"price_id":          structpb.NewStringValue(sub.PriceID),
+				"previous_price_id": structpb.NewStringValue(renewal.PreviousPriceID),
+				"effective_at":      structpb.NewStringValue(end.Format(time.RFC3339)),
+			})
+		}
+		for _, change := range renewal.QuantityChanges {
+			insertEvent(ctx, s.outbox, s.logger, subdomain.QuantityUpdatedEvent(sub, change, ""))
+		}
+		if renewal.Schedule != nil {
+			s.advanceSchedule(ctx, sub, *renewal.Schedule)
+		}
+		s.emit(ctx, "subscription.renewed", sub.TenantID, map[string]*structpb.Value{
+			"subscription_id":       structpb.NewStringValue(sub.ID),
+			"price_id":              structpb.NewStringValue(sub.PriceID),
+			"invoice_id":            structpb.NewStringValue(invoiceID),
+			"previous_period_start": structpb.NewStringValue(start.Format(time.RFC3339)),
+			"previous_period_end":   structpb.NewStringValue(end.Format(time.RFC3339)),
+			"period_start":          structpb.NewStringValue(sub.CurrentPeriodStart.Format(time.RFC3339)),
+			"period_end":            structpb.NewStringValue(sub.CurrentPeriodEnd.Format(time.RFC3339)),
+		})
+	}
+}
+
+// advanceSchedule swaps the discount of the phase that ended for the coupon of
+// the phase that started and emits the schedule's events. The invoice for the
+// period that ended was generated first, so it still carries the old discount.
+func (s *RenewalScheduler) advanceSchedule(ctx context.Context, sub subdomain.Subscription, progress subdomain.ScheduleProgress) {
+	schedule := progress.Schedule
+	if progress.PhaseStarted {
+		if schedule.DiscountID != "" {
+			if err := s.discounts.EndDiscount(ctx, sub.TenantID, schedule.DiscountID, sub.CurrentPeriodStart); err != nil {
+				s.logger.Error("failed to end schedule phase discount", zap.Error(err), zap.String("schedule_id", schedule.ID))
+			}
+			schedule.DiscountID = ""
+		}
+		if code := schedule.Phase().CouponCode; code != "" {
+			discount, err := s.discounts.Redeem(ctx, coupondomain.RedeemRequest{
+				TenantID:       sub.TenantID,
+				CouponCode:     code,
+				SubscriptionID: sub.ID,
+			})
+			if err != nil {
+				s.logger.Error("failed to redeem schedule phase coupon", zap.Error(err), zap.String("schedule_id", schedule.ID), zap.String("coupon_code", code))
+			} else {
+				schedule.DiscountID = discount.ID
+			}
+		}
+		if err := s.subscriptions.UpdateSchedule(ctx, schedule); err != nil {
+			s.logger.Error("failed to update subscription schedule", zap.Error(err), zap.String("schedule_id", schedule.ID))
+		}
+		insertEvent(ctx, s.outbox, s.logger, subdomain.PhaseStartedEvent(sub, schedule))
+	}
+	if progress.Completed {
+		insertEvent(ctx, s.outbox, s.logger, subdomain.ScheduleCompletedEvent(sub, schedule))
+	}
+}
+
+func (s *RenewalScheduler) emit(ctx context.Context, subject, tenantID string, data map[string]*structpb.Value) {
+	insertEvent(ctx, s.outbox, s.logger, newEvent(subject, tenantID, data))
+}
+
+func newEvent(subject, tenantID string, data map[string]*structpb.Value) *eventv1.Event {
+	return &eventv1.Event{
+		Subject:  subject,
+		TenantId: tenantID,
+		Data:     &structpb.Struct{Fields: data},
+	}
+}
+
+// insertEvent stores evt in the outbox for publishing.
+func insertEvent(ctx context.Context, repo outbox.OutboxRepository, logger *zap.Logger, evt *eventv1.Event) {
+	if err := repo.InsertOutboxEvent(ctx, &outbox.OutboxEvent{Subject: evt.GetSubject(), TenantID: evt.GetTenantId(), Event: evt}); err != nil {
+		logger.Error("failed to persist subscription event", zap.Error(err), zap.String("subject", evt.GetSubject()), zap.String("tenant_id", evt.GetTenantId()))
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/smallbiznis/corebilling/internal/billingcycle/repository"
 	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
 	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -48,7 +49,7 @@ func (s *Service) CloseBillingCycle(ctx context.Context, tenantID string) error
 	}
 
 	newStart := cycle.PeriodEnd
-	newEnd := newStart.AddDate(0, 1, 0)
+	newEnd := pricing.AddMonths(newStart, 1, cycleAnchorDay(cycle))
 
 	if err := s.repo.UpdateBillingCycle(ctx, UpdateBillingCycleParams{
 		TenantID:    tenantID,
@@ -62,6 +63,13 @@ func (s *Service) CloseBillingCycle(ctx context.Context, tenantID string) error
 	return nil
 }
 
+// cycleAnchorDay recovers the day of month the cycle is anchored to. At most
+// one boundary of a monthly cycle is clamped to a short month, so the later
+// day of the two is the anchor.
+func cycleAnchorDay(cycle BillingCycle) int {
+	return max(cycle.PeriodStart.Day(), cycle.PeriodEnd.Day())
+}
+
 func (s *Service) emitClosedEvent(ctx context.Context, cycle BillingCycle) error {
 	evt := &eventv1.Event{
 		Subject:  "billing_cycle.closed",
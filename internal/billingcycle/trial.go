@@ -0,0 +1,148 @@
+package billingcycle
+
+import (
+	"context"
+	"time"
+
+	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	subdomain "github.com/smallbiznis/corebilling/internal/subscription/domain"
+	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// trialReminderLead is how long before the trial ends subscription.trial_will_end is emitted.
+const trialReminderLead = 72 * time.Hour
+
+// PaymentMethodChecker reports whether a customer can be charged.
+type PaymentMethodChecker interface {
+	HasPaymentMethod(ctx context.Context, tenantID, customerID string) (bool, error)
+}
+
+// TrialScheduler ends expired trials: subscriptions whose customer has a
+// payment method on file are activated and invoiced for their first period,
+// the others, and those set to cancel, are canceled. Customers are reminded three days before.
+type TrialScheduler struct {
+	subscriptions  *subdomain.Service
+	invoicer       SubscriptionInvoicer
+	paymentMethods PaymentMethodChecker
+	outbox         outbox.OutboxRepository
+	logger         *zap.Logger
+}
+
+// NewTrialScheduler constructs a trial scheduler.
+func NewTrialScheduler(subscriptions *subdomain.Service, invoicer SubscriptionInvoicer, paymentMethods PaymentMethodChecker, outboxRepo outbox.OutboxRepository, logger *zap.Logger) *TrialScheduler {
+	return &TrialScheduler{
+		subscriptions:  subscriptions,
+		invoicer:       invoicer,
+		paymentMethods: paymentMethods,
+		outbox:         outboxRepo,
+		logger:         logger.Named("billingcycle.trial"),
+	}
+}
+
+// Run starts the periodic worker.
+func (s *TrialScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.process(ctx, time.Now().UTC())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *TrialScheduler) process(ctx context.Context, now time.Time) {
+	s.remind(ctx, now)
+	s.expire(ctx, now)
+}
+
+// remind emits subscription.trial_will_end once for trials ending within the lead time.
+func (s *TrialScheduler) remind(ctx context.Context, now time.Time) {
+	subs, err := s.subscriptions.ListTrialsEnding(ctx, subdomain.TrialFilter{
+		EndsAfter:  now,
+		EndsBy:     now.Add(trialReminderLead),
+		Status:     int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING),
+		Unreminded: true,
+		Limit:      renewalBatchSize,
+	})
+	if err != nil {
+		s.logger.Error("failed to list trials ending soon", zap.Error(err))
+		return
+	}
+	for _, sub := range subs {
+		s.emit(ctx, "subscription.trial_will_end", sub.TenantID, map[string]*structpb.Value{
+			"subscription_id": structpb.NewStringValue(sub.ID),
+			"customer_id":     structpb.NewStringValue(sub.CustomerID),
+			"trial_end_at":    structpb.NewStringValue(sub.TrialEndAt.Format(time.RFC3339)),
+		})
+		if _, err := s.subscriptions.MarkTrialReminderSent(ctx, sub, now); err != nil {
+			s.logger.Error("failed to record trial reminder", zap.Error(err), zap.String("subscription_id", sub.ID))
+		}
+	}
+}
+
+// expire ends every trial whose end has passed.
+func (s *TrialScheduler) expire(ctx context.Context, now time.Time) {
+	subs, err := s.subscriptions.ListTrialsEnding(ctx, subdomain.TrialFilter{
+		EndsBy: now,
+		Status: int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING),
+		Limit:  renewalBatchSize,
+	})
+	if err != nil {
+		s.logger.Error("failed to list expired trials", zap.Error(err))
+		return
+	}
+	for _, sub := range subs {
+		s.endTrial(ctx, sub)
+	}
+}
+
+func (s *TrialScheduler) endTrial(ctx context.Context, sub subdomain.Subscription) {
+	outcome := subdomain.TrialCanceled
+	if !sub.CancellationPending() {
+		hasMethod, err := s.paymentMethods.HasPaymentMethod(ctx, sub.TenantID, sub.CustomerID)
+		if err != nil {
+			s.logger.Error("failed to check payment methods", zap.Error(err), zap.String("subscription_id", sub.ID))
+			return
+		}
+		if hasMethod {
+			outcome = subdomain.TrialConverted
+		}
+	}
+
+	ended, statusEvent, err := s.subscriptions.EndTrial(ctx, sub, outcome)
+	if err != nil {
+		s.logger.Error("failed to end trial", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return
+	}
+	insertEvent(ctx, s.outbox, s.logger, statusEvent)
+
+	// The first period is billed in advance. When that fails the renewal
+	// still invoices the fee once the period ends.
+	var invoiceID string
+	if outcome == subdomain.TrialConverted {
+		invoiceID, err = s.invoicer.InvoicePeriod(ctx, ended, ended.CurrentPeriodStart, ended.CurrentPeriodEnd)
+		if err != nil {
+			s.logger.Error("failed to invoice first period", zap.Error(err), zap.String("subscription_id", sub.ID))
+		}
+	}
+
+	s.emit(ctx, "subscription.trial_ended", ended.TenantID, map[string]*structpb.Value{
+		"subscription_id": structpb.NewStringValue(ended.ID),
+		"customer_id":     structpb.NewStringValue(ended.CustomerID),
+		"trial_end_at":    structpb.NewStringValue(sub.TrialEndAt.Format(time.RFC3339)),
+		"outcome":         structpb.NewStringValue(string(outcome)),
+		"invoice_id":      structpb.NewStringValue(invoiceID),
+		"period_start":    structpb.NewStringValue(ended.CurrentPeriodStart.Format(time.RFC3339)),
+		"period_end":      structpb.NewStringValue(ended.CurrentPeriodEnd.Format(time.RFC3339)),
+	})
+}
+
+func (s *TrialScheduler) emit(ctx context.Context, subject, tenantID string, data map[string]*structpb.Value) {
+	insertEvent(ctx, s.outbox, s.logger, newEvent(subject, tenantID, data))
+}
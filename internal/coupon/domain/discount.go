@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smallbiznis/corebilling/internal/money"
+)
+
+// Validate checks that the coupon has exactly one kind of discount and a usable duration.
+func (c Coupon) Validate() error {
+	switch {
+	case c.Code == "":
+		return fmt.Errorf("%w: code required", ErrInvalidCoupon)
+	case c.PercentOff != 0 && c.AmountOffCents != 0:
+		return fmt.Errorf("%w: set either percent_off or amount_off_cents", ErrInvalidCoupon)
+	case c.PercentOff < 0 || c.PercentOff > 100:
+		return fmt.Errorf("%w: percent_off must be between 0 and 100", ErrInvalidCoupon)
+	case c.AmountOffCents < 0:
+		return fmt.Errorf("%w: amount_off_cents must be positive", ErrInvalidCoupon)
+	case c.PercentOff == 0 && c.AmountOffCents == 0:
+		return fmt.Errorf("%w: percent_off or amount_off_cents required", ErrInvalidCoupon)
+	case c.AmountOffCents > 0 && c.Currency == "":
+		return fmt.Errorf("%w: currency required for amount_off_cents", ErrInvalidCoupon)
+	}
+	switch c.Duration {
+	case DurationOnce, DurationForever:
+	case DurationRepeating:
+		if c.DurationInPeriods <= 0 {
+			return fmt.Errorf("%w: duration_in_periods required for repeating coupons", ErrInvalidCoupon)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported duration %q", ErrInvalidCoupon, c.Duration)
+	}
+	return nil
+}
+
+// Redeemable reports whether the coupon can be redeemed at the given time.
+func (c Coupon) Redeemable(at time.Time) bool {
+	if !c.Active {
+		return false
+	}
+	if c.RedeemBy != nil && at.After(*c.RedeemBy) {
+		return false
+	}
+	return c.MaxRedemptions == 0 || c.TimesRedeemed < c.MaxRedemptions
+}
+
+// Redeemable reports whether the promotion code can be used by customerID at the given time.
+func (p PromotionCode) Redeemable(customerID string, at time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.ExpiresAt != nil && at.After(*p.ExpiresAt) {
+		return false
+	}
+	if p.CustomerID != "" && p.CustomerID != customerID {
+		return false
+	}
+	return p.MaxRedemptions == 0 || p.TimesRedeemed < p.MaxRedemptions
+}
+
+// Applies reports whether the discount still covers another billing period.
+func (d Discount) Applies() bool {
+	if d.EndedAt != nil {
+		return false
+	}
+	switch d.Coupon.Duration {
+	case DurationOnce:
+		return d.PeriodsApplied == 0
+	case DurationRepeating:
+		return d.PeriodsApplied < d.Coupon.DurationInPeriods
+	default:
+		return true
+	}
+}
+
+// AmountOff returns the discount on a subtotal in cents, never exceeding it.
+// Amount-off coupons in another currency do not apply.
+func (c Coupon) AmountOff(subtotalCents int64, currency string) int64 {
+	if subtotalCents <= 0 {
+		return 0
+	}
+	var off int64
+	switch {
+	case c.PercentOff > 0:
+		// A subtotal beyond money.MaxCents cannot be discounted by a percentage.
+		amount := money.FromCents(subtotalCents).Percent(c.PercentOff)
+		if amount.Err() != nil {
+			return 0
+		}
+		off = amount.Cents()
+	case strings.EqualFold(c.Currency, currency):
+		off = c.AmountOffCents
+	}
+	if off > subtotalCents {
+		return subtotalCents
+	}
+	return off
+}
+
+// Description is the invoice line label for the discount.
+func (c Coupon) Description() string {
+	name := c.Name
+	if name == "" {
+		name = c.Code
+	}
+	if c.PercentOff > 0 {
+		return fmt.Sprintf("Discount: %s (%s%% off)", name, strconv.FormatFloat(c.PercentOff, 'f', -1, 64))
+	}
+	return fmt.Sprintf("Discount: %s", name)
+}
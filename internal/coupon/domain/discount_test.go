@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCouponValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		coupon  Coupon
+		wantErr bool
+	}{
+		{name: "percent forever", coupon: Coupon{Code: "TEN", PercentOff: 10, Duration: DurationForever}},
+		{name: "amount once", coupon: Coupon{Code: "FIVE", AmountOffCents: 500, Currency: "USD", Duration: DurationOnce}},
+		{name: "repeating", coupon: Coupon{Code: "R", PercentOff: 5, Duration: DurationRepeating, DurationInPeriods: 3}},
+		{name: "missing code", coupon: Coupon{PercentOff: 10, Duration: DurationOnce}, wantErr: true},
+		{name: "both kinds", coupon: Coupon{Code: "X", PercentOff: 10, AmountOffCents: 100, Currency: "USD", Duration: DurationOnce}, wantErr: true},
+		{name: "no discount", coupon: Coupon{Code: "X", Duration: DurationOnce}, wantErr: true},
+		{name: "percent over 100", coupon: Coupon{Code: "X", PercentOff: 120, Duration: DurationOnce}, wantErr: true},
+		{name: "amount without currency", coupon: Coupon{Code: "X", AmountOffCents: 100, Duration: DurationOnce}, wantErr: true},
+		{name: "repeating without periods", coupon: Coupon{Code: "X", PercentOff: 5, Duration: DurationRepeating}, wantErr: true},
+		{name: "unknown duration", coupon: Coupon{Code: "X", PercentOff: 5, Duration: "weekly"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.coupon.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidCoupon) {
+				t.Fatalf("expected ErrInvalidCoupon got %v", err)
+			}
+		})
+	}
+}
+
+func TestDiscountApplies(t *testing.T) {
+	ended := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		discount Discount
+		want     bool
+	}{
+		{name: "once unused", discount: Discount{Coupon: Coupon{Duration: DurationOnce}}, want: true},
+		{name: "once used", discount: Discount{PeriodsApplied: 1, Coupon: Coupon{Duration: DurationOnce}}},
+		{name: "repeating within", discount: Discount{PeriodsApplied: 2, Coupon: Coupon{Duration: DurationRepeating, DurationInPeriods: 3}}, want: true},
+		{name: "repeating exhausted", discount: Discount{PeriodsApplied: 3, Coupon: Coupon{Duration: DurationRepeating, DurationInPeriods: 3}}},
+		{name: "forever", discount: Discount{PeriodsApplied: 40, Coupon: Coupon{Duration: DurationForever}}, want: true},
+		{name: "ended", discount: Discount{EndedAt: &ended, Coupon: Coupon{Duration: DurationForever}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.discount.Applies(); got != tt.want {
+				t.Fatalf("expected %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCouponAmountOff(t *testing.T) {
+	tests := []struct {
+		name     string
+		coupon   Coupon
+		subtotal int64
+		currency string
+		want     int64
+	}{
+		{name: "percent", coupon: Coupon{PercentOff: 15}, subtotal: 1999, currency: "USD", want: 300},
+		{name: "amount", coupon: Coupon{AmountOffCents: 500, Currency: "USD"}, subtotal: 2000, currency: "usd", want: 500},
+		{name: "amount capped", coupon: Coupon{AmountOffCents: 500, Currency: "USD"}, subtotal: 300, currency: "USD", want: 300},
+		{name: "other currency", coupon: Coupon{AmountOffCents: 500, Currency: "EUR"}, subtotal: 2000, currency: "USD"},
+		{name: "empty subtotal", coupon: Coupon{PercentOff: 50}, subtotal: 0, currency: "USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.coupon.AmountOff(tt.subtotal, tt.currency); got != tt.want {
+				t.Fatalf("expected %d got %d", tt.want, got)
+			}
+		})
+	}
+}
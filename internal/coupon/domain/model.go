@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Duration controls how many billing periods a coupon discounts.
+type Duration string
+
+const (
+	DurationOnce      Duration = "once"
+	DurationRepeating Duration = "repeating"
+	DurationForever   Duration = "forever"
+)
+
+var (
+	// ErrCouponNotRedeemable is returned for inactive, expired or exhausted coupons and promotion codes.
+	ErrCouponNotRedeemable = errors.New("coupon is not redeemable")
+	// ErrCouponNotFound is returned when a coupon or promotion code does not exist.
+	ErrCouponNotFound = errors.New("coupon not found")
+	// ErrTargetRequired is returned when a redemption names neither a customer nor a subscription.
+	ErrTargetRequired = errors.New("customer_id or subscription_id required")
+	// ErrInvalidCoupon is returned when a coupon definition is inconsistent.
+	ErrInvalidCoupon = errors.New("invalid coupon")
+)
+
+// Coupon defines a reusable discount.
+type Coupon struct {
+	ID                string
+	TenantID          string
+	Code              string
+	Name              string
+	PercentOff        float64
+	AmountOffCents    int64
+	Currency          string
+	Duration          Duration
+	DurationInPeriods int32
+	MaxRedemptions    int32
+	TimesRedeemed     int32
+	RedeemBy          *time.Time
+	Active            bool
+	Metadata          map[string]interface{}
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// PromotionCode is a customer-facing code that redeems a coupon.
+type PromotionCode struct {
+	ID             string
+	TenantID       string
+	CouponID       string
+	Code           string
+	CustomerID     string
+	MaxRedemptions int32
+	TimesRedeemed  int32
+	ExpiresAt      *time.Time
+	Active         bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Discount attaches a redeemed coupon to a customer or a subscription.
+type Discount struct {
+	ID              string
+	TenantID        string
+	CouponID        string
+	PromotionCodeID string
+	CustomerID      string
+	SubscriptionID  string
+	Coupon          Coupon
+	PeriodsApplied  int32
+	StartAt         time.Time
+	EndedAt         *time.Time
+	CreatedAt       time.Time
+}
+
+// RedeemRequest identifies the coupon or promotion code to attach and its target.
+type RedeemRequest struct {
+	TenantID       string
+	CouponCode     string
+	PromotionCode  string
+	CustomerID     string
+	SubscriptionID string
+}
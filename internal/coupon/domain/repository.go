@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Repository persists coupons, promotion codes and discounts.
+type Repository interface {
+	CreateCoupon(ctx context.Context, coupon Coupon) error
+	GetCouponByCode(ctx context.Context, tenantID, code string) (Coupon, error)
+	GetCoupon(ctx context.Context, tenantID, id string) (Coupon, error)
+	CreatePromotionCode(ctx context.Context, code PromotionCode) error
+	GetPromotionCode(ctx context.Context, tenantID, code string) (PromotionCode, error)
+	// Redeem stores the discount and increments the redemption counters,
+	// failing with ErrCouponNotRedeemable when a limit has been reached.
+	Redeem(ctx context.Context, discount Discount) error
+	// ListActiveDiscounts returns discounts attached to the subscription or customer.
+	ListActiveDiscounts(ctx context.Context, tenantID, customerID, subscriptionID string) ([]Discount, error)
+	// EndDiscount stops an open discount from applying to later periods.
+	EndDiscount(ctx context.Context, tenantID, discountID string, at time.Time) error
+	// RevokeDiscount deletes a discount never applied to an invoice and gives
+	// its redemption back to the coupon and promotion code.
+	RevokeDiscount(ctx context.Context, tenantID, discountID string) error
+}
@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+	"go.uber.org/zap"
+)
+
+// Service manages coupons, promotion codes and their redemption.
+type Service struct {
+	repo   Repository
+	logger *zap.Logger
+
+	genID *snowflake.Node
+}
+
+// NewService constructs the coupon service.
+func NewService(repo Repository, logger *zap.Logger, genID *snowflake.Node) *Service {
+	return &Service{repo: repo, logger: logger.Named("coupon.service"), genID: genID}
+}
+
+// CreateCoupon validates and stores a coupon.
+func (s *Service) CreateCoupon(ctx context.Context, coupon Coupon) (Coupon, error) {
+	coupon.Currency = strings.ToUpper(coupon.Currency)
+	if err := coupon.Validate(); err != nil {
+		return Coupon{}, err
+	}
+	now := time.Now().UTC()
+	coupon.ID = s.genID.Generate().String()
+	coupon.Active = true
+	coupon.TimesRedeemed = 0
+	coupon.CreatedAt = now
+	coupon.UpdatedAt = now
+	if err := s.repo.CreateCoupon(ctx, coupon); err != nil {
+		s.logger.Error("create coupon", zap.Error(err))
+		return Coupon{}, err
+	}
+	s.logger.Info("coupon created", zap.String("id", coupon.ID), zap.String("code", coupon.Code))
+	return coupon, nil
+}
+
+// CreatePromotionCode issues a promotion code for an existing coupon.
+func (s *Service) CreatePromotionCode(ctx context.Context, code PromotionCode) (PromotionCode, error) {
+	if code.Code == "" || code.CouponID == "" {
+		return PromotionCode{}, fmt.Errorf("%w: code and coupon_id required", ErrInvalidCoupon)
+	}
+	if _, err := s.repo.GetCoupon(ctx, code.TenantID, code.CouponID); err != nil {
+		return PromotionCode{}, err
+	}
+	now := time.Now().UTC()
+	code.ID = s.genID.Generate().String()
+	code.Active = true
+	code.TimesRedeemed = 0
+	code.CreatedAt = now
+	code.UpdatedAt = now
+	if err := s.repo.CreatePromotionCode(ctx, code); err != nil {
+		s.logger.Error("create promotion code", zap.Error(err))
+		return PromotionCode{}, err
+	}
+	return code, nil
+}
+
+// Redeem attaches a coupon, directly or through a promotion code, to a
+// customer or subscription.
+func (s *Service) Redeem(ctx context.Context, req RedeemRequest) (Discount, error) {
+	if req.CustomerID == "" && req.SubscriptionID == "" {
+		return Discount{}, ErrTargetRequired
+	}
+	now := time.Now().UTC()
+
+	var promo PromotionCode
+	couponCode := req.CouponCode
+	if req.PromotionCode != "" {
+		var err error
+		promo, err = s.repo.GetPromotionCode(ctx, req.TenantID, req.PromotionCode)
+		if err != nil {
+			return Discount{}, err
+		}
+		if !promo.Redeemable(req.CustomerID, now) {
+			return Discount{}, ErrCouponNotRedeemable
+		}
+	}
+
+	var (
+		coupon Coupon
+		err    error
+	)
+	switch {
+	case promo.ID != "":
+		coupon, err = s.repo.GetCoupon(ctx, req.TenantID, promo.CouponID)
+	case couponCode != "":
+		coupon, err = s.repo.GetCouponByCode(ctx, req.TenantID, couponCode)
+	default:
+		return Discount{}, fmt.Errorf("%w: coupon_code or promotion_code required", ErrInvalidCoupon)
+	}
+	if err != nil {
+		return Discount{}, err
+	}
+	if !coupon.Redeemable(now) {
+		return Discount{}, ErrCouponNotRedeemable
+	}
+
+	discount := Discount{
+		ID:              s.genID.Generate().String(),
+		TenantID:        req.TenantID,
+		CouponID:        coupon.ID,
+		PromotionCodeID: promo.ID,
+		CustomerID:      req.CustomerID,
+		SubscriptionID:  req.SubscriptionID,
+		Coupon:          coupon,
+		StartAt:         now,
+		CreatedAt:       now,
+	}
+	if err := s.repo.Redeem(ctx, discount); err != nil {
+		return Discount{}, err
+	}
+	s.logger.Info("coupon redeemed",
+		zap.String("discount_id", discount.ID),
+		zap.String("coupon_id", coupon.ID),
+		zap.String("subscription_id", req.SubscriptionID),
+		zap.String("customer_id", req.CustomerID),
+	)
+	return discount, nil
+}
+
+// EndDiscount stops a discount at at, e.g. when the schedule phase it was
+// redeemed for ends. Invoices for periods already billed keep it.
+func (s *Service) EndDiscount(ctx context.Context, tenantID, discountID string, at time.Time) error {
+	if err := s.repo.EndDiscount(ctx, tenantID, discountID, at); err != nil {
+		s.logger.Error("end discount", zap.Error(err))
+		return err
+	}
+	s.logger.Info("discount ended", zap.String("discount_id", discountID))
+	return nil
+}
+
+// RevokeDiscount undoes a redemption whose subscription or customer could not
+// be set up, so it does not count against the coupon's limits.
+func (s *Service) RevokeDiscount(ctx context.Context, tenantID, discountID string) error {
+	if err := s.repo.RevokeDiscount(ctx, tenantID, discountID); err != nil {
+		s.logger.Error("revoke discount", zap.Error(err))
+		return err
+	}
+	s.logger.Info("discount revoked", zap.String("discount_id", discountID))
+	return nil
+}
@@ -0,0 +1,239 @@
+package coupon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/smallbiznis/corebilling/internal/coupon/domain"
+	"github.com/smallbiznis/corebilling/internal/headers"
+	"go.uber.org/fx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ModuleHTTP = fx.Invoke(RegisterHTTP)
+
+func RegisterHTTP(lc fx.Lifecycle, mux *runtime.ServeMux, svc *domain.Service) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := mux.HandlePath(http.MethodPost, "/v1/coupons", createCouponHandler(svc)); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPost, "/v1/promotion_codes", createPromotionCodeHandler(svc)); err != nil {
+				return err
+			}
+			return mux.HandlePath(http.MethodPost, "/v1/discounts", redeemHandler(svc))
+		},
+	})
+}
+
+type couponRequest struct {
+	Code              string                 `json:"code"`
+	Name              string                 `json:"name"`
+	PercentOff        float64                `json:"percent_off"`
+	AmountOffCents    int64                  `json:"amount_off_cents"`
+	Currency          string                 `json:"currency"`
+	Duration          string                 `json:"duration"`
+	DurationInPeriods int32                  `json:"duration_in_periods"`
+	MaxRedemptions    int32                  `json:"max_redemptions"`
+	RedeemBy          *time.Time             `json:"redeem_by"`
+	Metadata          map[string]interface{} `json:"metadata"`
+}
+
+type couponResponse struct {
+	ID                string     `json:"id"`
+	Code              string     `json:"code"`
+	Name              string     `json:"name,omitempty"`
+	PercentOff        float64    `json:"percent_off,omitempty"`
+	AmountOffCents    int64      `json:"amount_off_cents,omitempty"`
+	Currency          string     `json:"currency,omitempty"`
+	Duration          string     `json:"duration"`
+	DurationInPeriods int32      `json:"duration_in_periods,omitempty"`
+	MaxRedemptions    int32      `json:"max_redemptions,omitempty"`
+	TimesRedeemed     int32      `json:"times_redeemed"`
+	RedeemBy          *time.Time `json:"redeem_by,omitempty"`
+	Active            bool       `json:"active"`
+}
+
+type promotionCodeRequest struct {
+	CouponID       string     `json:"coupon_id"`
+	Code           string     `json:"code"`
+	CustomerID     string     `json:"customer_id"`
+	MaxRedemptions int32      `json:"max_redemptions"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
+
+type promotionCodeResponse struct {
+	ID             string     `json:"id"`
+	CouponID       string     `json:"coupon_id"`
+	Code           string     `json:"code"`
+	CustomerID     string     `json:"customer_id,omitempty"`
+	MaxRedemptions int32      `json:"max_redemptions,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	Active         bool       `json:"active"`
+}
+
+type redeemRequest struct {
+	CouponCode     string `json:"coupon_code"`
+	PromotionCode  string `json:"promotion_code"`
+	CustomerID     string `json:"customer_id"`
+	SubscriptionID string `json:"subscription_id"`
+}
+
+type discountResponse struct {
+	ID              string         `json:"id"`
+	CustomerID      string         `json:"customer_id,omitempty"`
+	SubscriptionID  string         `json:"subscription_id,omitempty"`
+	PromotionCodeID string         `json:"promotion_code_id,omitempty"`
+	Coupon          couponResponse `json:"coupon"`
+	StartAt         time.Time      `json:"start_at"`
+}
+
+func createCouponHandler(svc *domain.Service) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		tenantID := r.Header.Get(headers.HeaderTenantID)
+		if tenantID == "" {
+			writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+			return
+		}
+		var body couponRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+		coupon, err := svc.CreateCoupon(r.Context(), domain.Coupon{
+			TenantID:          tenantID,
+			Code:              body.Code,
+			Name:              body.Name,
+			PercentOff:        body.PercentOff,
+			AmountOffCents:    body.AmountOffCents,
+			Currency:          body.Currency,
+			Duration:          domain.Duration(strings.ToLower(body.Duration)),
+			DurationInPeriods: body.DurationInPeriods,
+			MaxRedemptions:    body.MaxRedemptions,
+			RedeemBy:          body.RedeemBy,
+			Metadata:          body.Metadata,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toCouponResponse(coupon))
+	}
+}
+
+func createPromotionCodeHandler(svc *domain.Service) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		tenantID := r.Header.Get(headers.HeaderTenantID)
+		if tenantID == "" {
+			writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+			return
+		}
+		var body promotionCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+		code, err := svc.CreatePromotionCode(r.Context(), domain.PromotionCode{
+			TenantID:       tenantID,
+			CouponID:       body.CouponID,
+			Code:           body.Code,
+			CustomerID:     body.CustomerID,
+			MaxRedemptions: body.MaxRedemptions,
+			ExpiresAt:      body.ExpiresAt,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, promotionCodeResponse{
+			ID:             code.ID,
+			CouponID:       code.CouponID,
+			Code:           code.Code,
+			CustomerID:     code.CustomerID,
+			MaxRedemptions: code.MaxRedemptions,
+			ExpiresAt:      code.ExpiresAt,
+			Active:         code.Active,
+		})
+	}
+}
+
+func redeemHandler(svc *domain.Service) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		tenantID := r.Header.Get(headers.HeaderTenantID)
+		if tenantID == "" {
+			writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+			return
+		}
+		var body redeemRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+		discount, err := svc.Redeem(r.Context(), domain.RedeemRequest{
+			TenantID:       tenantID,
+			CouponCode:     body.CouponCode,
+			PromotionCode:  body.PromotionCode,
+			CustomerID:     body.CustomerID,
+			SubscriptionID: body.SubscriptionID,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, discountResponse{
+			ID:              discount.ID,
+			CustomerID:      discount.CustomerID,
+			SubscriptionID:  discount.SubscriptionID,
+			PromotionCodeID: discount.PromotionCodeID,
+			Coupon:          toCouponResponse(discount.Coupon),
+			StartAt:         discount.StartAt,
+		})
+	}
+}
+
+func toCouponResponse(c domain.Coupon) couponResponse {
+	return couponResponse{
+		ID:                c.ID,
+		Code:              c.Code,
+		Name:              c.Name,
+		PercentOff:        c.PercentOff,
+		AmountOffCents:    c.AmountOffCents,
+		Currency:          c.Currency,
+		Duration:          string(c.Duration),
+		DurationInPeriods: c.DurationInPeriods,
+		MaxRedemptions:    c.MaxRedemptions,
+		TimesRedeemed:     c.TimesRedeemed,
+		RedeemBy:          c.RedeemBy,
+		Active:            c.Active,
+	}
+}
+
+// toStatus maps domain errors onto gRPC status codes for the HTTP response.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrCouponNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrCouponNotRedeemable):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, domain.ErrInvalidCoupon), errors.Is(err, domain.ErrTargetRequired):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(toStatus(err))
+	writeJSON(w, runtime.HTTPStatusFromCode(st.Code()), map[string]string{"error": st.Message()})
+}
@@ -0,0 +1,15 @@
+package coupon
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/smallbiznis/corebilling/internal/coupon/domain"
+	reposqlc "github.com/smallbiznis/corebilling/internal/coupon/repository/sqlc"
+)
+
+// Module wires coupon services.
+var Module = fx.Options(
+	fx.Provide(reposqlc.NewRepository),
+	fx.Provide(domain.NewService),
+	ModuleHTTP,
+)
@@ -0,0 +1,376 @@
+package sqlc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/smallbiznis/corebilling/internal/coupon/domain"
+)
+
+// Repository handles coupon persistence.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository constructs repository.
+func NewRepository(pool *pgxpool.Pool) domain.Repository {
+	return &Repository{pool: pool}
+}
+
+const couponColumns = `
+	id, tenant_id, code, COALESCE(name, ''), percent_off, amount_off_cents,
+	COALESCE(currency, ''), duration, duration_in_periods, max_redemptions,
+	times_redeemed, redeem_by, active, metadata, created_at, updated_at`
+
+const promotionCodeColumns = `
+	id, tenant_id, coupon_id, code, COALESCE(customer_id::TEXT, ''), max_redemptions,
+	times_redeemed, expires_at, active, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// CreateCoupon inserts a coupon.
+func (r *Repository) CreateCoupon(ctx context.Context, c domain.Coupon) error {
+	metadata, err := marshalJSON(c.Metadata)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO coupons (
+			id, tenant_id, code, name, percent_off, amount_off_cents, currency,
+			duration, duration_in_periods, max_redemptions, times_redeemed,
+			redeem_by, active, metadata, created_at, updated_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+	`,
+		c.ID,
+		c.TenantID,
+		c.Code,
+		nullIfEmpty(c.Name),
+		c.PercentOff,
+		c.AmountOffCents,
+		nullIfEmpty(c.Currency),
+		string(c.Duration),
+		c.DurationInPeriods,
+		c.MaxRedemptions,
+		c.TimesRedeemed,
+		c.RedeemBy,
+		c.Active,
+		metadata,
+		c.CreatedAt,
+		c.UpdatedAt,
+	)
+	return err
+}
+
+// GetCoupon fetches a coupon by id.
+func (r *Repository) GetCoupon(ctx context.Context, tenantID, id string) (domain.Coupon, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+couponColumns+` FROM coupons WHERE tenant_id=$1 AND id=$2`, tenantID, id)
+	return scanCoupon(row)
+}
+
+// GetCouponByCode fetches a coupon by its tenant-unique code.
+func (r *Repository) GetCouponByCode(ctx context.Context, tenantID, code string) (domain.Coupon, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+couponColumns+` FROM coupons WHERE tenant_id=$1 AND code=$2`, tenantID, code)
+	return scanCoupon(row)
+}
+
+// CreatePromotionCode inserts a promotion code.
+func (r *Repository) CreatePromotionCode(ctx context.Context, p domain.PromotionCode) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO promotion_codes (
+			id, tenant_id, coupon_id, code, customer_id, max_redemptions,
+			times_redeemed, expires_at, active, created_at, updated_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+	`,
+		p.ID,
+		p.TenantID,
+		p.CouponID,
+		p.Code,
+		nullIfEmpty(p.CustomerID),
+		p.MaxRedemptions,
+		p.TimesRedeemed,
+		p.ExpiresAt,
+		p.Active,
+		p.CreatedAt,
+		p.UpdatedAt,
+	)
+	return err
+}
+
+// GetPromotionCode fetches a promotion code by code.
+func (r *Repository) GetPromotionCode(ctx context.Context, tenantID, code string) (domain.PromotionCode, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+promotionCodeColumns+` FROM promotion_codes WHERE tenant_id=$1 AND code=$2`, tenantID, code)
+	var p domain.PromotionCode
+	if err := row.Scan(
+		&p.ID,
+		&p.TenantID,
+		&p.CouponID,
+		&p.Code,
+		&p.CustomerID,
+		&p.MaxRedemptions,
+		&p.TimesRedeemed,
+		&p.ExpiresAt,
+		&p.Active,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.PromotionCode{}, domain.ErrCouponNotFound
+		}
+		return domain.PromotionCode{}, err
+	}
+	return p, nil
+}
+
+// Redeem stores a discount and bumps redemption counters in one transaction.
+// The conditional updates keep max_redemptions exact under concurrency.
+func (r *Repository) Redeem(ctx context.Context, d domain.Discount) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE coupons SET times_redeemed = times_redeemed + 1, updated_at = $3
+		WHERE tenant_id=$1 AND id=$2 AND active
+		  AND (max_redemptions = 0 OR times_redeemed < max_redemptions)
+	`, d.TenantID, d.CouponID, d.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrCouponNotRedeemable
+	}
+
+	if d.PromotionCodeID != "" {
+		tag, err = tx.Exec(ctx, `
+			UPDATE promotion_codes SET times_redeemed = times_redeemed + 1, updated_at = $3
+			WHERE tenant_id=$1 AND id=$2 AND active
+			  AND (max_redemptions = 0 OR times_redeemed < max_redemptions)
+		`, d.TenantID, d.PromotionCodeID, d.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return domain.ErrCouponNotRedeemable
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO discounts (
+			id, tenant_id, coupon_id, promotion_code_id, customer_id,
+			subscription_id, periods_applied, start_at, created_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`,
+		d.ID,
+		d.TenantID,
+		d.CouponID,
+		nullIfEmpty(d.PromotionCodeID),
+		nullIfEmpty(d.CustomerID),
+		nullIfEmpty(d.SubscriptionID),
+		d.PeriodsApplied,
+		d.StartAt,
+		d.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListActiveDiscounts returns open discounts on the subscription or, for
+// customer-level discounts, on the customer. Subscription discounts come first.
+func (r *Repository) ListActiveDiscounts(ctx context.Context, tenantID, customerID, subscriptionID string) ([]domain.Discount, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT d.id, d.tenant_id, d.coupon_id, COALESCE(d.promotion_code_id::TEXT, ''),
+		       COALESCE(d.customer_id::TEXT, ''), COALESCE(d.subscription_id::TEXT, ''),
+		       d.periods_applied, d.start_at, d.ended_at, d.created_at,
+		       c.id, c.tenant_id, c.code, COALESCE(c.name, ''), c.percent_off, c.amount_off_cents,
+		       COALESCE(c.currency, ''), c.duration, c.duration_in_periods, c.max_redemptions,
+		       c.times_redeemed, c.redeem_by, c.active, c.metadata, c.created_at, c.updated_at
+		FROM discounts d
+		JOIN coupons c ON c.id = d.coupon_id
+		WHERE d.tenant_id=$1 AND d.ended_at IS NULL
+		  AND (d.subscription_id::TEXT = $2 OR (d.subscription_id IS NULL AND d.customer_id::TEXT = $3))
+		ORDER BY (d.subscription_id IS NULL), d.created_at
+	`, tenantID, subscriptionID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var discounts []domain.Discount
+	for rows.Next() {
+		var d domain.Discount
+		var metadata []byte
+		c := &d.Coupon
+		if err := rows.Scan(
+			&d.ID,
+			&d.TenantID,
+			&d.CouponID,
+			&d.PromotionCodeID,
+			&d.CustomerID,
+			&d.SubscriptionID,
+			&d.PeriodsApplied,
+			&d.StartAt,
+			&d.EndedAt,
+			&d.CreatedAt,
+			&c.ID,
+			&c.TenantID,
+			&c.Code,
+			&c.Name,
+			&c.PercentOff,
+			&c.AmountOffCents,
+			&c.Currency,
+			&c.Duration,
+			&c.DurationInPeriods,
+			&c.MaxRedemptions,
+			&c.TimesRedeemed,
+			&c.RedeemBy,
+			&c.Active,
+			&metadata,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		c.Metadata = jsonToMap(metadata)
+		discounts = append(discounts, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return discounts, nil
+}
+
+// EndDiscount sets ended_at on an open discount.
+func (r *Repository) EndDiscount(ctx context.Context, tenantID, discountID string, at time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE discounts SET ended_at = $3
+		WHERE tenant_id = $1 AND id = $2 AND ended_at IS NULL
+	`, tenantID, discountID, at)
+	return err
+}
+
+// RevokeDiscount deletes an unapplied discount and decrements the redemption
+// counters Redeem incremented for it.
+func (r *Repository) RevokeDiscount(ctx context.Context, tenantID, discountID string) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var couponID, promotionCodeID string
+	err = tx.QueryRow(ctx, `
+		DELETE FROM discounts
+		WHERE tenant_id=$1 AND id=$2 AND periods_applied = 0
+		RETURNING coupon_id::TEXT, COALESCE(promotion_code_id::TEXT, '')
+	`, tenantID, discountID).Scan(&couponID, &promotionCodeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE coupons SET times_redeemed = times_redeemed - 1
+		WHERE tenant_id=$1 AND id=$2 AND times_redeemed > 0
+	`, tenantID, couponID); err != nil {
+		return err
+	}
+	if promotionCodeID != "" {
+		if _, err := tx.Exec(ctx, `
+			UPDATE promotion_codes SET times_redeemed = times_redeemed - 1
+			WHERE tenant_id=$1 AND id=$2 AND times_redeemed > 0
+		`, tenantID, promotionCodeID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkAppliedTx increments periods_applied and ends discounts whose duration
+// is used up, in tx, for callers storing the invoice that applied them.
+func MarkAppliedTx(ctx context.Context, tx pgx.Tx, discountIDs []string, at time.Time) error {
+	if len(discountIDs) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE discounts d SET
+			periods_applied = d.periods_applied + 1,
+			ended_at = CASE
+				WHEN c.duration = 'once' THEN $2
+				WHEN c.duration = 'repeating' AND d.periods_applied + 1 >= c.duration_in_periods THEN $2
+				ELSE d.ended_at
+			END
+		FROM coupons c
+		WHERE c.id = d.coupon_id AND d.id = ANY($1::BIGINT[])
+	`, "{"+strings.Join(discountIDs, ",")+"}", at)
+	return err
+}
+
+func scanCoupon(row rowScanner) (domain.Coupon, error) {
+	var c domain.Coupon
+	var metadata []byte
+	if err := row.Scan(
+		&c.ID,
+		&c.TenantID,
+		&c.Code,
+		&c.Name,
+		&c.PercentOff,
+		&c.AmountOffCents,
+		&c.Currency,
+		&c.Duration,
+		&c.DurationInPeriods,
+		&c.MaxRedemptions,
+		&c.TimesRedeemed,
+		&c.RedeemBy,
+		&c.Active,
+		&metadata,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Coupon{}, domain.ErrCouponNotFound
+		}
+		return domain.Coupon{}, err
+	}
+	c.Metadata = jsonToMap(metadata)
+	return c, nil
+}
+
+func marshalJSON(value map[string]interface{}) ([]byte, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
+
+func jsonToMap(value []byte) map[string]interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(value, &data); err != nil {
+		return nil
+	}
+	return data
+}
+
+func nullIfEmpty(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+var _ domain.Repository = (*Repository)(nil)
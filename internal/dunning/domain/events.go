@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"time"
+
+	invoicedomain "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	paymentdomain "github.com/smallbiznis/corebilling/internal/payment/domain"
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// stepActionRetry is the action of dunning steps that retry the payment; the
+// last step carries the policy's final action instead.
+const stepActionRetry = "retry"
+
+// paymentFailedEvent builds invoice.payment_failed, telling the customer a
+// payment did not go through and when it will be retried.
+func paymentFailedEvent(inv invoicedomain.Invoice, c Case, attempt paymentdomain.Attempt) *eventv1.Event {
+	nextRetryAt := ""
+	if c.NextRetryAt != nil {
+		nextRetryAt = c.NextRetryAt.Format(time.RFC3339)
+	}
+	return invoiceEvent("invoice.payment_failed", inv, map[string]*structpb.Value{
+		"payment_attempt_id": structpb.NewStringValue(attempt.ID),
+		"payment_method_id":  structpb.NewStringValue(attempt.PaymentMethodID),
+		"failure_reason":     structpb.NewStringValue(attempt.FailureReason),
+		"attempt_count":      structpb.NewNumberValue(float64(c.Failures)),
+		"next_retry_at":      structpb.NewStringValue(nextRetryAt),
+	})
+}
+
+// stepEvent builds invoice.dunning.step for step number c.Failures of the
+// case: a payment retry, or the final action once retries are exhausted.
+func stepEvent(inv invoicedomain.Invoice, c Case, action string) *eventv1.Event {
+	return invoiceEvent("invoice.dunning.step", inv, map[string]*structpb.Value{
+		"dunning_case_id": structpb.NewStringValue(c.ID),
+		"step":            structpb.NewNumberValue(float64(c.Failures)),
+		"action":          structpb.NewStringValue(action),
+	})
+}
+
+func invoiceEvent(subject string, inv invoicedomain.Invoice, data map[string]*structpb.Value) *eventv1.Event {
+	data["invoice_id"] = structpb.NewStringValue(inv.ID)
+	data["customer_id"] = structpb.NewStringValue(inv.CustomerID)
+	data["subscription_id"] = structpb.NewStringValue(inv.SubscriptionID)
+	data["amount_due_cents"] = structpb.NewNumberValue(float64(inv.TotalCents))
+	data["currency"] = structpb.NewStringValue(inv.CurrencyCode)
+	return &eventv1.Event{
+		Subject:  subject,
+		TenantId: inv.TenantID,
+		Data:     &structpb.Struct{Fields: data},
+	}
+}
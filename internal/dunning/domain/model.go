@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FinalAction is applied to the subscription of an invoice that is still
+// unpaid after the last retry.
+type FinalAction string
+
+const (
+	// FinalActionUnpaid leaves the subscription unpaid: it stops renewing
+	// until the invoice is paid.
+	FinalActionUnpaid FinalAction = "unpaid"
+	// FinalActionCancel cancels the subscription immediately.
+	FinalActionCancel FinalAction = "cancel"
+	// FinalActionPause pauses the subscription without a resume date.
+	FinalActionPause FinalAction = "pause"
+)
+
+// maxRetries bounds the retry schedule of a policy.
+const maxRetries = 10
+
+var (
+	// ErrInvalidPolicy is returned when a dunning policy is inconsistent.
+	ErrInvalidPolicy = errors.New("invalid dunning policy")
+	// ErrInvoiceNotPayable is returned for payments reported on invoices that
+	// are not open.
+	ErrInvoiceNotPayable = errors.New("invoice is not open")
+)
+
+// ParseFinalAction validates a final action, defaulting to unpaid.
+func ParseFinalAction(raw string) (FinalAction, error) {
+	switch a := FinalAction(strings.ToLower(raw)); a {
+	case "":
+		return FinalActionUnpaid, nil
+	case FinalActionUnpaid, FinalActionCancel, FinalActionPause:
+		return a, nil
+	}
+	return "", fmt.Errorf("%w: unsupported final_action %q", ErrInvalidPolicy, raw)
+}
+
+// Policy is a tenant's dunning configuration. RetrySchedule holds the wait
+// before each retry, counted from the failure of the previous attempt.
+type Policy struct {
+	TenantID      string
+	RetrySchedule []time.Duration
+	FinalAction   FinalAction
+	UpdatedAt     time.Time
+}
+
+// DefaultPolicy applies to tenants that have not configured dunning: three
+// retries 3, 5 and 7 days apart, then the subscription is left unpaid.
+func DefaultPolicy(tenantID string) Policy {
+	day := 24 * time.Hour
+	return Policy{
+		TenantID:      tenantID,
+		RetrySchedule: []time.Duration{3 * day, 5 * day, 7 * day},
+		FinalAction:   FinalActionUnpaid,
+	}
+}
+
+// Validate checks the retry schedule and final action.
+func (p Policy) Validate() error {
+	if len(p.RetrySchedule) > maxRetries {
+		return fmt.Errorf("%w: at most %d retries", ErrInvalidPolicy, maxRetries)
+	}
+	for _, wait := range p.RetrySchedule {
+		if wait <= 0 {
+			return fmt.Errorf("%w: retry waits must be positive", ErrInvalidPolicy)
+		}
+	}
+	if _, err := ParseFinalAction(string(p.FinalAction)); err != nil || p.FinalAction == "" {
+		return fmt.Errorf("%w: unsupported final_action %q", ErrInvalidPolicy, p.FinalAction)
+	}
+	return nil
+}
+
+// NextRetry returns when to retry an invoice whose payment has failed
+// failures times, the last at failedAt. It reports false once the schedule is
+// exhausted.
+func (p Policy) NextRetry(failures int, failedAt time.Time) (time.Time, bool) {
+	if failures < 1 || failures > len(p.RetrySchedule) {
+		return time.Time{}, false
+	}
+	return failedAt.Add(p.RetrySchedule[failures-1]), true
+}
+
+// CaseStatus tracks the collection of one invoice.
+type CaseStatus int16
+
+const (
+	CaseStatusUnspecified CaseStatus = 0
+	// CaseStatusOpen cases have a retry scheduled or in flight.
+	CaseStatusOpen CaseStatus = 1
+	// CaseStatusRecovered cases ended with the invoice paid or otherwise settled.
+	CaseStatusRecovered CaseStatus = 2
+	// CaseStatusExhausted cases ran out of retries.
+	CaseStatusExhausted CaseStatus = 3
+)
+
+var caseStatusNames = map[CaseStatus]string{
+	CaseStatusOpen:      "open",
+	CaseStatusRecovered: "recovered",
+	CaseStatusExhausted: "exhausted",
+}
+
+func (s CaseStatus) String() string {
+	return caseStatusNames[s]
+}
+
+// Case follows an invoice from its first failed payment until it is paid or
+// the retries run out.
+type Case struct {
+	ID             string
+	TenantID       string
+	InvoiceID      string
+	CustomerID     string
+	SubscriptionID string
+	Status         CaseStatus
+	// Failures counts the failed payments, so the retry in flight or
+	// scheduled next is retry number Failures.
+	Failures          int
+	LastFailureReason string
+	// NextRetryAt is when the next retry is due. It is nil while a retry
+	// awaits its result and once the case is closed.
+	NextRetryAt *time.Time
+	// FinalAction is the action applied when the case was exhausted.
+	FinalAction FinalAction
+	// FinalActionAt is when the final action was applied. It is nil while
+	// the action of an exhausted case is still pending.
+	FinalActionAt *time.Time
+	ClosedAt      *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// FinalActionPending reports whether the case ran out of retries but its final
+// action has not been applied yet.
+func (c Case) FinalActionPending() bool {
+	return c.Status == CaseStatusExhausted && c.FinalActionAt == nil
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{name: "default", policy: DefaultPolicy("tenant")},
+		{name: "no retries", policy: Policy{FinalAction: FinalActionCancel}},
+		{name: "pause", policy: Policy{RetrySchedule: []time.Duration{day}, FinalAction: FinalActionPause}},
+		{name: "zero wait", policy: Policy{RetrySchedule: []time.Duration{day, 0}, FinalAction: FinalActionUnpaid}, wantErr: true},
+		{name: "too many retries", policy: Policy{RetrySchedule: make([]time.Duration, maxRetries+1), FinalAction: FinalActionUnpaid}, wantErr: true},
+		{name: "missing final action", policy: Policy{RetrySchedule: []time.Duration{day}}, wantErr: true},
+		{name: "unknown final action", policy: Policy{FinalAction: "refund"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPolicy) {
+				t.Fatalf("expected ErrInvalidPolicy got %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicyNextRetry(t *testing.T) {
+	failedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	policy := DefaultPolicy("tenant")
+
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Time
+		wantOK   bool
+	}{
+		{name: "first failure", failures: 1, want: failedAt.AddDate(0, 0, 3), wantOK: true},
+		{name: "second failure", failures: 2, want: failedAt.AddDate(0, 0, 5), wantOK: true},
+		{name: "last retry", failures: 3, want: failedAt.AddDate(0, 0, 7), wantOK: true},
+		{name: "exhausted", failures: 4},
+		{name: "no failure", failures: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := policy.NextRetry(tt.failures, failedAt)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Fatalf("expected %s %v got %s %v", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+
+	if _, err := ParseFinalAction("Cancel"); err != nil {
+		t.Fatalf("expected final action to parse, got %v", err)
+	}
+	if action, _ := ParseFinalAction(""); action != FinalActionUnpaid {
+		t.Fatalf("expected unpaid default got %q", action)
+	}
+}
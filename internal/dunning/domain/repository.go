@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+)
+
+// RetryFilter selects open cases whose next retry is due.
+type RetryFilter struct {
+	At    time.Time
+	Limit int
+}
+
+// Repository persists dunning policies and cases.
+type Repository interface {
+	// GetPolicy returns the tenant's policy, reporting false when none is stored.
+	GetPolicy(ctx context.Context, tenantID string) (Policy, bool, error)
+	// SavePolicy creates or replaces the tenant's policy.
+	SavePolicy(ctx context.Context, policy Policy) error
+	// CreateCase inserts the case and stores evts in the outbox in the same
+	// transaction.
+	CreateCase(ctx context.Context, c Case, evts ...*eventv1.Event) error
+	// UpdateCase saves the case and stores evts in the outbox in the same
+	// transaction.
+	UpdateCase(ctx context.Context, c Case, evts ...*eventv1.Event) error
+	// GetOpenCase returns the open case of an invoice, reporting false when
+	// the invoice is not in dunning.
+	GetOpenCase(ctx context.Context, tenantID, invoiceID string) (Case, bool, error)
+	// HasOpenCases reports whether any invoice of the subscription is in dunning.
+	HasOpenCases(ctx context.Context, tenantID, subscriptionID string) (bool, error)
+	// ListRetriesDue returns open cases with NextRetryAt at or before At.
+	ListRetriesDue(ctx context.Context, filter RetryFilter) ([]Case, error)
+	// ListFinalActionsPending returns exhausted cases whose final action has
+	// not been applied, oldest first.
+	ListFinalActionsPending(ctx context.Context, limit int) ([]Case, error)
+}
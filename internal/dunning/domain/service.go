@@ -0,0 +1,346 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+	invoicedomain "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	paymentdomain "github.com/smallbiznis/corebilling/internal/payment/domain"
+	subdomain "github.com/smallbiznis/corebilling/internal/subscription/domain"
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
+	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
+	"go.uber.org/zap"
+)
+
+// Service runs dunning: it schedules retries for invoices whose payment
+// failed, moves their subscriptions past due and applies the tenant's final
+// action once the retries run out. Events of a case are stored with it; the
+// events of other changes are returned for the caller to publish.
+type Service struct {
+	repo          Repository
+	invoices      *invoicedomain.Service
+	subscriptions *subdomain.Service
+	logger        *zap.Logger
+
+	genID *snowflake.Node
+}
+
+// NewService constructs the dunning service.
+func NewService(repo Repository, invoices *invoicedomain.Service, subscriptions *subdomain.Service, logger *zap.Logger, genID *snowflake.Node) *Service {
+	return &Service{
+		repo:          repo,
+		invoices:      invoices,
+		subscriptions: subscriptions,
+		logger:        logger.Named("dunning.service"),
+		genID:         genID,
+	}
+}
+
+// GetPolicy returns the tenant's dunning policy, or the default policy when
+// the tenant has not configured one.
+func (s *Service) GetPolicy(ctx context.Context, tenantID string) (Policy, error) {
+	policy, ok, err := s.repo.GetPolicy(ctx, tenantID)
+	if err != nil {
+		return Policy{}, err
+	}
+	if !ok {
+		return DefaultPolicy(tenantID), nil
+	}
+	return policy, nil
+}
+
+// UpdatePolicy validates and stores the tenant's dunning policy. Open cases
+// follow the new schedule from their next failure on.
+func (s *Service) UpdatePolicy(ctx context.Context, policy Policy) (Policy, error) {
+	if err := policy.Validate(); err != nil {
+		return Policy{}, err
+	}
+	policy.UpdatedAt = time.Now().UTC()
+	if err := s.repo.SavePolicy(ctx, policy); err != nil {
+		s.logger.Error("save dunning policy", zap.Error(err))
+		return Policy{}, err
+	}
+	s.logger.Info("dunning policy updated", zap.String("tenant_id", policy.TenantID), zap.Int("retries", len(policy.RetrySchedule)))
+	return policy, nil
+}
+
+// PaymentFailed records a failed attempt against the invoice's dunning case,
+// opening one on the first failure, and schedules the next retry. Once the
+// schedule is exhausted the case is closed and the final action applied. All
+// events are stored with the case. A final action that fails stays pending
+// and is retried by the dunning worker.
+func (s *Service) PaymentFailed(ctx context.Context, attempt paymentdomain.Attempt) (Case, error) {
+	inv, err := s.openInvoice(ctx, attempt)
+	if err != nil {
+		return Case{}, err
+	}
+	policy, err := s.GetPolicy(ctx, inv.TenantID)
+	if err != nil {
+		return Case{}, err
+	}
+	c, found, err := s.repo.GetOpenCase(ctx, inv.TenantID, inv.ID)
+	if err != nil {
+		return Case{}, err
+	}
+	now := time.Now().UTC()
+	if !found {
+		c = Case{
+			ID:             s.genID.Generate().String(),
+			TenantID:       inv.TenantID,
+			InvoiceID:      inv.ID,
+			CustomerID:     inv.CustomerID,
+			SubscriptionID: inv.SubscriptionID,
+			Status:         CaseStatusOpen,
+			CreatedAt:      now,
+		}
+	}
+	c.Failures++
+	c.LastFailureReason = attempt.FailureReason
+	c.NextRetryAt = nil
+	c.UpdatedAt = now
+
+	sub, hasSub, err := s.subscription(ctx, inv)
+	if err != nil {
+		return Case{}, err
+	}
+	if next, ok := policy.NextRetry(c.Failures, attempt.AttemptedAt); ok {
+		c.NextRetryAt = &next
+	}
+	evts := []*eventv1.Event{paymentFailedEvent(inv, c, attempt)}
+	if hasSub && subscriptionv1.SubscriptionStatus(sub.Status) == subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE {
+		var evt *eventv1.Event
+		if sub, evt, err = s.subscriptions.MarkPastDue(ctx, sub); err != nil {
+			return Case{}, err
+		}
+		evts = append(evts, evt)
+	}
+	if c.NextRetryAt == nil {
+		c.Status = CaseStatusExhausted
+		c.FinalAction = policy.FinalAction
+		c.ClosedAt = &now
+		evts = append(evts, stepEvent(inv, c, string(policy.FinalAction)))
+	}
+
+	// The case is saved with its events before the final action is applied,
+	// so a failure reported again cannot apply it twice. Until the action is
+	// applied the exhausted case has no FinalActionAt.
+	if found {
+		err = s.repo.UpdateCase(ctx, c, evts...)
+	} else {
+		err = s.repo.CreateCase(ctx, c, evts...)
+	}
+	if err != nil {
+		s.logger.Error("save dunning case", zap.Error(err))
+		return Case{}, err
+	}
+	s.logger.Info("invoice payment failed",
+		zap.String("invoice_id", inv.ID),
+		zap.Int("failures", c.Failures),
+		zap.String("status", c.Status.String()),
+	)
+	if c.FinalActionPending() {
+		applied, err := s.applyFinalAction(ctx, c, sub, hasSub)
+		if err != nil {
+			// The case stays pending; the dunning worker retries the action.
+			return c, nil
+		}
+		c = applied
+	}
+	return c, nil
+}
+
+// ListFinalActionsPending returns exhausted cases whose final action has not
+// been applied yet.
+func (s *Service) ListFinalActionsPending(ctx context.Context, limit int) ([]Case, error) {
+	return s.repo.ListFinalActionsPending(ctx, limit)
+}
+
+// ApplyFinalAction applies the final action of an exhausted case whose action
+// is still pending and marks it applied. The action's event is stored with
+// the case.
+func (s *Service) ApplyFinalAction(ctx context.Context, c Case) (Case, error) {
+	if !c.FinalActionPending() {
+		return c, nil
+	}
+	var sub subdomain.Subscription
+	hasSub := c.SubscriptionID != ""
+	if hasSub {
+		var err error
+		if sub, err = s.subscriptions.Get(ctx, c.SubscriptionID); err != nil {
+			return Case{}, err
+		}
+	}
+	return s.applyFinalAction(ctx, c, sub, hasSub)
+}
+
+func (s *Service) applyFinalAction(ctx context.Context, c Case, sub subdomain.Subscription, hasSub bool) (Case, error) {
+	now := time.Now().UTC()
+	var evts []*eventv1.Event
+	if hasSub {
+		evt, err := s.finalAction(ctx, sub, c.FinalAction, now)
+		if err != nil {
+			s.logger.Error("apply dunning final action", zap.Error(err), zap.String("subscription_id", sub.ID))
+			return Case{}, err
+		}
+		if evt != nil {
+			evts = append(evts, evt)
+		}
+	}
+	c.FinalActionAt = &now
+	c.UpdatedAt = now
+	if err := s.repo.UpdateCase(ctx, c, evts...); err != nil {
+		s.logger.Error("save dunning case", zap.Error(err))
+		return Case{}, err
+	}
+	return c, nil
+}
+
+// PaymentSucceeded marks the invoice paid, closes its dunning case and
+// reactivates its subscription when no other invoice of it is in dunning.
+func (s *Service) PaymentSucceeded(ctx context.Context, attempt paymentdomain.Attempt) ([]*eventv1.Event, error) {
+	inv, err := s.openInvoice(ctx, attempt)
+	if err != nil {
+		return nil, err
+	}
+	inv, evt, err := s.invoices.MarkPaid(ctx, inv, attempt.AttemptedAt)
+	if err != nil {
+		return nil, err
+	}
+	evts := []*eventv1.Event{evt}
+
+	c, found, err := s.repo.GetOpenCase(ctx, inv.TenantID, inv.ID)
+	if err != nil {
+		return nil, err
+	}
+	var recovered []*eventv1.Event
+	if found {
+		recovered, err = s.close(ctx, c)
+	} else {
+		// Invoices paid after dunning gave up reactivate unpaid subscriptions.
+		recovered, err = s.recover(ctx, inv.TenantID, inv.SubscriptionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(evts, recovered...), nil
+}
+
+// ListRetriesDue returns open cases whose next retry is due at at.
+func (s *Service) ListRetriesDue(ctx context.Context, at time.Time, limit int) ([]Case, error) {
+	return s.repo.ListRetriesDue(ctx, RetryFilter{At: at, Limit: limit})
+}
+
+// Retry starts the scheduled retry of a case. Its invoice.dunning.step event,
+// stored with the case, asks the payment integration to charge the invoice
+// again and report the outcome as a payment attempt. Cases of invoices that
+// were settled some other way are closed instead.
+func (s *Service) Retry(ctx context.Context, c Case) ([]*eventv1.Event, error) {
+	inv, err := s.invoices.Get(ctx, c.InvoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoicev1.InvoiceStatus(inv.Status) != invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN {
+		return s.close(ctx, c)
+	}
+	c.NextRetryAt = nil
+	c.UpdatedAt = time.Now().UTC()
+	if err := s.repo.UpdateCase(ctx, c, stepEvent(inv, c, stepActionRetry)); err != nil {
+		s.logger.Error("save dunning case", zap.Error(err))
+		return nil, err
+	}
+	s.logger.Info("invoice payment retry started", zap.String("invoice_id", c.InvoiceID), zap.Int("step", c.Failures))
+	return nil, nil
+}
+
+// close marks a case recovered and reactivates its subscription.
+func (s *Service) close(ctx context.Context, c Case) ([]*eventv1.Event, error) {
+	now := time.Now().UTC()
+	c.Status = CaseStatusRecovered
+	c.NextRetryAt = nil
+	c.ClosedAt = &now
+	c.UpdatedAt = now
+	if err := s.repo.UpdateCase(ctx, c); err != nil {
+		s.logger.Error("save dunning case", zap.Error(err))
+		return nil, err
+	}
+	s.logger.Info("invoice recovered", zap.String("invoice_id", c.InvoiceID), zap.Int("failures", c.Failures))
+	return s.recover(ctx, c.TenantID, c.SubscriptionID)
+}
+
+// recover reactivates a past due or unpaid subscription unless another of its
+// invoices is still in dunning.
+func (s *Service) recover(ctx context.Context, tenantID, subscriptionID string) ([]*eventv1.Event, error) {
+	if subscriptionID == "" {
+		return nil, nil
+	}
+	pending, err := s.repo.HasOpenCases(ctx, tenantID, subscriptionID)
+	if err != nil || pending {
+		return nil, err
+	}
+	sub, err := s.subscriptions.Get(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	_, evt, err := s.subscriptions.Recover(ctx, sub)
+	if err != nil || evt == nil {
+		return nil, err
+	}
+	return []*eventv1.Event{evt}, nil
+}
+
+// finalAction applies action to a past due subscription. Subscriptions that
+// left past due in the meantime, e.g. by being paused, are left alone and a
+// nil event is returned.
+func (s *Service) finalAction(ctx context.Context, sub subdomain.Subscription, action FinalAction, at time.Time) (*eventv1.Event, error) {
+	if subscriptionv1.SubscriptionStatus(sub.Status) != subdomain.SubscriptionStatusPastDue {
+		s.logger.Info("skipping dunning final action",
+			zap.String("subscription_id", sub.ID),
+			zap.String("status", subdomain.StatusName(subscriptionv1.SubscriptionStatus(sub.Status))),
+		)
+		return nil, nil
+	}
+	var evt *eventv1.Event
+	var err error
+	switch action {
+	case FinalActionCancel:
+		_, evt, err = s.subscriptions.Cancel(ctx, sub, at)
+	case FinalActionPause:
+		_, evt, err = s.subscriptions.Pause(ctx, sub, subdomain.PauseBehaviorVoid, nil, at)
+	default:
+		_, evt, err = s.subscriptions.MarkUnpaid(ctx, sub)
+	}
+	return evt, err
+}
+
+// CheckPayable returns ErrInvoiceNotPayable unless the attempt's invoice
+// belongs to its tenant and is open, so the attempt can be recorded.
+func (s *Service) CheckPayable(ctx context.Context, attempt paymentdomain.Attempt) error {
+	_, err := s.openInvoice(ctx, attempt)
+	return err
+}
+
+// openInvoice loads the invoice an attempt was made for, which must belong
+// to the attempt's tenant and still be open.
+func (s *Service) openInvoice(ctx context.Context, attempt paymentdomain.Attempt) (invoicedomain.Invoice, error) {
+	inv, err := s.invoices.Get(ctx, attempt.InvoiceID)
+	if err != nil {
+		return invoicedomain.Invoice{}, err
+	}
+	if inv.TenantID != attempt.TenantID || invoicev1.InvoiceStatus(inv.Status) != invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN {
+		return invoicedomain.Invoice{}, ErrInvoiceNotPayable
+	}
+	return inv, nil
+}
+
+func (s *Service) subscription(ctx context.Context, inv invoicedomain.Invoice) (subdomain.Subscription, bool, error) {
+	if inv.SubscriptionID == "" {
+		return subdomain.Subscription{}, false, nil
+	}
+	sub, err := s.subscriptions.Get(ctx, inv.SubscriptionID)
+	if err != nil {
+		return subdomain.Subscription{}, false, err
+	}
+	return sub, true, nil
+}
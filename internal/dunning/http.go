@@ -0,0 +1,272 @@
+package dunning
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/smallbiznis/corebilling/internal/dunning/domain"
+	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	"github.com/smallbiznis/corebilling/internal/headers"
+	invoicedomain "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	paymentdomain "github.com/smallbiznis/corebilling/internal/payment/domain"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ModuleHTTP = fx.Invoke(RegisterHTTP)
+
+type handler struct {
+	svc      *domain.Service
+	payments *paymentdomain.Service
+	outbox   outbox.OutboxRepository
+	logger   *zap.Logger
+}
+
+// RegisterHTTP exposes the tenant's dunning policy and the endpoint payment
+// integrations report charge outcomes to.
+func RegisterHTTP(lc fx.Lifecycle, mux *runtime.ServeMux, svc *domain.Service, payments *paymentdomain.Service, outboxRepo outbox.OutboxRepository, logger *zap.Logger) {
+	h := &handler{svc: svc, payments: payments, outbox: outboxRepo, logger: logger.Named("dunning.http")}
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := mux.HandlePath(http.MethodGet, "/v1/dunning/policy", h.getPolicy); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPut, "/v1/dunning/policy", h.updatePolicy); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodGet, "/v1/invoices/{invoice_id}/payment_attempts", h.listAttempts); err != nil {
+				return err
+			}
+			return mux.HandlePath(http.MethodPost, "/v1/invoices/{invoice_id}/payment_attempts", h.recordAttempt)
+		},
+	})
+}
+
+type policyRequest struct {
+	RetryScheduleDays []int  `json:"retry_schedule_days"`
+	FinalAction       string `json:"final_action"`
+}
+
+type policyResponse struct {
+	RetryScheduleDays []int  `json:"retry_schedule_days"`
+	FinalAction       string `json:"final_action"`
+}
+
+type attemptRequest struct {
+	PaymentMethodID       string     `json:"payment_method_id"`
+	Status                string     `json:"status"`
+	ProviderTransactionID string     `json:"provider_transaction_id"`
+	FailureReason         string     `json:"failure_reason"`
+	AttemptedAt           *time.Time `json:"attempted_at"`
+}
+
+type attemptResponse struct {
+	ID                    string    `json:"id"`
+	InvoiceID             string    `json:"invoice_id"`
+	PaymentMethodID       string    `json:"payment_method_id"`
+	Status                string    `json:"status"`
+	ProviderTransactionID string    `json:"provider_transaction_id,omitempty"`
+	FailureReason         string    `json:"failure_reason,omitempty"`
+	AttemptedAt           time.Time `json:"attempted_at"`
+}
+
+type caseResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Failures    int        `json:"failures"`
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	FinalAction string     `json:"final_action,omitempty"`
+}
+
+func (h *handler) getPolicy(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+		return
+	}
+	policy, err := h.svc.GetPolicy(r.Context(), tenantID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toPolicyResponse(policy))
+}
+
+func (h *handler) updatePolicy(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+		return
+	}
+	var body policyRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+		return
+	}
+	action, err := domain.ParseFinalAction(body.FinalAction)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	policy := domain.Policy{TenantID: tenantID, FinalAction: action}
+	for _, days := range body.RetryScheduleDays {
+		policy.RetrySchedule = append(policy.RetrySchedule, time.Duration(days)*24*time.Hour)
+	}
+	policy, err = h.svc.UpdatePolicy(r.Context(), policy)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toPolicyResponse(policy))
+}
+
+func (h *handler) listAttempts(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+		return
+	}
+	attempts, err := h.payments.ListAttempts(r.Context(), tenantID, params["invoice_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := make([]attemptResponse, 0, len(attempts))
+	for _, a := range attempts {
+		resp = append(resp, toAttemptResponse(a))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"payment_attempts": resp})
+}
+
+// recordAttempt stores a charge outcome reported by a payment integration.
+// Failures open or advance the invoice's dunning case; a success marks the
+// invoice paid and ends dunning. An outcome reported again for the same
+// provider transaction returns the stored attempt and changes nothing.
+func (h *handler) recordAttempt(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+		return
+	}
+	var body attemptRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+		return
+	}
+	attemptStatus, err := paymentdomain.ParseAttemptStatus(body.Status)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	attempt := paymentdomain.Attempt{
+		TenantID:              tenantID,
+		InvoiceID:             params["invoice_id"],
+		PaymentMethodID:       body.PaymentMethodID,
+		Status:                attemptStatus,
+		ProviderTransactionID: body.ProviderTransactionID,
+		FailureReason:         body.FailureReason,
+	}
+	if body.AttemptedAt != nil {
+		attempt.AttemptedAt = body.AttemptedAt.UTC()
+	}
+	existing, found, err := h.payments.FindAttempt(r.Context(), tenantID, attempt.ProviderTransactionID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if found {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"payment_attempt": toAttemptResponse(existing)})
+		return
+	}
+	if err := h.svc.CheckPayable(r.Context(), attempt); err != nil {
+		writeError(w, err)
+		return
+	}
+	attempt, recorded, err := h.payments.RecordAttempt(r.Context(), attempt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !recorded {
+		// A concurrent request recorded the transaction first.
+		writeJSON(w, http.StatusOK, map[string]interface{}{"payment_attempt": toAttemptResponse(attempt)})
+		return
+	}
+
+	resp := map[string]interface{}{"payment_attempt": toAttemptResponse(attempt)}
+	switch attempt.Status {
+	case paymentdomain.AttemptStatusFailed:
+		c, err := h.svc.PaymentFailed(r.Context(), attempt)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		resp["dunning_case"] = toCaseResponse(c)
+	case paymentdomain.AttemptStatusSucceeded:
+		evts, err := h.svc.PaymentSucceeded(r.Context(), attempt)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		insertEvents(r.Context(), h.outbox, h.logger, evts)
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func toPolicyResponse(p domain.Policy) policyResponse {
+	days := make([]int, 0, len(p.RetrySchedule))
+	for _, wait := range p.RetrySchedule {
+		days = append(days, int(wait/(24*time.Hour)))
+	}
+	return policyResponse{RetryScheduleDays: days, FinalAction: string(p.FinalAction)}
+}
+
+func toAttemptResponse(a paymentdomain.Attempt) attemptResponse {
+	return attemptResponse{
+		ID:                    a.ID,
+		InvoiceID:             a.InvoiceID,
+		PaymentMethodID:       a.PaymentMethodID,
+		Status:                a.Status.String(),
+		ProviderTransactionID: a.ProviderTransactionID,
+		FailureReason:         a.FailureReason,
+		AttemptedAt:           a.AttemptedAt,
+	}
+}
+
+func toCaseResponse(c domain.Case) caseResponse {
+	return caseResponse{
+		ID:          c.ID,
+		Status:      c.Status.String(),
+		Failures:    c.Failures,
+		NextRetryAt: c.NextRetryAt,
+		FinalAction: string(c.FinalAction),
+	}
+}
+
+// toStatus maps domain errors onto gRPC status codes for the HTTP response.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidPolicy), errors.Is(err, paymentdomain.ErrInvalidPaymentAttempt):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrInvoiceNotPayable), errors.Is(err, invoicedomain.ErrInvalidInvoiceTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(toStatus(err))
+	writeJSON(w, runtime.HTTPStatusFromCode(st.Code()), map[string]string{"error": st.Message()})
+}
@@ -0,0 +1,30 @@
+package dunning
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"github.com/smallbiznis/corebilling/internal/dunning/domain"
+	reposqlc "github.com/smallbiznis/corebilling/internal/dunning/repository/sqlc"
+)
+
+// Module wires dunning services and the retry scheduler.
+var Module = fx.Options(
+	fx.Provide(reposqlc.NewRepository),
+	fx.Provide(domain.NewService),
+	fx.Provide(NewScheduler),
+	fx.Invoke(startScheduler),
+	ModuleHTTP,
+)
+
+func startScheduler(lc fx.Lifecycle, scheduler *Scheduler, logger *zap.Logger) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go scheduler.Run(ctx)
+			logger.Info("dunning scheduler started")
+			return nil
+		},
+	})
+}
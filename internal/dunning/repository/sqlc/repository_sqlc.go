@@ -0,0 +1,269 @@
+package sqlc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/smallbiznis/corebilling/internal/dunning/domain"
+	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+)
+
+const defaultRetryBatchSize = 100
+
+// Repository handles dunning policy and case persistence.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository constructs repository.
+func NewRepository(pool *pgxpool.Pool) domain.Repository {
+	return &Repository{pool: pool}
+}
+
+// GetPolicy returns the tenant's stored policy.
+func (r *Repository) GetPolicy(ctx context.Context, tenantID string) (domain.Policy, bool, error) {
+	var p domain.Policy
+	var seconds []int64
+	var finalAction string
+	err := r.pool.QueryRow(ctx, `
+		SELECT tenant_id::TEXT, retry_schedule_seconds, final_action, updated_at
+		FROM dunning_policies
+		WHERE tenant_id = $1
+	`, tenantID).Scan(&p.TenantID, &seconds, &finalAction, &p.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Policy{}, false, nil
+	}
+	if err != nil {
+		return domain.Policy{}, false, err
+	}
+	p.FinalAction = domain.FinalAction(finalAction)
+	for _, s := range seconds {
+		p.RetrySchedule = append(p.RetrySchedule, time.Duration(s)*time.Second)
+	}
+	return p, true, nil
+}
+
+// SavePolicy inserts or replaces the tenant's policy.
+func (r *Repository) SavePolicy(ctx context.Context, policy domain.Policy) error {
+	seconds := make([]int64, 0, len(policy.RetrySchedule))
+	for _, wait := range policy.RetrySchedule {
+		seconds = append(seconds, int64(wait/time.Second))
+	}
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO dunning_policies (tenant_id, retry_schedule_seconds, final_action, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$4)
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			retry_schedule_seconds = EXCLUDED.retry_schedule_seconds,
+			final_action = EXCLUDED.final_action,
+			updated_at = EXCLUDED.updated_at
+	`, policy.TenantID, seconds, string(policy.FinalAction), policy.UpdatedAt)
+	return err
+}
+
+// CreateCase inserts a dunning case with its events.
+func (r *Repository) CreateCase(ctx context.Context, c domain.Case, evts ...*eventv1.Event) error {
+	return r.withEvents(ctx, evts, func(tx pgx.Tx) error {
+		return createCase(ctx, tx, c)
+	})
+}
+
+func createCase(ctx context.Context, tx pgx.Tx, c domain.Case) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO dunning_cases (
+			id, tenant_id, invoice_id, customer_id, subscription_id, status, failures,
+			last_failure_reason, next_retry_at, final_action, final_action_at, closed_at,
+			created_at, updated_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+	`,
+		c.ID,
+		c.TenantID,
+		c.InvoiceID,
+		nullIfEmpty(c.CustomerID),
+		nullIfEmpty(c.SubscriptionID),
+		int16(c.Status),
+		c.Failures,
+		nullIfEmpty(c.LastFailureReason),
+		c.NextRetryAt,
+		nullIfEmpty(string(c.FinalAction)),
+		c.FinalActionAt,
+		c.ClosedAt,
+		c.CreatedAt,
+		c.UpdatedAt,
+	)
+	return err
+}
+
+// UpdateCase stores the progress of a dunning case with its events.
+func (r *Repository) UpdateCase(ctx context.Context, c domain.Case, evts ...*eventv1.Event) error {
+	return r.withEvents(ctx, evts, func(tx pgx.Tx) error {
+		return updateCase(ctx, tx, c)
+	})
+}
+
+// withEvents runs store and inserts evts into the outbox in one transaction.
+func (r *Repository) withEvents(ctx context.Context, evts []*eventv1.Event, store func(pgx.Tx) error) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := store(tx); err != nil {
+		return err
+	}
+	for _, evt := range evts {
+		if err := outbox.InsertOutboxEventTx(ctx, tx, &outbox.OutboxEvent{Subject: evt.GetSubject(), TenantID: evt.GetTenantId(), Event: evt}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func updateCase(ctx context.Context, tx pgx.Tx, c domain.Case) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE dunning_cases SET
+			status = $2,
+			failures = $3,
+			last_failure_reason = $4,
+			next_retry_at = $5,
+			final_action = $6,
+			final_action_at = $7,
+			closed_at = $8,
+			updated_at = $9
+		WHERE id = $1
+	`,
+		c.ID,
+		int16(c.Status),
+		c.Failures,
+		nullIfEmpty(c.LastFailureReason),
+		c.NextRetryAt,
+		nullIfEmpty(string(c.FinalAction)),
+		c.FinalActionAt,
+		c.ClosedAt,
+		c.UpdatedAt,
+	)
+	return err
+}
+
+// GetOpenCase returns the invoice's open case.
+func (r *Repository) GetOpenCase(ctx context.Context, tenantID, invoiceID string) (domain.Case, bool, error) {
+	c, err := scanCase(r.pool.QueryRow(ctx, `
+		SELECT `+caseColumns+`
+		FROM dunning_cases
+		WHERE tenant_id = $1 AND invoice_id = $2 AND status = $3
+	`, tenantID, invoiceID, int16(domain.CaseStatusOpen)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Case{}, false, nil
+	}
+	if err != nil {
+		return domain.Case{}, false, err
+	}
+	return c, true, nil
+}
+
+// HasOpenCases reports whether the subscription has an invoice in dunning.
+func (r *Repository) HasOpenCases(ctx context.Context, tenantID, subscriptionID string) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM dunning_cases
+			WHERE tenant_id = $1 AND subscription_id = $2 AND status = $3
+		)
+	`, tenantID, subscriptionID, int16(domain.CaseStatusOpen)).Scan(&exists)
+	return exists, err
+}
+
+// ListRetriesDue returns open cases whose next retry is due, oldest first.
+func (r *Repository) ListRetriesDue(ctx context.Context, filter domain.RetryFilter) ([]domain.Case, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultRetryBatchSize
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+caseColumns+`
+		FROM dunning_cases
+		WHERE status = $1 AND next_retry_at <= $2
+		ORDER BY next_retry_at
+		LIMIT $3
+	`, int16(domain.CaseStatusOpen), filter.At, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanCases(rows)
+}
+
+// ListFinalActionsPending returns exhausted cases whose final action has not
+// been applied, oldest first.
+func (r *Repository) ListFinalActionsPending(ctx context.Context, limit int) ([]domain.Case, error) {
+	if limit <= 0 {
+		limit = defaultRetryBatchSize
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+caseColumns+`
+		FROM dunning_cases
+		WHERE status = $1 AND final_action_at IS NULL
+		ORDER BY closed_at
+		LIMIT $2
+	`, int16(domain.CaseStatusExhausted), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanCases(rows)
+}
+
+func scanCases(rows pgx.Rows) ([]domain.Case, error) {
+	defer rows.Close()
+
+	var cases []domain.Case
+	for rows.Next() {
+		c, err := scanCase(rows)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, rows.Err()
+}
+
+const caseColumns = `id::TEXT, tenant_id::TEXT, invoice_id::TEXT, COALESCE(customer_id::TEXT, ''),
+	COALESCE(subscription_id::TEXT, ''), status, failures, COALESCE(last_failure_reason, ''),
+	next_retry_at, COALESCE(final_action, ''), final_action_at, closed_at, created_at, updated_at`
+
+func scanCase(row pgx.Row) (domain.Case, error) {
+	var c domain.Case
+	var finalAction string
+	if err := row.Scan(
+		&c.ID,
+		&c.TenantID,
+		&c.InvoiceID,
+		&c.CustomerID,
+		&c.SubscriptionID,
+		&c.Status,
+		&c.Failures,
+		&c.LastFailureReason,
+		&c.NextRetryAt,
+		&finalAction,
+		&c.FinalActionAt,
+		&c.ClosedAt,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	); err != nil {
+		return domain.Case{}, err
+	}
+	c.FinalAction = domain.FinalAction(finalAction)
+	return c, nil
+}
+
+func nullIfEmpty(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+var _ domain.Repository = (*Repository)(nil)
@@ -0,0 +1,81 @@
+package dunning
+
+import (
+	"context"
+	"time"
+
+	"github.com/smallbiznis/corebilling/internal/dunning/domain"
+	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+	"go.uber.org/zap"
+)
+
+const retryBatchSize = 100
+
+// Scheduler starts the payment retries of dunning cases once they are due and
+// retries final actions that failed to apply.
+type Scheduler struct {
+	svc    *domain.Service
+	outbox outbox.OutboxRepository
+	logger *zap.Logger
+}
+
+// NewScheduler constructs a dunning scheduler.
+func NewScheduler(svc *domain.Service, outboxRepo outbox.OutboxRepository, logger *zap.Logger) *Scheduler {
+	return &Scheduler{
+		svc:    svc,
+		outbox: outboxRepo,
+		logger: logger.Named("dunning.scheduler"),
+	}
+}
+
+// Run starts the periodic worker.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.process(ctx, time.Now().UTC())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Scheduler) process(ctx context.Context, now time.Time) {
+	cases, err := s.svc.ListRetriesDue(ctx, now, retryBatchSize)
+	if err != nil {
+		s.logger.Error("failed to list dunning retries due", zap.Error(err))
+		return
+	}
+	for _, c := range cases {
+		evts, err := s.svc.Retry(ctx, c)
+		if err != nil {
+			s.logger.Error("failed to start payment retry", zap.Error(err), zap.String("invoice_id", c.InvoiceID))
+			continue
+		}
+		insertEvents(ctx, s.outbox, s.logger, evts)
+	}
+
+	pending, err := s.svc.ListFinalActionsPending(ctx, retryBatchSize)
+	if err != nil {
+		s.logger.Error("failed to list pending dunning final actions", zap.Error(err))
+		return
+	}
+	for _, c := range pending {
+		if _, err := s.svc.ApplyFinalAction(ctx, c); err != nil {
+			s.logger.Error("failed to apply dunning final action", zap.Error(err), zap.String("invoice_id", c.InvoiceID))
+		}
+	}
+}
+
+// insertEvents stores evts in the outbox for publishing.
+func insertEvents(ctx context.Context, repo outbox.OutboxRepository, logger *zap.Logger, evts []*eventv1.Event) {
+	for _, evt := range evts {
+		if err := repo.InsertOutboxEvent(ctx, &outbox.OutboxEvent{Subject: evt.GetSubject(), TenantID: evt.GetTenantId(), Event: evt}); err != nil {
+			logger.Error("failed to persist dunning event", zap.Error(err), zap.String("subject", evt.GetSubject()), zap.String("tenant_id", evt.GetTenantId()))
+		}
+	}
+}
@@ -55,7 +55,8 @@ func (h *SubscriptionCreatedHandler) Handle(ctx context.Context, evt *events.Eve
 	if err != nil {
 		return err
 	}
-	if err := h.svc.Create(ctx, sub); err != nil {
+	sub, err = h.svc.Create(ctx, sub)
+	if err != nil {
 		return err
 	}
 	if h.publisher != nil {
@@ -3,10 +3,12 @@ package subscription
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/smallbiznis/corebilling/internal/events"
 	"github.com/smallbiznis/corebilling/internal/events/handler"
 	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	invoiceengine "github.com/smallbiznis/corebilling/internal/invoice_engine/domain"
 	subdomain "github.com/smallbiznis/corebilling/internal/subscription/domain"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -15,6 +17,7 @@ import (
 // SubscriptionUpgradedHandler handles subscription.upgraded events.
 type SubscriptionUpgradedHandler struct {
 	svc       *subdomain.Service
+	engine    *invoiceengine.Service
 	tracker   *outbox.IdempotencyTracker
 	publisher events.Publisher
 	logger    *zap.Logger
@@ -23,6 +26,7 @@ type SubscriptionUpgradedHandler struct {
 // NewSubscriptionUpgradedHandler constructs the handler.
 func NewSubscriptionUpgradedHandler(
 	svc *subdomain.Service,
+	engine *invoiceengine.Service,
 	publisher events.Publisher,
 	tracker *outbox.IdempotencyTracker,
 	logger *zap.Logger,
@@ -30,6 +34,7 @@ func NewSubscriptionUpgradedHandler(
 	return handler.HandlerOut{
 		Handler: &SubscriptionUpgradedHandler{
 			svc:       svc,
+			engine:    engine,
 			publisher: publisher,
 			tracker:   tracker,
 			logger:    logger.Named("subscription.upgraded"),
@@ -54,22 +59,62 @@ func (h *SubscriptionUpgradedHandler) Handle(ctx context.Context, evt *events.Ev
 	if subID == "" {
 		return errors.New("subscription_id required")
 	}
+	behavior, err := invoiceengine.ParseProrationBehavior(handler.ParseString(evt.GetData(), "proration_behavior"))
+	if err != nil {
+		return err
+	}
+	granularity, err := invoiceengine.ParseProrationGranularity(handler.ParseString(evt.GetData(), "proration_granularity"))
+	if err != nil {
+		return err
+	}
 	sub, err := h.svc.Get(ctx, subID)
 	if err != nil {
 		return err
 	}
 	newPrice := handler.ParseString(evt.GetData(), "price_id")
-	if newPrice != "" {
-		sub.PriceID = newPrice
+	if newPrice == "" || newPrice == sub.PriceID {
+		return nil
+	}
+	if err := h.svc.CheckPrice(ctx, sub, newPrice); err != nil {
+		return err
+	}
+	at, err := changeTime(evt)
+	if err != nil {
+		return err
 	}
+
+	// Proration is keyed by the event id, so a redelivery after a failed
+	// update does not credit or charge the change twice.
+	result, err := h.engine.Prorate(ctx, invoiceengine.ProrationRequest{
+		Subscription: sub,
+		NewPriceID:   newPrice,
+		Behavior:     behavior,
+		Granularity:  granularity,
+		At:           at,
+		SourceKey:    evt.GetId(),
+	})
+	if err != nil {
+		return err
+	}
+
+	previous := sub.PriceID
+	sub.PriceID = newPrice
+	sub.UpdatedAt = time.Now().UTC()
 	if err := h.svc.Update(ctx, sub); err != nil {
 		return err
 	}
 
 	if h.publisher != nil {
 		payload := map[string]*structpb.Value{
-			"subscription_id": structpb.NewStringValue(sub.ID),
-			"price_id":        structpb.NewStringValue(sub.PriceID),
+			"subscription_id":    structpb.NewStringValue(sub.ID),
+			"price_id":           structpb.NewStringValue(sub.PriceID),
+			"previous_price_id":  structpb.NewStringValue(previous),
+			"proration_behavior": structpb.NewStringValue(string(behavior)),
+			"credit_cents":       structpb.NewNumberValue(float64(result.CreditCents)),
+			"debit_cents":        structpb.NewNumberValue(float64(result.DebitCents)),
+		}
+		if result.InvoiceID != "" {
+			payload["invoice_id"] = structpb.NewStringValue(result.InvoiceID)
 		}
 		if child, childErr := handler.NewFollowUpEvent(evt, "subscription.price.updated", sub.TenantID, payload); childErr == nil {
 			_ = h.publisher.Publish(ctx, events.EventEnvelope{Event: child})
@@ -77,3 +122,18 @@ func (h *SubscriptionUpgradedHandler) Handle(ctx context.Context, evt *events.Ev
 	}
 	return nil
 }
+
+// changeTime returns the proration date from the event, defaulting to when the event was created.
+func changeTime(evt *events.Event) (time.Time, error) {
+	at, err := handler.ParseTime(evt.GetData(), "proration_date")
+	if err != nil {
+		return time.Time{}, err
+	}
+	if at != nil {
+		return at.UTC(), nil
+	}
+	if evt.GetCreatedAt() != nil {
+		return evt.GetCreatedAt().AsTime(), nil
+	}
+	return time.Now().UTC(), nil
+}
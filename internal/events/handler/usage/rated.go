@@ -59,6 +59,9 @@ func (h *UsageRatedHandler) Handle(ctx context.Context, evt *events.Event) error
 		"subscription_id": structpb.NewStringValue(subscriptionID),
 		"amount_cents":    structpb.NewNumberValue(amount),
 	}
+	if currency := handler.ParseString(data, "currency"); currency != "" {
+		payload["currency"] = structpb.NewStringValue(currency)
+	}
 	if child, childErr := handler.NewFollowUpEvent(evt, "invoice.generated", evt.GetTenantId(), payload); childErr == nil {
 		return h.publisher.Publish(ctx, events.EventEnvelope{Event: child})
 	}
@@ -8,6 +8,8 @@ import (
 	"github.com/smallbiznis/corebilling/internal/events"
 	"github.com/smallbiznis/corebilling/internal/events/handler"
 	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	ratingdomain "github.com/smallbiznis/corebilling/internal/rating/domain"
+	subdomain "github.com/smallbiznis/corebilling/internal/subscription/domain"
 	usagedomain "github.com/smallbiznis/corebilling/internal/usage/domain"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -16,6 +18,7 @@ import (
 // UsageReportedHandler processes usage.reported events.
 type UsageReportedHandler struct {
 	svc       *usagedomain.Service
+	rating    *ratingdomain.Service
 	tracker   *outbox.IdempotencyTracker
 	publisher events.Publisher
 	logger    *zap.Logger
@@ -24,6 +27,7 @@ type UsageReportedHandler struct {
 // NewUsageReportedHandler constructs the handler.
 func NewUsageReportedHandler(
 	svc *usagedomain.Service,
+	rating *ratingdomain.Service,
 	publisher events.Publisher,
 	tracker *outbox.IdempotencyTracker,
 	logger *zap.Logger,
@@ -31,6 +35,7 @@ func NewUsageReportedHandler(
 	return handler.HandlerOut{
 		Handler: &UsageReportedHandler{
 			svc:       svc,
+			rating:    rating,
 			tracker:   tracker,
 			publisher: publisher,
 			logger:    logger.Named("usage.reported"),
@@ -59,11 +64,25 @@ func (h *UsageReportedHandler) Handle(ctx context.Context, evt *events.Event) er
 		return err
 	}
 
+	result, err := h.rating.RateUsage(ctx, record)
+	if errors.Is(err, ratingdomain.ErrNoPriceForMeter) || errors.Is(err, subdomain.ErrSubscriptionPaused) {
+		h.logger.Warn("usage not rated", zap.Error(err), zap.String("usage_id", record.ID), zap.String("meter_code", record.MeterCode))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
 	if h.publisher != nil {
 		payload := map[string]*structpb.Value{
 			"usage_id":        structpb.NewStringValue(record.ID),
 			"subscription_id": structpb.NewStringValue(record.SubscriptionID),
 			"value":           structpb.NewNumberValue(record.Value),
+			"rating_id":       structpb.NewStringValue(result.ID),
+			"price_id":        structpb.NewStringValue(result.PriceID),
+			"amount":          structpb.NewStringValue(result.Amount.String()),
+			"amount_cents":    structpb.NewNumberValue(float64(result.Amount.Cents())),
+			"currency":        structpb.NewStringValue(result.Currency),
 		}
 		if child, childErr := handler.NewFollowUpEvent(evt, "usage.rated", record.TenantID, payload); childErr == nil {
 			_ = h.publisher.Publish(ctx, events.EventEnvelope{Event: child})
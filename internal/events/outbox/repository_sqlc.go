@@ -57,12 +57,26 @@ func NewRepositoryWithConfig(pool *pgxpool.Pool, logger *zap.Logger, cfg Config)
 
 // InsertOutboxEvent inserts a new event into billing_events with pending status.
 func (r *Repository) InsertOutboxEvent(ctx context.Context, evt *OutboxEvent) error {
+	return insertOutboxEvent(ctx, r.tracer, r.pool, evt)
+}
+
+// InsertOutboxEventTx inserts evt like InsertOutboxEvent within tx, for
+// callers storing an event together with the change it describes.
+func InsertOutboxEventTx(ctx context.Context, tx pgx.Tx, evt *OutboxEvent) error {
+	return insertOutboxEvent(ctx, otel.Tracer("events.outbox"), tx, evt)
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func insertOutboxEvent(ctx context.Context, tracer trace.Tracer, db execer, evt *OutboxEvent) error {
 	if evt == nil || evt.Event == nil {
 		return errors.New("event payload required")
 	}
 
 	ctx, cid := correlation.EnsureCorrelationID(ctx)
-	ctx, span := r.tracer.Start(ctx, "outbox.write")
+	ctx, span := tracer.Start(ctx, "outbox.write")
 	defer span.End()
 
 	log := ctxlogger.FromContext(ctx)
@@ -108,7 +122,7 @@ func (r *Repository) InsertOutboxEvent(ctx context.Context, evt *OutboxEvent) er
 		return err
 	}
 
-	_, err = r.exec(ctx,
+	_, err = db.Exec(ctx,
 		`INSERT INTO billing_events (id, subject, tenant_id, resource_id, payload, created_at) VALUES ($1,$2,$3,$4,$5,now())`,
 		evt.Event.Id, evt.Event.Subject, evt.Event.TenantId, nullIfEmpty(evt.ResourceID), payload,
 	)
@@ -0,0 +1,144 @@
+package invoice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	customer "github.com/smallbiznis/corebilling/internal/customer/domain"
+	"github.com/smallbiznis/corebilling/internal/invoice/domain"
+	"github.com/smallbiznis/corebilling/internal/invoice/pdf"
+	tenant "github.com/smallbiznis/corebilling/internal/tenant/domain"
+	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
+	"go.uber.org/zap"
+)
+
+// Tenant.Metadata keys read for invoice branding.
+const (
+	brandNameKey           = "brand_name"
+	brandColorKey          = "brand_color"
+	brandEmailKey          = "billing_email"
+	brandTaxIDKey          = "tax_id"
+	brandAddressKey        = "address"
+	paymentInstructionsKey = "payment_instructions"
+	invoiceFooterKey       = "invoice_footer"
+)
+
+// documentBuilder assembles the printable form of invoices from the tenant's
+// branding and the customer's billing details.
+type documentBuilder struct {
+	tenants   tenant.Repository
+	customers customer.Repository
+	logger    *zap.Logger
+}
+
+// NewDocumentBuilder constructs the builder used to render invoice PDFs.
+func NewDocumentBuilder(tenants tenant.Repository, customers customer.Repository, logger *zap.Logger) *documentBuilder {
+	return &documentBuilder{tenants: tenants, customers: customers, logger: logger.Named("invoice.document")}
+}
+
+// Render returns the invoice as a PDF. Missing tenant or customer records
+// leave their blocks empty rather than failing the download.
+func (b *documentBuilder) Render(ctx context.Context, inv domain.Invoice) []byte {
+	doc := pdf.Invoice{
+		Number:         inv.InvoiceNumber,
+		Status:         strings.ToLower(strings.TrimPrefix(domain.StatusName(invoicev1.InvoiceStatus(inv.Status)), "INVOICE_STATUS_")),
+		Currency:       inv.CurrencyCode,
+		IssuedAt:       inv.IssuedAt,
+		DueAt:          inv.DueAt,
+		PaidAt:         inv.PaidAt,
+		SubtotalCents:  inv.SubtotalCents,
+		TaxCents:       inv.TaxCents,
+		TotalCents:     inv.TotalCents,
+		CreditedCents:  inv.CreditedCents,
+		AmountDueCents: inv.AmountDue(),
+	}
+	for _, item := range inv.LineItems {
+		if item.Type == domain.LineItemTypeTax {
+			doc.Taxes = append(doc.Taxes, pdf.TaxLine{Description: item.Description, AmountCents: item.AmountCents})
+			continue
+		}
+		line := pdf.Line{
+			Description:     item.Description,
+			Quantity:        item.Quantity,
+			UnitAmountCents: item.UnitAmountCents,
+			AmountCents:     item.AmountCents,
+		}
+		if item.PeriodStart != nil && item.PeriodEnd != nil {
+			line.Period = fmt.Sprintf("%s - %s", item.PeriodStart.UTC().Format("Jan 2, 2006"), item.PeriodEnd.UTC().Format("Jan 2, 2006"))
+		}
+		doc.Lines = append(doc.Lines, line)
+	}
+
+	if t, err := b.tenants.GetByID(ctx, inv.TenantID); err != nil {
+		b.logger.Warn("tenant lookup failed, rendering without branding", zap.Error(err), zap.String("tenant_id", inv.TenantID))
+	} else {
+		doc.Seller = pdf.Party{
+			Name:    metadataString(t.Metadata, brandNameKey),
+			Email:   metadataString(t.Metadata, brandEmailKey),
+			TaxID:   metadataString(t.Metadata, brandTaxIDKey),
+			Address: addressLines(t.Metadata[brandAddressKey]),
+		}
+		if doc.Seller.Name == "" {
+			doc.Seller.Name = t.Name
+		}
+		doc.BrandColor = metadataString(t.Metadata, brandColorKey)
+		doc.PaymentInstructions = metadataString(t.Metadata, paymentInstructionsKey)
+		doc.Footer = metadataString(t.Metadata, invoiceFooterKey)
+	}
+
+	if inv.CustomerID != "" {
+		if c, err := b.customers.GetByID(ctx, inv.CustomerID); err != nil {
+			b.logger.Warn("customer lookup failed, rendering without billing address", zap.Error(err), zap.String("customer_id", inv.CustomerID))
+		} else {
+			doc.Customer = pdf.Party{
+				Name:    c.Name,
+				Email:   c.Email,
+				TaxID:   metadataString(c.Metadata, brandTaxIDKey),
+				Address: addressLines(c.BillingAddress),
+			}
+		}
+	}
+	return pdf.Render(doc)
+}
+
+// addressLines formats a structured address (line1, line2, city, state,
+// postal_code, country) or a preformatted string.
+func addressLines(value interface{}) []string {
+	switch address := value.(type) {
+	case string:
+		return strings.Split(address, "\n")
+	case map[string]interface{}:
+		var lines []string
+		for _, key := range []string{"line1", "line2"} {
+			if v := metadataString(address, key); v != "" {
+				lines = append(lines, v)
+			}
+		}
+		var city []string
+		for _, key := range []string{"postal_code", "city", "state"} {
+			if v := metadataString(address, key); v != "" {
+				city = append(city, v)
+			}
+		}
+		if len(city) > 0 {
+			lines = append(lines, strings.Join(city, " "))
+		}
+		country := metadataString(address, "country")
+		if country == "" {
+			country = metadataString(address, "country_code")
+		}
+		if country != "" {
+			lines = append(lines, strings.ToUpper(country))
+		}
+		return lines
+	}
+	return nil
+}
+
+func metadataString(metadata map[string]interface{}, key string) string {
+	if v, ok := metadata[key].(string); ok {
+		return strings.TrimSpace(v)
+	}
+	return ""
+}
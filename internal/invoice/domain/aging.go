@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// AgingBucket groups receivables by how long they are past due.
+type AgingBucket int
+
+const (
+	AgingCurrent AgingBucket = iota
+	Aging1To30
+	Aging31To60
+	Aging61To90
+	AgingOver90
+
+	agingBucketCount
+)
+
+// String returns the bucket's report key.
+func (b AgingBucket) String() string {
+	switch b {
+	case AgingCurrent:
+		return "current"
+	case Aging1To30:
+		return "1_30"
+	case Aging31To60:
+		return "31_60"
+	case Aging61To90:
+		return "61_90"
+	case AgingOver90:
+		return "over_90"
+	}
+	return "unknown"
+}
+
+// AgingBuckets lists the buckets in report order.
+var AgingBuckets = []AgingBucket{AgingCurrent, Aging1To30, Aging31To60, Aging61To90, AgingOver90}
+
+// AgingBucketOf classifies an amount due at dueAt as of now. Invoices without
+// a due date, or not due yet, are current.
+func AgingBucketOf(dueAt *time.Time, now time.Time) AgingBucket {
+	if dueAt == nil || !now.After(*dueAt) {
+		return AgingCurrent
+	}
+	const day = 24 * time.Hour
+	switch late := now.Sub(*dueAt); {
+	case late <= 30*day:
+		return Aging1To30
+	case late <= 60*day:
+		return Aging31To60
+	case late <= 90*day:
+		return Aging61To90
+	}
+	return AgingOver90
+}
+
+// AgingRow is what one customer owes in one currency, per bucket.
+type AgingRow struct {
+	CustomerID   string
+	Currency     string
+	BucketCents  [agingBucketCount]int64
+	TotalCents   int64
+	InvoiceCount int
+}
+
+func (r *AgingRow) add(bucket AgingBucket, cents int64) {
+	r.BucketCents[bucket] += cents
+	r.TotalCents += cents
+	r.InvoiceCount++
+}
+
+// AgingReport is the accounts receivable aging of a tenant as of AsOf.
+// Totals has one row per currency with an empty CustomerID.
+type AgingReport struct {
+	TenantID string
+	AsOf     time.Time
+	Rows     []AgingRow
+	Totals   []AgingRow
+}
+
+// BuildAgingReport buckets the amount due of open invoices by their due date.
+// Rows are sorted by customer and currency, totals by currency.
+func BuildAgingReport(tenantID string, invoices []Invoice, now time.Time) AgingReport {
+	rows := map[[2]string]*AgingRow{}
+	totals := map[string]*AgingRow{}
+	for _, inv := range invoices {
+		due := inv.AmountDue()
+		if due <= 0 {
+			continue
+		}
+		bucket := AgingBucketOf(inv.DueAt, now)
+		key := [2]string{inv.CustomerID, inv.CurrencyCode}
+		if rows[key] == nil {
+			rows[key] = &AgingRow{CustomerID: inv.CustomerID, Currency: inv.CurrencyCode}
+		}
+		rows[key].add(bucket, due)
+		if totals[inv.CurrencyCode] == nil {
+			totals[inv.CurrencyCode] = &AgingRow{Currency: inv.CurrencyCode}
+		}
+		totals[inv.CurrencyCode].add(bucket, due)
+	}
+
+	report := AgingReport{TenantID: tenantID, AsOf: now, Rows: []AgingRow{}, Totals: []AgingRow{}}
+	for _, row := range rows {
+		report.Rows = append(report.Rows, *row)
+	}
+	for _, row := range totals {
+		report.Totals = append(report.Totals, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		if report.Rows[i].CustomerID != report.Rows[j].CustomerID {
+			return report.Rows[i].CustomerID < report.Rows[j].CustomerID
+		}
+		return report.Rows[i].Currency < report.Rows[j].Currency
+	})
+	sort.Slice(report.Totals, func(i, j int) bool { return report.Totals[i].Currency < report.Totals[j].Currency })
+	return report
+}
+
+// AgingReport returns the tenant's receivables aging as of now.
+func (s *Service) AgingReport(ctx context.Context, tenantID string, now time.Time) (AgingReport, error) {
+	invoices, err := s.repo.ListOutstanding(ctx, tenantID)
+	if err != nil {
+		return AgingReport{}, err
+	}
+	return BuildAgingReport(tenantID, invoices, now), nil
+}
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCreditNote is returned for credit notes with an inconsistent amount or lines.
+	ErrInvalidCreditNote = errors.New("invalid credit note")
+	// ErrInvoiceNotCreditable is returned when crediting a draft or void invoice.
+	ErrInvoiceNotCreditable = errors.New("invoice cannot be credited")
+	// ErrInvoiceChanged is returned when an invoice was credited or changed
+	// status while a credit note was being created. The request can be retried.
+	ErrInvoiceChanged = errors.New("invoice changed concurrently")
+)
+
+// CreditNoteReason records why an invoice was credited.
+type CreditNoteReason string
+
+const (
+	CreditNoteReasonDuplicate             CreditNoteReason = "duplicate"
+	CreditNoteReasonFraudulent            CreditNoteReason = "fraudulent"
+	CreditNoteReasonOrderChange           CreditNoteReason = "order_change"
+	CreditNoteReasonProductUnsatisfactory CreditNoteReason = "product_unsatisfactory"
+)
+
+// ParseCreditNoteReason validates a credit note reason.
+func ParseCreditNoteReason(raw string) (CreditNoteReason, error) {
+	switch r := CreditNoteReason(strings.ToLower(raw)); r {
+	case CreditNoteReasonDuplicate, CreditNoteReasonFraudulent, CreditNoteReasonOrderChange, CreditNoteReasonProductUnsatisfactory:
+		return r, nil
+	}
+	return "", fmt.Errorf("%w: unsupported reason %q", ErrInvalidCreditNote, raw)
+}
+
+// CreditNote reverses part or all of an issued invoice. The part covering
+// the invoice's amount due reduces it; the rest, already paid, is owed to the
+// customer as credit.
+type CreditNote struct {
+	ID         string
+	TenantID   string
+	InvoiceID  string
+	CustomerID string
+	Reason     CreditNoteReason
+	Memo       string
+	Currency   string
+	// AmountCents is the total credited, the sum of Lines when given.
+	AmountCents int64
+	// BalanceCents is the part that reduced the invoice's amount due.
+	BalanceCents int64
+	// CustomerCreditCents is the part that was already paid.
+	CustomerCreditCents int64
+	Lines               []CreditNoteLine
+	CreatedAt           time.Time
+}
+
+// CreditNoteLine credits part of one invoice line.
+type CreditNoteLine struct {
+	ID           string
+	CreditNoteID string
+	LineItemID   string
+	Description  string
+	AmountCents  int64
+}
+
+// apply validates note against inv and the invoice's earlier credit notes,
+// then splits its amount between the invoice balance and customer credit.
+func (note *CreditNote) apply(inv Invoice, previous []CreditNote) error {
+	credited := make(map[string]int64)
+	for _, p := range previous {
+		for _, line := range p.Lines {
+			credited[line.LineItemID] += line.AmountCents
+		}
+	}
+	items := make(map[string]LineItem, len(inv.LineItems))
+	for _, item := range inv.LineItems {
+		items[item.ID] = item
+	}
+
+	var linesTotal int64
+	for _, line := range note.Lines {
+		item, ok := items[line.LineItemID]
+		if !ok {
+			return fmt.Errorf("%w: line item %s not on invoice", ErrInvalidCreditNote, line.LineItemID)
+		}
+		if line.AmountCents <= 0 || line.AmountCents > item.AmountCents-credited[item.ID] {
+			return fmt.Errorf("%w: line item %s: amount must be positive and at most the uncredited %d", ErrInvalidCreditNote, item.ID, item.AmountCents-credited[item.ID])
+		}
+		credited[item.ID] += line.AmountCents
+		linesTotal += line.AmountCents
+	}
+	if len(note.Lines) > 0 {
+		if note.AmountCents != 0 && note.AmountCents != linesTotal {
+			return fmt.Errorf("%w: amount differs from the sum of its lines", ErrInvalidCreditNote)
+		}
+		note.AmountCents = linesTotal
+	}
+	if note.AmountCents <= 0 {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidCreditNote)
+	}
+	if remaining := inv.TotalCents - inv.CreditedCents; note.AmountCents > remaining {
+		return fmt.Errorf("%w: amount exceeds the %d left to credit", ErrInvalidCreditNote, remaining)
+	}
+
+	note.BalanceCents = min(note.AmountCents, inv.AmountDue())
+	note.CustomerCreditCents = note.AmountCents - note.BalanceCents
+	return nil
+}
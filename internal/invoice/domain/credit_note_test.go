@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
+)
+
+func TestCreditNoteApply(t *testing.T) {
+	open := int32(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN)
+	paid := int32(invoicev1.InvoiceStatus_INVOICE_STATUS_PAID)
+	lines := []LineItem{{ID: "l1", AmountCents: 6000}, {ID: "l2", AmountCents: 4000}}
+	earlier := []CreditNote{{AmountCents: 1000, Lines: []CreditNoteLine{{LineItemID: "l1", AmountCents: 1000}}}}
+
+	tests := []struct {
+		name         string
+		invoice      Invoice
+		previous     []CreditNote
+		note         CreditNote
+		wantErr      bool
+		wantAmount   int64
+		wantBalance  int64
+		wantCustomer int64
+	}{
+		{
+			name:        "open invoice reduces balance",
+			invoice:     Invoice{Status: open, TotalCents: 10000, LineItems: lines},
+			note:        CreditNote{AmountCents: 2500},
+			wantAmount:  2500,
+			wantBalance: 2500,
+		},
+		{
+			name:         "paid invoice credits customer",
+			invoice:      Invoice{Status: paid, TotalCents: 10000, LineItems: lines},
+			note:         CreditNote{AmountCents: 2500},
+			wantAmount:   2500,
+			wantCustomer: 2500,
+		},
+		{
+			name:        "credits the rest of an open invoice",
+			invoice:     Invoice{Status: open, TotalCents: 10000, CreditedCents: 9000, LineItems: lines},
+			note:        CreditNote{AmountCents: 1000},
+			wantAmount:  1000,
+			wantBalance: 1000,
+		},
+		{
+			name:        "lines set the amount",
+			invoice:     Invoice{Status: open, TotalCents: 10000, CreditedCents: 1000, LineItems: lines},
+			previous:    earlier,
+			note:        CreditNote{Lines: []CreditNoteLine{{LineItemID: "l1", AmountCents: 5000}, {LineItemID: "l2", AmountCents: 500}}},
+			wantAmount:  5500,
+			wantBalance: 5500,
+		},
+		{
+			name:     "line above uncredited amount",
+			invoice:  Invoice{Status: open, TotalCents: 10000, CreditedCents: 1000, LineItems: lines},
+			previous: earlier,
+			note:     CreditNote{Lines: []CreditNoteLine{{LineItemID: "l1", AmountCents: 5001}}},
+			wantErr:  true,
+		},
+		{
+			name:    "unknown line",
+			invoice: Invoice{Status: open, TotalCents: 10000, LineItems: lines},
+			note:    CreditNote{Lines: []CreditNoteLine{{LineItemID: "l9", AmountCents: 100}}},
+			wantErr: true,
+		},
+		{
+			name:    "amount differs from lines",
+			invoice: Invoice{Status: open, TotalCents: 10000, LineItems: lines},
+			note:    CreditNote{AmountCents: 900, Lines: []CreditNoteLine{{LineItemID: "l2", AmountCents: 100}}},
+			wantErr: true,
+		},
+		{
+			name:    "zero amount",
+			invoice: Invoice{Status: open, TotalCents: 10000, LineItems: lines},
+			note:    CreditNote{},
+			wantErr: true,
+		},
+		{
+			name:    "exceeds total",
+			invoice: Invoice{Status: paid, TotalCents: 10000, CreditedCents: 9500, LineItems: lines},
+			note:    CreditNote{AmountCents: 600},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			note := tt.note
+			err := note.apply(tt.invoice, tt.previous)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidCreditNote) {
+					t.Fatalf("expected ErrInvalidCreditNote got %v", err)
+				}
+				return
+			}
+			if note.AmountCents != tt.wantAmount || note.BalanceCents != tt.wantBalance || note.CustomerCreditCents != tt.wantCustomer {
+				t.Fatalf("expected %d/%d/%d got %d/%d/%d", tt.wantAmount, tt.wantBalance, tt.wantCustomer, note.AmountCents, note.BalanceCents, note.CustomerCreditCents)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// voidedEvent builds invoice.voided. amountCents is the amount due that was
+// cancelled with the invoice.
+func voidedEvent(inv Invoice, amountCents int64, at time.Time) *eventv1.Event {
+	return &eventv1.Event{
+		Subject:  "invoice.voided",
+		TenantId: inv.TenantID,
+		Data: &structpb.Struct{Fields: map[string]*structpb.Value{
+			"invoice_id":          structpb.NewStringValue(inv.ID),
+			"invoice_number":      structpb.NewStringValue(inv.InvoiceNumber),
+			"customer_id":         structpb.NewStringValue(inv.CustomerID),
+			"subscription_id":     structpb.NewStringValue(inv.SubscriptionID),
+			"amount_voided_cents": structpb.NewNumberValue(float64(amountCents)),
+			"currency":            structpb.NewStringValue(inv.CurrencyCode),
+			"voided_at":           structpb.NewStringValue(at.Format(time.RFC3339)),
+		}},
+	}
+}
+
+// overdueEvent builds invoice.overdue for an invoice still unpaid after its
+// due date.
+func overdueEvent(inv Invoice, at time.Time) *eventv1.Event {
+	return &eventv1.Event{
+		Subject:  "invoice.overdue",
+		TenantId: inv.TenantID,
+		Data: &structpb.Struct{Fields: map[string]*structpb.Value{
+			"invoice_id":       structpb.NewStringValue(inv.ID),
+			"invoice_number":   structpb.NewStringValue(inv.InvoiceNumber),
+			"customer_id":      structpb.NewStringValue(inv.CustomerID),
+			"subscription_id":  structpb.NewStringValue(inv.SubscriptionID),
+			"amount_due_cents": structpb.NewNumberValue(float64(inv.AmountDue())),
+			"currency":         structpb.NewStringValue(inv.CurrencyCode),
+			"due_at":           structpb.NewStringValue(inv.DueAt.Format(time.RFC3339)),
+			"overdue_at":       structpb.NewStringValue(at.Format(time.RFC3339)),
+		}},
+	}
+}
+
+// creditNoteCreatedEvent builds credit_note.created with the invoice's
+// amount due after the note.
+func creditNoteCreatedEvent(note CreditNote, inv Invoice) *eventv1.Event {
+	return &eventv1.Event{
+		Subject:  "credit_note.created",
+		TenantId: note.TenantID,
+		Data: &structpb.Struct{Fields: map[string]*structpb.Value{
+			"credit_note_id":        structpb.NewStringValue(note.ID),
+			"invoice_id":            structpb.NewStringValue(note.InvoiceID),
+			"customer_id":           structpb.NewStringValue(note.CustomerID),
+			"reason":                structpb.NewStringValue(string(note.Reason)),
+			"amount_cents":          structpb.NewNumberValue(float64(note.AmountCents)),
+			"balance_cents":         structpb.NewNumberValue(float64(note.BalanceCents)),
+			"customer_credit_cents": structpb.NewNumberValue(float64(note.CustomerCreditCents)),
+			"amount_due_cents":      structpb.NewNumberValue(float64(inv.AmountDue())),
+			"currency":              structpb.NewStringValue(note.Currency),
+		}},
+	}
+}
@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
+	"go.uber.org/zap"
+)
+
+// Ledger accounts invoices are posted to. The ledger keeps one of each per
+// tenant and currency.
+const (
+	LedgerAccountReceivable     = "accounts_receivable"
+	LedgerAccountRevenue        = "revenue"
+	LedgerAccountBadDebt        = "bad_debt"
+	LedgerAccountCustomerCredit = "customer_credit"
+	LedgerAccountCash           = "cash"
+)
+
+const (
+	ledgerPostingBatchSize  = 100
+	ledgerPostingMaxBackoff = time.Hour
+)
+
+// LedgerPosting moves AmountCents from the Credit account to the Debit
+// account, referencing the invoice or credit note it records. Postings are
+// stored with the invoice change they record and posted afterwards; ID keys
+// the ledger journal so a retried posting is applied once.
+type LedgerPosting struct {
+	ID            string
+	TenantID      string
+	Currency      string
+	ReferenceType string
+	ReferenceID   string
+	Description   string
+	Debit         string
+	Credit        string
+	AmountCents   int64
+	// Attempts counts failed attempts to post.
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Ledger records invoice accounting. Posting the same ID twice must not
+// post it again.
+type Ledger interface {
+	Post(ctx context.Context, posting LedgerPosting) error
+}
+
+// ReceivablePosting books an issued invoice's total as owed by the customer.
+func ReceivablePosting(inv Invoice) LedgerPosting {
+	return invoicePosting(inv, "invoice issued", LedgerAccountReceivable, LedgerAccountRevenue, inv.TotalCents)
+}
+
+// PaymentPosting books a payment of the amount due, clearing it from
+// receivables, or recovering it from bad debt once written off.
+func PaymentPosting(inv Invoice) LedgerPosting {
+	from := LedgerAccountReceivable
+	if invoicev1.InvoiceStatus(inv.Status) == InvoiceStatusUncollectible {
+		from = LedgerAccountBadDebt
+	}
+	return invoicePosting(inv, "invoice paid", LedgerAccountCash, from, inv.AmountDue())
+}
+
+func invoicePosting(inv Invoice, description, debit, credit string, amountCents int64) LedgerPosting {
+	return LedgerPosting{
+		TenantID:      inv.TenantID,
+		Currency:      inv.CurrencyCode,
+		ReferenceType: "invoice",
+		ReferenceID:   inv.ID,
+		Description:   description,
+		Debit:         debit,
+		Credit:        credit,
+		AmountCents:   amountCents,
+	}
+}
+
+// creditNotePosting reverses revenue for the part of a credit note taken off
+// the credit account.
+func creditNotePosting(note CreditNote, credit string, amountCents int64) LedgerPosting {
+	return LedgerPosting{
+		TenantID:      note.TenantID,
+		Currency:      note.Currency,
+		ReferenceType: "credit_note",
+		ReferenceID:   note.ID,
+		Description:   "credit note " + string(note.Reason),
+		Debit:         LedgerAccountRevenue,
+		Credit:        credit,
+		AmountCents:   amountCents,
+	}
+}
+
+// postings assigns ids to the postings with an amount. It returns none when
+// the service has no ledger.
+func (s *Service) postings(postings ...LedgerPosting) []LedgerPosting {
+	if s.ledger == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	var out []LedgerPosting
+	for _, posting := range postings {
+		if posting.AmountCents <= 0 {
+			continue
+		}
+		posting.ID = s.genID.Generate().String()
+		posting.CreatedAt = now
+		out = append(out, posting)
+	}
+	return out
+}
+
+// PostLedger posts the postings waiting at now. A failed posting is retried
+// with exponential backoff. It returns how many were posted.
+func (s *Service) PostLedger(ctx context.Context, now time.Time) (int, error) {
+	if s.ledger == nil {
+		return 0, nil
+	}
+	pending, err := s.repo.ListPendingPostings(ctx, now, ledgerPostingBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	posted := 0
+	for _, posting := range pending {
+		if err := s.ledger.Post(ctx, posting); err != nil {
+			s.logger.Error("post invoice to ledger",
+				zap.Error(err),
+				zap.String("posting_id", posting.ID),
+				zap.String("reference_type", posting.ReferenceType),
+				zap.String("reference_id", posting.ReferenceID),
+				zap.Int("attempts", posting.Attempts+1),
+			)
+			next := now.Add(postingBackoff(posting.Attempts + 1))
+			if err := s.repo.RetryPosting(ctx, posting.ID, next, err.Error()); err != nil {
+				return posted, err
+			}
+			continue
+		}
+		if err := s.repo.MarkPosted(ctx, posting.ID, now); err != nil {
+			return posted, err
+		}
+		posted++
+	}
+	return posted, nil
+}
+
+// postingBackoff returns the delay before retrying a posting that failed
+// attempts times.
+func postingBackoff(attempts int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempts && delay < ledgerPostingMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, ledgerPostingMaxBackoff)
+}
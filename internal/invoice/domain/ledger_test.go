@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
+)
+
+func TestPaymentPosting(t *testing.T) {
+	open := Invoice{Status: int32(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN), TotalCents: 1000, CreditedCents: 300}
+	if got := PaymentPosting(open); got.Debit != LedgerAccountCash || got.Credit != LedgerAccountReceivable || got.AmountCents != 700 {
+		t.Fatalf("unexpected posting for open invoice %+v", got)
+	}
+	written := Invoice{Status: int32(InvoiceStatusUncollectible), TotalCents: 1000}
+	if got := PaymentPosting(written); got.Credit != LedgerAccountBadDebt || got.AmountCents != 1000 {
+		t.Fatalf("unexpected posting for uncollectible invoice %+v", got)
+	}
+}
+
+func TestPostingBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 5, want: 16 * time.Second},
+		{attempts: 40, want: ledgerPostingMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := postingBackoff(tt.attempts); got != tt.want {
+			t.Fatalf("attempts %d: expected %s got %s", tt.attempts, tt.want, got)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
+)
 
 // Invoice represents a bill issued by a tenant.
 type Invoice struct {
@@ -13,11 +17,58 @@ type Invoice struct {
 	TotalCents     int64
 	SubtotalCents  int64
 	TaxCents       int64
-	InvoiceNumber  string
-	IssuedAt       *time.Time
-	DueAt          *time.Time
-	PaidAt         *time.Time
-	Metadata       map[string]interface{}
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	// CreditedCents is the total of the invoice's credit notes.
+	CreditedCents int64
+	InvoiceNumber string
+	IssuedAt      *time.Time
+	DueAt         *time.Time
+	PaidAt        *time.Time
+	// OverdueAt is when the open invoice was found unpaid past its due date.
+	OverdueAt *time.Time
+	Metadata  map[string]interface{}
+	LineItems []LineItem
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// AmountDue returns what is left to collect on an open or uncollectible
+// invoice after its credit notes.
+func (inv Invoice) AmountDue() int64 {
+	switch invoicev1.InvoiceStatus(inv.Status) {
+	case invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN, InvoiceStatusUncollectible:
+		if due := inv.TotalCents - inv.CreditedCents; due > 0 {
+			return due
+		}
+	}
+	return 0
+}
+
+// LineItemType classifies what an invoice line charges or credits.
+type LineItemType string
+
+const (
+	LineItemTypeRecurring LineItemType = "recurring"
+	LineItemTypeUsage     LineItemType = "usage"
+	LineItemTypeProration LineItemType = "proration"
+	LineItemTypeDiscount  LineItemType = "discount"
+	LineItemTypeTax       LineItemType = "tax"
+)
+
+// LineItem is a single itemized charge or credit on an invoice.
+type LineItem struct {
+	ID              string
+	InvoiceID       string
+	TenantID        string
+	Type            LineItemType
+	Description     string
+	PriceID         string
+	MeterCode       string
+	Quantity        float64
+	UnitAmountCents int64
+	AmountCents     int64
+	Currency        string
+	PeriodStart     *time.Time
+	PeriodEnd       *time.Time
+	Metadata        map[string]interface{}
+	CreatedAt       time.Time
 }
@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrInvalidNumberingScheme is returned for prefixes or templates that cannot
+// render an invoice number.
+var ErrInvalidNumberingScheme = errors.New("invalid invoice numbering scheme")
+
+const (
+	// DefaultNumberPrefix is used by tenants that have not configured numbering.
+	DefaultNumberPrefix = "INV"
+	// DefaultNumberTemplate renders numbers like INV-000042.
+	DefaultNumberTemplate = "{prefix}-{seq:6}"
+
+	maxSeqWidth = 12
+)
+
+// numberToken matches the placeholders of a numbering template: {prefix},
+// {yyyy}, {yy}, {mm} and {seq} with an optional zero-padded width, {seq:6}.
+var numberToken = regexp.MustCompile(`\{([a-z]+)(?::(\d+))?\}`)
+
+// NumberingScheme renders a tenant's invoice numbers. Numbers are drawn from a
+// gap-free counter per series; the series is the template rendered without
+// its sequence, so a template with {yyyy} restarts every year.
+type NumberingScheme struct {
+	TenantID  string
+	Prefix    string
+	Template  string
+	UpdatedAt time.Time
+}
+
+// DefaultNumberingScheme returns the scheme of tenants without configuration.
+func DefaultNumberingScheme(tenantID string) NumberingScheme {
+	return NumberingScheme{TenantID: tenantID, Prefix: DefaultNumberPrefix, Template: DefaultNumberTemplate}
+}
+
+// Validate checks that the template holds exactly one {seq} and only known
+// placeholders.
+func (n NumberingScheme) Validate() error {
+	if strings.ContainsAny(n.Prefix, "{}") {
+		return fmt.Errorf("%w: prefix must not contain braces", ErrInvalidNumberingScheme)
+	}
+	seqs := 0
+	for _, m := range numberToken.FindAllStringSubmatch(n.Template, -1) {
+		switch m[1] {
+		case "seq":
+			seqs++
+			if m[2] != "" {
+				if width, _ := strconv.Atoi(m[2]); width < 1 || width > maxSeqWidth {
+					return fmt.Errorf("%w: seq width must be between 1 and %d", ErrInvalidNumberingScheme, maxSeqWidth)
+				}
+			}
+		case "prefix", "yyyy", "yy", "mm":
+			if m[2] != "" {
+				return fmt.Errorf("%w: {%s} takes no width", ErrInvalidNumberingScheme, m[1])
+			}
+		default:
+			return fmt.Errorf("%w: unknown placeholder {%s}", ErrInvalidNumberingScheme, m[1])
+		}
+	}
+	if seqs != 1 {
+		return fmt.Errorf("%w: template needs exactly one {seq}", ErrInvalidNumberingScheme)
+	}
+	if strings.ContainsAny(numberToken.ReplaceAllString(n.Template, ""), "{}") {
+		return fmt.Errorf("%w: unbalanced braces in template", ErrInvalidNumberingScheme)
+	}
+	return nil
+}
+
+// Series returns the counter an invoice issued at at draws its number from.
+func (n NumberingScheme) Series(at time.Time) string {
+	return n.render(at, func(string) string { return "{seq}" })
+}
+
+// Format renders the number of the seq-th invoice of the series at at.
+func (n NumberingScheme) Format(at time.Time, seq int64) string {
+	return n.render(at, func(width string) string {
+		w, _ := strconv.Atoi(width)
+		return fmt.Sprintf("%0*d", w, seq)
+	})
+}
+
+func (n NumberingScheme) render(at time.Time, seq func(width string) string) string {
+	return numberToken.ReplaceAllStringFunc(n.Template, func(token string) string {
+		m := numberToken.FindStringSubmatch(token)
+		switch m[1] {
+		case "prefix":
+			return n.Prefix
+		case "yyyy":
+			return at.Format("2006")
+		case "yy":
+			return at.Format("06")
+		case "mm":
+			return at.Format("01")
+		case "seq":
+			return seq(m[2])
+		}
+		return token
+	})
+}
+
+// NumberedAt returns the time an invoice's number series is chosen by: its
+// issue date, or its creation when not issued yet.
+func (inv Invoice) NumberedAt() time.Time {
+	if inv.IssuedAt != nil {
+		return *inv.IssuedAt
+	}
+	return inv.CreatedAt
+}
+
+// LoadNumberingScheme returns the tenant's numbering scheme, or the default
+// when none is configured.
+func LoadNumberingScheme(ctx context.Context, repo Repository, tenantID string) (NumberingScheme, error) {
+	scheme, ok, err := repo.GetNumberingScheme(ctx, tenantID)
+	if err != nil {
+		return NumberingScheme{}, err
+	}
+	if !ok {
+		return DefaultNumberingScheme(tenantID), nil
+	}
+	return scheme, nil
+}
+
+// NumberingScheme returns the tenant's numbering scheme.
+func (s *Service) NumberingScheme(ctx context.Context, tenantID string) (NumberingScheme, error) {
+	return LoadNumberingScheme(ctx, s.repo, tenantID)
+}
+
+// UpdateNumberingScheme validates and stores a tenant's numbering scheme. It
+// applies to invoices numbered from now on; changing the prefix or the
+// template outside {seq} starts a new series at 1.
+func (s *Service) UpdateNumberingScheme(ctx context.Context, scheme NumberingScheme) (NumberingScheme, error) {
+	if scheme.Prefix == "" {
+		scheme.Prefix = DefaultNumberPrefix
+	}
+	if scheme.Template == "" {
+		scheme.Template = DefaultNumberTemplate
+	}
+	if err := scheme.Validate(); err != nil {
+		return NumberingScheme{}, err
+	}
+	scheme.UpdatedAt = time.Now().UTC()
+	if err := s.repo.SaveNumberingScheme(ctx, scheme); err != nil {
+		s.logger.Error("save invoice numbering scheme", zap.Error(err))
+		return NumberingScheme{}, err
+	}
+	s.logger.Info("invoice numbering scheme updated", zap.String("tenant_id", scheme.TenantID), zap.String("template", scheme.Template))
+	return scheme, nil
+}
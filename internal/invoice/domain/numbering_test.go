@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNumberingSchemeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scheme  NumberingScheme
+		wantErr bool
+	}{
+		{name: "default", scheme: DefaultNumberingScheme("t1")},
+		{name: "yearly series", scheme: NumberingScheme{Prefix: "ACME", Template: "{prefix}/{yyyy}/{seq:4}"}},
+		{name: "plain seq", scheme: NumberingScheme{Template: "{seq}"}},
+		{name: "no seq", scheme: NumberingScheme{Prefix: "INV", Template: "{prefix}-{yyyy}"}, wantErr: true},
+		{name: "two seqs", scheme: NumberingScheme{Template: "{seq}-{seq}"}, wantErr: true},
+		{name: "unknown placeholder", scheme: NumberingScheme{Template: "{customer}-{seq}"}, wantErr: true},
+		{name: "width out of range", scheme: NumberingScheme{Template: "{seq:20}"}, wantErr: true},
+		{name: "width on year", scheme: NumberingScheme{Template: "{yyyy:2}-{seq}"}, wantErr: true},
+		{name: "unbalanced brace", scheme: NumberingScheme{Template: "{prefix-{seq}"}, wantErr: true},
+		{name: "brace in prefix", scheme: NumberingScheme{Prefix: "{seq}", Template: "{prefix}-{seq}"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.scheme.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidNumberingScheme) {
+				t.Fatalf("expected ErrInvalidNumberingScheme got %v", err)
+			}
+		})
+	}
+}
+
+func TestNumberingSchemeFormat(t *testing.T) {
+	at := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		scheme     NumberingScheme
+		seq        int64
+		wantSeries string
+		wantNumber string
+	}{
+		{name: "default", scheme: DefaultNumberingScheme("t1"), seq: 42, wantSeries: "INV-{seq}", wantNumber: "INV-000042"},
+		{name: "yearly", scheme: NumberingScheme{Prefix: "ACME", Template: "{prefix}/{yyyy}/{seq:4}"}, seq: 7, wantSeries: "ACME/2026/{seq}", wantNumber: "ACME/2026/0007"},
+		{name: "monthly short year", scheme: NumberingScheme{Template: "{yy}{mm}-{seq}"}, seq: 12, wantSeries: "2603-{seq}", wantNumber: "2603-12"},
+		{name: "seq wider than padding", scheme: NumberingScheme{Prefix: "F", Template: "{prefix}{seq:2}"}, seq: 1234, wantSeries: "F{seq}", wantNumber: "F1234"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scheme.Series(at); got != tt.wantSeries {
+				t.Fatalf("expected series %q got %q", tt.wantSeries, got)
+			}
+			if got := tt.scheme.Format(at, tt.seq); got != tt.wantNumber {
+				t.Fatalf("expected number %q got %q", tt.wantNumber, got)
+			}
+		})
+	}
+}
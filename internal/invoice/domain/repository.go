@@ -1,6 +1,11 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+)
 
 // ListInvoicesFilter configures pagination and filters for invoice queries.
 type ListInvoicesFilter struct {
@@ -16,5 +21,45 @@ type ListInvoicesFilter struct {
 type Repository interface {
 	Create(ctx context.Context, invoice Invoice) error
 	GetByID(ctx context.Context, id string) (Invoice, error)
+	// Update stores the invoice's status, number, dates and metadata, and
+	// queues the ledger postings recording the change in the same
+	// transaction. Line items are immutable once created.
+	Update(ctx context.Context, invoice Invoice, postings ...LedgerPosting) error
 	List(ctx context.Context, filter ListInvoicesFilter) ([]Invoice, bool, error)
+	// CreateCreditNote stores the note with its lines together with the
+	// invoice's new credited amount and status, and queues its postings. It
+	// returns ErrInvoiceChanged unless the stored invoice still has the
+	// credited amount and status of previous.
+	CreateCreditNote(ctx context.Context, note CreditNote, previous, invoice Invoice, postings ...LedgerPosting) error
+	// ListCreditNotes returns an invoice's credit notes, oldest first.
+	ListCreditNotes(ctx context.Context, invoiceID string) ([]CreditNote, error)
+	GetNumberingScheme(ctx context.Context, tenantID string) (NumberingScheme, bool, error)
+	SaveNumberingScheme(ctx context.Context, scheme NumberingScheme) error
+	// CreateNumbered inserts the invoice like Create, numbering it with the
+	// next value of the scheme's series in the same transaction so a failed
+	// insert does not use up a number. Postings are queued like in Update.
+	CreateNumbered(ctx context.Context, invoice Invoice, scheme NumberingScheme, postings ...LedgerPosting) (Invoice, error)
+	// UpdateNumbered stores the invoice like Update after numbering it the
+	// same way.
+	UpdateNumbered(ctx context.Context, invoice Invoice, scheme NumberingScheme, postings ...LedgerPosting) (Invoice, error)
+	// GetPaymentTerms returns the terms stored for a customer, or for the
+	// tenant when customerID is empty.
+	GetPaymentTerms(ctx context.Context, tenantID, customerID string) (PaymentTermsSetting, bool, error)
+	SavePaymentTerms(ctx context.Context, setting PaymentTermsSetting) error
+	DeletePaymentTerms(ctx context.Context, tenantID, customerID string) error
+	// ListPastDue returns open invoices of all tenants due before now that
+	// are not marked overdue, without line items, oldest due first.
+	ListPastDue(ctx context.Context, now time.Time, limit int) ([]Invoice, error)
+	// MarkOverdue sets overdue_at on an open invoice not marked overdue yet
+	// and reports whether it did. evt is stored in the outbox in the same
+	// transaction when the invoice is marked.
+	MarkOverdue(ctx context.Context, id string, at time.Time, evt *eventv1.Event) (bool, error)
+	// ListOutstanding returns the tenant's open invoices without line items.
+	ListOutstanding(ctx context.Context, tenantID string) ([]Invoice, error)
+	// ListPendingPostings returns queued ledger postings of all tenants due
+	// for an attempt at now, oldest first.
+	ListPendingPostings(ctx context.Context, now time.Time, limit int) ([]LedgerPosting, error)
+	MarkPosted(ctx context.Context, id string, at time.Time) error
+	// RetryPosting counts a failed attempt and schedules the next at next.
+	RetryPosting(ctx context.Context, id string, next time.Time, lastError string) error
 }
@@ -2,28 +2,55 @@ package domain
 
 import (
 	"context"
+	"time"
 
+	"github.com/bwmarrin/snowflake"
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
 	"go.uber.org/zap"
 )
 
 // Service exposes invoice operations.
 type Service struct {
 	repo   Repository
+	ledger Ledger
 	logger *zap.Logger
+
+	genID *snowflake.Node
 }
 
-// NewService constructs Service.
-func NewService(repo Repository, logger *zap.Logger) *Service {
-	return &Service{repo: repo, logger: logger.Named("invoice.service")}
+// NewService constructs Service. A nil ledger skips ledger postings.
+func NewService(repo Repository, ledger Ledger, logger *zap.Logger, genID *snowflake.Node) *Service {
+	return &Service{repo: repo, ledger: ledger, logger: logger.Named("invoice.service"), genID: genID}
 }
 
-// Create stores an invoice.
+// Create stores an invoice. Issued invoices without a number are given the
+// next one of the tenant's series and, without a due date, are dated by the
+// customer's payment terms; drafts are numbered when finalized.
 func (s *Service) Create(ctx context.Context, invoice Invoice) error {
-	if err := s.repo.Create(ctx, invoice); err != nil {
+	if invoicev1.InvoiceStatus(invoice.Status) != invoicev1.InvoiceStatus_INVOICE_STATUS_DRAFT {
+		if err := s.applyPaymentTerms(ctx, &invoice); err != nil {
+			return err
+		}
+	}
+	if invoice.InvoiceNumber != "" || invoicev1.InvoiceStatus(invoice.Status) == invoicev1.InvoiceStatus_INVOICE_STATUS_DRAFT {
+		if err := s.repo.Create(ctx, invoice); err != nil {
+			s.logger.Error("create invoice", zap.Error(err))
+			return err
+		}
+		s.logger.Info("invoice created", zap.String("id", invoice.ID))
+		return nil
+	}
+	scheme, err := s.NumberingScheme(ctx, invoice.TenantID)
+	if err != nil {
+		return err
+	}
+	invoice, err = s.repo.CreateNumbered(ctx, invoice, scheme)
+	if err != nil {
 		s.logger.Error("create invoice", zap.Error(err))
 		return err
 	}
-	s.logger.Info("invoice created", zap.String("id", invoice.ID))
+	s.logger.Info("invoice created", zap.String("id", invoice.ID), zap.String("invoice_number", invoice.InvoiceNumber))
 	return nil
 }
 
@@ -36,3 +63,164 @@ func (s *Service) Get(ctx context.Context, id string) (Invoice, error) {
 func (s *Service) List(ctx context.Context, filter ListInvoicesFilter) ([]Invoice, bool, error) {
 	return s.repo.List(ctx, filter)
 }
+
+// MarkPaid records that an open invoice was paid at at and books the payment
+// of its amount due. The returned event records the status change.
+func (s *Service) MarkPaid(ctx context.Context, inv Invoice, at time.Time) (Invoice, *eventv1.Event, error) {
+	payment := PaymentPosting(inv)
+	evt, err := inv.ApplyLifecycle(InvoiceLifecyclePaid, invoicev1.InvoiceStatus_INVOICE_STATUS_PAID)
+	if err != nil {
+		return Invoice{}, nil, err
+	}
+	inv.PaidAt = &at
+	inv.UpdatedAt = time.Now().UTC()
+	if err := s.update(ctx, inv, s.postings(payment)...); err != nil {
+		return Invoice{}, nil, err
+	}
+	s.logger.Info("invoice paid", zap.String("id", inv.ID))
+	return inv, evt, nil
+}
+
+// Finalize opens a draft invoice for collection, numbers it, dates it by the
+// customer's payment terms unless it has a due date, and books its total as
+// receivable.
+func (s *Service) Finalize(ctx context.Context, inv Invoice) (Invoice, *eventv1.Event, error) {
+	evt, err := inv.ApplyLifecycle(InvoiceLifecycleOpened, invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN)
+	if err != nil {
+		return Invoice{}, nil, err
+	}
+	now := time.Now().UTC()
+	if inv.IssuedAt == nil {
+		inv.IssuedAt = &now
+	}
+	if err := s.applyPaymentTerms(ctx, &inv); err != nil {
+		return Invoice{}, nil, err
+	}
+	inv.UpdatedAt = now
+	if inv.InvoiceNumber == "" {
+		scheme, err := s.NumberingScheme(ctx, inv.TenantID)
+		if err != nil {
+			return Invoice{}, nil, err
+		}
+		if inv, err = s.repo.UpdateNumbered(ctx, inv, scheme, s.postings(ReceivablePosting(inv))...); err != nil {
+			s.logger.Error("number invoice", zap.Error(err))
+			return Invoice{}, nil, err
+		}
+	} else if err := s.update(ctx, inv, s.postings(ReceivablePosting(inv))...); err != nil {
+		return Invoice{}, nil, err
+	}
+	s.logger.Info("invoice finalized", zap.String("id", inv.ID), zap.String("invoice_number", inv.InvoiceNumber))
+	return inv, evt, nil
+}
+
+// Void cancels a draft, open or uncollectible invoice at at. The amount still
+// due is reversed out of receivables, or out of bad debt once written off.
+// Paid invoices are reversed with credit notes instead.
+func (s *Service) Void(ctx context.Context, inv Invoice, at time.Time) (Invoice, []*eventv1.Event, error) {
+	due := inv.AmountDue()
+	from := LedgerAccountReceivable
+	if invoicev1.InvoiceStatus(inv.Status) == InvoiceStatusUncollectible {
+		from = LedgerAccountBadDebt
+	}
+	evt, err := inv.ApplyLifecycle(InvoiceLifecycleVoided, invoicev1.InvoiceStatus_INVOICE_STATUS_VOID)
+	if err != nil {
+		return Invoice{}, nil, err
+	}
+	inv.UpdatedAt = time.Now().UTC()
+	if err := s.update(ctx, inv, s.postings(invoicePosting(inv, "invoice voided", LedgerAccountRevenue, from, due))...); err != nil {
+		return Invoice{}, nil, err
+	}
+	s.logger.Info("invoice voided", zap.String("id", inv.ID), zap.Int64("amount_voided_cents", due))
+	return inv, []*eventv1.Event{evt, voidedEvent(inv, due, at)}, nil
+}
+
+// MarkUncollectible writes off an open invoice's amount due as bad debt. The
+// invoice can still be paid or voided.
+func (s *Service) MarkUncollectible(ctx context.Context, inv Invoice) (Invoice, *eventv1.Event, error) {
+	due := inv.AmountDue()
+	evt, err := inv.ApplyLifecycle(InvoiceLifecycleUncollectible, InvoiceStatusUncollectible)
+	if err != nil {
+		return Invoice{}, nil, err
+	}
+	inv.UpdatedAt = time.Now().UTC()
+	if err := s.update(ctx, inv, s.postings(invoicePosting(inv, "invoice written off", LedgerAccountBadDebt, LedgerAccountReceivable, due))...); err != nil {
+		return Invoice{}, nil, err
+	}
+	s.logger.Info("invoice marked uncollectible", zap.String("id", inv.ID))
+	return inv, evt, nil
+}
+
+// CreateCreditNote credits an issued invoice, in total or against some of its
+// lines. The credit reduces the invoice's amount due, marking it paid once
+// nothing is left, and any part already paid becomes customer credit.
+func (s *Service) CreateCreditNote(ctx context.Context, inv Invoice, note CreditNote) (CreditNote, Invoice, []*eventv1.Event, error) {
+	status := invoicev1.InvoiceStatus(inv.Status)
+	switch status {
+	case invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN, invoicev1.InvoiceStatus_INVOICE_STATUS_PAID, InvoiceStatusUncollectible:
+	default:
+		return CreditNote{}, Invoice{}, nil, ErrInvoiceNotCreditable
+	}
+	previous, err := s.repo.ListCreditNotes(ctx, inv.ID)
+	if err != nil {
+		return CreditNote{}, Invoice{}, nil, err
+	}
+	if err := note.apply(inv, previous); err != nil {
+		return CreditNote{}, Invoice{}, nil, err
+	}
+
+	now := time.Now().UTC()
+	note.ID = s.genID.Generate().String()
+	note.TenantID = inv.TenantID
+	note.InvoiceID = inv.ID
+	note.CustomerID = inv.CustomerID
+	note.Currency = inv.CurrencyCode
+	note.CreatedAt = now
+	for i := range note.Lines {
+		note.Lines[i].ID = s.genID.Generate().String()
+		note.Lines[i].CreditNoteID = note.ID
+	}
+
+	var evts []*eventv1.Event
+	previousInv := inv
+	inv.CreditedCents += note.AmountCents
+	if note.BalanceCents > 0 && inv.AmountDue() == 0 {
+		evt, err := inv.ApplyLifecycle(InvoiceLifecyclePaid, invoicev1.InvoiceStatus_INVOICE_STATUS_PAID)
+		if err != nil {
+			return CreditNote{}, Invoice{}, nil, err
+		}
+		inv.PaidAt = &now
+		evts = append(evts, evt)
+	}
+	inv.UpdatedAt = now
+	from := LedgerAccountReceivable
+	if status == InvoiceStatusUncollectible {
+		from = LedgerAccountBadDebt
+	}
+	postings := s.postings(
+		creditNotePosting(note, from, note.BalanceCents),
+		creditNotePosting(note, LedgerAccountCustomerCredit, note.CustomerCreditCents),
+	)
+	if err := s.repo.CreateCreditNote(ctx, note, previousInv, inv, postings...); err != nil {
+		s.logger.Error("create credit note", zap.Error(err))
+		return CreditNote{}, Invoice{}, nil, err
+	}
+	s.logger.Info("credit note created",
+		zap.String("id", note.ID),
+		zap.String("invoice_id", inv.ID),
+		zap.Int64("amount_cents", note.AmountCents),
+	)
+	return note, inv, append(evts, creditNoteCreatedEvent(note, inv)), nil
+}
+
+// ListCreditNotes returns an invoice's credit notes, oldest first.
+func (s *Service) ListCreditNotes(ctx context.Context, invoiceID string) ([]CreditNote, error) {
+	return s.repo.ListCreditNotes(ctx, invoiceID)
+}
+
+func (s *Service) update(ctx context.Context, inv Invoice, postings ...LedgerPosting) error {
+	if err := s.repo.Update(ctx, inv, postings...); err != nil {
+		s.logger.Error("update invoice", zap.Error(err))
+		return err
+	}
+	return nil
+}
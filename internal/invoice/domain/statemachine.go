@@ -17,13 +17,22 @@ const (
 	InvoiceLifecycleOpened  InvoiceLifecycle = "invoice.opened"
 	InvoiceLifecyclePaid    InvoiceLifecycle = "invoice.paid"
 	InvoiceLifecycleVoided  InvoiceLifecycle = "invoice.voided"
+	// InvoiceLifecycleUncollectible writes off an open invoice that is not
+	// expected to be paid.
+	InvoiceLifecycleUncollectible InvoiceLifecycle = "invoice.uncollectible"
 )
 
+// InvoiceStatusUncollectible has no value in the published proto enum; it is
+// stored in the status column next to the proto statuses. Uncollectible
+// invoices can still be paid or voided.
+const InvoiceStatusUncollectible = invoicev1.InvoiceStatus(101)
+
 var invoiceTransitions = map[InvoiceLifecycle]transitionRuleInvoice{
-	InvoiceLifecycleCreated: {from: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_UNSPECIFIED)}, to: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_DRAFT)}},
-	InvoiceLifecycleOpened:  {from: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_DRAFT)}, to: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN)}},
-	InvoiceLifecyclePaid:    {from: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN)}, to: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_PAID)}},
-	InvoiceLifecycleVoided:  {from: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_DRAFT), invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN)}, to: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_VOID)}},
+	InvoiceLifecycleCreated:       {from: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_UNSPECIFIED)}, to: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_DRAFT)}},
+	InvoiceLifecycleOpened:        {from: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_DRAFT)}, to: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN)}},
+	InvoiceLifecyclePaid:          {from: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN), InvoiceStatusUncollectible}, to: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_PAID)}},
+	InvoiceLifecycleVoided:        {from: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_DRAFT), invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN), InvoiceStatusUncollectible}, to: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_VOID)}},
+	InvoiceLifecycleUncollectible: {from: []invoicev1.InvoiceStatus{invoiceStatus(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN)}, to: []invoicev1.InvoiceStatus{InvoiceStatusUncollectible}},
 }
 
 type transitionRuleInvoice struct {
@@ -45,13 +54,21 @@ func (inv *Invoice) ApplyLifecycle(event InvoiceLifecycle, target invoicev1.Invo
 	}
 	current := invoicev1.InvoiceStatus(inv.Status)
 	if !containsInvoiceStatus(rule.from, current) && current != invoicev1.InvoiceStatus_INVOICE_STATUS_UNSPECIFIED {
-		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidInvoiceTransition, current.String(), target.String())
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidInvoiceTransition, StatusName(current), StatusName(target))
 	}
 	if !containsInvoiceStatus(rule.to, target) {
-		return nil, fmt.Errorf("%w: invalid target %s for %s", ErrInvalidInvoiceTransition, target.String(), event)
+		return nil, fmt.Errorf("%w: invalid target %s for %s", ErrInvalidInvoiceTransition, StatusName(target), event)
 	}
 	inv.Status = int32(target)
-	return buildInvoiceEvent(inv, target.String())
+	return buildInvoiceEvent(inv, StatusName(target))
+}
+
+// StatusName returns the enum name of a status, including domain-only statuses.
+func StatusName(status invoicev1.InvoiceStatus) string {
+	if status == InvoiceStatusUncollectible {
+		return "INVOICE_STATUS_UNCOLLECTIBLE"
+	}
+	return status.String()
 }
 
 func containsInvoiceStatus(list []invoicev1.InvoiceStatus, status invoicev1.InvoiceStatus) bool {
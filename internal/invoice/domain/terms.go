@@ -0,0 +1,185 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidPaymentTerms is returned for payment terms outside the supported
+// set.
+var ErrInvalidPaymentTerms = errors.New("invalid payment terms")
+
+// PaymentTerms sets how long a customer has to pay an invoice after it is
+// issued.
+type PaymentTerms string
+
+const (
+	PaymentTermsDueOnReceipt PaymentTerms = "due_on_receipt"
+	PaymentTermsNet15        PaymentTerms = "net_15"
+	PaymentTermsNet30        PaymentTerms = "net_30"
+	PaymentTermsNet60        PaymentTerms = "net_60"
+
+	// DefaultPaymentTerms applies to tenants and customers without terms.
+	// Invoices due on receipt would turn overdue on the next scheduler run,
+	// so they must be chosen explicitly.
+	DefaultPaymentTerms = PaymentTermsNet30
+)
+
+var paymentTermDays = map[PaymentTerms]int{
+	PaymentTermsDueOnReceipt: 0,
+	PaymentTermsNet15:        15,
+	PaymentTermsNet30:        30,
+	PaymentTermsNet60:        60,
+}
+
+// ParsePaymentTerms validates terms given by name.
+func ParsePaymentTerms(value string) (PaymentTerms, error) {
+	terms := PaymentTerms(value)
+	if _, ok := paymentTermDays[terms]; !ok {
+		return "", fmt.Errorf("%w: %q, expected due_on_receipt, net_15, net_30 or net_60", ErrInvalidPaymentTerms, value)
+	}
+	return terms, nil
+}
+
+// Days returns the number of days between issue and due date.
+func (t PaymentTerms) Days() int {
+	return paymentTermDays[t]
+}
+
+// DueAt returns the due date of an invoice issued at issuedAt.
+func (t PaymentTerms) DueAt(issuedAt time.Time) time.Time {
+	return issuedAt.AddDate(0, 0, t.Days())
+}
+
+// PaymentTermsSetting stores terms for a tenant, or for one of its customers
+// when CustomerID is set. Customer terms take precedence.
+type PaymentTermsSetting struct {
+	TenantID   string
+	CustomerID string
+	Terms      PaymentTerms
+	UpdatedAt  time.Time
+}
+
+// ResolvePaymentTerms returns the customer's terms, falling back to the
+// tenant's and then to DefaultPaymentTerms.
+func ResolvePaymentTerms(ctx context.Context, repo Repository, tenantID, customerID string) (PaymentTerms, error) {
+	if customerID != "" {
+		setting, ok, err := repo.GetPaymentTerms(ctx, tenantID, customerID)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return setting.Terms, nil
+		}
+	}
+	setting, ok, err := repo.GetPaymentTerms(ctx, tenantID, "")
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return DefaultPaymentTerms, nil
+	}
+	return setting.Terms, nil
+}
+
+// ApplyPaymentTerms sets the due date from the issue date. Invoices that are
+// not issued yet are left unchanged.
+func (inv *Invoice) ApplyPaymentTerms(terms PaymentTerms) {
+	if inv.IssuedAt == nil {
+		return
+	}
+	due := terms.DueAt(*inv.IssuedAt)
+	inv.DueAt = &due
+}
+
+// PastDue reports whether an open invoice still has an amount due after its
+// due date.
+func (inv Invoice) PastDue(now time.Time) bool {
+	return invoicev1.InvoiceStatus(inv.Status) == invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN &&
+		inv.DueAt != nil && now.After(*inv.DueAt) && inv.AmountDue() > 0
+}
+
+// PaymentTerms returns the terms that apply to the customer's invoices, or to
+// the tenant's when customerID is empty.
+func (s *Service) PaymentTerms(ctx context.Context, tenantID, customerID string) (PaymentTerms, error) {
+	return ResolvePaymentTerms(ctx, s.repo, tenantID, customerID)
+}
+
+// SetPaymentTerms stores terms for the tenant or one of its customers. They
+// apply to invoices issued from now on.
+func (s *Service) SetPaymentTerms(ctx context.Context, setting PaymentTermsSetting) (PaymentTermsSetting, error) {
+	if _, err := ParsePaymentTerms(string(setting.Terms)); err != nil {
+		return PaymentTermsSetting{}, err
+	}
+	setting.UpdatedAt = time.Now().UTC()
+	if err := s.repo.SavePaymentTerms(ctx, setting); err != nil {
+		s.logger.Error("save payment terms", zap.Error(err))
+		return PaymentTermsSetting{}, err
+	}
+	s.logger.Info("payment terms updated",
+		zap.String("tenant_id", setting.TenantID),
+		zap.String("customer_id", setting.CustomerID),
+		zap.String("terms", string(setting.Terms)),
+	)
+	return setting, nil
+}
+
+// ClearPaymentTerms removes a customer's terms so the tenant's apply again.
+func (s *Service) ClearPaymentTerms(ctx context.Context, tenantID, customerID string) error {
+	if err := s.repo.DeletePaymentTerms(ctx, tenantID, customerID); err != nil {
+		s.logger.Error("delete payment terms", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ListPastDue returns open invoices whose due date passed before now and
+// that are not marked overdue yet.
+func (s *Service) ListPastDue(ctx context.Context, now time.Time, limit int) ([]Invoice, error) {
+	return s.repo.ListPastDue(ctx, now, limit)
+}
+
+// MarkOverdue records that a past due invoice became overdue at at. The
+// invoice stays open; overdue only flags it for collection and reporting.
+// The invoice.overdue event is stored with the flag.
+func (s *Service) MarkOverdue(ctx context.Context, inv Invoice, at time.Time) (Invoice, error) {
+	if !inv.PastDue(at) {
+		return Invoice{}, fmt.Errorf("%w: invoice %s is not past due", ErrInvalidInvoiceTransition, inv.ID)
+	}
+	if inv.OverdueAt != nil {
+		return inv, nil
+	}
+	// Only the flag is written: the invoice may have been paid or voided
+	// since it was listed.
+	overdue := inv
+	overdue.OverdueAt = &at
+	marked, err := s.repo.MarkOverdue(ctx, inv.ID, at, overdueEvent(overdue, at))
+	if err != nil {
+		s.logger.Error("mark invoice overdue", zap.Error(err), zap.String("id", inv.ID))
+		return Invoice{}, err
+	}
+	if !marked {
+		return inv, nil
+	}
+	s.logger.Info("invoice overdue", zap.String("id", inv.ID), zap.Time("due_at", *inv.DueAt))
+	return overdue, nil
+}
+
+// applyPaymentTerms dates an issued invoice without a due date by the terms of
+// its customer.
+func (s *Service) applyPaymentTerms(ctx context.Context, inv *Invoice) error {
+	if inv.IssuedAt == nil || inv.DueAt != nil {
+		return nil
+	}
+	terms, err := s.PaymentTerms(ctx, inv.TenantID, inv.CustomerID)
+	if err != nil {
+		return err
+	}
+	inv.ApplyPaymentTerms(terms)
+	return nil
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
+)
+
+func TestPaymentTermsDueAt(t *testing.T) {
+	issued := time.Date(2026, 1, 31, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "due_on_receipt", want: issued},
+		{value: "net_15", want: time.Date(2026, 2, 15, 9, 30, 0, 0, time.UTC)},
+		{value: "net_30", want: time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)},
+		{value: "net_60", want: time.Date(2026, 4, 1, 9, 30, 0, 0, time.UTC)},
+		{value: "net_45", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			terms, err := ParsePaymentTerms(tt.value)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPaymentTerms) {
+					t.Fatalf("expected ErrInvalidPaymentTerms got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			inv := Invoice{IssuedAt: &issued}
+			inv.ApplyPaymentTerms(terms)
+			if inv.DueAt == nil || !inv.DueAt.Equal(tt.want) {
+				t.Fatalf("expected due %s got %v", tt.want, inv.DueAt)
+			}
+		})
+	}
+}
+
+func TestInvoicePastDue(t *testing.T) {
+	due := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	open := int32(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN)
+	tests := []struct {
+		name string
+		inv  Invoice
+		now  time.Time
+		want bool
+	}{
+		{name: "past due", inv: Invoice{Status: open, TotalCents: 1000, DueAt: &due}, now: due.Add(time.Second), want: true},
+		{name: "on due date", inv: Invoice{Status: open, TotalCents: 1000, DueAt: &due}, now: due},
+		{name: "no due date", inv: Invoice{Status: open, TotalCents: 1000}, now: due.Add(time.Hour)},
+		{name: "fully credited", inv: Invoice{Status: open, TotalCents: 1000, CreditedCents: 1000, DueAt: &due}, now: due.Add(time.Hour)},
+		{name: "paid", inv: Invoice{Status: int32(invoicev1.InvoiceStatus_INVOICE_STATUS_PAID), TotalCents: 1000, DueAt: &due}, now: due.Add(time.Hour)},
+		{name: "uncollectible", inv: Invoice{Status: int32(InvoiceStatusUncollectible), TotalCents: 1000, DueAt: &due}, now: due.Add(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.inv.PastDue(tt.now); got != tt.want {
+				t.Fatalf("expected %v got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildAgingReport(t *testing.T) {
+	now := time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		at := now.AddDate(0, 0, -days)
+		return &at
+	}
+	open := int32(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN)
+	invoices := []Invoice{
+		{CustomerID: "c2", CurrencyCode: "USD", Status: open, TotalCents: 500, DueAt: daysAgo(-5)},
+		{CustomerID: "c2", CurrencyCode: "USD", Status: open, TotalCents: 700},
+		{CustomerID: "c1", CurrencyCode: "USD", Status: open, TotalCents: 1000, CreditedCents: 200, DueAt: daysAgo(30)},
+		{CustomerID: "c1", CurrencyCode: "USD", Status: open, TotalCents: 300, DueAt: daysAgo(31)},
+		{CustomerID: "c1", CurrencyCode: "EUR", Status: open, TotalCents: 400, DueAt: daysAgo(90)},
+		{CustomerID: "c1", CurrencyCode: "EUR", Status: open, TotalCents: 900, DueAt: daysAgo(91)},
+		{CustomerID: "c1", CurrencyCode: "EUR", Status: open, TotalCents: 100, CreditedCents: 100, DueAt: daysAgo(91)},
+	}
+
+	report := BuildAgingReport("t1", invoices, now)
+	want := []AgingRow{
+		{CustomerID: "c1", Currency: "EUR", BucketCents: [agingBucketCount]int64{AgingOver90: 900, Aging61To90: 400}, TotalCents: 1300, InvoiceCount: 2},
+		{CustomerID: "c1", Currency: "USD", BucketCents: [agingBucketCount]int64{Aging1To30: 800, Aging31To60: 300}, TotalCents: 1100, InvoiceCount: 2},
+		{CustomerID: "c2", Currency: "USD", BucketCents: [agingBucketCount]int64{AgingCurrent: 1200}, TotalCents: 1200, InvoiceCount: 2},
+	}
+	if len(report.Rows) != len(want) {
+		t.Fatalf("expected %d rows got %+v", len(want), report.Rows)
+	}
+	for i := range want {
+		if report.Rows[i] != want[i] {
+			t.Fatalf("row %d: expected %+v got %+v", i, want[i], report.Rows[i])
+		}
+	}
+
+	wantTotals := []AgingRow{
+		{Currency: "EUR", BucketCents: [agingBucketCount]int64{AgingOver90: 900, Aging61To90: 400}, TotalCents: 1300, InvoiceCount: 2},
+		{Currency: "USD", BucketCents: [agingBucketCount]int64{AgingCurrent: 1200, Aging1To30: 800, Aging31To60: 300}, TotalCents: 2300, InvoiceCount: 4},
+	}
+	if len(report.Totals) != len(wantTotals) {
+		t.Fatalf("expected %d totals got %+v", len(wantTotals), report.Totals)
+	}
+	for i := range wantTotals {
+		if report.Totals[i] != wantTotals[i] {
+			t.Fatalf("total %d: expected %+v got %+v", i, wantTotals[i], report.Totals[i])
+		}
+	}
+}
@@ -3,7 +3,9 @@ package invoice
 import (
 	"context"
 	"strconv"
+	"time"
 
+	"github.com/smallbiznis/corebilling/internal/events/outbox"
 	"github.com/smallbiznis/corebilling/internal/invoice/domain"
 	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
 	"go.uber.org/fx"
@@ -17,8 +19,8 @@ import (
 // ModuleGRPC registers the invoice service with the shared gRPC server.
 var ModuleGRPC = fx.Invoke(RegisterGRPC)
 
-func RegisterService(svc *domain.Service) *grpcService {
-	return &grpcService{svc: svc}
+func RegisterService(svc *domain.Service, outboxRepo outbox.OutboxRepository, documents *documentBuilder) *grpcService {
+	return &grpcService{svc: svc, outbox: outboxRepo, documents: documents}
 }
 
 // RegisterGRPC attaches the invoice handler.
@@ -28,7 +30,9 @@ func RegisterGRPC(server *grpc.Server, svc *grpcService) {
 
 type grpcService struct {
 	invoicev1.UnimplementedInvoiceServiceServer
-	svc *domain.Service
+	svc       *domain.Service
+	outbox    outbox.OutboxRepository
+	documents *documentBuilder
 }
 
 const (
@@ -113,10 +117,67 @@ func (g *grpcService) toProto(inv domain.Invoice) *invoicev1.Invoice {
 		IssuedAt:       issuedAt,
 		DueAt:          dueAt,
 		PaidAt:         paidAt,
-		Metadata:       mapToStruct(inv.Metadata),
+		Metadata:       mapToStruct(withCredits(withLineItems(inv.Metadata, inv.LineItems), inv)),
 	}
 }
 
+// withCredits exposes the credited amount and what is left to collect once an
+// invoice has credit notes.
+func withCredits(metadata map[string]interface{}, inv domain.Invoice) map[string]interface{} {
+	if inv.CreditedCents == 0 {
+		return metadata
+	}
+	out := make(map[string]interface{}, len(metadata)+2)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	out["credited_cents"] = float64(inv.CreditedCents)
+	out["amount_due_cents"] = float64(inv.AmountDue())
+	return out
+}
+
+// withLineItems exposes itemized charges under the line_items metadata key,
+// since the invoice message carries totals only.
+func withLineItems(metadata map[string]interface{}, items []domain.LineItem) map[string]interface{} {
+	if len(items) == 0 {
+		return metadata
+	}
+	out := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	lines := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		line := map[string]interface{}{
+			"id":                item.ID,
+			"type":              string(item.Type),
+			"description":       item.Description,
+			"quantity":          item.Quantity,
+			"unit_amount_cents": float64(item.UnitAmountCents),
+			"amount_cents":      float64(item.AmountCents),
+			"currency":          item.Currency,
+		}
+		if item.PriceID != "" {
+			line["price_id"] = item.PriceID
+		}
+		if item.MeterCode != "" {
+			line["meter_code"] = item.MeterCode
+		}
+		if item.PeriodStart != nil {
+			line["period_start"] = item.PeriodStart.Format(time.RFC3339)
+		}
+		if item.PeriodEnd != nil {
+			line["period_end"] = item.PeriodEnd.Format(time.RFC3339)
+		}
+		if len(item.Metadata) > 0 {
+			line["metadata"] = item.Metadata
+		}
+		lines = append(lines, line)
+	}
+	out["line_items"] = lines
+	return out
+}
+
 func parsePageToken(token string) int {
 	if token == "" {
 		return 0
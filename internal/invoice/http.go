@@ -2,11 +2,25 @@ package invoice
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	"github.com/smallbiznis/corebilling/internal/headers"
+	"github.com/smallbiznis/corebilling/internal/invoice/domain"
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
 	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
 	"go.uber.org/fx"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 var ModuleHTTP = fx.Invoke(RegisterHTTP)
@@ -17,7 +31,490 @@ func RegisterHTTP(lc fx.Lifecycle, s *grpc.Server, mux *runtime.ServeMux, svc *g
 			if err := invoicev1.RegisterInvoiceServiceHandlerServer(ctx, mux, svc); err != nil {
 				return err
 			}
-			return nil
+			if err := mux.HandlePath(http.MethodPost, "/v1/invoices/{id}/finalize", svc.finalizeHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPost, "/v1/invoices/{id}/void", svc.voidHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPost, "/v1/invoices/{id}/mark_uncollectible", svc.markUncollectibleHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodGet, "/v1/invoices/{id}/credit_notes", svc.listCreditNotesHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPost, "/v1/invoices/{id}/credit_notes", svc.createCreditNoteHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodGet, "/v1/invoices/{id}/pdf", svc.pdfHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodGet, "/v1/invoice_numbering", svc.getNumberingHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPut, "/v1/invoice_numbering", svc.updateNumberingHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodGet, "/v1/invoice_payment_terms", svc.getPaymentTermsHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPut, "/v1/invoice_payment_terms", svc.setPaymentTermsHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodGet, "/v1/customers/{customer_id}/payment_terms", svc.getPaymentTermsHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPut, "/v1/customers/{customer_id}/payment_terms", svc.setPaymentTermsHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodDelete, "/v1/customers/{customer_id}/payment_terms", svc.clearPaymentTermsHandler); err != nil {
+				return err
+			}
+			return mux.HandlePath(http.MethodGet, "/v1/invoice_aging", svc.agingHandler)
 		},
 	})
 }
+
+// finalizeHandler opens a draft invoice for collection.
+func (g *grpcService) finalizeHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	inv, err := g.tenantInvoice(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	inv, evt, err := g.svc.Finalize(r.Context(), inv)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	g.emit(r.Context(), evt)
+	writeProto(w, g.toProto(inv))
+}
+
+// voidHandler cancels an unpaid invoice.
+func (g *grpcService) voidHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	inv, err := g.tenantInvoice(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	inv, evts, err := g.svc.Void(r.Context(), inv, time.Now().UTC())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for _, evt := range evts {
+		g.emit(r.Context(), evt)
+	}
+	writeProto(w, g.toProto(inv))
+}
+
+// markUncollectibleHandler writes off an open invoice as bad debt.
+func (g *grpcService) markUncollectibleHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	inv, err := g.tenantInvoice(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	inv, evt, err := g.svc.MarkUncollectible(r.Context(), inv)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	g.emit(r.Context(), evt)
+	writeProto(w, g.toProto(inv))
+}
+
+// pdfHandler downloads the invoice as a PDF document.
+func (g *grpcService) pdfHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	inv, err := g.tenantInvoice(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	name := inv.InvoiceNumber
+	if name == "" {
+		name = "invoice-" + inv.ID
+	}
+	body := g.documents.Render(r.Context(), inv)
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", strings.NewReplacer("/", "-", "\\", "-").Replace(name)+".pdf"))
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+type creditNoteLineRequest struct {
+	LineItemID  string `json:"line_item_id"`
+	Description string `json:"description"`
+	AmountCents int64  `json:"amount_cents"`
+}
+
+type creditNoteRequest struct {
+	Reason      string                  `json:"reason"`
+	Memo        string                  `json:"memo"`
+	AmountCents int64                   `json:"amount_cents"`
+	Lines       []creditNoteLineRequest `json:"lines"`
+}
+
+type creditNoteLineResponse struct {
+	ID          string `json:"id"`
+	LineItemID  string `json:"line_item_id"`
+	Description string `json:"description,omitempty"`
+	AmountCents int64  `json:"amount_cents"`
+}
+
+type creditNoteResponse struct {
+	ID                  string                   `json:"id"`
+	InvoiceID           string                   `json:"invoice_id"`
+	CustomerID          string                   `json:"customer_id,omitempty"`
+	Reason              string                   `json:"reason"`
+	Memo                string                   `json:"memo,omitempty"`
+	Currency            string                   `json:"currency"`
+	AmountCents         int64                    `json:"amount_cents"`
+	BalanceCents        int64                    `json:"balance_cents"`
+	CustomerCreditCents int64                    `json:"customer_credit_cents"`
+	Lines               []creditNoteLineResponse `json:"lines"`
+	CreatedAt           time.Time                `json:"created_at"`
+}
+
+func toCreditNoteResponse(note domain.CreditNote) creditNoteResponse {
+	lines := make([]creditNoteLineResponse, 0, len(note.Lines))
+	for _, line := range note.Lines {
+		lines = append(lines, creditNoteLineResponse{
+			ID:          line.ID,
+			LineItemID:  line.LineItemID,
+			Description: line.Description,
+			AmountCents: line.AmountCents,
+		})
+	}
+	return creditNoteResponse{
+		ID:                  note.ID,
+		InvoiceID:           note.InvoiceID,
+		CustomerID:          note.CustomerID,
+		Reason:              string(note.Reason),
+		Memo:                note.Memo,
+		Currency:            note.Currency,
+		AmountCents:         note.AmountCents,
+		BalanceCents:        note.BalanceCents,
+		CustomerCreditCents: note.CustomerCreditCents,
+		Lines:               lines,
+		CreatedAt:           note.CreatedAt,
+	}
+}
+
+// createCreditNoteHandler credits an issued invoice by amount_cents or by
+// the given lines.
+func (g *grpcService) createCreditNoteHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	inv, err := g.tenantInvoice(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var body creditNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+		return
+	}
+	reason, err := domain.ParseCreditNoteReason(body.Reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	note := domain.CreditNote{Reason: reason, Memo: body.Memo, AmountCents: body.AmountCents}
+	for _, line := range body.Lines {
+		note.Lines = append(note.Lines, domain.CreditNoteLine{
+			LineItemID:  line.LineItemID,
+			Description: line.Description,
+			AmountCents: line.AmountCents,
+		})
+	}
+	note, inv, evts, err := g.svc.CreateCreditNote(r.Context(), inv, note)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for _, evt := range evts {
+		g.emit(r.Context(), evt)
+	}
+	payload, err := protojson.Marshal(g.toProto(inv))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"credit_note": toCreditNoteResponse(note),
+		"invoice":     json.RawMessage(payload),
+	})
+}
+
+func (g *grpcService) listCreditNotesHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	inv, err := g.tenantInvoice(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	notes, err := g.svc.ListCreditNotes(r.Context(), inv.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := make([]creditNoteResponse, 0, len(notes))
+	for _, note := range notes {
+		resp = append(resp, toCreditNoteResponse(note))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"credit_notes": resp})
+}
+
+type numberingRequest struct {
+	Prefix   string `json:"prefix"`
+	Template string `json:"template"`
+}
+
+type numberingResponse struct {
+	Prefix   string `json:"prefix"`
+	Template string `json:"template"`
+	// Example is the number the template renders for the first invoice of
+	// the current series.
+	Example string `json:"example"`
+}
+
+func toNumberingResponse(scheme domain.NumberingScheme) numberingResponse {
+	return numberingResponse{
+		Prefix:   scheme.Prefix,
+		Template: scheme.Template,
+		Example:  scheme.Format(time.Now().UTC(), 1),
+	}
+}
+
+func (g *grpcService) getNumberingHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+		return
+	}
+	scheme, err := g.svc.NumberingScheme(r.Context(), tenantID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toNumberingResponse(scheme))
+}
+
+// updateNumberingHandler replaces the tenant's invoice number prefix and
+// template.
+func (g *grpcService) updateNumberingHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+		return
+	}
+	var body numberingRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+		return
+	}
+	scheme, err := g.svc.UpdateNumberingScheme(r.Context(), domain.NumberingScheme{
+		TenantID: tenantID,
+		Prefix:   body.Prefix,
+		Template: body.Template,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toNumberingResponse(scheme))
+}
+
+type paymentTermsRequest struct {
+	Terms string `json:"terms"`
+}
+
+type paymentTermsResponse struct {
+	CustomerID string `json:"customer_id,omitempty"`
+	Terms      string `json:"terms"`
+	NetDays    int    `json:"net_days"`
+}
+
+func toPaymentTermsResponse(customerID string, terms domain.PaymentTerms) paymentTermsResponse {
+	return paymentTermsResponse{CustomerID: customerID, Terms: string(terms), NetDays: terms.Days()}
+}
+
+// getPaymentTermsHandler returns the terms in effect for the tenant, or for a
+// customer when the path names one.
+func (g *grpcService) getPaymentTermsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+		return
+	}
+	terms, err := g.svc.PaymentTerms(r.Context(), tenantID, params["customer_id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toPaymentTermsResponse(params["customer_id"], terms))
+}
+
+// setPaymentTermsHandler replaces the payment terms of the tenant or of a
+// customer.
+func (g *grpcService) setPaymentTermsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+		return
+	}
+	var body paymentTermsRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+		return
+	}
+	setting, err := g.svc.SetPaymentTerms(r.Context(), domain.PaymentTermsSetting{
+		TenantID:   tenantID,
+		CustomerID: params["customer_id"],
+		Terms:      domain.PaymentTerms(body.Terms),
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toPaymentTermsResponse(setting.CustomerID, setting.Terms))
+}
+
+// clearPaymentTermsHandler removes a customer's terms and returns the
+// tenant's terms that apply instead.
+func (g *grpcService) clearPaymentTermsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	customerID := params["customer_id"]
+	if tenantID == "" || customerID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "tenant_id and customer_id required"))
+		return
+	}
+	if err := g.svc.ClearPaymentTerms(r.Context(), tenantID, customerID); err != nil {
+		writeError(w, err)
+		return
+	}
+	terms, err := g.svc.PaymentTerms(r.Context(), tenantID, customerID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toPaymentTermsResponse(customerID, terms))
+}
+
+type agingRowResponse struct {
+	CustomerID   string           `json:"customer_id,omitempty"`
+	Currency     string           `json:"currency"`
+	Buckets      map[string]int64 `json:"buckets"`
+	TotalCents   int64            `json:"total_cents"`
+	InvoiceCount int              `json:"invoice_count"`
+}
+
+type agingResponse struct {
+	AsOf      time.Time          `json:"as_of"`
+	Customers []agingRowResponse `json:"customers"`
+	Totals    []agingRowResponse `json:"totals"`
+}
+
+func toAgingRows(rows []domain.AgingRow) []agingRowResponse {
+	resp := make([]agingRowResponse, 0, len(rows))
+	for _, row := range rows {
+		buckets := make(map[string]int64, len(domain.AgingBuckets))
+		for _, bucket := range domain.AgingBuckets {
+			buckets[bucket.String()] = row.BucketCents[bucket]
+		}
+		resp = append(resp, agingRowResponse{
+			CustomerID:   row.CustomerID,
+			Currency:     row.Currency,
+			Buckets:      buckets,
+			TotalCents:   row.TotalCents,
+			InvoiceCount: row.InvoiceCount,
+		})
+	}
+	return resp
+}
+
+// agingHandler reports the tenant's open receivables by days past due, as of
+// now or the as_of query parameter.
+func (g *grpcService) agingHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+		return
+	}
+	asOf := time.Now().UTC()
+	if value := r.URL.Query().Get("as_of"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "as_of must be an RFC 3339 timestamp"))
+			return
+		}
+		asOf = parsed.UTC()
+	}
+	report, err := g.svc.AgingReport(r.Context(), tenantID, asOf)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, agingResponse{
+		AsOf:      report.AsOf,
+		Customers: toAgingRows(report.Rows),
+		Totals:    toAgingRows(report.Totals),
+	})
+}
+
+// tenantInvoice loads an invoice owned by the request's tenant.
+func (g *grpcService) tenantInvoice(r *http.Request, id string) (domain.Invoice, error) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" || id == "" {
+		return domain.Invoice{}, status.Error(codes.InvalidArgument, "tenant_id and invoice id required")
+	}
+	inv, err := g.svc.Get(r.Context(), id)
+	if err != nil || inv.TenantID != tenantID {
+		return domain.Invoice{}, status.Error(codes.NotFound, "invoice not found")
+	}
+	return inv, nil
+}
+
+// emit stores an invoice event in the outbox.
+func (g *grpcService) emit(ctx context.Context, evt *eventv1.Event) {
+	if g.outbox == nil || evt == nil {
+		return
+	}
+	_ = g.outbox.InsertOutboxEvent(ctx, &outbox.OutboxEvent{Subject: evt.GetSubject(), TenantID: evt.GetTenantId(), Event: evt})
+}
+
+// toStatus maps domain errors onto gRPC status codes for the HTTP response.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCreditNote), errors.Is(err, domain.ErrInvalidNumberingScheme), errors.Is(err, domain.ErrInvalidPaymentTerms):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrInvalidInvoiceTransition), errors.Is(err, domain.ErrInvoiceNotCreditable):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, domain.ErrInvoiceChanged):
+		return status.Error(codes.Aborted, err.Error())
+	}
+	return err
+}
+
+func writeProto(w http.ResponseWriter, inv *invoicev1.Invoice) {
+	body, err := protojson.Marshal(inv)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(toStatus(err))
+	writeJSON(w, runtime.HTTPStatusFromCode(st.Code()), map[string]string{"error": st.Message()})
+}
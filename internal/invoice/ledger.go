@@ -0,0 +1,60 @@
+package invoice
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/smallbiznis/corebilling/internal/invoice/domain"
+	ledgerdomain "github.com/smallbiznis/corebilling/internal/ledger/domain"
+)
+
+// ledgerAdapter posts invoice accounting to the tenant's billing accounts.
+type ledgerAdapter struct {
+	ledger *ledgerdomain.Service
+}
+
+// NewLedger adapts the ledger service for invoice postings.
+func NewLedger(ledger *ledgerdomain.Service) domain.Ledger {
+	return &ledgerAdapter{ledger: ledger}
+}
+
+func (a *ledgerAdapter) Post(ctx context.Context, posting domain.LedgerPosting) error {
+	return a.ledger.PostBilling(ctx, ledgerdomain.JournalEntry{
+		ID:            posting.ID,
+		TenantID:      posting.TenantID,
+		ReferenceID:   posting.ReferenceID,
+		ReferenceType: posting.ReferenceType,
+		Description:   posting.Description,
+	}, posting.Currency, posting.Debit, posting.Credit, posting.AmountCents)
+}
+
+// LedgerWorker posts the ledger postings queued with invoice changes,
+// retrying failed ones.
+type LedgerWorker struct {
+	svc    *domain.Service
+	logger *zap.Logger
+}
+
+// NewLedgerWorker constructs the ledger posting worker.
+func NewLedgerWorker(svc *domain.Service, logger *zap.Logger) *LedgerWorker {
+	return &LedgerWorker{svc: svc, logger: logger.Named("invoice.ledger_worker")}
+}
+
+// Run starts the periodic worker.
+func (w *LedgerWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := w.svc.PostLedger(ctx, time.Now().UTC()); err != nil {
+				w.logger.Error("failed to post invoice ledger postings", zap.Error(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -1,17 +1,46 @@
 package invoice
 
 import (
+	"context"
+
 	"go.uber.org/fx"
+	"go.uber.org/zap"
 
 	"github.com/smallbiznis/corebilling/internal/invoice/domain"
 	reposqlc "github.com/smallbiznis/corebilling/internal/invoice/repository/sqlc"
 )
 
-// Module wires invoice services.
+// Module wires invoice services, the overdue scheduler and the ledger worker.
 var Module = fx.Options(
 	fx.Provide(reposqlc.NewRepository),
+	fx.Provide(NewLedger),
+	fx.Provide(NewDocumentBuilder),
 	fx.Provide(domain.NewService),
 	fx.Provide(RegisterService),
+	fx.Provide(NewScheduler),
+	fx.Invoke(startScheduler),
+	fx.Provide(NewLedgerWorker),
+	fx.Invoke(startLedgerWorker),
 	ModuleGRPC,
 	ModuleHTTP,
 )
+
+func startScheduler(lc fx.Lifecycle, scheduler *Scheduler, logger *zap.Logger) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go scheduler.Run(ctx)
+			logger.Info("invoice overdue scheduler started")
+			return nil
+		},
+	})
+}
+
+func startLedgerWorker(lc fx.Lifecycle, worker *LedgerWorker, logger *zap.Logger) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go worker.Run(ctx)
+			logger.Info("invoice ledger worker started")
+			return nil
+		},
+	})
+}
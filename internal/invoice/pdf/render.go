@@ -0,0 +1,307 @@
+// Package pdf renders invoices as PDF documents using only the standard
+// Helvetica fonts, without external dependencies.
+package pdf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Party is the seller or customer block of an invoice.
+type Party struct {
+	Name    string
+	Email   string
+	TaxID   string
+	Address []string
+}
+
+// Line is a row of the invoice table.
+type Line struct {
+	Description     string
+	Period          string
+	Quantity        float64
+	UnitAmountCents int64
+	AmountCents     int64
+}
+
+// TaxLine is one entry of the tax breakdown.
+type TaxLine struct {
+	Description string
+	AmountCents int64
+}
+
+// Invoice holds everything printed on an invoice document.
+type Invoice struct {
+	Number   string
+	Status   string
+	Currency string
+	IssuedAt *time.Time
+	DueAt    *time.Time
+	PaidAt   *time.Time
+	Seller   Party
+	Customer Party
+	// BrandColor is the seller's accent color as #rrggbb.
+	BrandColor          string
+	Lines               []Line
+	Taxes               []TaxLine
+	SubtotalCents       int64
+	TaxCents            int64
+	TotalCents          int64
+	CreditedCents       int64
+	AmountDueCents      int64
+	PaymentInstructions string
+	Footer              string
+}
+
+const (
+	margin    = 50.0
+	right     = pageWidth - margin
+	bottom    = 90.0
+	colQty    = 360.0
+	colUnit   = 450.0
+	bodySize  = 9.5
+	leading   = 14.0
+	dateStyle = "Jan 2, 2006"
+)
+
+// Render lays out the invoice on as many A4 pages as its lines need.
+func Render(inv Invoice) []byte {
+	r := &renderer{inv: inv, accent: parseColor(inv.BrandColor)}
+	r.header()
+	r.parties()
+	r.table()
+	r.totals()
+	r.instructions()
+	r.footers()
+	return r.w.bytes()
+}
+
+type renderer struct {
+	w      writer
+	inv    Invoice
+	accent color
+	y      float64
+}
+
+func (r *renderer) header() {
+	r.w.newPage()
+	r.w.rect(0, pageHeight-8, pageWidth, 8, r.accent)
+	y := pageHeight - margin - 10
+	name := r.inv.Seller.Name
+	if name == "" {
+		name = "Invoice"
+	}
+	r.w.text(margin, y, bold, 20, r.accent, fit(name, bold, 20, 300))
+	r.w.textRight(right, y, bold, 20, black, "INVOICE")
+
+	y -= 24
+	for _, field := range [][2]string{
+		{"Invoice number", r.inv.Number},
+		{"Date of issue", formatDate(r.inv.IssuedAt)},
+		{"Date due", formatDate(r.inv.DueAt)},
+		{"Date paid", formatDate(r.inv.PaidAt)},
+		{"Status", r.inv.Status},
+	} {
+		if field[1] == "" {
+			continue
+		}
+		r.w.textRight(right-120, y, regular, bodySize, gray, field[0])
+		r.w.textRight(right, y, bold, bodySize, black, field[1])
+		y -= leading
+	}
+	r.y = y - 10
+}
+
+func (r *renderer) parties() {
+	top := r.y
+	left := r.party(margin, top, "From", r.inv.Seller)
+	next := r.party(320, top, "Bill to", r.inv.Customer)
+	r.y = min(left, next) - 16
+}
+
+// party prints a labelled party block at x and returns the y below it.
+func (r *renderer) party(x, y float64, label string, p Party) float64 {
+	r.w.text(x, y, bold, bodySize, gray, strings.ToUpper(label))
+	y -= leading
+	rows := append([]string{p.Name}, p.Address...)
+	rows = append(rows, p.Email)
+	if p.TaxID != "" {
+		rows = append(rows, "Tax ID: "+p.TaxID)
+	}
+	for i, row := range rows {
+		if row == "" {
+			continue
+		}
+		f := regular
+		if i == 0 {
+			f = bold
+		}
+		r.w.text(x, y, f, bodySize, black, fit(row, f, bodySize, 220))
+		y -= leading
+	}
+	return y
+}
+
+func (r *renderer) tableHeader() {
+	r.w.text(margin, r.y, bold, bodySize, gray, "DESCRIPTION")
+	r.w.textRight(colQty+30, r.y, bold, bodySize, gray, "QTY")
+	r.w.textRight(colUnit+50, r.y, bold, bodySize, gray, "UNIT PRICE")
+	r.w.textRight(right, r.y, bold, bodySize, gray, "AMOUNT")
+	r.y -= 6
+	r.w.rule(margin, right, r.y, gray)
+	r.y -= leading
+}
+
+func (r *renderer) table() {
+	r.tableHeader()
+	for _, line := range r.inv.Lines {
+		rows := wrap(line.Description, regular, bodySize, colQty-margin-40)
+		if line.Period != "" {
+			rows = append(rows, line.Period)
+		}
+		if len(rows) == 0 {
+			rows = []string{""}
+		}
+		r.ensure(float64(len(rows))*leading, true)
+		r.w.textRight(colQty+30, r.y, regular, bodySize, black, formatQuantity(line.Quantity))
+		r.w.textRight(colUnit+50, r.y, regular, bodySize, black, r.money(line.UnitAmountCents))
+		r.w.textRight(right, r.y, regular, bodySize, black, r.money(line.AmountCents))
+		for i, row := range rows {
+			c := black
+			if line.Period != "" && i == len(rows)-1 {
+				c = gray
+			}
+			r.w.text(margin, r.y, regular, bodySize, c, row)
+			r.y -= leading
+		}
+		r.y -= 2
+	}
+	r.w.rule(margin, right, r.y+leading/2, gray)
+	r.y -= 6
+}
+
+func (r *renderer) totals() {
+	rows := [][2]string{{"Subtotal", r.money(r.inv.SubtotalCents)}}
+	for _, tax := range r.inv.Taxes {
+		rows = append(rows, [2]string{tax.Description, r.money(tax.AmountCents)})
+	}
+	if len(r.inv.Taxes) == 0 && r.inv.TaxCents != 0 {
+		rows = append(rows, [2]string{"Tax", r.money(r.inv.TaxCents)})
+	}
+	rows = append(rows, [2]string{"Total", r.money(r.inv.TotalCents)})
+	if r.inv.CreditedCents != 0 {
+		rows = append(rows, [2]string{"Credited", r.money(-r.inv.CreditedCents)})
+	}
+	r.ensure(float64(len(rows)+1)*leading, false)
+	for _, row := range rows {
+		r.w.textRight(colUnit+50, r.y, regular, bodySize, gray, fit(row[0], regular, bodySize, 200))
+		r.w.textRight(right, r.y, regular, bodySize, black, row[1])
+		r.y -= leading
+	}
+	r.y -= 4
+	r.w.textRight(colUnit+50, r.y, bold, 11, black, "Amount due")
+	r.w.textRight(right, r.y, bold, 11, r.accent, r.money(r.inv.AmountDueCents))
+	r.y -= 2 * leading
+}
+
+func (r *renderer) instructions() {
+	if r.inv.PaymentInstructions == "" {
+		return
+	}
+	var rows []string
+	for _, paragraph := range strings.Split(r.inv.PaymentInstructions, "\n") {
+		rows = append(rows, wrap(paragraph, regular, bodySize, right-margin)...)
+	}
+	r.ensure(float64(len(rows)+1)*leading, false)
+	r.w.text(margin, r.y, bold, bodySize, gray, "PAYMENT INSTRUCTIONS")
+	r.y -= leading
+	for _, row := range rows {
+		r.w.text(margin, r.y, regular, bodySize, black, row)
+		r.y -= leading
+	}
+}
+
+// footers prints the footer text and page numbers once the page count is
+// known.
+func (r *renderer) footers() {
+	for i := range r.w.pages {
+		r.w.current = i
+		if r.inv.Footer != "" {
+			r.w.text(margin, 40, regular, 8, gray, fit(r.inv.Footer, regular, 8, 400))
+		}
+		r.w.textRight(right, 40, regular, 8, gray, fmt.Sprintf("Page %d of %d", i+1, len(r.w.pages)))
+	}
+}
+
+// ensure starts a new page unless height fits above the bottom margin. Pages
+// continuing the table repeat its header.
+func (r *renderer) ensure(height float64, tableHeader bool) {
+	if r.y-height >= bottom {
+		return
+	}
+	r.w.newPage()
+	r.w.rect(0, pageHeight-8, pageWidth, 8, r.accent)
+	r.y = pageHeight - margin - 10
+	if tableHeader {
+		r.tableHeader()
+	}
+}
+
+func (r *renderer) money(cents int64) string {
+	return formatMoney(cents, r.inv.Currency)
+}
+
+// zeroDecimal lists currencies without a minor unit.
+var zeroDecimal = map[string]bool{"JPY": true, "KRW": true, "VND": true, "CLP": true, "ISK": true}
+
+// formatMoney prints minor units with thousands separators and the currency
+// code, e.g. 1,234.50 USD.
+func formatMoney(cents int64, currency string) string {
+	currency = strings.ToUpper(currency)
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	whole, frac := cents, int64(0)
+	if !zeroDecimal[currency] {
+		whole, frac = cents/100, cents%100
+	}
+	digits := strconv.FormatInt(whole, 10)
+	var grouped strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(d)
+	}
+	amount := sign + grouped.String()
+	if !zeroDecimal[currency] {
+		amount += fmt.Sprintf(".%02d", frac)
+	}
+	return strings.TrimSpace(amount + " " + currency)
+}
+
+func formatQuantity(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(dateStyle)
+}
+
+// parseColor reads #rrggbb, defaulting to a dark slate.
+func parseColor(hex string) color {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) == 6 {
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return color{float64(v>>16&0xff) / 255, float64(v>>8&0xff) / 255, float64(v&0xff) / 255}
+		}
+	}
+	return color{0.16, 0.2, 0.27}
+}
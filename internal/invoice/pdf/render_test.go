@@ -0,0 +1,74 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	issued := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	inv := Invoice{
+		Number:     "INV-000042",
+		Status:     "open",
+		Currency:   "eur",
+		IssuedAt:   &issued,
+		DueAt:      &issued,
+		Seller:     Party{Name: "Acme (EU) GmbH", Address: []string{"Hauptstraße 1", "10115 Berlin"}, TaxID: "DE123"},
+		Customer:   Party{Name: "Globex", Email: "billing@globex.test"},
+		BrandColor: "#ff6600",
+		Taxes:      []TaxLine{{Description: "VAT 19%", AmountCents: 1900}},
+		TotalCents: 11900, SubtotalCents: 10000, TaxCents: 1900, AmountDueCents: 11900,
+		PaymentInstructions: "IBAN DE00 1234\nReference INV-000042",
+	}
+	for i := 0; i < 45; i++ {
+		inv.Lines = append(inv.Lines, Line{Description: fmt.Sprintf("Seat %d", i), Quantity: 1, UnitAmountCents: 125, AmountCents: 125})
+	}
+
+	out := Render(inv)
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("missing PDF header or trailer")
+	}
+	for _, want := range []string{`(Acme \(EU\) GmbH)`, `(Hauptstra\337e 1)`, `(VAT 19%)`, `(119.00 EUR)`, `(Reference INV-000042)`, `(Page 2 of 2)`} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Fatalf("expected %s in output", want)
+		}
+	}
+	if !bytes.Contains(out, []byte("/Count 2")) {
+		t.Fatalf("expected the lines to span two pages")
+	}
+
+	// Every cross-reference entry must point at the start of its object.
+	start := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(out)
+	xref, _ := strconv.Atoi(string(start[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(out[xref:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Fatalf("xref entry %d points at %q", i+1, out[offset:offset+10])
+		}
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		cents    int64
+		currency string
+		want     string
+	}{
+		{cents: 0, currency: "usd", want: "0.00 USD"},
+		{cents: 123456789, currency: "USD", want: "1,234,567.89 USD"},
+		{cents: -2500, currency: "EUR", want: "-25.00 EUR"},
+		{cents: 1500, currency: "JPY", want: "1,500 JPY"},
+		{cents: 5, currency: "", want: "0.05"},
+	}
+
+	for _, tt := range tests {
+		if got := formatMoney(tt.cents, tt.currency); got != tt.want {
+			t.Fatalf("formatMoney(%d, %q): expected %q got %q", tt.cents, tt.currency, tt.want, got)
+		}
+	}
+}
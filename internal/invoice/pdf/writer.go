@@ -0,0 +1,227 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Page size in points (A4).
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+)
+
+// font selects one of the two standard fonts every PDF reader provides, so
+// no font data has to be embedded.
+type font int
+
+const (
+	regular font = iota
+	bold
+)
+
+func (f font) resource() string {
+	if f == bold {
+		return "/F2"
+	}
+	return "/F1"
+}
+
+// color is an RGB fill color with components between 0 and 1.
+type color struct{ r, g, b float64 }
+
+var (
+	black = color{0, 0, 0}
+	gray  = color{0.42, 0.42, 0.42}
+)
+
+// writer lays out text and rules on pages and serializes them as a PDF 1.4
+// file.
+type writer struct {
+	pages []*bytes.Buffer
+	// current is the page drawn on.
+	current int
+}
+
+func (w *writer) newPage() {
+	w.pages = append(w.pages, &bytes.Buffer{})
+	w.current = len(w.pages) - 1
+}
+
+func (w *writer) page() *bytes.Buffer {
+	if len(w.pages) == 0 {
+		w.newPage()
+	}
+	return w.pages[w.current]
+}
+
+// text draws s with its baseline starting at x, y, measured from the bottom
+// left corner of the page.
+func (w *writer) text(x, y float64, f font, size float64, c color, s string) {
+	fmt.Fprintf(w.page(), "BT %s %.1f Tf %.3f %.3f %.3f rg %.2f %.2f Td (%s) Tj ET\n",
+		f.resource(), size, c.r, c.g, c.b, x, y, escape(encode(s)))
+}
+
+// textRight draws s ending at x.
+func (w *writer) textRight(x, y float64, f font, size float64, c color, s string) {
+	w.text(x-textWidth(s, f, size), y, f, size, c, s)
+}
+
+// rule draws a horizontal line from x1 to x2.
+func (w *writer) rule(x1, x2, y float64, c color) {
+	fmt.Fprintf(w.page(), "%.3f %.3f %.3f RG 0.5 w %.2f %.2f m %.2f %.2f l S\n", c.r, c.g, c.b, x1, y, x2, y)
+}
+
+// rect fills a rectangle with its lower left corner at x, y.
+func (w *writer) rect(x, y, width, height float64, c color) {
+	fmt.Fprintf(w.page(), "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n", c.r, c.g, c.b, x, y, width, height)
+}
+
+// bytes serializes the pages: catalog, page tree, fonts, then each page with
+// its content stream, followed by the cross-reference table.
+func (w *writer) bytes() []byte {
+	if len(w.pages) == 0 {
+		w.newPage()
+	}
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	const firstPage = 5
+	kids := make([]string, len(w.pages))
+	for i := range w.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(w.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range w.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// encode converts s to WinAnsi bytes. Characters outside Latin-1, other than
+// the euro sign, are replaced with '?'.
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '€':
+			out = append(out, 0x80)
+		case r == '\t' || r == '\n' || r == '\r':
+			out = append(out, ' ')
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// escape quotes the delimiters of a PDF string literal and writes bytes
+// outside ASCII as octal escapes.
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c >= 0x80:
+			fmt.Fprintf(&sb, "\\%03o", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// textWidth returns the width of s in points.
+func textWidth(s string, f font, size float64) float64 {
+	widths := &helveticaWidths
+	if f == bold {
+		widths = &helveticaBoldWidths
+	}
+	var units int
+	for _, c := range encode(s) {
+		if c >= 0x20 && c < 0x7f {
+			units += widths[c-0x20]
+		} else {
+			units += 556
+		}
+	}
+	return float64(units) * size / 1000
+}
+
+// fit shortens s with an ellipsis until it fits in width.
+func fit(s string, f font, size, width float64) string {
+	if textWidth(s, f, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", f, size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// wrap breaks s into lines no wider than width, at spaces where possible.
+func wrap(s string, f font, size, width float64) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if textWidth(candidate, f, size) <= width {
+			current = candidate
+			continue
+		}
+		if current != "" {
+			lines = append(lines, current)
+		}
+		current = fit(word, f, size, width)
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// Glyph widths of the printable ASCII range, space to tilde, in 1/1000 em,
+// from the Adobe font metrics of the standard fonts.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -3,13 +3,18 @@ package sqlc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/smallbiznis/corebilling/internal/events/outbox"
 	"github.com/smallbiznis/corebilling/internal/invoice/domain"
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
 )
 
 const defaultInvoicePageSize = 50
@@ -24,20 +29,66 @@ func NewRepository(pool *pgxpool.Pool) domain.Repository {
 	return &Repository{pool: pool}
 }
 
-// Create inserts invoice.
+// Create inserts invoice together with its line items.
 func (r *Repository) Create(ctx context.Context, inv domain.Invoice) error {
-	metadata, err := marshalJSON(inv.Metadata)
+	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback(ctx)
+
+	if err := insertInvoice(ctx, tx, inv); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// CreateNumbered draws the invoice's number and inserts it with its postings
+// in one transaction.
+func (r *Repository) CreateNumbered(ctx context.Context, inv domain.Invoice, scheme domain.NumberingScheme, postings ...domain.LedgerPosting) (domain.Invoice, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Invoice{}, err
+	}
+	defer tx.Rollback(ctx)
 
-	_, err = r.pool.Exec(ctx, `
+	if inv, err = CreateNumberedTx(ctx, tx, inv, scheme, postings...); err != nil {
+		return domain.Invoice{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Invoice{}, err
+	}
+	return inv, nil
+}
+
+// CreateNumberedTx does the work of CreateNumbered in tx, for callers storing
+// the invoice together with rows of their own.
+func CreateNumberedTx(ctx context.Context, tx pgx.Tx, inv domain.Invoice, scheme domain.NumberingScheme, postings ...domain.LedgerPosting) (domain.Invoice, error) {
+	var err error
+	if inv.InvoiceNumber, err = nextInvoiceNumber(ctx, tx, scheme, inv.NumberedAt()); err != nil {
+		return domain.Invoice{}, err
+	}
+	if err := insertInvoice(ctx, tx, inv); err != nil {
+		return domain.Invoice{}, err
+	}
+	if err := insertPostings(ctx, tx, postings); err != nil {
+		return domain.Invoice{}, err
+	}
+	return inv, nil
+}
+
+func insertInvoice(ctx context.Context, tx pgx.Tx, inv domain.Invoice) error {
+	metadata, err := marshalJSON(inv.Metadata)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
 		INSERT INTO invoices (
 			id, tenant_id, customer_id, subscription_id, status,
-			currency_code, total_cents, subtotal_cents, tax_cents,
-			invoice_number, issued_at, due_at, paid_at,
+			currency_code, total_cents, subtotal_cents, tax_cents, credited_cents,
+			invoice_number, issued_at, due_at, paid_at, overdue_at,
 			metadata, created_at, updated_at
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18)
 	`,
 		inv.ID,
 		inv.TenantID,
@@ -48,31 +99,59 @@ func (r *Repository) Create(ctx context.Context, inv domain.Invoice) error {
 		inv.TotalCents,
 		inv.SubtotalCents,
 		inv.TaxCents,
+		inv.CreditedCents,
 		inv.InvoiceNumber,
 		inv.IssuedAt,
 		inv.DueAt,
 		inv.PaidAt,
+		inv.OverdueAt,
 		metadata,
 		inv.CreatedAt,
 		inv.UpdatedAt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	for _, item := range inv.LineItems {
+		if err := insertLineItem(ctx, tx, item); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// GetByID fetches invoice.
-func (r *Repository) GetByID(ctx context.Context, id string) (domain.Invoice, error) {
-	row := r.pool.QueryRow(ctx, `
-		SELECT id, tenant_id, customer_id, subscription_id, status,
-		       currency_code, total_cents, subtotal_cents, tax_cents,
-		       invoice_number, issued_at, due_at, paid_at,
-		       metadata, created_at, updated_at
-		FROM invoices
-		WHERE id=$1
-	`, id)
+// nextInvoiceNumber increments the counter of the scheme's series at at and
+// renders the new value. The counter row stays locked until the transaction
+// ends, so concurrent invoices of a series are numbered one after another and
+// a rollback returns the number.
+func nextInvoiceNumber(ctx context.Context, tx pgx.Tx, scheme domain.NumberingScheme, at time.Time) (string, error) {
+	var seq int64
+	err := tx.QueryRow(ctx, `
+		INSERT INTO invoice_number_sequences (tenant_id, series, last_value, updated_at)
+		VALUES ($1,$2,1,now())
+		ON CONFLICT (tenant_id, series) DO UPDATE SET
+			last_value = invoice_number_sequences.last_value + 1,
+			updated_at = now()
+		RETURNING last_value
+	`, scheme.TenantID, scheme.Series(at)).Scan(&seq)
+	if err != nil {
+		return "", err
+	}
+	return scheme.Format(at, seq), nil
+}
+
+// invoiceColumns are the columns read by scanInvoice.
+const invoiceColumns = `
+	id, tenant_id, customer_id, subscription_id, status,
+	currency_code, total_cents, subtotal_cents, tax_cents, credited_cents,
+	invoice_number, issued_at, due_at, paid_at, overdue_at,
+	metadata, created_at, updated_at`
 
+// scanInvoice reads a row selected with invoiceColumns.
+func scanInvoice(row pgx.Row) (domain.Invoice, error) {
 	var inv domain.Invoice
 	var metadata []byte
-	var issuedAt, dueAt, paidAt *time.Time
 	if err := row.Scan(
 		&inv.ID,
 		&inv.TenantID,
@@ -83,24 +162,111 @@ func (r *Repository) GetByID(ctx context.Context, id string) (domain.Invoice, er
 		&inv.TotalCents,
 		&inv.SubtotalCents,
 		&inv.TaxCents,
+		&inv.CreditedCents,
 		&inv.InvoiceNumber,
-		&issuedAt,
-		&dueAt,
-		&paidAt,
+		&inv.IssuedAt,
+		&inv.DueAt,
+		&inv.PaidAt,
+		&inv.OverdueAt,
 		&metadata,
 		&inv.CreatedAt,
 		&inv.UpdatedAt,
 	); err != nil {
 		return domain.Invoice{}, err
 	}
-
-	inv.IssuedAt = issuedAt
-	inv.DueAt = dueAt
-	inv.PaidAt = paidAt
 	inv.Metadata = jsonToMap(metadata)
 	return inv, nil
 }
 
+// GetByID fetches invoice.
+func (r *Repository) GetByID(ctx context.Context, id string) (domain.Invoice, error) {
+	inv, err := scanInvoice(r.pool.QueryRow(ctx, `SELECT `+invoiceColumns+` FROM invoices WHERE id=$1`, id))
+	if err != nil {
+		return domain.Invoice{}, err
+	}
+
+	items, err := r.listLineItems(ctx, []string{inv.ID})
+	if err != nil {
+		return domain.Invoice{}, err
+	}
+	inv.LineItems = items[inv.ID]
+	return inv, nil
+}
+
+// Update stores the mutable columns of an invoice with its postings.
+func (r *Repository) Update(ctx context.Context, inv domain.Invoice, postings ...domain.LedgerPosting) error {
+	if len(postings) == 0 {
+		return updateInvoice(ctx, r.pool, inv)
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateInvoice(ctx, tx, inv); err != nil {
+		return err
+	}
+	if err := insertPostings(ctx, tx, postings); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UpdateNumbered draws the invoice's number and stores it with the invoice's
+// other changes and its postings in one transaction.
+func (r *Repository) UpdateNumbered(ctx context.Context, inv domain.Invoice, scheme domain.NumberingScheme, postings ...domain.LedgerPosting) (domain.Invoice, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return domain.Invoice{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if inv.InvoiceNumber, err = nextInvoiceNumber(ctx, tx, scheme, inv.NumberedAt()); err != nil {
+		return domain.Invoice{}, err
+	}
+	if err := updateInvoice(ctx, tx, inv); err != nil {
+		return domain.Invoice{}, err
+	}
+	if err := insertPostings(ctx, tx, postings); err != nil {
+		return domain.Invoice{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Invoice{}, err
+	}
+	return inv, nil
+}
+
+func updateInvoice(ctx context.Context, db DBTX, inv domain.Invoice) error {
+	metadata, err := marshalJSON(inv.Metadata)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		UPDATE invoices SET
+			status = $2,
+			invoice_number = $3,
+			issued_at = $4,
+			due_at = $5,
+			paid_at = $6,
+			overdue_at = $7,
+			metadata = $8,
+			updated_at = $9
+		WHERE id = $1
+	`,
+		inv.ID,
+		inv.Status,
+		inv.InvoiceNumber,
+		inv.IssuedAt,
+		inv.DueAt,
+		inv.PaidAt,
+		inv.OverdueAt,
+		metadata,
+		inv.UpdatedAt,
+	)
+	return err
+}
+
 // List returns invoices matching the filter.
 func (r *Repository) List(ctx context.Context, filter domain.ListInvoicesFilter) ([]domain.Invoice, bool, error) {
 	clauses := []string{}
@@ -124,13 +290,7 @@ func (r *Repository) List(ctx context.Context, filter domain.ListInvoicesFilter)
 		addClause("status", filter.Status)
 	}
 
-	query := `
-		SELECT id, tenant_id, customer_id, subscription_id, status,
-		       currency_code, total_cents, subtotal_cents, tax_cents,
-		       invoice_number, issued_at, due_at, paid_at,
-		       metadata, created_at, updated_at
-		FROM invoices
-	`
+	query := `SELECT ` + invoiceColumns + ` FROM invoices`
 	if len(clauses) > 0 {
 		query += " WHERE " + strings.Join(clauses, " AND ")
 	}
@@ -156,49 +316,475 @@ func (r *Repository) List(ctx context.Context, filter domain.ListInvoicesFilter)
 	if err != nil {
 		return nil, false, err
 	}
-	defer rows.Close()
 
+	invoices, err := collectInvoices(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := false
+	if len(invoices) > limit {
+		hasMore = true
+		invoices = invoices[:limit]
+	}
+
+	if len(invoices) > 0 {
+		ids := make([]string, 0, len(invoices))
+		for _, inv := range invoices {
+			ids = append(ids, inv.ID)
+		}
+		items, err := r.listLineItems(ctx, ids)
+		if err != nil {
+			return nil, false, err
+		}
+		for i := range invoices {
+			invoices[i].LineItems = items[invoices[i].ID]
+		}
+	}
+	return invoices, hasMore, nil
+}
+
+// ListPastDue returns open invoices due before now that are not marked
+// overdue, across tenants.
+func (r *Repository) ListPastDue(ctx context.Context, now time.Time, limit int) ([]domain.Invoice, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+invoiceColumns+`
+		FROM invoices
+		WHERE status = $1 AND overdue_at IS NULL AND due_at < $2 AND total_cents > credited_cents
+		ORDER BY due_at, id
+		LIMIT $3
+	`, int32(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN), now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectInvoices(rows)
+}
+
+// MarkOverdue flags an open invoice that is not overdue yet and stores evt
+// with the flag. It reports false when the invoice was paid, voided or
+// flagged in the meantime.
+func (r *Repository) MarkOverdue(ctx context.Context, id string, at time.Time, evt *eventv1.Event) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE invoices SET overdue_at = $2, updated_at = $3
+		WHERE id = $1 AND status = $4 AND overdue_at IS NULL
+	`, id, at, time.Now().UTC(), int32(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN))
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() != 1 {
+		return false, nil
+	}
+	if evt != nil {
+		if err := outbox.InsertOutboxEventTx(ctx, tx, &outbox.OutboxEvent{Subject: evt.GetSubject(), TenantID: evt.GetTenantId(), Event: evt}); err != nil {
+			return false, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListOutstanding returns the tenant's open invoices.
+func (r *Repository) ListOutstanding(ctx context.Context, tenantID string) ([]domain.Invoice, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+invoiceColumns+`
+		FROM invoices
+		WHERE tenant_id = $1 AND status = $2
+		ORDER BY due_at NULLS FIRST, id
+	`, tenantID, int32(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN))
+	if err != nil {
+		return nil, err
+	}
+	return collectInvoices(rows)
+}
+
+// collectInvoices scans and closes rows selected with invoiceColumns.
+func collectInvoices(rows pgx.Rows) ([]domain.Invoice, error) {
+	defer rows.Close()
 	var invoices []domain.Invoice
 	for rows.Next() {
-		var inv domain.Invoice
-		var metadata []byte
-		var issuedAt, dueAt, paidAt *time.Time
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
+
+// CreateCreditNote inserts a credit note with its lines and postings and
+// stores the invoice's credited amount and status in the same transaction.
+// The invoice is only updated while it still has the credited amount and
+// status the note was validated against.
+func (r *Repository) CreateCreditNote(ctx context.Context, note domain.CreditNote, previous, inv domain.Invoice, postings ...domain.LedgerPosting) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE invoices SET credited_cents = $2, status = $3, paid_at = $4, updated_at = $5
+		WHERE id = $1 AND credited_cents = $6 AND status = $7 AND credited_cents + $8 <= total_cents
+	`, inv.ID, inv.CreditedCents, inv.Status, inv.PaidAt, inv.UpdatedAt, previous.CreditedCents, previous.Status, note.AmountCents)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrInvoiceChanged
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO credit_notes (
+			id, tenant_id, invoice_id, customer_id, reason, memo, currency,
+			amount_cents, balance_cents, customer_credit_cents, created_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+	`,
+		note.ID,
+		note.TenantID,
+		note.InvoiceID,
+		nullIfEmpty(note.CustomerID),
+		string(note.Reason),
+		nullIfEmpty(note.Memo),
+		note.Currency,
+		note.AmountCents,
+		note.BalanceCents,
+		note.CustomerCreditCents,
+		note.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	for _, line := range note.Lines {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO credit_note_lines (id, credit_note_id, line_item_id, description, amount_cents)
+			VALUES ($1,$2,$3,$4,$5)
+		`, line.ID, line.CreditNoteID, line.LineItemID, nullIfEmpty(line.Description), line.AmountCents)
+		if err != nil {
+			return err
+		}
+	}
+	if err := insertPostings(ctx, tx, postings); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListCreditNotes returns an invoice's credit notes with their lines, oldest first.
+func (r *Repository) ListCreditNotes(ctx context.Context, invoiceID string) ([]domain.CreditNote, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id::TEXT, tenant_id::TEXT, invoice_id::TEXT, COALESCE(customer_id::TEXT, ''),
+		       reason, COALESCE(memo, ''), currency, amount_cents, balance_cents,
+		       customer_credit_cents, created_at
+		FROM credit_notes
+		WHERE invoice_id = $1
+		ORDER BY created_at, id
+	`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []domain.CreditNote
+	index := make(map[string]int)
+	for rows.Next() {
+		var note domain.CreditNote
+		var reason string
 		if err := rows.Scan(
-			&inv.ID,
-			&inv.TenantID,
-			&inv.CustomerID,
-			&inv.SubscriptionID,
-			&inv.Status,
-			&inv.CurrencyCode,
-			&inv.TotalCents,
-			&inv.SubtotalCents,
-			&inv.TaxCents,
-			&inv.InvoiceNumber,
-			&issuedAt,
-			&dueAt,
-			&paidAt,
-			&metadata,
-			&inv.CreatedAt,
-			&inv.UpdatedAt,
+			&note.ID,
+			&note.TenantID,
+			&note.InvoiceID,
+			&note.CustomerID,
+			&reason,
+			&note.Memo,
+			&note.Currency,
+			&note.AmountCents,
+			&note.BalanceCents,
+			&note.CustomerCreditCents,
+			&note.CreatedAt,
 		); err != nil {
-			return nil, false, err
+			return nil, err
 		}
-		inv.IssuedAt = issuedAt
-		inv.DueAt = dueAt
-		inv.PaidAt = paidAt
-		inv.Metadata = jsonToMap(metadata)
-		invoices = append(invoices, inv)
+		note.Reason = domain.CreditNoteReason(reason)
+		index[note.ID] = len(notes)
+		notes = append(notes, note)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, false, err
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return notes, nil
 	}
 
-	hasMore := false
-	if len(invoices) > limit {
-		hasMore = true
-		invoices = invoices[:limit]
+	lines, err := r.pool.Query(ctx, `
+		SELECT l.id::TEXT, l.credit_note_id::TEXT, l.line_item_id::TEXT, COALESCE(l.description, ''), l.amount_cents
+		FROM credit_note_lines l
+		JOIN credit_notes n ON n.id = l.credit_note_id
+		WHERE n.invoice_id = $1
+		ORDER BY l.id
+	`, invoiceID)
+	if err != nil {
+		return nil, err
 	}
-	return invoices, hasMore, nil
+	defer lines.Close()
+	for lines.Next() {
+		var line domain.CreditNoteLine
+		if err := lines.Scan(&line.ID, &line.CreditNoteID, &line.LineItemID, &line.Description, &line.AmountCents); err != nil {
+			return nil, err
+		}
+		if i, ok := index[line.CreditNoteID]; ok {
+			notes[i].Lines = append(notes[i].Lines, line)
+		}
+	}
+	return notes, lines.Err()
+}
+
+// GetNumberingScheme returns the tenant's stored numbering scheme.
+func (r *Repository) GetNumberingScheme(ctx context.Context, tenantID string) (domain.NumberingScheme, bool, error) {
+	scheme := domain.NumberingScheme{TenantID: tenantID}
+	err := r.pool.QueryRow(ctx, `
+		SELECT prefix, template, updated_at
+		FROM invoice_numbering_schemes
+		WHERE tenant_id = $1
+	`, tenantID).Scan(&scheme.Prefix, &scheme.Template, &scheme.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.NumberingScheme{}, false, nil
+	}
+	if err != nil {
+		return domain.NumberingScheme{}, false, err
+	}
+	return scheme, true, nil
+}
+
+// SaveNumberingScheme inserts or replaces the tenant's numbering scheme.
+func (r *Repository) SaveNumberingScheme(ctx context.Context, scheme domain.NumberingScheme) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO invoice_numbering_schemes (tenant_id, prefix, template, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$4)
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			prefix = EXCLUDED.prefix,
+			template = EXCLUDED.template,
+			updated_at = EXCLUDED.updated_at
+	`, scheme.TenantID, scheme.Prefix, scheme.Template, scheme.UpdatedAt)
+	return err
+}
+
+// GetPaymentTerms returns the terms stored for the customer, or for the
+// tenant when customerID is empty.
+func (r *Repository) GetPaymentTerms(ctx context.Context, tenantID, customerID string) (domain.PaymentTermsSetting, bool, error) {
+	setting := domain.PaymentTermsSetting{TenantID: tenantID, CustomerID: customerID}
+	var terms string
+	err := r.pool.QueryRow(ctx, `
+		SELECT terms, updated_at
+		FROM invoice_payment_terms
+		WHERE tenant_id = $1 AND customer_id = $2
+	`, tenantID, customerKey(customerID)).Scan(&terms, &setting.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.PaymentTermsSetting{}, false, nil
+	}
+	if err != nil {
+		return domain.PaymentTermsSetting{}, false, err
+	}
+	setting.Terms = domain.PaymentTerms(terms)
+	return setting, true, nil
+}
+
+// SavePaymentTerms inserts or replaces the terms of a tenant or customer.
+func (r *Repository) SavePaymentTerms(ctx context.Context, setting domain.PaymentTermsSetting) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO invoice_payment_terms (tenant_id, customer_id, terms, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$4)
+		ON CONFLICT (tenant_id, customer_id) DO UPDATE SET
+			terms = EXCLUDED.terms,
+			updated_at = EXCLUDED.updated_at
+	`, setting.TenantID, customerKey(setting.CustomerID), string(setting.Terms), setting.UpdatedAt)
+	return err
+}
+
+// DeletePaymentTerms removes the terms of a tenant or customer.
+func (r *Repository) DeletePaymentTerms(ctx context.Context, tenantID, customerID string) error {
+	_, err := r.pool.Exec(ctx, `
+		DELETE FROM invoice_payment_terms WHERE tenant_id = $1 AND customer_id = $2
+	`, tenantID, customerKey(customerID))
+	return err
+}
+
+// ListPendingPostings returns queued postings due for an attempt at now.
+func (r *Repository) ListPendingPostings(ctx context.Context, now time.Time, limit int) ([]domain.LedgerPosting, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, tenant_id, currency, reference_type, reference_id, description,
+		       debit_account, credit_account, amount_cents, attempts, created_at
+		FROM invoice_ledger_postings
+		WHERE posted_at IS NULL AND next_attempt_at <= $1
+		ORDER BY created_at, id
+		LIMIT $2
+	`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var postings []domain.LedgerPosting
+	for rows.Next() {
+		var posting domain.LedgerPosting
+		if err := rows.Scan(
+			&posting.ID,
+			&posting.TenantID,
+			&posting.Currency,
+			&posting.ReferenceType,
+			&posting.ReferenceID,
+			&posting.Description,
+			&posting.Debit,
+			&posting.Credit,
+			&posting.AmountCents,
+			&posting.Attempts,
+			&posting.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		postings = append(postings, posting)
+	}
+	return postings, rows.Err()
+}
+
+// MarkPosted records that a posting reached the ledger.
+func (r *Repository) MarkPosted(ctx context.Context, id string, at time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE invoice_ledger_postings SET posted_at = $2, last_error = NULL WHERE id = $1
+	`, id, at)
+	return err
+}
+
+// RetryPosting counts a failed attempt and schedules the next one.
+func (r *Repository) RetryPosting(ctx context.Context, id string, next time.Time, lastError string) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE invoice_ledger_postings
+		SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3
+		WHERE id = $1
+	`, id, next, lastError)
+	return err
+}
+
+func insertPostings(ctx context.Context, tx pgx.Tx, postings []domain.LedgerPosting) error {
+	for _, posting := range postings {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO invoice_ledger_postings (
+				id, tenant_id, currency, reference_type, reference_id, description,
+				debit_account, credit_account, amount_cents, next_attempt_at, created_at
+			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$10)
+		`,
+			posting.ID,
+			posting.TenantID,
+			posting.Currency,
+			posting.ReferenceType,
+			posting.ReferenceID,
+			posting.Description,
+			posting.Debit,
+			posting.Credit,
+			posting.AmountCents,
+			posting.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// customerKey maps the tenant-wide terms onto customer id 0.
+func customerKey(customerID string) string {
+	if customerID == "" {
+		return "0"
+	}
+	return customerID
+}
+
+func insertLineItem(ctx context.Context, tx pgx.Tx, item domain.LineItem) error {
+	metadata, err := marshalJSON(item.Metadata)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO invoice_line_items (
+			id, invoice_id, tenant_id, line_type, description,
+			price_id, meter_code, quantity, unit_amount_cents, amount_cents,
+			currency, period_start, period_end, metadata, created_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)
+	`,
+		item.ID,
+		item.InvoiceID,
+		item.TenantID,
+		string(item.Type),
+		item.Description,
+		nullIfEmpty(item.PriceID),
+		nullIfEmpty(item.MeterCode),
+		item.Quantity,
+		item.UnitAmountCents,
+		item.AmountCents,
+		item.Currency,
+		item.PeriodStart,
+		item.PeriodEnd,
+		metadata,
+		item.CreatedAt,
+	)
+	return err
+}
+
+// listLineItems returns line items grouped by invoice id.
+func (r *Repository) listLineItems(ctx context.Context, invoiceIDs []string) (map[string][]domain.LineItem, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, invoice_id, tenant_id, line_type, description,
+		       COALESCE(price_id::TEXT, ''), COALESCE(meter_code, ''),
+		       quantity, unit_amount_cents, amount_cents, currency,
+		       period_start, period_end, metadata, created_at
+		FROM invoice_line_items
+		WHERE invoice_id = ANY($1::BIGINT[])
+		ORDER BY invoice_id, created_at, id
+	`, buildIDArray(invoiceIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[string][]domain.LineItem, len(invoiceIDs))
+	for rows.Next() {
+		var item domain.LineItem
+		var lineType string
+		var metadata []byte
+		if err := rows.Scan(
+			&item.ID,
+			&item.InvoiceID,
+			&item.TenantID,
+			&lineType,
+			&item.Description,
+			&item.PriceID,
+			&item.MeterCode,
+			&item.Quantity,
+			&item.UnitAmountCents,
+			&item.AmountCents,
+			&item.Currency,
+			&item.PeriodStart,
+			&item.PeriodEnd,
+			&metadata,
+			&item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		item.Type = domain.LineItemType(lineType)
+		item.Metadata = jsonToMap(metadata)
+		items[item.InvoiceID] = append(items[item.InvoiceID], item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 func marshalJSON(value map[string]interface{}) ([]byte, error) {
@@ -208,6 +794,17 @@ func marshalJSON(value map[string]interface{}) ([]byte, error) {
 	return json.Marshal(value)
 }
 
+func buildIDArray(ids []string) string {
+	return "{" + strings.Join(ids, ",") + "}"
+}
+
+func nullIfEmpty(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
 func jsonToMap(value []byte) map[string]interface{} {
 	if len(value) == 0 {
 		return nil
@@ -0,0 +1,53 @@
+package invoice
+
+import (
+	"context"
+	"time"
+
+	"github.com/smallbiznis/corebilling/internal/invoice/domain"
+	"go.uber.org/zap"
+)
+
+const overdueBatchSize = 100
+
+// Scheduler marks open invoices overdue once their due date has passed.
+type Scheduler struct {
+	svc    *domain.Service
+	logger *zap.Logger
+}
+
+// NewScheduler constructs the overdue scheduler.
+func NewScheduler(svc *domain.Service, logger *zap.Logger) *Scheduler {
+	return &Scheduler{
+		svc:    svc,
+		logger: logger.Named("invoice.scheduler"),
+	}
+}
+
+// Run starts the periodic worker.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.process(ctx, time.Now().UTC())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Scheduler) process(ctx context.Context, now time.Time) {
+	invoices, err := s.svc.ListPastDue(ctx, now, overdueBatchSize)
+	if err != nil {
+		s.logger.Error("failed to list past due invoices", zap.Error(err))
+		return
+	}
+	for _, inv := range invoices {
+		if _, err := s.svc.MarkOverdue(ctx, inv, now); err != nil {
+			s.logger.Error("failed to mark invoice overdue", zap.Error(err), zap.String("invoice_id", inv.ID))
+		}
+	}
+}
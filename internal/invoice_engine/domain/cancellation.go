@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"time"
+
+	coupon "github.com/smallbiznis/corebilling/internal/coupon/domain"
+	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	subscription "github.com/smallbiznis/corebilling/internal/subscription/domain"
+	usage "github.com/smallbiznis/corebilling/internal/usage/domain"
+)
+
+// CancellationRequest describes a subscription canceled before its current
+// period ends. Subscription must still hold that period.
+type CancellationRequest struct {
+	Subscription subscription.Subscription
+	At           time.Time
+	// Prorate bills the recurring fee only for the time used, crediting the
+	// unused time when the period was invoiced in advance.
+	Prorate bool
+}
+
+// cancellationCharges prices the current period cut short at at: the usage
+// recorded so far and the recurring fees, in full or for the elapsed time.
+func cancellationCharges(items []pricedItem, records []usage.UsageRecord, pending []invoice.LineItem, discounts []coupon.Discount, tax *TaxRule, start, end, at time.Time, prepaid, prorate bool) (charges, error) {
+	period := billingPeriod{Start: start, End: at, Prepaid: prepaid}
+	if prorate {
+		period.FullEnd = end
+		if prepaid {
+			pending = append([]invoice.LineItem(nil), pending...)
+			for _, item := range items {
+				if item.From.After(start) || !item.Until.IsZero() {
+					continue
+				}
+				credit, err := unusedTimeCredit(item.Price, item.Quantity, start, end, at)
+				if err != nil {
+					return charges{}, err
+				}
+				pending = append(pending, credit...)
+			}
+		}
+	}
+	return computeCharges(items, records, pending, discounts, tax, period)
+}
@@ -0,0 +1,384 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	coupon "github.com/smallbiznis/corebilling/internal/coupon/domain"
+	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	"github.com/smallbiznis/corebilling/internal/money"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+	usage "github.com/smallbiznis/corebilling/internal/usage/domain"
+)
+
+// charges summarizes the computed line items and amounts for a single invoice.
+type charges struct {
+	Lines         []invoice.LineItem
+	BaseCents     int64
+	UsageCents    int64
+	UsageQuantity float64
+	PendingCents  int64
+	SubtotalCents int64
+	DiscountCents int64
+	TaxCents      int64
+	TotalCents    int64
+	// DiscountIDs lists the discounts that produced a line on this invoice.
+	DiscountIDs []string
+
+	// err is the first amount that overflowed, see cents.
+	err error
+}
+
+// cents rounds amount for a line, recording an overflowed amount as the
+// charges' error.
+func (c *charges) cents(amount money.Amount) int64 {
+	if err := amount.Err(); err != nil {
+		if c.err == nil {
+			c.err = err
+		}
+		return 0
+	}
+	return amount.Cents()
+}
+
+// billingPeriod is the span an invoice covers. FullStart and FullEnd bound the
+// full-length period it belongs to and default to Start and End; when the
+// invoice covers less, as in the first period of a calendar-anchored
+// subscription or a period cut short by cancellation, the recurring fee is
+// prorated. Prepaid periods had their recurring fee invoiced in advance and
+// only bill usage. Their amount-off discounts were taken in full on the
+// advance invoice, so only percent-off discounts apply to what is billed now.
+type billingPeriod struct {
+	Start     time.Time
+	End       time.Time
+	FullStart time.Time
+	FullEnd   time.Time
+	Prepaid   bool
+}
+
+// fraction returns the share of the full period covered, as seconds covered
+// over seconds in the full period. It returns 1/1 for full periods.
+func (p billingPeriod) fraction() (int64, int64) {
+	fullStart, fullEnd := p.FullStart, p.FullEnd
+	if fullStart.IsZero() || fullStart.After(p.Start) {
+		fullStart = p.Start
+	}
+	if fullEnd.IsZero() || fullEnd.Before(p.End) {
+		fullEnd = p.End
+	}
+	if !p.End.After(p.Start) || (fullStart.Equal(p.Start) && fullEnd.Equal(p.End)) {
+		return 1, 1
+	}
+	return int64(p.End.Sub(p.Start) / time.Second), int64(fullEnd.Sub(fullStart) / time.Second)
+}
+
+// pricedItem is a subscription item resolved to its price in the
+// subscription's currency. The primary price has an empty ItemID.
+type pricedItem struct {
+	ItemID   string
+	Price    pricing.Price
+	Tiers    []pricing.PriceTier
+	Quantity int64
+	// From and Until bound the part of the period the item was billed on the
+	// subscription, for items added or removed within it. Zero values mean
+	// the whole period.
+	From  time.Time
+	Until time.Time
+	// FeeFrom starts the item's fee after the part of an arrears period
+	// billed at an earlier price, see feeSegments.
+	FeeFrom time.Time
+	// FeeOnly marks an earlier price of an item, billed for its part of the
+	// period but never for usage.
+	FeeOnly bool
+}
+
+// period returns the part of p the item is billed for. An item added after p
+// started was not on the invoice issued in advance for a prepaid period, so
+// its fee is still owed.
+func (it pricedItem) period(p billingPeriod) billingPeriod {
+	if p.FullStart.IsZero() {
+		p.FullStart = p.Start
+	}
+	if p.FullEnd.IsZero() {
+		p.FullEnd = p.End
+	}
+	if it.From.After(p.Start) {
+		p.Start = it.From
+		p.Prepaid = false
+	}
+	if !it.Until.IsZero() && it.Until.Before(p.End) {
+		p.End = it.Until
+	}
+	return p
+}
+
+// feePeriod returns the part of p the item's recurring fee is billed for.
+func (it pricedItem) feePeriod(p billingPeriod) billingPeriod {
+	p = it.period(p)
+	if it.FeeFrom.After(p.Start) {
+		p.Start = it.FeeFrom
+	}
+	return p
+}
+
+// feeSegments splits the recurring fees of a period billed in arrears at the
+// changes recorded in it. Each item is billed at the quantity it had when the
+// period started, since increases are billed immediately; an item whose
+// price changed is billed at each earlier price up to the change that
+// replaced it, returned as FeeOnly items after items, and at its current
+// price from the last change. prices holds the earlier prices by id.
+func feeSegments(items []pricedItem, changes []FeeChange, prices map[string]pricing.Price) []pricedItem {
+	byItem := map[string][]FeeChange{}
+	for _, change := range changes {
+		byItem[change.ItemID] = append(byItem[change.ItemID], change)
+	}
+	out := make([]pricedItem, 0, len(items))
+	var segments []pricedItem
+	for _, item := range items {
+		itemChanges := byItem[item.ItemID]
+		if len(itemChanges) == 0 {
+			out = append(out, item)
+			continue
+		}
+		sort.SliceStable(itemChanges, func(i, j int) bool { return itemChanges[i].At.Before(itemChanges[j].At) })
+		item.Quantity = itemChanges[0].Quantity
+
+		current := strconv.FormatInt(item.Price.ID, 10)
+		segmentPrice, segmentFrom := itemChanges[0].PriceID, item.From
+		for i, change := range itemChanges {
+			next := current
+			if i+1 < len(itemChanges) {
+				next = itemChanges[i+1].PriceID
+			}
+			if next == segmentPrice {
+				continue
+			}
+			if price, ok := prices[segmentPrice]; ok {
+				segments = append(segments, pricedItem{
+					ItemID:   item.ItemID,
+					Price:    price,
+					Quantity: item.Quantity,
+					From:     segmentFrom,
+					Until:    change.At,
+					FeeOnly:  true,
+				})
+			}
+			segmentPrice, segmentFrom = next, change.At
+		}
+		item.FeeFrom = segmentFrom
+		out = append(out, item)
+	}
+	return append(out, segments...)
+}
+
+// computeCharges prices a subscription period from its items and usage,
+// adding any pending items such as prorations. Usage of a meter is billed on
+// the item whose price rates that meter, or else on the first item rating
+// every meter. Discounts reduce the subtotal in order before tax is applied.
+// Line items are returned without identifiers; the caller assigns them on
+// persist. An amount too large for money.Amount fails with money.ErrOverflow.
+func computeCharges(items []pricedItem, records []usage.UsageRecord, pending []invoice.LineItem, discounts []coupon.Discount, tax *TaxRule, period billingPeriod) (charges, error) {
+	var c charges
+	currency := ""
+	if len(items) > 0 {
+		currency = strings.ToUpper(items[0].Price.Currency)
+	}
+
+	for _, item := range items {
+		c.addFee(item, item.feePeriod(period))
+	}
+	var usageQuantity money.Quantity
+	for _, meter := range groupUsageByMeter(records) {
+		if err := meter.quantity.Err(); err != nil {
+			return charges{}, err
+		}
+		usageQuantity = usageQuantity.Add(meter.quantity)
+		if i := usageItem(items, meter.code); i >= 0 {
+			c.addUsage(items[i], meter, items[i].period(period))
+		}
+	}
+	c.UsageQuantity = usageQuantity.Float64()
+
+	if period.Prepaid {
+		discounts = percentDiscounts(discounts)
+	}
+	c.finalize(pending, discounts, tax, currency, period.Start, period.End)
+	if c.err != nil {
+		return charges{}, c.err
+	}
+	return c, nil
+}
+
+// addFee adds the recurring fee of a licensed item, prorated when it covers
+// less than the full period.
+func (c *charges) addFee(item pricedItem, period billingPeriod) {
+	price := item.Price
+	if price.IsMetered() || period.Prepaid || !period.End.After(period.Start) {
+		return
+	}
+	quantity := item.Quantity
+	if quantity < 1 {
+		quantity = 1
+	}
+	amount := price.UnitPrice().Mul(quantity)
+	line := newItemLine(item, invoice.LineItemTypeRecurring, "Subscription fee", period)
+	if num, den := period.fraction(); num != den {
+		amount = amount.MulFraction(num, den)
+		line.Description = "Subscription fee (partial period)"
+		line.Metadata["proration_fraction"] = float64(num) / float64(den)
+	}
+	line.Quantity = float64(quantity)
+	line.UnitAmountCents = price.UnitAmountCents
+	line.AmountCents = c.cents(amount)
+	c.BaseCents += line.AmountCents
+	c.Lines = append(c.Lines, line)
+}
+
+// addUsage adds a usage line for a meter billed on item.
+func (c *charges) addUsage(item pricedItem, meter meterUsage, period billingPeriod) {
+	price := item.Price
+	// Usage is accumulated at sub-cent precision and rounded once per line.
+	quantity := meter.quantity.Float64()
+	amount := c.cents(pricing.UsageAmount(price, item.Tiers, quantity))
+
+	c.UsageCents += amount
+	line := newItemLine(item, invoice.LineItemTypeUsage, fmt.Sprintf("Usage: %s", meter.code), period)
+	line.MeterCode = meter.code
+	line.Quantity = quantity
+	line.AmountCents = amount
+	line.Metadata["record_count"] = meter.records
+	line.Metadata["unit_amount_decimal"] = price.UnitPrice().String()
+	switch price.PricingModel {
+	case pricing.PricingModelPerUnit:
+		line.UnitAmountCents = price.UnitAmountCents
+	case pricing.PricingModelPackage:
+		line.UnitAmountCents = price.UnitAmountCents
+		line.Metadata["package_size"] = price.PackageSize
+		line.Metadata["packages"] = pricing.PackageCount(quantity, price.PackageSize, price.PackageRounding)
+	}
+	c.Lines = append(c.Lines, line)
+}
+
+func newItemLine(item pricedItem, lineType invoice.LineItemType, description string, period billingPeriod) invoice.LineItem {
+	start, end := period.Start, period.End
+	line := invoice.LineItem{
+		Type:        lineType,
+		Description: description,
+		PriceID:     strconv.FormatInt(item.Price.ID, 10),
+		Currency:    strings.ToUpper(item.Price.Currency),
+		PeriodStart: &start,
+		PeriodEnd:   &end,
+		Metadata:    map[string]interface{}{},
+	}
+	if item.ItemID != "" {
+		line.Metadata["subscription_item_id"] = item.ItemID
+	}
+	return line
+}
+
+// usageItem returns the index of the item billing usage of meter, chosen like
+// rating does with pricing.UsagePriceIndex. The earlier prices of an item do
+// not bill usage. It returns -1 when no item bills the meter.
+func usageItem(items []pricedItem, meter string) int {
+	prices := make([]pricing.Price, len(items))
+	for i, item := range items {
+		prices[i] = item.Price
+	}
+	return pricing.UsagePriceIndex(prices, func(i int) bool {
+		return !items[i].FeeOnly && items[i].Price.ChargesUsage(len(items[i].Tiers) > 0)
+	}, meter)
+}
+
+// percentDiscounts returns the percent-off discounts, which take their share
+// of any lines billed after the advance invoice of a prepaid period.
+func percentDiscounts(discounts []coupon.Discount) []coupon.Discount {
+	var out []coupon.Discount
+	for _, d := range discounts {
+		if d.Coupon.PercentOff > 0 {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// finalize appends pending items, discounts and tax to the priced lines and
+// computes the invoice totals.
+func (c *charges) finalize(pending []invoice.LineItem, discounts []coupon.Discount, tax *TaxRule, currency string, start, end time.Time) {
+	for _, line := range pending {
+		c.PendingCents += line.AmountCents
+		c.Lines = append(c.Lines, line)
+	}
+	c.SubtotalCents = c.BaseCents + c.UsageCents + c.PendingCents
+
+	newLine := func(lineType invoice.LineItemType, description string) invoice.LineItem {
+		return invoice.LineItem{
+			Type:        lineType,
+			Description: description,
+			Currency:    currency,
+			PeriodStart: &start,
+			PeriodEnd:   &end,
+		}
+	}
+	for _, d := range discounts {
+		if !d.Applies() {
+			continue
+		}
+		off := d.Coupon.AmountOff(c.SubtotalCents-c.DiscountCents, currency)
+		if off == 0 {
+			continue
+		}
+		c.DiscountCents += off
+		c.DiscountIDs = append(c.DiscountIDs, d.ID)
+		line := newLine(invoice.LineItemTypeDiscount, d.Coupon.Description())
+		line.AmountCents = -off
+		line.Metadata = map[string]interface{}{"discount_id": d.ID, "coupon_id": d.CouponID}
+		c.Lines = append(c.Lines, line)
+	}
+
+	// A net credit, e.g. from a downgrade proration, is not taxed.
+	taxable := c.SubtotalCents - c.DiscountCents
+	if tax != nil && tax.RatePercent > 0 && taxable > 0 {
+		c.TaxCents = c.cents(money.FromCents(taxable).Percent(tax.RatePercent))
+		line := newLine(invoice.LineItemTypeTax, fmt.Sprintf("%s (%s%%)", taxName(tax), strconv.FormatFloat(tax.RatePercent, 'f', -1, 64)))
+		line.AmountCents = c.TaxCents
+		line.Metadata = map[string]interface{}{"tax_rule_id": tax.ID, "rate_percent": tax.RatePercent}
+		c.Lines = append(c.Lines, line)
+	}
+	c.TotalCents = taxable + c.TaxCents
+}
+
+type meterUsage struct {
+	code     string
+	quantity money.Quantity
+	records  int
+}
+
+// groupUsageByMeter sums usage per meter code in a stable order. Quantities
+// are summed as fixed-point decimals so float error cannot push a total into
+// the next tier.
+func groupUsageByMeter(records []usage.UsageRecord) []meterUsage {
+	index := map[string]int{}
+	var meters []meterUsage
+	for _, r := range records {
+		i, ok := index[r.MeterCode]
+		if !ok {
+			i = len(meters)
+			index[r.MeterCode] = i
+			meters = append(meters, meterUsage{code: r.MeterCode})
+		}
+		meters[i].quantity = meters[i].quantity.Add(money.QuantityFromFloat(r.Value))
+		meters[i].records++
+	}
+	sort.Slice(meters, func(i, j int) bool { return meters[i].code < meters[j].code })
+	return meters
+}
+
+func taxName(rule *TaxRule) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return "Tax"
+}
@@ -0,0 +1,249 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	coupon "github.com/smallbiznis/corebilling/internal/coupon/domain"
+	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	"github.com/smallbiznis/corebilling/internal/money"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+	usage "github.com/smallbiznis/corebilling/internal/usage/domain"
+)
+
+func TestComputeCharges(t *testing.T) {
+	tiers := []pricing.PriceTier{
+		{StartQuantity: 0, EndQuantity: 100, UnitAmountCents: 10},
+		{StartQuantity: 100, EndQuantity: 0, UnitAmountCents: 5},
+	}
+	records := []usage.UsageRecord{{MeterCode: "api_calls", Value: 120}, {MeterCode: "api_calls", Value: 30}}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name      string
+		price     pricing.Price
+		tiers     []pricing.PriceTier
+		pending   []invoice.LineItem
+		discounts []coupon.Discount
+		tax       *TaxRule
+		subtotal  int64
+		discount  int64
+		taxCents  int64
+		lines     []invoice.LineItemType
+	}{
+		{
+			name:     "flat recurring fee",
+			price:    pricing.Price{PricingModel: pricing.PricingModelFlat, UnitAmountCents: 2500},
+			tax:      &TaxRule{Name: "VAT", RatePercent: 10},
+			subtotal: 2500,
+			taxCents: 250,
+			lines:    []invoice.LineItemType{invoice.LineItemTypeRecurring, invoice.LineItemTypeTax},
+		},
+		{
+			name:     "per unit usage",
+			price:    pricing.Price{PricingModel: pricing.PricingModelPerUnit, UnitAmountCents: 3},
+			subtotal: 450,
+			lines:    []invoice.LineItemType{invoice.LineItemTypeUsage},
+		},
+		{
+			name:     "graduated tiers",
+			price:    pricing.Price{PricingModel: pricing.PricingModelTiered},
+			tiers:    tiers,
+			tax:      &TaxRule{RatePercent: 11},
+			subtotal: 100*10 + 50*5,
+			taxCents: 138,
+			lines:    []invoice.LineItemType{invoice.LineItemTypeUsage, invoice.LineItemTypeTax},
+		},
+		{
+			name:     "package rounded up",
+			price:    pricing.Price{PricingModel: pricing.PricingModelPackage, UnitAmountCents: 500, PackageSize: 100, PackageRounding: pricing.PackageRoundingUp},
+			subtotal: 1000,
+			lines:    []invoice.LineItemType{invoice.LineItemTypeUsage},
+		},
+		{
+			name:  "discounts before tax",
+			price: pricing.Price{PricingModel: pricing.PricingModelFlat, UnitAmountCents: 2500, Currency: "usd"},
+			discounts: []coupon.Discount{
+				{ID: "1", Coupon: coupon.Coupon{Duration: coupon.DurationForever, PercentOff: 20}},
+				{ID: "2", Coupon: coupon.Coupon{Duration: coupon.DurationOnce, AmountOffCents: 300, Currency: "USD"}},
+				{ID: "3", PeriodsApplied: 1, Coupon: coupon.Coupon{Duration: coupon.DurationOnce, AmountOffCents: 300, Currency: "USD"}},
+				{ID: "4", Coupon: coupon.Coupon{Duration: coupon.DurationForever, AmountOffCents: 300, Currency: "EUR"}},
+			},
+			tax:      &TaxRule{RatePercent: 10},
+			subtotal: 2500,
+			discount: 500 + 300,
+			taxCents: 170,
+			lines:    []invoice.LineItemType{invoice.LineItemTypeRecurring, invoice.LineItemTypeDiscount, invoice.LineItemTypeDiscount, invoice.LineItemTypeTax},
+		},
+		{
+			name:     "pending proration lines",
+			price:    pricing.Price{PricingModel: pricing.PricingModelFlat, UnitAmountCents: 2500},
+			pending:  []invoice.LineItem{{Type: invoice.LineItemTypeProration, AmountCents: -1000}, {Type: invoice.LineItemTypeProration, AmountCents: 1800}},
+			tax:      &TaxRule{RatePercent: 10},
+			subtotal: 3300,
+			taxCents: 330,
+			lines:    []invoice.LineItemType{invoice.LineItemTypeRecurring, invoice.LineItemTypeProration, invoice.LineItemTypeProration, invoice.LineItemTypeTax},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := computeCharges([]pricedItem{{Price: tt.price, Tiers: tt.tiers}}, records, tt.pending, tt.discounts, tt.tax, billingPeriod{Start: start, End: end})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.SubtotalCents != tt.subtotal {
+				t.Fatalf("expected subtotal %d got %d", tt.subtotal, got.SubtotalCents)
+			}
+			if got.DiscountCents != tt.discount {
+				t.Fatalf("expected discount %d got %d", tt.discount, got.DiscountCents)
+			}
+			if got.TaxCents != tt.taxCents {
+				t.Fatalf("expected tax %d got %d", tt.taxCents, got.TaxCents)
+			}
+			if want := tt.subtotal - tt.discount + tt.taxCents; got.TotalCents != want {
+				t.Fatalf("expected total %d got %d", want, got.TotalCents)
+			}
+			if len(got.Lines) != len(tt.lines) {
+				t.Fatalf("expected %d lines got %d", len(tt.lines), len(got.Lines))
+			}
+			var sum int64
+			for i, line := range got.Lines {
+				if line.Type != tt.lines[i] {
+					t.Fatalf("line %d: expected %s got %s", i, tt.lines[i], line.Type)
+				}
+				sum += line.AmountCents
+			}
+			if sum != got.TotalCents {
+				t.Fatalf("line items sum to %d, total is %d", sum, got.TotalCents)
+			}
+		})
+	}
+}
+
+func TestComputeChargesPartialPeriod(t *testing.T) {
+	// A calendar-anchored subscription starting Jan 15 is billed 17 of 31 days.
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	price := pricing.Price{PricingModel: pricing.PricingModelFlat, UnitAmountCents: 3100}
+
+	got, err := computeCharges([]pricedItem{{Price: price}}, nil, nil, nil, nil, billingPeriod{Start: start, End: end, FullStart: price.PeriodStart(end, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.BaseCents != 1700 || got.TotalCents != 1700 {
+		t.Fatalf("expected prorated fee 1700, got base %d total %d", got.BaseCents, got.TotalCents)
+	}
+
+	full, err := computeCharges([]pricedItem{{Price: price}}, nil, nil, nil, nil, billingPeriod{Start: end, End: end.AddDate(0, 1, 0), FullStart: end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.BaseCents != 3100 {
+		t.Fatalf("expected full fee 3100, got %d", full.BaseCents)
+	}
+}
+
+func TestComputeChargesOverflow(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	price := pricing.Price{PricingModel: pricing.PricingModelPerUnit, UnitAmountCents: 1_000_000, Currency: "usd"}
+	records := []usage.UsageRecord{{MeterCode: "api_calls", Value: 1e7}}
+
+	if _, err := computeCharges([]pricedItem{{Price: price}}, records, nil, nil, nil, billingPeriod{Start: start, End: end}); !errors.Is(err, money.ErrOverflow) {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+}
+
+func TestComputeChargesFractionalUsage(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	price := pricing.Price{PricingModel: pricing.PricingModelVolume, Currency: "usd"}
+	tiers := []pricing.PriceTier{
+		{StartQuantity: 0, EndQuantity: 0.3, UnitAmountCents: 1000},
+		{StartQuantity: 0.3, EndQuantity: 0, UnitAmountCents: 500},
+	}
+	// Summed as float64, 0.1 + 0.2 exceeds 0.3 and reaches the cheaper tier.
+	records := []usage.UsageRecord{{MeterCode: "api_calls", Value: 0.1}, {MeterCode: "api_calls", Value: 0.2}}
+
+	got, err := computeCharges([]pricedItem{{Price: price, Tiers: tiers}}, records, nil, nil, nil, billingPeriod{Start: start, End: end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UsageQuantity != 0.3 || got.UsageCents != 300 {
+		t.Fatalf("expected 0.3 units for 300 cents, got %v units for %d cents", got.UsageQuantity, got.UsageCents)
+	}
+}
+
+func TestComputeChargesPrepaidPeriod(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	price := pricing.Price{PricingModel: pricing.PricingModelFlat, UnitAmountCents: 2500}
+	tiers := []pricing.PriceTier{{StartQuantity: 0, UnitAmountCents: 2}}
+	records := []usage.UsageRecord{{MeterCode: "api_calls", Value: 50}}
+
+	got, err := computeCharges([]pricedItem{{Price: price, Tiers: tiers}}, records, nil, nil, nil, billingPeriod{Start: start, End: end, Prepaid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.BaseCents != 0 || got.UsageCents != 100 || len(got.Lines) != 1 || got.Lines[0].Type != invoice.LineItemTypeUsage {
+		t.Fatalf("expected usage-only charges, got base %d usage %d lines %d", got.BaseCents, got.UsageCents, len(got.Lines))
+	}
+}
+
+func TestComputeChargesPrepaidPeriodDiscounts(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	price := pricing.Price{PricingModel: pricing.PricingModelFlat, UnitAmountCents: 2500, Currency: "usd"}
+	tiers := []pricing.PriceTier{{StartQuantity: 0, UnitAmountCents: 2}}
+	records := []usage.UsageRecord{{MeterCode: "api_calls", Value: 50}}
+	// The amount-off coupon was taken on the advance invoice; the percent-off
+	// coupon takes its share of the usage billed at the end of the period.
+	discounts := []coupon.Discount{
+		{ID: "1", Coupon: coupon.Coupon{Duration: coupon.DurationForever, AmountOffCents: 50, Currency: "USD"}},
+		{ID: "2", Coupon: coupon.Coupon{Duration: coupon.DurationForever, PercentOff: 10}},
+	}
+
+	got, err := computeCharges([]pricedItem{{Price: price, Tiers: tiers}}, records, nil, discounts, nil, billingPeriod{Start: start, End: end, Prepaid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DiscountCents != 10 || len(got.DiscountIDs) != 1 || got.DiscountIDs[0] != "2" {
+		t.Fatalf("expected only the percent-off discount of 10, got %d from %v", got.DiscountCents, got.DiscountIDs)
+	}
+}
+
+func TestComputeChargesItems(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	items := []pricedItem{
+		{Price: pricing.Price{ID: 1, PricingModel: pricing.PricingModelFlat, UnitAmountCents: 2500, Currency: "usd"}, Quantity: 1},
+		// Three seats added halfway through the period.
+		{ItemID: "seats", Price: pricing.Price{ID: 2, PricingModel: pricing.PricingModelFlat, UnitAmountCents: 1000, Currency: "usd"}, Quantity: 3, From: start.AddDate(0, 0, 15)},
+		{ItemID: "api", Price: pricing.Price{ID: 3, PricingModel: pricing.PricingModelPerUnit, UnitAmountCents: 2, Currency: "usd", Metadata: map[string]interface{}{pricing.MetadataMeterCode: "api_calls"}}},
+		{ItemID: "other", Price: pricing.Price{ID: 4, PricingModel: pricing.PricingModelPerUnit, UnitAmountCents: 1, Currency: "usd"}},
+	}
+	records := []usage.UsageRecord{{MeterCode: "api_calls", Value: 100}, {MeterCode: "storage", Value: 50}}
+
+	got, err := computeCharges(items, records, nil, nil, nil, billingPeriod{Start: start, End: end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.BaseCents != 2500+1500 {
+		t.Fatalf("expected fees 4000, got %d", got.BaseCents)
+	}
+	if got.UsageCents != 200+50 {
+		t.Fatalf("expected usage 250, got %d", got.UsageCents)
+	}
+	if len(got.Lines) != 4 || got.TotalCents != 4250 {
+		t.Fatalf("expected 4 lines totalling 4250, got %d lines total %d", len(got.Lines), got.TotalCents)
+	}
+	if got.Lines[2].MeterCode != "api_calls" || got.Lines[2].PriceID != "3" || got.Lines[3].PriceID != "4" {
+		t.Fatalf("usage billed on wrong items: %+v", got.Lines[2:])
+	}
+	if got.Lines[1].Quantity != 3 || got.Lines[1].Metadata["subscription_item_id"] != "seats" {
+		t.Fatalf("unexpected seat line %+v", got.Lines[1])
+	}
+}
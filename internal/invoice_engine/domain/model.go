@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
+)
 
 // Run represents an invoice generation invocation.
 type Run struct {
@@ -13,3 +17,46 @@ type Run struct {
 	PeriodEnd      time.Time
 	CreatedAt      time.Time
 }
+
+// TaxRule describes a tax rate applied to invoice subtotals for a region.
+type TaxRule struct {
+	ID          string
+	TenantID    string
+	RegionCode  string
+	Name        string
+	RatePercent float64
+	IsDefault   bool
+}
+
+// PendingItem is a charge or credit recorded outside an invoice run, such as a
+// proration, that is added to the next invoice generated for the subscription.
+type PendingItem struct {
+	ID             string
+	TenantID       string
+	CustomerID     string
+	SubscriptionID string
+	// SourceKey identifies the change that produced the item so retries do not duplicate it.
+	SourceKey string
+	Line      invoice.LineItem
+	InvoiceID string
+	CreatedAt time.Time
+}
+
+// FeeChange records how an item was billed up to a mid-period change at At:
+// its price and the quantity billed at period end. Quantity increases are
+// billed immediately for the rest of the period, so the quantity of the first
+// change in a period holds for all of it. Price changes are only recorded in
+// periods billed in arrears; prepaid ones are prorated instead.
+type FeeChange struct {
+	ID             string
+	TenantID       string
+	SubscriptionID string
+	// ItemID is empty for the subscription's primary price.
+	ItemID   string
+	PriceID  string
+	Quantity int64
+	At       time.Time
+	// SourceKey identifies the change so retries do not record it twice.
+	SourceKey string
+	CreatedAt time.Time
+}
@@ -0,0 +1,211 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+	subscription "github.com/smallbiznis/corebilling/internal/subscription/domain"
+	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
+	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PreviewRequest asks for the invoice a subscription will be sent at the end
+// of its current period. NewPriceID simulates switching the primary price at
+// At with the given proration behavior and granularity.
+type PreviewRequest struct {
+	TenantID       string
+	SubscriptionID string
+	NewPriceID     string
+	Behavior       ProrationBehavior
+	Granularity    ProrationGranularity
+	At             time.Time
+}
+
+// InvoicePreview is an invoice computed without being stored. Invoice has no
+// id or number; it is issued at PeriodEnd and due by the customer's payment
+// terms.
+type InvoicePreview struct {
+	Invoice       invoice.Invoice
+	PeriodStart   time.Time
+	PeriodEnd     time.Time
+	DiscountCents int64
+	// Prorations are the lines of the simulated price change. They are part
+	// of Invoice unless the behavior bills them immediately.
+	Prorations []invoice.LineItem
+	// ImmediateCents is what an always_invoice price change bills right away.
+	ImmediateCents int64
+}
+
+// PreviewInvoice runs the invoice computation for the subscription's current
+// period without persisting anything: recurring fees, usage recorded so far,
+// pending prorations, discounts and tax.
+func (s *Service) PreviewInvoice(ctx context.Context, req PreviewRequest) (InvoicePreview, error) {
+	if req.TenantID == "" || req.SubscriptionID == "" {
+		return InvoicePreview{}, status.Error(codes.InvalidArgument, "tenant_id and subscription_id required")
+	}
+	sub, err := s.subscriptionRepo.GetByID(ctx, req.SubscriptionID)
+	if err != nil || sub.TenantID != req.TenantID {
+		return InvoicePreview{}, status.Error(codes.NotFound, "subscription not found")
+	}
+	if sub.Status == int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_CANCELED) {
+		return InvoicePreview{}, status.Error(codes.FailedPrecondition, "canceled subscriptions have no upcoming invoice")
+	}
+	start, end := sub.CurrentPeriodStart, sub.CurrentPeriodEnd
+	if !end.After(start) {
+		return InvoicePreview{}, status.Error(codes.FailedPrecondition, "subscription has no current period")
+	}
+
+	// A period invoiced in advance only bills what was added since.
+	_, prepaid, err := s.runRepo.FindBySubscriptionPeriod(ctx, sub.ID, start, end)
+	if err != nil {
+		s.logger.Error("failed to look up invoice run", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return InvoicePreview{}, err
+	}
+
+	var preview InvoicePreview
+	var prorations []invoice.LineItem
+	var simulated []FeeChange
+	if req.NewPriceID != "" && req.NewPriceID != sub.PriceID {
+		next, newPrice, err := s.simulatePriceChange(ctx, sub, req.NewPriceID)
+		if err != nil {
+			return InvoicePreview{}, err
+		}
+		if req.Behavior != ProrationNone {
+			oldPrice, err := s.loadPrice(ctx, sub)
+			if err != nil {
+				return InvoicePreview{}, err
+			}
+			at := req.At
+			if at.IsZero() {
+				at = time.Now().UTC()
+			}
+			// As in Prorate, a period billed in arrears bills the old price up
+			// to the change at its end and prorates only the units billed in
+			// advance.
+			quantity := sub.BillableItems(nil)[0].Quantity
+			if !prepaid && at.Before(end) {
+				changes, err := s.feeChanges.ListInPeriod(ctx, sub.ID, start, end)
+				if err != nil {
+					s.logger.Error("failed to load fee changes", zap.Error(err), zap.String("subscription_id", sub.ID))
+					return InvoicePreview{}, err
+				}
+				arrears := arrearsQuantity(changes, "", quantity)
+				simulated = append(simulated, FeeChange{
+					SubscriptionID: sub.ID,
+					PriceID:        sub.PriceID,
+					Quantity:       arrears,
+					At:             changeBoundary(start, at, req.Granularity),
+				})
+				quantity -= arrears
+			}
+			if quantity > 0 {
+				if preview.Prorations, err = prorationLines(oldPrice, newPrice, quantity, start, end, at, req.Granularity); err != nil {
+					return InvoicePreview{}, s.chargeError(err, sub.ID)
+				}
+			}
+			if req.Behavior == ProrationAlwaysInvoice {
+				for _, line := range preview.Prorations {
+					preview.ImmediateCents += line.AmountCents
+				}
+			} else {
+				prorations = preview.Prorations
+			}
+		}
+		sub = next
+	}
+
+	items, err := s.loadItems(ctx, sub, start, end)
+	if err != nil {
+		return InvoicePreview{}, err
+	}
+	price := items[0].Price
+	items, err = s.splitFees(ctx, sub, items, start, end, simulated...)
+	if err != nil {
+		return InvoicePreview{}, err
+	}
+	records, err := s.listUsage(ctx, sub.TenantID, sub.ID, start, end)
+	if err != nil {
+		s.logger.Error("failed to fetch usage", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return InvoicePreview{}, err
+	}
+	taxRule, err := s.resolveTaxRule(ctx, sub.TenantID, sub.CustomerID)
+	if err != nil {
+		s.logger.Error("failed to resolve tax rule", zap.Error(err), zap.String("customer_id", sub.CustomerID))
+		return InvoicePreview{}, err
+	}
+	pending, err := s.pendingRepo.ListOpen(ctx, sub.TenantID, sub.ID)
+	if err != nil {
+		s.logger.Error("failed to load pending items", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return InvoicePreview{}, err
+	}
+	discounts, err := s.couponRepo.ListActiveDiscounts(ctx, sub.TenantID, sub.CustomerID, sub.ID)
+	if err != nil {
+		s.logger.Error("failed to load discounts", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return InvoicePreview{}, err
+	}
+
+	amounts, err := computeCharges(items, records, append(pendingLines(pending), prorations...), discounts, taxRule, billingPeriod{
+		Start:     start,
+		End:       end,
+		FullStart: price.PeriodStart(end, sub.BillingAnchorDay),
+		Prepaid:   prepaid,
+	})
+	if err != nil {
+		return InvoicePreview{}, s.chargeError(err, sub.ID)
+	}
+	for i := range amounts.Lines {
+		amounts.Lines[i].TenantID = sub.TenantID
+	}
+
+	now := time.Now().UTC()
+	preview.DiscountCents = amounts.DiscountCents
+	preview.Invoice = invoice.Invoice{
+		TenantID:       sub.TenantID,
+		CustomerID:     sub.CustomerID,
+		SubscriptionID: sub.ID,
+		Status:         int32(invoicev1.InvoiceStatus_INVOICE_STATUS_DRAFT),
+		CurrencyCode:   strings.ToUpper(price.Currency),
+		TotalCents:     amounts.TotalCents,
+		SubtotalCents:  amounts.SubtotalCents,
+		TaxCents:       amounts.TaxCents,
+		IssuedAt:       &end,
+		Metadata: map[string]interface{}{
+			"billing_reason":  "upcoming",
+			"base_amount":     amounts.BaseCents,
+			"usage_charges":   amounts.UsageCents,
+			"usage_quantity":  amounts.UsageQuantity,
+			"pending_amount":  amounts.PendingCents,
+			"discount_amount": amounts.DiscountCents,
+		},
+		LineItems: amounts.Lines,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	preview.PeriodStart, preview.PeriodEnd = start, end
+	terms, err := invoice.ResolvePaymentTerms(ctx, s.invoiceRepo, sub.TenantID, sub.CustomerID)
+	if err != nil {
+		return InvoicePreview{}, err
+	}
+	preview.Invoice.ApplyPaymentTerms(terms)
+	return preview, nil
+}
+
+// simulatePriceChange returns sub switched to priceID with the new price,
+// rejecting prices the subscription cannot be billed in.
+func (s *Service) simulatePriceChange(ctx context.Context, sub subscription.Subscription, priceID string) (subscription.Subscription, pricing.Price, error) {
+	sub.PriceID = priceID
+	price, err := s.loadPrice(ctx, sub)
+	if err != nil {
+		return subscription.Subscription{}, pricing.Price{}, err
+	}
+	if price.IsArchived() {
+		return subscription.Subscription{}, pricing.Price{}, status.Errorf(codes.FailedPrecondition, "price %s is archived", priceID)
+	}
+	return sub, price, nil
+}
@@ -0,0 +1,199 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	coupon "github.com/smallbiznis/corebilling/internal/coupon/domain"
+	customer "github.com/smallbiznis/corebilling/internal/customer/domain"
+	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+	subscription "github.com/smallbiznis/corebilling/internal/subscription/domain"
+	usage "github.com/smallbiznis/corebilling/internal/usage/domain"
+	"go.uber.org/zap"
+)
+
+type previewRuns struct {
+	Repository
+	run *Run
+}
+
+func (r previewRuns) FindBySubscriptionPeriod(context.Context, string, time.Time, time.Time) (Run, bool, error) {
+	if r.run == nil {
+		return Run{}, false, nil
+	}
+	return *r.run, true, nil
+}
+
+type previewTaxes struct{ TaxRepository }
+
+func (previewTaxes) FindApplicable(context.Context, string, string) (*TaxRule, error) {
+	return nil, nil
+}
+
+type previewPending struct{ PendingItemRepository }
+
+func (previewPending) ListOpen(context.Context, string, string) ([]PendingItem, error) {
+	return nil, nil
+}
+
+type previewFeeChanges struct {
+	FeeChangeRepository
+	changes []FeeChange
+}
+
+func (r previewFeeChanges) ListInPeriod(context.Context, string, time.Time, time.Time) ([]FeeChange, error) {
+	return r.changes, nil
+}
+
+type previewInvoices struct{ invoice.Repository }
+
+func (previewInvoices) GetPaymentTerms(context.Context, string, string) (invoice.PaymentTermsSetting, bool, error) {
+	return invoice.PaymentTermsSetting{}, false, nil
+}
+
+type previewSubscriptions struct {
+	subscription.Repository
+	sub subscription.Subscription
+}
+
+func (r previewSubscriptions) GetByID(context.Context, string) (subscription.Subscription, error) {
+	return r.sub, nil
+}
+
+func (previewSubscriptions) ListItems(context.Context, subscription.ItemFilter) ([]subscription.Item, error) {
+	return nil, nil
+}
+
+type previewPrices struct {
+	pricing.Repository
+	prices map[int64]pricing.Price
+}
+
+func (r previewPrices) GetPrice(_ context.Context, _ int64, id int64) (pricing.Price, error) {
+	price, ok := r.prices[id]
+	if !ok {
+		return pricing.Price{}, fmt.Errorf("price %d not found", id)
+	}
+	return price, nil
+}
+
+func (previewPrices) ListPriceTiersByPriceIDs(context.Context, []int64) ([]pricing.PriceTier, error) {
+	return nil, nil
+}
+
+type previewUsage struct{ usage.Repository }
+
+func (previewUsage) List(context.Context, usage.ListUsageFilter) ([]usage.UsageRecord, bool, error) {
+	return nil, false, nil
+}
+
+type previewCustomers struct{ customer.Repository }
+
+func (previewCustomers) GetByID(context.Context, string) (customer.Customer, error) {
+	return customer.Customer{BillingAddress: map[string]interface{}{"country": "us"}}, nil
+}
+
+type previewCoupons struct{ coupon.Repository }
+
+func (previewCoupons) ListActiveDiscounts(context.Context, string, string, string) ([]coupon.Discount, error) {
+	return nil, nil
+}
+
+func TestPreviewInvoice(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	at := start.Add(15 * 24 * time.Hour)
+	basic := pricing.Price{ID: 1, Code: "basic", PricingModel: pricing.PricingModelFlat, UnitAmountCents: 1000, Currency: "usd"}
+	pro := pricing.Price{ID: 2, Code: "pro", PricingModel: pricing.PricingModelFlat, UnitAmountCents: 2000, Currency: "usd"}
+	// Raising 2 units to 3 five days in billed the added unit in advance.
+	increase := FeeChange{PriceID: "1", Quantity: 2, At: start.AddDate(0, 0, 5)}
+
+	tests := []struct {
+		name       string
+		newPriceID string
+		behavior   ProrationBehavior
+		quantity   int64
+		changes    []FeeChange
+		prepaid    bool
+		total      int64
+		prorations int64
+		immediate  int64
+	}{
+		{name: "current period", total: 1000},
+		// Half the period on each price: 500 + 1000, with nothing to prorate.
+		{name: "create_prorations in arrears", newPriceID: "2", behavior: ProrationCreateProrations, total: 1500},
+		// 2*(500 + 1000) for the units billed in arrears, plus the upgrade of
+		// the unit billed in advance: 1000 - 500.
+		{name: "create_prorations with units billed in advance", newPriceID: "2", behavior: ProrationCreateProrations, quantity: 3, changes: []FeeChange{increase}, total: 3500, prorations: 500},
+		{name: "always_invoice with units billed in advance", newPriceID: "2", behavior: ProrationAlwaysInvoice, quantity: 3, changes: []FeeChange{increase}, total: 3000, prorations: 500, immediate: 500},
+		// Without prorations the new price is billed for the whole period.
+		{name: "none", newPriceID: "2", behavior: ProrationNone, total: 2000},
+		// The fee was invoiced in advance, so only the prorations are owed.
+		{name: "prepaid period", prepaid: true, total: 0},
+		{name: "create_prorations in prepaid period", newPriceID: "2", behavior: ProrationCreateProrations, prepaid: true, total: 500, prorations: 500},
+		{name: "always_invoice in prepaid period", newPriceID: "2", behavior: ProrationAlwaysInvoice, prepaid: true, total: 0, prorations: 500, immediate: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := subscription.Subscription{
+				ID:                 "sub_1",
+				TenantID:           "1",
+				CustomerID:         "cus_1",
+				PriceID:            strconv.FormatInt(basic.ID, 10),
+				Quantity:           tt.quantity,
+				Currency:           "USD",
+				CurrentPeriodStart: start,
+				CurrentPeriodEnd:   end,
+			}
+			runs := previewRuns{}
+			if tt.prepaid {
+				runs.run = &Run{SubscriptionID: sub.ID, PeriodStart: start, PeriodEnd: end, CreatedAt: start}
+			}
+			svc := NewService(
+				runs,
+				previewTaxes{},
+				previewPending{},
+				previewFeeChanges{changes: tt.changes},
+				previewInvoices{},
+				previewSubscriptions{sub: sub},
+				previewPrices{prices: map[int64]pricing.Price{1: basic, 2: pro}},
+				previewUsage{},
+				previewCustomers{},
+				previewCoupons{},
+				nil,
+				zap.NewNop(),
+				nil,
+			)
+
+			preview, err := svc.PreviewInvoice(context.Background(), PreviewRequest{
+				TenantID:       sub.TenantID,
+				SubscriptionID: sub.ID,
+				NewPriceID:     tt.newPriceID,
+				Behavior:       tt.behavior,
+				Granularity:    ProrationBySecond,
+				At:             at,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if preview.Invoice.TotalCents != tt.total {
+				t.Fatalf("expected total %d got %d: %+v", tt.total, preview.Invoice.TotalCents, preview.Invoice.LineItems)
+			}
+			var prorations int64
+			for _, line := range preview.Prorations {
+				prorations += line.AmountCents
+			}
+			if prorations != tt.prorations || preview.ImmediateCents != tt.immediate {
+				t.Fatalf("expected prorations %d and immediate %d got %d and %d", tt.prorations, tt.immediate, prorations, preview.ImmediateCents)
+			}
+			if !preview.PeriodStart.Equal(start) || !preview.PeriodEnd.Equal(end) {
+				t.Fatalf("expected period %s - %s got %s - %s", start, end, preview.PeriodStart, preview.PeriodEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+	subscription "github.com/smallbiznis/corebilling/internal/subscription/domain"
+)
+
+// ProrationBehavior controls how a mid-period price change is billed.
+type ProrationBehavior string
+
+const (
+	// ProrationCreateProrations adds credit and debit lines to the next invoice.
+	ProrationCreateProrations ProrationBehavior = "create_prorations"
+	// ProrationAlwaysInvoice bills the proration lines on an immediate invoice.
+	ProrationAlwaysInvoice ProrationBehavior = "always_invoice"
+	// ProrationNone switches prices without charging or crediting the difference.
+	ProrationNone ProrationBehavior = "none"
+)
+
+// ProrationGranularity sets the time unit the remaining period is measured in.
+type ProrationGranularity string
+
+const (
+	ProrationBySecond ProrationGranularity = "second"
+	// ProrationByDay charges the day of the change in full.
+	ProrationByDay ProrationGranularity = "day"
+)
+
+// ParseProrationBehavior validates a behavior, defaulting to create_prorations.
+func ParseProrationBehavior(raw string) (ProrationBehavior, error) {
+	switch b := ProrationBehavior(strings.ToLower(raw)); b {
+	case "":
+		return ProrationCreateProrations, nil
+	case ProrationCreateProrations, ProrationAlwaysInvoice, ProrationNone:
+		return b, nil
+	}
+	return "", fmt.Errorf("unsupported proration_behavior %q", raw)
+}
+
+// ParseProrationGranularity validates a granularity, defaulting to seconds.
+func ParseProrationGranularity(raw string) (ProrationGranularity, error) {
+	switch g := ProrationGranularity(strings.ToLower(raw)); g {
+	case "":
+		return ProrationBySecond, nil
+	case ProrationBySecond, ProrationByDay:
+		return g, nil
+	}
+	return "", fmt.Errorf("unsupported proration_granularity %q", raw)
+}
+
+// ProrationRequest describes a price change on a subscription. Subscription
+// must still reference the old price.
+type ProrationRequest struct {
+	Subscription subscription.Subscription
+	NewPriceID   string
+	Behavior     ProrationBehavior
+	Granularity  ProrationGranularity
+	At           time.Time
+	// SourceKey identifies the change, typically the triggering event id.
+	SourceKey string
+}
+
+// QuantityProrationRequest describes a quantity increase on the subscription's
+// primary price, when ItemID is empty, or on one of its items. Subscription
+// must hold the current period.
+type QuantityProrationRequest struct {
+	Subscription subscription.Subscription
+	ItemID       string
+	PriceID      string
+	Previous     int64
+	Quantity     int64
+	At           time.Time
+	// SourceKey identifies the change so retries do not bill it twice.
+	SourceKey string
+}
+
+// ProrationResult summarizes the credit and debit recorded for a price change.
+type ProrationResult struct {
+	CreditCents int64
+	DebitCents  int64
+	// InvoiceID is set when the proration was billed immediately.
+	InvoiceID string
+}
+
+// prorationLines returns a credit for the unused part of quantity units of the
+// old price and a debit for the remaining part of the new one. Metered prices
+// bill usage in arrears and are not prorated.
+func prorationLines(oldPrice, newPrice pricing.Price, quantity int64, start, end, at time.Time, granularity ProrationGranularity) ([]invoice.LineItem, error) {
+	remaining, total := prorationFraction(start, end, at, granularity)
+	if remaining == 0 {
+		return nil, nil
+	}
+	var lines []invoice.LineItem
+	if !oldPrice.IsMetered() {
+		line, err := prorationLine(oldPrice, fmt.Sprintf("Unused time on %s", priceLabel(oldPrice)), -1, quantity, start, end, at, remaining, total)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	if !newPrice.IsMetered() {
+		line, err := prorationLine(newPrice, fmt.Sprintf("Remaining time on %s", priceLabel(newPrice)), 1, quantity, start, end, at, remaining, total)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// quantityProrationLines charges the units added by raising the quantity of a
+// licensed price from previous to quantity for the rest of the period.
+func quantityProrationLines(price pricing.Price, previous, quantity int64, start, end, at time.Time) ([]invoice.LineItem, error) {
+	remaining, total := prorationFraction(start, end, at, ProrationBySecond)
+	if remaining == 0 || price.IsMetered() || quantity <= previous {
+		return nil, nil
+	}
+	added := quantity - previous
+	line, err := prorationLine(price, fmt.Sprintf("Remaining time on %d × %s", added, priceLabel(price)), 1, added, start, end, at, remaining, total)
+	if err != nil {
+		return nil, err
+	}
+	return []invoice.LineItem{line}, nil
+}
+
+// arrearsQuantity returns the quantity of an item billed at the end of a
+// period invoiced in arrears: the quantity recorded by its first change in the
+// period, or current when it has not changed.
+func arrearsQuantity(changes []FeeChange, itemID string, current int64) int64 {
+	var first *FeeChange
+	for i := range changes {
+		if changes[i].ItemID == itemID && (first == nil || changes[i].At.Before(first.At)) {
+			first = &changes[i]
+		}
+	}
+	if first == nil {
+		return current
+	}
+	return first.Quantity
+}
+
+// changeBoundary returns when a price change at at takes effect within a
+// period starting at start. Day granularity starts the new price with the day
+// of the change.
+func changeBoundary(start, at time.Time, granularity ProrationGranularity) time.Time {
+	if granularity == ProrationByDay {
+		at = at.Truncate(24 * time.Hour)
+	}
+	if at.Before(start) {
+		return start
+	}
+	return at
+}
+
+// unusedTimeCredit credits quantity units of the part of a period billed in
+// advance that follows at, e.g. when the subscription is canceled.
+func unusedTimeCredit(price pricing.Price, quantity int64, start, end, at time.Time) ([]invoice.LineItem, error) {
+	remaining, total := prorationFraction(start, end, at, ProrationBySecond)
+	if remaining == 0 || price.IsMetered() {
+		return nil, nil
+	}
+	line, err := prorationLine(price, fmt.Sprintf("Unused time on %s", priceLabel(price)), -1, quantity, start, end, at, remaining, total)
+	if err != nil {
+		return nil, err
+	}
+	return []invoice.LineItem{line}, nil
+}
+
+// prorationLine charges, or with a negative sign credits, remaining/total of
+// quantity units of the price for the time from at to end. It fails with
+// money.ErrOverflow when the amount does not fit.
+func prorationLine(price pricing.Price, description string, sign, quantity int64, start, end, at time.Time, remaining, total int64) (invoice.LineItem, error) {
+	from := at
+	if from.Before(start) {
+		from = start
+	}
+	amount := price.UnitPrice().Mul(quantity).MulFraction(remaining, total)
+	if err := amount.Err(); err != nil {
+		return invoice.LineItem{}, err
+	}
+	return invoice.LineItem{
+		Type:            invoice.LineItemTypeProration,
+		Description:     description,
+		PriceID:         strconv.FormatInt(price.ID, 10),
+		Quantity:        float64(quantity),
+		UnitAmountCents: price.UnitAmountCents * sign,
+		AmountCents:     amount.Cents() * sign,
+		Currency:        strings.ToUpper(price.Currency),
+		PeriodStart:     &from,
+		PeriodEnd:       &end,
+		Metadata:        map[string]interface{}{"proration_fraction": float64(remaining) / float64(total)},
+	}, nil
+}
+
+// prorationFraction returns the remaining and total length of the period in
+// the granularity's unit. Day granularity counts the day of the change as remaining.
+func prorationFraction(start, end, at time.Time, granularity ProrationGranularity) (int64, int64) {
+	if !end.After(start) || !at.Before(end) {
+		return 0, 0
+	}
+	if at.Before(start) {
+		at = start
+	}
+	if granularity == ProrationByDay {
+		day := 24 * time.Hour
+		total := int64((end.Sub(start) + day - 1) / day)
+		remaining := int64((end.Sub(at.Truncate(day)) + day - 1) / day)
+		if remaining > total {
+			remaining = total
+		}
+		return remaining, total
+	}
+	return int64(end.Sub(at) / time.Second), int64(end.Sub(start) / time.Second)
+}
+
+func priceLabel(price pricing.Price) string {
+	if price.Code != "" {
+		return price.Code
+	}
+	return fmt.Sprintf("price %d", price.ID)
+}
+
+func pendingLines(items []PendingItem) []invoice.LineItem {
+	lines := make([]invoice.LineItem, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, item.Line)
+	}
+	return lines
+}
+
+func pendingIDs(items []PendingItem) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
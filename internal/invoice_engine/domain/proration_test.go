@@ -0,0 +1,242 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+)
+
+func TestProrationFraction(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name        string
+		at          time.Time
+		granularity ProrationGranularity
+		remaining   int64
+		total       int64
+	}{
+		{name: "half period by second", at: start.Add(15 * 24 * time.Hour), granularity: ProrationBySecond, remaining: 15 * 86400, total: 30 * 86400},
+		{name: "day counts change day", at: start.Add(15*24*time.Hour + 18*time.Hour), granularity: ProrationByDay, remaining: 15, total: 30},
+		{name: "before period", at: start.Add(-time.Hour), granularity: ProrationBySecond, remaining: 30 * 86400, total: 30 * 86400},
+		{name: "at period end", at: end, granularity: ProrationByDay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining, total := prorationFraction(start, end, tt.at, tt.granularity)
+			if remaining != tt.remaining || total != tt.total {
+				t.Fatalf("expected %d/%d got %d/%d", tt.remaining, tt.total, remaining, total)
+			}
+		})
+	}
+}
+
+func TestProrationLines(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	at := start.Add(10 * 24 * time.Hour)
+
+	basic := pricing.Price{ID: 1, Code: "basic", PricingModel: pricing.PricingModelFlat, UnitAmountCents: 3000, Currency: "usd"}
+	pro := pricing.Price{ID: 2, Code: "pro", PricingModel: pricing.PricingModelFlat, UnitAmountCents: 9000, Currency: "usd"}
+	metered := pricing.Price{ID: 3, Code: "api", PricingModel: pricing.PricingModelPerUnit, UnitAmountCents: 1, Currency: "usd"}
+
+	tests := []struct {
+		name    string
+		from    pricing.Price
+		to      pricing.Price
+		amounts []int64
+	}{
+		{name: "upgrade", from: basic, to: pro, amounts: []int64{-2000, 6000}},
+		{name: "downgrade", from: pro, to: basic, amounts: []int64{-6000, 2000}},
+		{name: "to metered", from: basic, to: metered, amounts: []int64{-2000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := prorationLines(tt.from, tt.to, 1, start, end, at, ProrationByDay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lines) != len(tt.amounts) {
+				t.Fatalf("expected %d lines got %d", len(tt.amounts), len(lines))
+			}
+			for i, line := range lines {
+				if line.AmountCents != tt.amounts[i] {
+					t.Fatalf("line %d: expected %d got %d", i, tt.amounts[i], line.AmountCents)
+				}
+				if !line.PeriodStart.Equal(at) || !line.PeriodEnd.Equal(end) {
+					t.Fatalf("line %d: unexpected period %s - %s", i, line.PeriodStart, line.PeriodEnd)
+				}
+			}
+		})
+	}
+}
+
+func TestQuantityProrationLines(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	at := start.AddDate(0, 0, 20)
+	seat := pricing.Price{ID: 1, Code: "seat", PricingModel: pricing.PricingModelFlat, UnitAmountCents: 1500, Currency: "usd"}
+
+	lines, err := quantityProrationLines(seat, 2, 5, start, end, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0].AmountCents != 1500 || lines[0].Quantity != 3 {
+		t.Fatalf("expected 3 seats for a third of the period (1500), got %+v", lines)
+	}
+	if lines, _ := quantityProrationLines(seat, 5, 2, start, end, at); len(lines) != 0 {
+		t.Fatalf("expected decreases not to be prorated, got %d lines", len(lines))
+	}
+}
+
+func TestCancellationCharges(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	at := start.AddDate(0, 0, 10)
+	price := pricing.Price{PricingModel: pricing.PricingModelFlat, UnitAmountCents: 3000, Currency: "usd"}
+
+	tests := []struct {
+		name    string
+		prepaid bool
+		prorate bool
+		total   int64
+	}{
+		{name: "arrears prorated", prorate: true, total: 1000},
+		{name: "arrears full fee", total: 3000},
+		{name: "prepaid credit", prepaid: true, prorate: true, total: -2000},
+		{name: "prepaid no credit", prepaid: true, total: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cancellationCharges([]pricedItem{{Price: price, Quantity: 1}}, nil, nil, nil, nil, start, end, at, tt.prepaid, tt.prorate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.TotalCents != tt.total {
+				t.Fatalf("expected total %d, got %d", tt.total, got.TotalCents)
+			}
+		})
+	}
+}
+
+func TestArrearsUpgrade(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	at := start.Add(15 * 24 * time.Hour)
+	basic := pricing.Price{ID: 1, Code: "basic", PricingModel: pricing.PricingModelFlat, UnitAmountCents: 1000, Currency: "usd"}
+	pro := pricing.Price{ID: 2, Code: "pro", PricingModel: pricing.PricingModelFlat, UnitAmountCents: 2000, Currency: "usd"}
+	prices := map[string]pricing.Price{"1": basic}
+	period := billingPeriod{Start: start, End: end}
+
+	tests := []struct {
+		name     string
+		arrears  int64
+		quantity int64
+		total    int64
+	}{
+		// Half the period on each price: 500 + 1000.
+		{name: "same quantity", arrears: 1, quantity: 1, total: 1500},
+		// The unit added before the upgrade was billed in advance at the old
+		// price; its upgrade is prorated: 2*(500 + 1000) + (1000 - 500).
+		{name: "units billed in advance", arrears: 2, quantity: 3, total: 3500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pending []invoice.LineItem
+			if added := tt.quantity - tt.arrears; added > 0 {
+				var err error
+				if pending, err = prorationLines(basic, pro, added, start, end, at, ProrationBySecond); err != nil {
+					t.Fatal(err)
+				}
+			}
+			changes := []FeeChange{{PriceID: "1", Quantity: tt.arrears, At: changeBoundary(start, at, ProrationBySecond)}}
+			items := feeSegments([]pricedItem{{Price: pro, Quantity: tt.quantity}}, changes, prices)
+			got, err := computeCharges(items, nil, pending, nil, nil, period)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.TotalCents != tt.total {
+				t.Fatalf("expected total %d got %d: %+v", tt.total, got.TotalCents, got.Lines)
+			}
+		})
+	}
+}
+
+func TestFeeSegments(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	first := start.AddDate(0, 0, 10)
+	second := start.AddDate(0, 0, 20)
+	basic := pricing.Price{ID: 1, PricingModel: pricing.PricingModelFlat, UnitAmountCents: 3000, Currency: "usd"}
+	pro := pricing.Price{ID: 2, PricingModel: pricing.PricingModelFlat, UnitAmountCents: 9000, Currency: "usd"}
+	prices := map[string]pricing.Price{"1": basic, "2": pro}
+
+	// basic -> pro -> basic, with an unchanged item alongside.
+	items := feeSegments(
+		[]pricedItem{{Price: basic, Quantity: 3}, {ItemID: "i1", Price: pro, Quantity: 1}},
+		[]FeeChange{{PriceID: "2", Quantity: 2, At: second}, {PriceID: "1", Quantity: 2, At: first}},
+		prices,
+	)
+	if len(items) != 4 {
+		t.Fatalf("expected 4 items got %+v", items)
+	}
+	if items[0].Quantity != 2 || !items[0].FeeFrom.Equal(second) || items[0].FeeOnly {
+		t.Fatalf("unexpected current item %+v", items[0])
+	}
+	if items[1].ItemID != "i1" || !items[1].FeeFrom.IsZero() {
+		t.Fatalf("expected unchanged item, got %+v", items[1])
+	}
+	if !items[2].FeeOnly || items[2].Price.ID != 1 || !items[2].From.IsZero() || !items[2].Until.Equal(first) {
+		t.Fatalf("unexpected first segment %+v", items[2])
+	}
+	if !items[3].FeeOnly || items[3].Price.ID != 2 || !items[3].From.Equal(first) || !items[3].Until.Equal(second) {
+		t.Fatalf("unexpected second segment %+v", items[3])
+	}
+}
+
+func TestQuantityIncreaseFlow(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	at := start.AddDate(0, 0, 20)
+	seat := pricing.Price{ID: 1, Code: "seat", PricingModel: pricing.PricingModelFlat, UnitAmountCents: 1500, Currency: "usd"}
+
+	// Raising 2 seats to 5 invoices the 3 added seats for the last third
+	// of the period right away.
+	lines, err := quantityProrationLines(seat, 2, 5, start, end, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	immediate, err := computeCharges(nil, nil, lines, nil, nil, billingPeriod{Start: at, End: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if immediate.TotalCents != 1500 {
+		t.Fatalf("expected immediate invoice of 1500 got %d", immediate.TotalCents)
+	}
+
+	// The period-end invoice bills the fee for the 2 seats the period
+	// started with.
+	changes := []FeeChange{{PriceID: "1", Quantity: arrearsQuantity(nil, "", 2), At: at}}
+	items := feeSegments([]pricedItem{{Price: seat, Quantity: 5}}, changes, map[string]pricing.Price{"1": seat})
+	final, err := computeCharges(items, nil, nil, nil, nil, billingPeriod{Start: start, End: end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.TotalCents != 3000 || len(final.Lines) != 1 || final.Lines[0].Quantity != 2 {
+		t.Fatalf("expected a fee for 2 seats (3000) got %d: %+v", final.TotalCents, final.Lines)
+	}
+
+	// A second increase keeps the quantity the period started with.
+	later := append(changes, FeeChange{PriceID: "1", Quantity: arrearsQuantity(changes, "", 5), At: at.AddDate(0, 0, 5)})
+	if got := later[1].Quantity; got != 2 {
+		t.Fatalf("expected the period-start quantity 2 got %d", got)
+	}
+	items = feeSegments([]pricedItem{{Price: seat, Quantity: 7}}, later, map[string]pricing.Price{"1": seat})
+	if final, _ := computeCharges(items, nil, nil, nil, nil, billingPeriod{Start: start, End: end}); final.TotalCents != 3000 {
+		t.Fatalf("expected 3000 after a second increase got %d", final.TotalCents)
+	}
+}
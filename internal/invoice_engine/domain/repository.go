@@ -2,9 +2,58 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
 )
 
+// ErrPeriodInvoiced is returned when storing a run for a period that was
+// already invoiced at or after its end.
+var ErrPeriodInvoiced = errors.New("period already invoiced")
+
+// Billed lists what an invoice bills besides its lines: the pending items
+// attached to it and the discounts it counts against for one more period.
+type Billed struct {
+	PendingItemIDs []string
+	DiscountIDs    []string
+}
+
 // Repository defines persistence for invoice engine runs.
 type Repository interface {
-	Create(ctx context.Context, run Run) error
+	// CreateWithInvoice numbers and stores the invoice with its postings and
+	// the run that produced it in one transaction, attaching the billed
+	// pending items and marking the billed discounts applied in it too. A
+	// nil run stores the invoice alone. A run only replaces the run of its
+	// period when that one was created before the period ended, i.e.
+	// invoiced it in advance; otherwise nothing is stored and
+	// ErrPeriodInvoiced is returned.
+	CreateWithInvoice(ctx context.Context, run *Run, inv invoice.Invoice, scheme invoice.NumberingScheme, billed Billed, postings ...invoice.LedgerPosting) (invoice.Invoice, error)
+	// FindBySubscriptionPeriod returns the run that invoiced the subscription for the period, if any.
+	FindBySubscriptionPeriod(ctx context.Context, subscriptionID string, start, end time.Time) (Run, bool, error)
+}
+
+// TaxRepository resolves tax rules used during invoice computation.
+type TaxRepository interface {
+	// FindApplicable returns the tenant's active rule for the region, falling
+	// back to the tenant's default rule. It returns nil when no rule applies.
+	FindApplicable(ctx context.Context, tenantID, regionCode string) (*TaxRule, error)
+}
+
+// PendingItemRepository stores items waiting for the next subscription invoice.
+type PendingItemRepository interface {
+	// Create stores the items, skipping any whose source key already exists.
+	Create(ctx context.Context, items []PendingItem) error
+	// ListOpen returns items for the subscription not yet attached to an invoice.
+	ListOpen(ctx context.Context, tenantID, subscriptionID string) ([]PendingItem, error)
+}
+
+// FeeChangeRepository stores the price and quantity changes made within a
+// period, see FeeChange.
+type FeeChangeRepository interface {
+	// Create stores the change unless its source key already exists.
+	Create(ctx context.Context, change FeeChange) error
+	// ListInPeriod returns the subscription's changes from start up to end,
+	// oldest first.
+	ListInPeriod(ctx context.Context, subscriptionID string, start, end time.Time) ([]FeeChange, error)
 }
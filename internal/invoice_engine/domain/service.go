@@ -2,11 +2,20 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/bwmarrin/snowflake"
+	coupon "github.com/smallbiznis/corebilling/internal/coupon/domain"
+	customer "github.com/smallbiznis/corebilling/internal/customer/domain"
 	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	"github.com/smallbiznis/corebilling/internal/money"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+	subscription "github.com/smallbiznis/corebilling/internal/subscription/domain"
+	usage "github.com/smallbiznis/corebilling/internal/usage/domain"
 	invoicev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice/v1"
 	invoiceenginev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice_engine/v1"
 	"go.uber.org/zap"
@@ -15,23 +24,88 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+const usagePageSize = 500
+
 // Service implements the invoice engine API.
 type Service struct {
 	invoiceenginev1.UnimplementedInvoiceEngineServiceServer
-	runRepo     Repository
-	invoiceRepo invoice.Repository
-	logger      *zap.Logger
+	runRepo          Repository
+	taxRepo          TaxRepository
+	pendingRepo      PendingItemRepository
+	feeChanges       FeeChangeRepository
+	invoiceRepo      invoice.Repository
+	subscriptionRepo subscription.Repository
+	pricingRepo      pricing.Repository
+	usageRepo        usage.Repository
+	customerRepo     customer.Repository
+	couponRepo       coupon.Repository
+	ledger           invoice.Ledger
+	logger           *zap.Logger
+
+	genID *snowflake.Node
 }
 
 // NewService constructs the invoice engine service.
-func NewService(runRepo Repository, invoiceRepo invoice.Repository, logger *zap.Logger) *Service {
+func NewService(
+	runRepo Repository,
+	taxRepo TaxRepository,
+	pendingRepo PendingItemRepository,
+	feeChanges FeeChangeRepository,
+	invoiceRepo invoice.Repository,
+	subscriptionRepo subscription.Repository,
+	pricingRepo pricing.Repository,
+	usageRepo usage.Repository,
+	customerRepo customer.Repository,
+	couponRepo coupon.Repository,
+	ledger invoice.Ledger,
+	logger *zap.Logger,
+	genID *snowflake.Node,
+) *Service {
 	return &Service{
-		runRepo:     runRepo,
-		invoiceRepo: invoiceRepo,
-		logger:      logger.Named("invoice_engine.service"),
+		runRepo:          runRepo,
+		taxRepo:          taxRepo,
+		pendingRepo:      pendingRepo,
+		feeChanges:       feeChanges,
+		invoiceRepo:      invoiceRepo,
+		subscriptionRepo: subscriptionRepo,
+		pricingRepo:      pricingRepo,
+		usageRepo:        usageRepo,
+		customerRepo:     customerRepo,
+		couponRepo:       couponRepo,
+		ledger:           ledger,
+		logger:           logger.Named("invoice_engine.service"),
+		genID:            genID,
 	}
 }
 
+// createInvoice dates an issued invoice by its customer's payment terms,
+// numbers and stores it, and books it as receivable. The run, if any, and
+// what the invoice billed are stored in the same transaction, so a failed
+// write never leaves pending items or discounts to be billed again; see
+// Repository.CreateWithInvoice.
+func (s *Service) createInvoice(ctx context.Context, inv invoice.Invoice, run *Run, billed Billed) error {
+	terms, err := invoice.ResolvePaymentTerms(ctx, s.invoiceRepo, inv.TenantID, inv.CustomerID)
+	if err != nil {
+		return err
+	}
+	inv.ApplyPaymentTerms(terms)
+	scheme, err := invoice.LoadNumberingScheme(ctx, s.invoiceRepo, inv.TenantID)
+	if err != nil {
+		return err
+	}
+	// The receivable is queued with the invoice and posted by the invoice
+	// ledger worker.
+	var postings []invoice.LedgerPosting
+	if s.ledger != nil && inv.TotalCents > 0 {
+		posting := invoice.ReceivablePosting(inv)
+		posting.ID = s.genID.Generate().String()
+		posting.CreatedAt = time.Now().UTC()
+		postings = append(postings, posting)
+	}
+	_, err = s.runRepo.CreateWithInvoice(ctx, run, inv, scheme, billed, postings...)
+	return err
+}
+
 func (s *Service) GenerateInvoice(
 	ctx context.Context,
 	req *invoiceenginev1.GenerateInvoiceRequest,
@@ -40,157 +114,711 @@ func (s *Service) GenerateInvoice(
 		return nil, status.Error(codes.InvalidArgument, "tenant_id and subscription_id required")
 	}
 
+	sub, err := s.subscriptionRepo.GetByID(ctx, req.GetSubscriptionId())
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "subscription not found: %v", err)
+	}
+	if sub.TenantID != req.GetTenantId() {
+		return nil, status.Error(codes.NotFound, "subscription not found")
+	}
+
+	customerID := req.GetCustomerId()
+	if customerID == "" {
+		customerID = sub.CustomerID
+	}
+
 	now := time.Now().UTC()
-	invoiceID := strconv.FormatInt(time.Now().UnixNano(), 10)
-	start := normalizeTimestamp(req.GetPeriodStart(), now.AddDate(0, -1, 0))
-	end := normalizeTimestamp(req.GetPeriodEnd(), now)
-
-	// // 1. Fetch Price Details
-	// priceID := req.GetPriceId()
-	// if priceID == "" {
-	// 	// Get from subscription
-	// 	return nil, status.Error(codes.InvalidArgument, "price_id required")
-	// }
-
-	// price, err := s.pricingRepo.GetPrice(ctx, parseID(priceID))
-	// if err != nil {
-	// 	return nil, status.Errorf(codes.NotFound, "price not found: %v", err)
-	// }
-
-	// // 2. Calculate Base Amount (recurring)
-	// subtotal := price.UnitAmountCents
-
-	// // 3. Aggregate Usage (if usage-based)
-	// usageRecords, _, err := s.usageRepo.List(ctx, usage.ListUsageFilter{
-	// 	TenantID:       req.GetTenantId(),
-	// 	SubscriptionID: req.GetSubscriptionId(),
-	// 	From:           start,
-	// 	To:             end,
-	// })
-	// if err != nil {
-	// 	s.logger.Error("failed to fetch usage", zap.Error(err))
-	// }
-
-	// // Calculate usage charges
-	// var usageCharges int64
-	// for _, record := range usageRecords {
-	// 	// Simple calculation: value * unit_amount
-	// 	// TODO: Apply price tiers if applicable
-	// 	usageCharges += int64(record.Value * float64(price.UnitAmountCents) / 1000)
-	// }
-
-	// // 4. Apply Price Tiers (if configured)
-	// if len(usageRecords) > 0 {
-	// 	tiers, err := s.pricingRepo.ListPriceTiersByPriceIDs(ctx, []int64{price.ID})
-	// 	if err == nil && len(tiers) > 0 {
-	// 		usageCharges = s.calculateTieredCharges(usageRecords, tiers)
-	// 	}
-	// }
-
-	// 5. Calculate Tax (simplified 10%)
-	// subtotalWithUsage := subtotal + usageCharges
-	// taxCents := int64(float64(subtotalWithUsage) * 0.10)
-	// totalCents := subtotalWithUsage + taxCents
-
-	// 6. Generate Invoice Number
-	invoiceNumber := s.generateInvoiceNumber(req.GetTenantId(), now)
-
-	// 7. Create Invoice
+	start := normalizeTimestamp(req.GetPeriodStart(), sub.CurrentPeriodStart)
+	end := normalizeTimestamp(req.GetPeriodEnd(), sub.CurrentPeriodEnd)
+	if !end.After(start) {
+		return nil, status.Error(codes.InvalidArgument, "period_end must be after period_start")
+	}
+
+	// A period is invoiced once; repeated requests, e.g. from a retried
+	// renewal, return the existing invoice. A period invoiced in advance, like
+	// the first period after a trial, is invoiced again once it ends to bill
+	// the usage recorded in it.
+	previous, invoiced, err := s.runRepo.FindBySubscriptionPeriod(ctx, sub.ID, start, end)
+	if err != nil {
+		s.logger.Error("failed to look up invoice run", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return nil, err
+	}
+	if invoiced && (!previous.CreatedAt.Before(end) || now.Before(end)) {
+		return &invoiceenginev1.GenerateInvoiceResponse{InvoiceId: previous.InvoiceID}, nil
+	}
+	prepaid := invoiced
+
+	// 1. Resolve the prices and tiers of the items billed in the period.
+	items, err := s.loadItems(ctx, sub, start, end)
+	if err != nil {
+		return nil, err
+	}
+	price := items[0].Price
+	// Fees of items billed in advance are skipped below, so the changes only
+	// affect the fees billed in arrears.
+	items, err = s.splitFees(ctx, sub, items, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Aggregate usage recorded within the billing period. Usage is billed
+	// in arrears, so an invoice issued in advance carries only the fee.
+	var records []usage.UsageRecord
+	if !now.Before(end) {
+		records, err = s.listUsage(ctx, sub.TenantID, sub.ID, start, end)
+		if err != nil {
+			s.logger.Error("failed to fetch usage", zap.Error(err), zap.String("subscription_id", sub.ID))
+			return nil, err
+		}
+	}
+
+	// 3. Resolve the applicable tax rule.
+	taxRule, err := s.resolveTaxRule(ctx, sub.TenantID, customerID)
+	if err != nil {
+		s.logger.Error("failed to resolve tax rule", zap.Error(err), zap.String("customer_id", customerID))
+		return nil, err
+	}
+
+	// 4. Collect pending items and discounts redeemed on the subscription or its customer.
+	pending, err := s.pendingRepo.ListOpen(ctx, sub.TenantID, sub.ID)
+	if err != nil {
+		s.logger.Error("failed to load pending items", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return nil, err
+	}
+	discounts, err := s.couponRepo.ListActiveDiscounts(ctx, sub.TenantID, customerID, sub.ID)
+	if err != nil {
+		s.logger.Error("failed to load discounts", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return nil, err
+	}
+
+	// 5. Compute line items, subtotal, discounts, tax and total in the price currency.
+	amounts, err := computeCharges(items, records, pendingLines(pending), discounts, taxRule, billingPeriod{
+		Start:     start,
+		End:       end,
+		FullStart: price.PeriodStart(end, sub.BillingAnchorDay),
+		Prepaid:   prepaid,
+	})
+	if err != nil {
+		return nil, s.chargeError(err, sub.ID)
+	}
+	if prepaid && len(amounts.Lines) == 0 {
+		return &invoiceenginev1.GenerateInvoiceResponse{InvoiceId: previous.InvoiceID}, nil
+	}
+
+	invoiceID := s.genID.Generate().String()
+	for i := range amounts.Lines {
+		amounts.Lines[i].ID = s.genID.Generate().String()
+		amounts.Lines[i].InvoiceID = invoiceID
+		amounts.Lines[i].TenantID = sub.TenantID
+		amounts.Lines[i].CreatedAt = now
+	}
+
+	var taxRate float64
+	if taxRule != nil {
+		taxRate = taxRule.RatePercent
+	}
 	inv := invoice.Invoice{
 		ID:             invoiceID,
-		TenantID:       req.GetTenantId(),
-		CustomerID:     req.GetCustomerId(),
-		SubscriptionID: req.GetSubscriptionId(),
+		TenantID:       sub.TenantID,
+		CustomerID:     customerID,
+		SubscriptionID: sub.ID,
 		Status:         int32(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN),
-		CurrencyCode:   "USD",
-		TotalCents:     0,
-		SubtotalCents:  100,
-		TaxCents:       0,
-		InvoiceNumber:  invoiceNumber,
-		IssuedAt:       &start,
-		DueAt:          &end,
-		// Metadata: map[string]interface{}{
-		// 	"price_id":       priceID,
-		// 	"base_amount":    subtotal,
-		// 	"usage_charges":  usageCharges,
-		// 	"usage_count":    len(usageRecords),
-		// 	"billing_period": "monthly",
-		// },
+		CurrencyCode:   strings.ToUpper(price.Currency),
+		TotalCents:     amounts.TotalCents,
+		SubtotalCents:  amounts.SubtotalCents,
+		TaxCents:       amounts.TaxCents,
+		IssuedAt:       &now,
+		Metadata: map[string]interface{}{
+			"price_id":         strconv.FormatInt(price.ID, 10),
+			"item_count":       len(items),
+			"base_amount":      amounts.BaseCents,
+			"usage_charges":    amounts.UsageCents,
+			"usage_quantity":   amounts.UsageQuantity,
+			"usage_count":      len(records),
+			"pending_amount":   amounts.PendingCents,
+			"discount_amount":  amounts.DiscountCents,
+			"tax_rate_percent": taxRate,
+		},
+		LineItems: amounts.Lines,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
-	if err := s.invoiceRepo.Create(ctx, inv); err != nil {
+	// 6. Store the invoice with the engine run that makes it idempotent.
+	run := Run{
+		ID:             s.genID.Generate().String(),
+		TenantID:       sub.TenantID,
+		CustomerID:     customerID,
+		SubscriptionID: sub.ID,
+		InvoiceID:      invoiceID,
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		CreatedAt:      now,
+	}
+	// The advance invoice of a prepaid period already counted it against the
+	// discounts' duration.
+	billed := Billed{PendingItemIDs: pendingIDs(pending)}
+	if !prepaid {
+		billed.DiscountIDs = amounts.DiscountIDs
+	}
+	if err := s.createInvoice(ctx, inv, &run, billed); err != nil {
+		if errors.Is(err, ErrPeriodInvoiced) {
+			// A concurrent request invoiced the period first.
+			return s.invoicedPeriod(ctx, sub.ID, start, end)
+		}
 		s.logger.Error("failed to create invoice", zap.Error(err))
 		return nil, err
 	}
 
-	// 8. Record Engine Run
+	s.logger.Info("invoice generated",
+		zap.String("invoice_id", invoiceID),
+		zap.String("subscription_id", sub.ID),
+		zap.Int64("total_cents", amounts.TotalCents),
+	)
+
+	return &invoiceenginev1.GenerateInvoiceResponse{InvoiceId: invoiceID}, nil
+}
+
+// invoicedPeriod returns the invoice of the run stored for the period.
+func (s *Service) invoicedPeriod(ctx context.Context, subscriptionID string, start, end time.Time) (*invoiceenginev1.GenerateInvoiceResponse, error) {
+	run, ok, err := s.runRepo.FindBySubscriptionPeriod(ctx, subscriptionID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrPeriodInvoiced
+	}
+	return &invoiceenginev1.GenerateInvoiceResponse{InvoiceId: run.InvoiceID}, nil
+}
+
+// Prorate credits the unused time on the subscription's current price and
+// charges the remaining time on the new price. Depending on the behavior the
+// lines wait for the next invoice or are invoiced right away. Calls with the
+// same SourceKey are idempotent.
+func (s *Service) Prorate(ctx context.Context, req ProrationRequest) (ProrationResult, error) {
+	sub := req.Subscription
+	if req.Behavior == ProrationNone || req.NewPriceID == "" || req.NewPriceID == sub.PriceID {
+		return ProrationResult{}, nil
+	}
+	if req.SourceKey == "" {
+		return ProrationResult{}, status.Error(codes.InvalidArgument, "source key required")
+	}
+	oldPrice, err := s.loadPrice(ctx, sub)
+	if err != nil {
+		return ProrationResult{}, err
+	}
+	next := sub
+	next.PriceID = req.NewPriceID
+	newPrice, err := s.loadPrice(ctx, next)
+	if err != nil {
+		return ProrationResult{}, err
+	}
+
+	at := req.At
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	start, end := sub.CurrentPeriodStart, sub.CurrentPeriodEnd
+	_, prepaid, err := s.runRepo.FindBySubscriptionPeriod(ctx, sub.ID, start, end)
+	if err != nil {
+		s.logger.Error("failed to look up invoice run", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return ProrationResult{}, err
+	}
+	// A period billed in arrears bills the old price up to the change at its
+	// end, so only the units added since it started, which were billed in
+	// advance, are prorated now.
+	quantity := sub.BillableItems(nil)[0].Quantity
+	if !prepaid && at.Before(end) {
+		changes, err := s.feeChanges.ListInPeriod(ctx, sub.ID, start, end)
+		if err != nil {
+			s.logger.Error("failed to load fee changes", zap.Error(err), zap.String("subscription_id", sub.ID))
+			return ProrationResult{}, err
+		}
+		arrears := arrearsQuantity(changes, "", quantity)
+		if err := s.recordFeeChange(ctx, sub, FeeChange{
+			PriceID:   sub.PriceID,
+			Quantity:  arrears,
+			At:        changeBoundary(start, at, req.Granularity),
+			SourceKey: fmt.Sprintf("%s:price", req.SourceKey),
+		}); err != nil {
+			return ProrationResult{}, err
+		}
+		quantity -= arrears
+	}
+	var lines []invoice.LineItem
+	if quantity > 0 {
+		if lines, err = prorationLines(oldPrice, newPrice, quantity, start, end, at, req.Granularity); err != nil {
+			return ProrationResult{}, s.chargeError(err, sub.ID)
+		}
+	}
+
+	now := time.Now().UTC()
+	var result ProrationResult
+	items := make([]PendingItem, 0, len(lines))
+	for _, line := range lines {
+		kind := "debit"
+		if line.AmountCents < 0 {
+			kind = "credit"
+			result.CreditCents -= line.AmountCents
+		} else {
+			result.DebitCents += line.AmountCents
+		}
+		line.TenantID = sub.TenantID
+		items = append(items, PendingItem{
+			ID:             s.genID.Generate().String(),
+			TenantID:       sub.TenantID,
+			CustomerID:     sub.CustomerID,
+			SubscriptionID: sub.ID,
+			SourceKey:      fmt.Sprintf("%s:proration:%s", req.SourceKey, kind),
+			Line:           line,
+			CreatedAt:      now,
+		})
+	}
+	if err := s.pendingRepo.Create(ctx, items); err != nil {
+		s.logger.Error("failed to store proration items", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return ProrationResult{}, err
+	}
+
+	if req.Behavior == ProrationAlwaysInvoice && len(items) > 0 {
+		invoiceID, err := s.invoicePendingItems(ctx, sub, now)
+		if err != nil {
+			return ProrationResult{}, err
+		}
+		result.InvoiceID = invoiceID
+	}
+
+	s.logger.Info("subscription prorated",
+		zap.String("subscription_id", sub.ID),
+		zap.String("from_price_id", sub.PriceID),
+		zap.String("to_price_id", req.NewPriceID),
+		zap.Int64("credit_cents", result.CreditCents),
+		zap.Int64("debit_cents", result.DebitCents),
+	)
+	return result, nil
+}
+
+// ProrateQuantity bills the units added by a quantity increase for the rest of
+// the current period on an immediate invoice. Calls with the same SourceKey
+// are idempotent.
+func (s *Service) ProrateQuantity(ctx context.Context, req QuantityProrationRequest) (ProrationResult, error) {
+	sub := req.Subscription
+	if req.Quantity <= req.Previous {
+		return ProrationResult{}, nil
+	}
+	if req.SourceKey == "" {
+		return ProrationResult{}, status.Error(codes.InvalidArgument, "source key required")
+	}
+	price, err := s.priceByID(ctx, sub, req.PriceID)
+	if err != nil {
+		return ProrationResult{}, err
+	}
+	at := req.At
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	start, end := sub.CurrentPeriodStart, sub.CurrentPeriodEnd
+	lines, err := quantityProrationLines(price, req.Previous, req.Quantity, start, end, at)
+	if err != nil {
+		return ProrationResult{}, s.chargeError(err, sub.ID)
+	}
+	if len(lines) == 0 {
+		return ProrationResult{}, nil
+	}
+	// The added units are billed now for the rest of the period, so the fee
+	// billed at its end keeps the quantity the period started with.
+	changes, err := s.feeChanges.ListInPeriod(ctx, sub.ID, start, end)
+	if err != nil {
+		s.logger.Error("failed to load fee changes", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return ProrationResult{}, err
+	}
+	if err := s.recordFeeChange(ctx, sub, FeeChange{
+		ItemID:    req.ItemID,
+		PriceID:   req.PriceID,
+		Quantity:  arrearsQuantity(changes, req.ItemID, req.Previous),
+		At:        at,
+		SourceKey: fmt.Sprintf("%s:quantity", req.SourceKey),
+	}); err != nil {
+		return ProrationResult{}, err
+	}
+
+	now := time.Now().UTC()
+	var result ProrationResult
+	items := make([]PendingItem, 0, len(lines))
+	for _, line := range lines {
+		result.DebitCents += line.AmountCents
+		line.TenantID = sub.TenantID
+		if req.ItemID != "" {
+			line.Metadata["subscription_item_id"] = req.ItemID
+		}
+		items = append(items, PendingItem{
+			ID:             s.genID.Generate().String(),
+			TenantID:       sub.TenantID,
+			CustomerID:     sub.CustomerID,
+			SubscriptionID: sub.ID,
+			SourceKey:      fmt.Sprintf("%s:quantity", req.SourceKey),
+			Line:           line,
+			CreatedAt:      now,
+		})
+	}
+	if err := s.pendingRepo.Create(ctx, items); err != nil {
+		s.logger.Error("failed to store quantity proration", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return ProrationResult{}, err
+	}
+	result.InvoiceID, err = s.invoicePendingItems(ctx, sub, now)
+	if err != nil {
+		return ProrationResult{}, err
+	}
+
+	s.logger.Info("subscription quantity prorated",
+		zap.String("subscription_id", sub.ID),
+		zap.String("item_id", req.ItemID),
+		zap.Int64("previous_quantity", req.Previous),
+		zap.Int64("quantity", req.Quantity),
+		zap.Int64("debit_cents", result.DebitCents),
+	)
+	return result, nil
+}
+
+// InvoiceCancellation bills what is outstanding on a subscription canceled
+// before its period ends: usage recorded so far, pending items and the
+// recurring fee, prorated to the time used when requested. It returns an
+// empty id when nothing is owed.
+func (s *Service) InvoiceCancellation(ctx context.Context, req CancellationRequest) (string, error) {
+	sub := req.Subscription
+	start, end := sub.CurrentPeriodStart, sub.CurrentPeriodEnd
+	at := req.At
+	if at.After(end) {
+		at = end
+	}
+	if !at.After(start) {
+		return "", nil
+	}
+
+	items, err := s.loadItems(ctx, sub, start, at)
+	if err != nil {
+		return "", err
+	}
+	price := items[0].Price
+	records, err := s.listUsage(ctx, sub.TenantID, sub.ID, start, at)
+	if err != nil {
+		s.logger.Error("failed to fetch usage", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return "", err
+	}
+	taxRule, err := s.resolveTaxRule(ctx, sub.TenantID, sub.CustomerID)
+	if err != nil {
+		return "", err
+	}
+	pending, err := s.pendingRepo.ListOpen(ctx, sub.TenantID, sub.ID)
+	if err != nil {
+		return "", err
+	}
+	discounts, err := s.couponRepo.ListActiveDiscounts(ctx, sub.TenantID, sub.CustomerID, sub.ID)
+	if err != nil {
+		return "", err
+	}
+	_, prepaid, err := s.runRepo.FindBySubscriptionPeriod(ctx, sub.ID, start, end)
+	if err != nil {
+		return "", err
+	}
+	if req.Prorate && !prepaid {
+		items, err = s.splitFees(ctx, sub, items, start, end)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	amounts, err := cancellationCharges(items, records, pendingLines(pending), discounts, taxRule, start, end, at, prepaid, req.Prorate)
+	if err != nil {
+		return "", s.chargeError(err, sub.ID)
+	}
+	if len(amounts.Lines) == 0 {
+		return "", nil
+	}
+
+	now := time.Now().UTC()
+	invoiceID := s.genID.Generate().String()
+	for i := range amounts.Lines {
+		amounts.Lines[i].ID = s.genID.Generate().String()
+		amounts.Lines[i].InvoiceID = invoiceID
+		amounts.Lines[i].TenantID = sub.TenantID
+		amounts.Lines[i].CreatedAt = now
+	}
+	inv := invoice.Invoice{
+		ID:             invoiceID,
+		TenantID:       sub.TenantID,
+		CustomerID:     sub.CustomerID,
+		SubscriptionID: sub.ID,
+		Status:         int32(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN),
+		CurrencyCode:   strings.ToUpper(price.Currency),
+		TotalCents:     amounts.TotalCents,
+		SubtotalCents:  amounts.SubtotalCents,
+		TaxCents:       amounts.TaxCents,
+		IssuedAt:       &now,
+		Metadata: map[string]interface{}{
+			"billing_reason":  "subscription_cancel",
+			"price_id":        strconv.FormatInt(price.ID, 10),
+			"base_amount":     amounts.BaseCents,
+			"usage_charges":   amounts.UsageCents,
+			"pending_amount":  amounts.PendingCents,
+			"discount_amount": amounts.DiscountCents,
+			"prorated":        req.Prorate,
+		},
+		LineItems: amounts.Lines,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
 	run := Run{
-		ID:             strconv.FormatInt(time.Now().UnixNano(), 10),
-		TenantID:       req.GetTenantId(),
-		CustomerID:     req.GetCustomerId(),
-		SubscriptionID: req.GetSubscriptionId(),
+		ID:             s.genID.Generate().String(),
+		TenantID:       sub.TenantID,
+		CustomerID:     sub.CustomerID,
+		SubscriptionID: sub.ID,
 		InvoiceID:      invoiceID,
 		PeriodStart:    start,
-		PeriodEnd:      end,
+		PeriodEnd:      at,
 		CreatedAt:      now,
 	}
+	billed := Billed{PendingItemIDs: pendingIDs(pending)}
+	if !prepaid {
+		billed.DiscountIDs = amounts.DiscountIDs
+	}
+	if err := s.createInvoice(ctx, inv, &run, billed); err != nil {
+		if errors.Is(err, ErrPeriodInvoiced) {
+			resp, err := s.invoicedPeriod(ctx, sub.ID, start, at)
+			if err != nil {
+				return "", err
+			}
+			return resp.GetInvoiceId(), nil
+		}
+		s.logger.Error("failed to create cancellation invoice", zap.Error(err))
+		return "", err
+	}
 
-	if err := s.runRepo.Create(ctx, run); err != nil {
-		s.logger.Error("failed to record invoice engine run", zap.Error(err))
+	s.logger.Info("cancellation invoiced",
+		zap.String("invoice_id", invoiceID),
+		zap.String("subscription_id", sub.ID),
+		zap.Int64("total_cents", amounts.TotalCents),
+	)
+	return invoiceID, nil
+}
+
+// invoicePendingItems bills the subscription's open pending items on a new
+// invoice. It returns an empty id when nothing is pending.
+func (s *Service) invoicePendingItems(ctx context.Context, sub subscription.Subscription, now time.Time) (string, error) {
+	pending, err := s.pendingRepo.ListOpen(ctx, sub.TenantID, sub.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(pending) == 0 {
+		return "", nil
+	}
+	taxRule, err := s.resolveTaxRule(ctx, sub.TenantID, sub.CustomerID)
+	if err != nil {
+		return "", err
 	}
 
-	// s.logger.Info("invoice generated",
-	// 	zap.String("invoice_id", invoiceID),
-	// 	zap.String("subscription_id", req.GetSubscriptionId()),
-	// 	zap.Int64("total_cents", totalCents),
-	// )
+	currency := strings.ToUpper(sub.Currency)
+	if currency == "" {
+		currency = pending[0].Line.Currency
+	}
+	var c charges
+	c.finalize(pendingLines(pending), nil, taxRule, currency, now, now)
 
-	return &invoiceenginev1.GenerateInvoiceResponse{InvoiceId: invoiceID}, nil
+	invoiceID := s.genID.Generate().String()
+	for i := range c.Lines {
+		c.Lines[i].ID = s.genID.Generate().String()
+		c.Lines[i].InvoiceID = invoiceID
+		c.Lines[i].TenantID = sub.TenantID
+		c.Lines[i].CreatedAt = now
+	}
+	inv := invoice.Invoice{
+		ID:             invoiceID,
+		TenantID:       sub.TenantID,
+		CustomerID:     sub.CustomerID,
+		SubscriptionID: sub.ID,
+		Status:         int32(invoicev1.InvoiceStatus_INVOICE_STATUS_OPEN),
+		CurrencyCode:   currency,
+		TotalCents:     c.TotalCents,
+		SubtotalCents:  c.SubtotalCents,
+		TaxCents:       c.TaxCents,
+		IssuedAt:       &now,
+		Metadata: map[string]interface{}{
+			"billing_reason":   "subscription_update",
+			"proration_amount": c.PendingCents,
+		},
+		LineItems: c.Lines,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.createInvoice(ctx, inv, nil, Billed{PendingItemIDs: pendingIDs(pending)}); err != nil {
+		s.logger.Error("failed to create proration invoice", zap.Error(err))
+		return "", err
+	}
+	return invoiceID, nil
 }
 
-// calculateTieredCharges applies tiered pricing to usage
-// func (s *Service) calculateTieredCharges(
-// 	records []usage.UsageRecord,
-// 	tiers []pricing.PriceTier,
-// ) int64 {
-// 	// Sum all usage
-// 	var totalUsage float64
-// 	for _, r := range records {
-// 		totalUsage += r.Value
-// 	}
-
-// 	// Apply tiers
-// 	var charges int64
-// 	remaining := totalUsage
-
-// 	for _, tier := range tiers {
-// 		if remaining <= 0 {
-// 			break
-// 		}
-
-// 		// Calculate units in this tier
-// 		tierSize := tier.EndQuantity - tier.StartQuantity
-// 		if tierSize <= 0 {
-// 			tierSize = remaining // Unlimited tier
-// 		}
-
-// 		unitsInTier := min(remaining, tierSize)
-// 		charges += int64(unitsInTier * float64(tier.UnitAmountCents))
-// 		remaining -= unitsInTier
-// 	}
-
-// 	return charges
-// }
-
-// generateInvoiceNumber creates unique invoice number
-func (s *Service) generateInvoiceNumber(tenantID string, now time.Time) string {
-	return fmt.Sprintf("INV-%s-%s-%d",
-		tenantID[len(tenantID)-6:], // Last 6 digits of tenant
-		now.Format("200601"),       // YYYYMM
-		now.Unix()%10000,           // Sequential
-	)
+// recordFeeChange stores how the subscription was billed before a change in a
+// period invoiced in arrears.
+func (s *Service) recordFeeChange(ctx context.Context, sub subscription.Subscription, change FeeChange) error {
+	change.ID = s.genID.Generate().String()
+	change.TenantID = sub.TenantID
+	change.SubscriptionID = sub.ID
+	change.CreatedAt = time.Now().UTC()
+	if err := s.feeChanges.Create(ctx, change); err != nil {
+		s.logger.Error("failed to record fee change", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return err
+	}
+	return nil
+}
+
+// splitFees bills the fees of a period invoiced in arrears at the prices and
+// quantities recorded by the changes made in it, see feeSegments. extra adds
+// changes not recorded yet, e.g. a simulated one.
+func (s *Service) splitFees(ctx context.Context, sub subscription.Subscription, items []pricedItem, start, end time.Time, extra ...FeeChange) ([]pricedItem, error) {
+	changes, err := s.feeChanges.ListInPeriod(ctx, sub.ID, start, end)
+	if err != nil {
+		s.logger.Error("failed to load fee changes", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return nil, err
+	}
+	changes = append(changes, extra...)
+	prices := map[string]pricing.Price{}
+	for _, change := range changes {
+		if _, ok := prices[change.PriceID]; ok {
+			continue
+		}
+		price, err := s.priceByID(ctx, sub, change.PriceID)
+		if err != nil {
+			return nil, err
+		}
+		prices[change.PriceID] = price
+	}
+	return feeSegments(items, changes, prices), nil
+}
+
+// loadItems resolves the subscription's primary price and the items billed
+// on it between start and end, with their tiers. The primary price comes first.
+func (s *Service) loadItems(ctx context.Context, sub subscription.Subscription, start, end time.Time) ([]pricedItem, error) {
+	items, err := s.subscriptionRepo.ListItems(ctx, subscription.ItemFilter{SubscriptionID: sub.ID, RemovedAfter: start})
+	if err != nil {
+		s.logger.Error("failed to load subscription items", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return nil, err
+	}
+	var priced []pricedItem
+	var priceIDs []int64
+	for _, item := range sub.BillableItems(items) {
+		if item.ID != "" && !item.CreatedAt.Before(end) {
+			continue
+		}
+		price, err := s.priceByID(ctx, sub, item.PriceID)
+		if err != nil {
+			return nil, err
+		}
+		p := pricedItem{ItemID: item.ID, Price: price, Quantity: item.Quantity}
+		if item.ID != "" {
+			p.From = item.CreatedAt
+			if item.RemovedAt != nil {
+				p.Until = *item.RemovedAt
+			}
+		}
+		priced = append(priced, p)
+		priceIDs = append(priceIDs, price.ID)
+	}
+	tiers, err := s.pricingRepo.ListPriceTiersByPriceIDs(ctx, priceIDs)
+	if err != nil {
+		s.logger.Error("failed to load price tiers", zap.Error(err), zap.String("subscription_id", sub.ID))
+		return nil, err
+	}
+	for _, tier := range tiers {
+		for i := range priced {
+			if priced[i].Price.ID == tier.PriceID {
+				priced[i].Tiers = append(priced[i].Tiers, tier)
+			}
+		}
+	}
+	return priced, nil
+}
+
+func (s *Service) loadPrice(ctx context.Context, sub subscription.Subscription) (pricing.Price, error) {
+	return s.priceByID(ctx, sub, sub.PriceID)
+}
+
+// priceByID loads a price billed on sub in the subscription's currency.
+func (s *Service) priceByID(ctx context.Context, sub subscription.Subscription, id string) (pricing.Price, error) {
+	tenantID, err := strconv.ParseInt(sub.TenantID, 10, 64)
+	if err != nil {
+		return pricing.Price{}, status.Error(codes.InvalidArgument, "invalid tenant_id")
+	}
+	priceID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return pricing.Price{}, status.Error(codes.FailedPrecondition, "subscription has invalid price_id")
+	}
+	price, err := s.pricingRepo.GetPrice(ctx, tenantID, priceID)
+	if err != nil {
+		return pricing.Price{}, status.Errorf(codes.NotFound, "price not found: %v", err)
+	}
+	resolved, ok := price.ForCurrency(sub.Currency)
+	if !ok {
+		return pricing.Price{}, status.Errorf(codes.FailedPrecondition, "price %d has no %s amount", price.ID, sub.Currency)
+	}
+	return resolved, nil
+}
+
+func (s *Service) listUsage(ctx context.Context, tenantID, subscriptionID string, start, end time.Time) ([]usage.UsageRecord, error) {
+	var records []usage.UsageRecord
+	offset := 0
+	for {
+		page, hasMore, err := s.usageRepo.List(ctx, usage.ListUsageFilter{
+			TenantID:       tenantID,
+			SubscriptionID: subscriptionID,
+			From:           start,
+			To:             end,
+			Limit:          usagePageSize,
+			Offset:         offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range page {
+			// The period end belongs to the next period.
+			if record.RecordedAt.Before(end) {
+				records = append(records, record)
+			}
+		}
+		if !hasMore {
+			return records, nil
+		}
+		offset += len(page)
+	}
+}
+
+func (s *Service) resolveTaxRule(ctx context.Context, tenantID, customerID string) (*TaxRule, error) {
+	region := ""
+	if customerID != "" {
+		cust, err := s.customerRepo.GetByID(ctx, customerID)
+		if err != nil {
+			s.logger.Warn("customer lookup failed, using default tax rule", zap.Error(err), zap.String("customer_id", customerID))
+		} else {
+			region = billingCountry(cust.BillingAddress)
+		}
+	}
+	return s.taxRepo.FindApplicable(ctx, tenantID, region)
+}
+
+// chargeError reports an amount too large to bill, see money.ErrOverflow.
+func (s *Service) chargeError(err error, subscriptionID string) error {
+	s.logger.Error("failed to compute charges", zap.Error(err), zap.String("subscription_id", subscriptionID))
+	if errors.Is(err, money.ErrOverflow) {
+		return status.Error(codes.OutOfRange, err.Error())
+	}
+	return err
+}
+
+func billingCountry(address map[string]interface{}) string {
+	for _, key := range []string{"country_code", "country"} {
+		if v, ok := address[key].(string); ok && v != "" {
+			return strings.ToUpper(v)
+		}
+	}
+	return ""
 }
 
 func normalizeTimestamp(ts *timestamppb.Timestamp, fallback time.Time) time.Time {
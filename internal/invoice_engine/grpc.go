@@ -1,12 +1,95 @@
 package invoice_engine
 
 import (
+	"context"
+	"encoding/json"
+
 	"github.com/smallbiznis/corebilling/internal/invoice_engine/domain"
 	invoiceenginev1 "github.com/smallbiznis/go-genproto/smallbiznis/invoice_engine/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // RegisterGRPC attaches the invoice engine service to the shared server.
 func RegisterGRPC(server *grpc.Server, svc *domain.Service) {
 	invoiceenginev1.RegisterInvoiceEngineServiceServer(server, svc)
+	server.RegisterService(&previewServiceDesc, &previewService{svc: svc})
+}
+
+// PreviewServer serves invoice previews over gRPC. The generated invoice engine
+// service has no preview RPC, so it is registered by hand as
+// smallbiznis.invoice_engine.v1.InvoicePreviewService. Requests and responses
+// are google.protobuf.Struct values with the fields of POST /v1/invoices/preview,
+// the request adding tenant_id.
+type PreviewServer interface {
+	PreviewInvoice(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+var previewServiceDesc = grpc.ServiceDesc{
+	ServiceName: "smallbiznis.invoice_engine.v1.InvoicePreviewService",
+	HandlerType: (*PreviewServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "PreviewInvoice", Handler: previewInvoiceHandler},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+type previewService struct {
+	svc *domain.Service
+}
+
+type grpcPreviewRequest struct {
+	TenantID string `json:"tenant_id"`
+	previewRequest
+}
+
+func (p *previewService) PreviewInvoice(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	raw, err := json.Marshal(in.AsMap())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid request")
+	}
+	var body grpcPreviewRequest
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid request")
+	}
+	if body.TenantID == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant_id required")
+	}
+	req, err := body.toDomain(body.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	preview, err := p.svc.PreviewInvoice(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	raw, err = json.Marshal(toPreviewResponse(preview))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to encode preview")
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, status.Error(codes.Internal, "failed to encode preview")
+	}
+	return structpb.NewStruct(out)
+}
+
+func previewInvoiceHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PreviewServer).PreviewInvoice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/smallbiznis.invoice_engine.v1.InvoicePreviewService/PreviewInvoice",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PreviewServer).PreviewInvoice(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
 }
@@ -0,0 +1,163 @@
+package invoice_engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/smallbiznis/corebilling/internal/headers"
+	invoicedomain "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	"github.com/smallbiznis/corebilling/internal/invoice_engine/domain"
+	"go.uber.org/fx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ModuleHTTP = fx.Invoke(RegisterHTTP)
+
+// RegisterHTTP exposes invoice previews.
+func RegisterHTTP(lc fx.Lifecycle, mux *runtime.ServeMux, svc *domain.Service) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			return mux.HandlePath(http.MethodPost, "/v1/invoices/preview", previewHandler(svc))
+		},
+	})
+}
+
+type previewRequest struct {
+	SubscriptionID       string     `json:"subscription_id"`
+	PriceID              string     `json:"price_id"`
+	ProrationBehavior    string     `json:"proration_behavior"`
+	ProrationGranularity string     `json:"proration_granularity"`
+	ProrationDate        *time.Time `json:"proration_date"`
+}
+
+type lineResponse struct {
+	Type            string     `json:"type"`
+	Description     string     `json:"description"`
+	PriceID         string     `json:"price_id,omitempty"`
+	MeterCode       string     `json:"meter_code,omitempty"`
+	Quantity        float64    `json:"quantity"`
+	UnitAmountCents int64      `json:"unit_amount_cents"`
+	AmountCents     int64      `json:"amount_cents"`
+	PeriodStart     *time.Time `json:"period_start,omitempty"`
+	PeriodEnd       *time.Time `json:"period_end,omitempty"`
+}
+
+type previewResponse struct {
+	SubscriptionID string         `json:"subscription_id"`
+	CustomerID     string         `json:"customer_id"`
+	Currency       string         `json:"currency"`
+	PeriodStart    time.Time      `json:"period_start"`
+	PeriodEnd      time.Time      `json:"period_end"`
+	DueAt          *time.Time     `json:"due_at"`
+	SubtotalCents  int64          `json:"subtotal_cents"`
+	DiscountCents  int64          `json:"discount_cents"`
+	TaxCents       int64          `json:"tax_cents"`
+	TotalCents     int64          `json:"total_cents"`
+	Lines          []lineResponse `json:"lines"`
+	// Prorations and ImmediateCents describe the simulated price change.
+	Prorations     []lineResponse `json:"prorations,omitempty"`
+	ImmediateCents int64          `json:"immediate_cents,omitempty"`
+}
+
+// previewHandler returns the invoice the subscription will be sent at the end
+// of its current period, optionally after switching to price_id.
+func previewHandler(svc *domain.Service) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		tenantID := r.Header.Get(headers.HeaderTenantID)
+		if tenantID == "" {
+			writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+			return
+		}
+		var body previewRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+		req, err := body.toDomain(tenantID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		preview, err := svc.PreviewInvoice(r.Context(), req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toPreviewResponse(preview))
+	}
+}
+
+// toDomain validates the request for the tenant.
+func (body previewRequest) toDomain(tenantID string) (domain.PreviewRequest, error) {
+	behavior, err := domain.ParseProrationBehavior(body.ProrationBehavior)
+	if err != nil {
+		return domain.PreviewRequest{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	granularity, err := domain.ParseProrationGranularity(body.ProrationGranularity)
+	if err != nil {
+		return domain.PreviewRequest{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	req := domain.PreviewRequest{
+		TenantID:       tenantID,
+		SubscriptionID: body.SubscriptionID,
+		NewPriceID:     body.PriceID,
+		Behavior:       behavior,
+		Granularity:    granularity,
+	}
+	if body.ProrationDate != nil {
+		req.At = body.ProrationDate.UTC()
+	}
+	return req, nil
+}
+
+func toPreviewResponse(p domain.InvoicePreview) previewResponse {
+	inv := p.Invoice
+	return previewResponse{
+		SubscriptionID: inv.SubscriptionID,
+		CustomerID:     inv.CustomerID,
+		Currency:       inv.CurrencyCode,
+		PeriodStart:    p.PeriodStart,
+		PeriodEnd:      p.PeriodEnd,
+		DueAt:          inv.DueAt,
+		SubtotalCents:  inv.SubtotalCents,
+		DiscountCents:  p.DiscountCents,
+		TaxCents:       inv.TaxCents,
+		TotalCents:     inv.TotalCents,
+		Lines:          toLineResponses(inv.LineItems),
+		Prorations:     toLineResponses(p.Prorations),
+		ImmediateCents: p.ImmediateCents,
+	}
+}
+
+func toLineResponses(lines []invoicedomain.LineItem) []lineResponse {
+	resp := make([]lineResponse, 0, len(lines))
+	for _, line := range lines {
+		resp = append(resp, lineResponse{
+			Type:            string(line.Type),
+			Description:     line.Description,
+			PriceID:         line.PriceID,
+			MeterCode:       line.MeterCode,
+			Quantity:        line.Quantity,
+			UnitAmountCents: line.UnitAmountCents,
+			AmountCents:     line.AmountCents,
+			PeriodStart:     line.PeriodStart,
+			PeriodEnd:       line.PeriodEnd,
+		})
+	}
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	writeJSON(w, runtime.HTTPStatusFromCode(st.Code()), map[string]string{"error": st.Message()})
+}
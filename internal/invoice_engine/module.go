@@ -9,8 +9,12 @@ import (
 
 var Module = fx.Options(
 	fx.Provide(reposqlc.NewRepository),
+	fx.Provide(reposqlc.NewTaxRepository),
+	fx.Provide(reposqlc.NewPendingItemRepository),
+	fx.Provide(reposqlc.NewFeeChangeRepository),
 	fx.Provide(domain.NewService),
 	ModuleGRPC,
+	ModuleHTTP,
 )
 
 var ModuleGRPC = fx.Invoke(RegisterGRPC)
@@ -0,0 +1,76 @@
+package sqlc
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/smallbiznis/corebilling/internal/invoice_engine/domain"
+)
+
+// FeeChangeRepository persists mid-period fee changes.
+type FeeChangeRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewFeeChangeRepository constructs a fee change repository.
+func NewFeeChangeRepository(pool *pgxpool.Pool) domain.FeeChangeRepository {
+	return &FeeChangeRepository{pool: pool}
+}
+
+func (r *FeeChangeRepository) Create(ctx context.Context, change domain.FeeChange) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO subscription_fee_changes (
+			id, tenant_id, subscription_id, item_id, price_id, quantity, changed_at, source_key, created_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		ON CONFLICT (tenant_id, source_key) DO NOTHING
+	`,
+		change.ID,
+		change.TenantID,
+		change.SubscriptionID,
+		nullIfEmpty(change.ItemID),
+		change.PriceID,
+		change.Quantity,
+		change.At,
+		change.SourceKey,
+		change.CreatedAt,
+	)
+	return err
+}
+
+func (r *FeeChangeRepository) ListInPeriod(ctx context.Context, subscriptionID string, start, end time.Time) ([]domain.FeeChange, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, tenant_id, subscription_id, COALESCE(item_id::TEXT, ''), price_id::TEXT,
+		       quantity, changed_at, source_key, created_at
+		FROM subscription_fee_changes
+		WHERE subscription_id=$1 AND changed_at >= $2 AND changed_at < $3
+		ORDER BY changed_at, id
+	`, subscriptionID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []domain.FeeChange
+	for rows.Next() {
+		var change domain.FeeChange
+		if err := rows.Scan(
+			&change.ID,
+			&change.TenantID,
+			&change.SubscriptionID,
+			&change.ItemID,
+			&change.PriceID,
+			&change.Quantity,
+			&change.At,
+			&change.SourceKey,
+			&change.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+var _ domain.FeeChangeRepository = (*FeeChangeRepository)(nil)
@@ -0,0 +1,129 @@
+package sqlc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	"github.com/smallbiznis/corebilling/internal/invoice_engine/domain"
+)
+
+// PendingItemRepository persists pending invoice items.
+type PendingItemRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPendingItemRepository constructs a pending invoice item repository.
+func NewPendingItemRepository(pool *pgxpool.Pool) domain.PendingItemRepository {
+	return &PendingItemRepository{pool: pool}
+}
+
+func (r *PendingItemRepository) Create(ctx context.Context, items []domain.PendingItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		var metadata []byte
+		if len(item.Line.Metadata) > 0 {
+			data, err := json.Marshal(item.Line.Metadata)
+			if err != nil {
+				return err
+			}
+			metadata = data
+		}
+		batch.Queue(`
+			INSERT INTO pending_invoice_items (
+				id, tenant_id, customer_id, subscription_id, source_key, line_type,
+				description, price_id, quantity, unit_amount_cents, amount_cents,
+				currency, period_start, period_end, metadata, created_at
+			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+			ON CONFLICT (tenant_id, source_key) DO NOTHING
+		`,
+			item.ID,
+			item.TenantID,
+			nullIfEmpty(item.CustomerID),
+			item.SubscriptionID,
+			item.SourceKey,
+			string(item.Line.Type),
+			item.Line.Description,
+			nullIfEmpty(item.Line.PriceID),
+			item.Line.Quantity,
+			item.Line.UnitAmountCents,
+			item.Line.AmountCents,
+			item.Line.Currency,
+			item.Line.PeriodStart,
+			item.Line.PeriodEnd,
+			metadata,
+			item.CreatedAt,
+		)
+	}
+	return r.pool.SendBatch(ctx, batch).Close()
+}
+
+func (r *PendingItemRepository) ListOpen(ctx context.Context, tenantID, subscriptionID string) ([]domain.PendingItem, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, tenant_id, COALESCE(customer_id::TEXT, ''), subscription_id, source_key,
+		       line_type, description, COALESCE(price_id::TEXT, ''), quantity,
+		       unit_amount_cents, amount_cents, currency, period_start, period_end,
+		       metadata, created_at
+		FROM pending_invoice_items
+		WHERE tenant_id=$1 AND subscription_id=$2 AND invoice_id IS NULL
+		ORDER BY created_at, id
+	`, tenantID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []domain.PendingItem
+	for rows.Next() {
+		var item domain.PendingItem
+		var lineType string
+		var metadata []byte
+		if err := rows.Scan(
+			&item.ID,
+			&item.TenantID,
+			&item.CustomerID,
+			&item.SubscriptionID,
+			&item.SourceKey,
+			&lineType,
+			&item.Line.Description,
+			&item.Line.PriceID,
+			&item.Line.Quantity,
+			&item.Line.UnitAmountCents,
+			&item.Line.AmountCents,
+			&item.Line.Currency,
+			&item.Line.PeriodStart,
+			&item.Line.PeriodEnd,
+			&metadata,
+			&item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		item.Line.Type = invoice.LineItemType(lineType)
+		if len(metadata) > 0 {
+			_ = json.Unmarshal(metadata, &item.Line.Metadata)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// attachPendingItems marks the items as billed on invoiceID.
+func attachPendingItems(ctx context.Context, tx pgx.Tx, ids []string, invoiceID string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE pending_invoice_items SET invoice_id=$2
+		WHERE id = ANY($1::BIGINT[]) AND invoice_id IS NULL
+	`, "{"+strings.Join(ids, ",")+"}", invoiceID)
+	return err
+}
+
+var _ domain.PendingItemRepository = (*PendingItemRepository)(nil)
@@ -2,9 +2,15 @@ package sqlc
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	couponsqlc "github.com/smallbiznis/corebilling/internal/coupon/repository/sqlc"
+	invoice "github.com/smallbiznis/corebilling/internal/invoice/domain"
+	invoicesqlc "github.com/smallbiznis/corebilling/internal/invoice/repository/sqlc"
 	"github.com/smallbiznis/corebilling/internal/invoice_engine/domain"
 )
 
@@ -18,8 +24,50 @@ func NewRepository(pool *pgxpool.Pool) domain.Repository {
 	return &Repository{pool: pool}
 }
 
-func (r *Repository) Create(ctx context.Context, run domain.Run) error {
-	_, err := r.pool.Exec(ctx, `INSERT INTO invoice_engine_runs (id, tenant_id, customer_id, subscription_id, invoice_id, period_start, period_end, created_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`,
+// CreateWithInvoice stores the invoice, its run and what it billed in one
+// transaction. The upsert on the period lets the final invoice of a prepaid
+// period replace the run of the advance one, and nothing else.
+func (r *Repository) CreateWithInvoice(ctx context.Context, run *domain.Run, inv invoice.Invoice, scheme invoice.NumberingScheme, billed domain.Billed, postings ...invoice.LedgerPosting) (invoice.Invoice, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return invoice.Invoice{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if run != nil {
+		if err := insertRun(ctx, tx, *run); err != nil {
+			return invoice.Invoice{}, err
+		}
+	}
+	if inv, err = invoicesqlc.CreateNumberedTx(ctx, tx, inv, scheme, postings...); err != nil {
+		return invoice.Invoice{}, err
+	}
+	if err := attachPendingItems(ctx, tx, billed.PendingItemIDs, inv.ID); err != nil {
+		return invoice.Invoice{}, err
+	}
+	if err := couponsqlc.MarkAppliedTx(ctx, tx, billed.DiscountIDs, inv.CreatedAt); err != nil {
+		return invoice.Invoice{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return invoice.Invoice{}, err
+	}
+	return inv, nil
+}
+
+// insertRun stores the run, failing with domain.ErrPeriodInvoiced when it may
+// not replace the run of its period.
+func insertRun(ctx context.Context, tx pgx.Tx, run domain.Run) error {
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO invoice_engine_runs (id, tenant_id, customer_id, subscription_id, invoice_id, period_start, period_end, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		ON CONFLICT (subscription_id, period_start, period_end) DO UPDATE SET
+			id = EXCLUDED.id,
+			customer_id = EXCLUDED.customer_id,
+			invoice_id = EXCLUDED.invoice_id,
+			created_at = EXCLUDED.created_at
+		WHERE invoice_engine_runs.created_at < invoice_engine_runs.period_end
+		  AND EXCLUDED.created_at >= EXCLUDED.period_end
+	`,
 		run.ID,
 		run.TenantID,
 		nullIfEmpty(run.CustomerID),
@@ -29,7 +77,38 @@ func (r *Repository) Create(ctx context.Context, run domain.Run) error {
 		run.PeriodEnd,
 		run.CreatedAt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrPeriodInvoiced
+	}
+	return nil
+}
+
+func (r *Repository) FindBySubscriptionPeriod(ctx context.Context, subscriptionID string, start, end time.Time) (domain.Run, bool, error) {
+	var run domain.Run
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, tenant_id, COALESCE(customer_id::TEXT, ''), COALESCE(subscription_id::TEXT, ''), invoice_id, period_start, period_end, created_at
+		FROM invoice_engine_runs
+		WHERE subscription_id=$1 AND period_start=$2 AND period_end=$3
+	`, subscriptionID, start, end).Scan(
+		&run.ID,
+		&run.TenantID,
+		&run.CustomerID,
+		&run.SubscriptionID,
+		&run.InvoiceID,
+		&run.PeriodStart,
+		&run.PeriodEnd,
+		&run.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Run{}, false, nil
+	}
+	if err != nil {
+		return domain.Run{}, false, err
+	}
+	return run, true, nil
 }
 
 func nullIfEmpty(value string) any {
@@ -0,0 +1,43 @@
+package sqlc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/smallbiznis/corebilling/internal/invoice_engine/domain"
+)
+
+// TaxRepository reads tax rules for invoice computation.
+type TaxRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewTaxRepository constructs a tax rule repository.
+func NewTaxRepository(pool *pgxpool.Pool) domain.TaxRepository {
+	return &TaxRepository{pool: pool}
+}
+
+// FindApplicable prefers the tenant's region specific rule over its default rule.
+func (r *TaxRepository) FindApplicable(ctx context.Context, tenantID, regionCode string) (*domain.TaxRule, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT id, tenant_id, region_code, name, rate_percent, is_default
+		FROM tax_rules
+		WHERE tenant_id=$1 AND is_active AND (region_code=$2 OR is_default)
+		ORDER BY (region_code=$2) DESC, is_default DESC
+		LIMIT 1
+	`, tenantID, regionCode)
+
+	var rule domain.TaxRule
+	if err := row.Scan(&rule.ID, &rule.TenantID, &rule.RegionCode, &rule.Name, &rule.RatePercent, &rule.IsDefault); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+var _ domain.TaxRepository = (*TaxRepository)(nil)
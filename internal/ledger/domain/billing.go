@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"go.uber.org/zap"
+)
+
+// Billing accounts receive the postings of invoices and credit notes. Each
+// tenant gets one of each per currency, created on first use.
+const (
+	AccountReceivable     = "accounts_receivable"
+	AccountRevenue        = "revenue"
+	AccountBadDebt        = "bad_debt"
+	AccountCustomerCredit = "customer_credit"
+	AccountCash           = "cash"
+)
+
+var billingAccountTypes = map[string]int32{
+	AccountReceivable:     AccountTypeAsset,
+	AccountRevenue:        AccountTypeRevenue,
+	AccountBadDebt:        AccountTypeExpense,
+	AccountCustomerCredit: AccountTypeLiability,
+	AccountCash:           AccountTypeAsset,
+}
+
+// PostBilling posts amountCents from the tenant's credit billing account to
+// its debit billing account in currency. Posting a journal id twice posts it
+// once.
+func (s *Service) PostBilling(ctx context.Context, journal JournalEntry, currency, debit, credit string, amountCents int64) error {
+	debitAccount, err := s.billingAccount(ctx, journal.TenantID, debit, currency)
+	if err != nil {
+		return err
+	}
+	creditAccount, err := s.billingAccount(ctx, journal.TenantID, credit, currency)
+	if err != nil {
+		return err
+	}
+	return s.CreateJournalEntry(ctx, journal, []LedgerEntry{
+		{AccountID: debitAccount.ID, Type: EntryTypeDebit, AmountCents: amountCents},
+		{AccountID: creditAccount.ID, Type: EntryTypeCredit, AmountCents: amountCents},
+	})
+}
+
+// billingAccount returns the tenant's billing account called name in
+// currency, creating it when missing. Concurrent callers get the same account.
+func (s *Service) billingAccount(ctx context.Context, tenantID, name, currency string) (Account, error) {
+	accountType, ok := billingAccountTypes[name]
+	if !ok {
+		return Account{}, fmt.Errorf("unknown billing account %q", name)
+	}
+	currency = strings.ToUpper(currency)
+	account, found, err := s.repo.FindAccount(ctx, tenantID, name, currency)
+	if err != nil || found {
+		return account, err
+	}
+	now := time.Now().UTC()
+	account, err = s.repo.EnsureAccount(ctx, Account{
+		ID:        ulid.Make().String(),
+		TenantID:  tenantID,
+		Name:      name,
+		Type:      accountType,
+		Currency:  currency,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return Account{}, err
+	}
+	s.logger.Info("billing account ensured", zap.String("tenant_id", tenantID), zap.String("name", name), zap.String("currency", currency))
+	return account, nil
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"github.com/smallbiznis/corebilling/internal/money"
+)
 
 // AccountType values.
 const (
@@ -54,3 +58,9 @@ type LedgerEntry struct {
 	AmountCents    int64
 	CreatedAt      time.Time
 }
+
+// Amount returns the entry amount as a money value. Ledger postings are always
+// whole minor units; sub-cent precision is rounded away before posting.
+func (e LedgerEntry) Amount() money.Amount {
+	return money.FromCents(e.AmountCents)
+}
@@ -7,6 +7,13 @@ type Repository interface {
 	CreateAccount(ctx context.Context, account Account) error
 	GetAccount(ctx context.Context, id string) (Account, error)
 	ListAccounts(ctx context.Context, tenantID string) ([]Account, error)
+	// FindAccount returns the tenant's account called name in currency.
+	FindAccount(ctx context.Context, tenantID, name, currency string) (Account, bool, error)
+	// EnsureAccount creates the account unless the tenant has one with its
+	// name and currency, returning the stored one.
+	EnsureAccount(ctx context.Context, account Account) (Account, error)
+	// CreateJournalAndEntries stores the journal with its entries. A journal
+	// whose id is already stored is skipped.
 	CreateJournalAndEntries(ctx context.Context, journal JournalEntry, entries []LedgerEntry) error
 	Transfer(ctx context.Context, journal JournalEntry, entries []LedgerEntry) error
 }
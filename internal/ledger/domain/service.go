@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/oklog/ulid/v2"
+	"github.com/smallbiznis/corebilling/internal/money"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/types/known/structpb"
 )
@@ -68,19 +69,25 @@ func (s *Service) Transfer(ctx context.Context, journal JournalEntry, entries []
 	return s.CreateJournalEntry(ctx, journal, entries)
 }
 
+// validateEntries checks that debits and credits balance. Each side is summed
+// as a checked money.Amount so a journal too large to represent is rejected
+// rather than wrapping around to a false balance.
 func validateEntries(entries []LedgerEntry) error {
-	var sum int64
+	debits, credits := money.Zero, money.Zero
 	for _, entry := range entries {
 		switch entry.Type {
 		case EntryTypeDebit:
-			sum += entry.AmountCents
+			debits = debits.Add(entry.Amount())
 		case EntryTypeCredit:
-			sum -= entry.AmountCents
+			credits = credits.Add(entry.Amount())
 		default:
 			return errors.New("invalid entry type")
 		}
 	}
-	if sum != 0 {
+	if debits.Err() != nil || credits.Err() != nil {
+		return errors.New("entry amounts out of range")
+	}
+	if !debits.Sub(credits).IsZero() {
 		return errors.New("entries must balance")
 	}
 	return nil
@@ -3,7 +3,9 @@ package pgx
 import (
 	"context"
 	"encoding/json"
+	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/smallbiznis/corebilling/internal/ledger/domain"
@@ -59,6 +61,73 @@ func (r *Repository) GetAccount(ctx context.Context, id string) (domain.Account,
 	return acc, nil
 }
 
+func (r *Repository) FindAccount(ctx context.Context, tenantID, name, currency string) (domain.Account, bool, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT id, tenant_id, name, type, currency,
+		       balance_cents, metadata, created_at, updated_at
+		FROM ledger_accounts
+		WHERE tenant_id=$1 AND name=$2 AND currency=$3
+		ORDER BY created_at
+		LIMIT 1
+	`, tenantID, name, currency)
+	var acc domain.Account
+	var metadata []byte
+	err := row.Scan(
+		&acc.ID,
+		&acc.TenantID,
+		&acc.Name,
+		&acc.Type,
+		&acc.Currency,
+		&acc.BalanceCents,
+		&metadata,
+		&acc.CreatedAt,
+		&acc.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Account{}, false, nil
+	}
+	if err != nil {
+		return domain.Account{}, false, err
+	}
+	acc.Metadata = jsonToMap(metadata)
+	return acc, true, nil
+}
+
+// EnsureAccount inserts the account unless the tenant already has one with
+// its name and currency, and returns the stored account.
+func (r *Repository) EnsureAccount(ctx context.Context, account domain.Account) (domain.Account, error) {
+	metadata, err := marshalJSON(account.Metadata)
+	if err != nil {
+		return domain.Account{}, err
+	}
+	row := r.pool.QueryRow(ctx, `
+		INSERT INTO ledger_accounts (
+			id, tenant_id, name, type, currency,
+			balance_cents, metadata, created_at, updated_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		ON CONFLICT (tenant_id, name, currency) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id, tenant_id, name, type, currency,
+		          balance_cents, metadata, created_at, updated_at
+	`, account.ID, account.TenantID, account.Name, account.Type, account.Currency, account.BalanceCents, metadata, account.CreatedAt, account.UpdatedAt)
+	var acc domain.Account
+	var stored []byte
+	if err := row.Scan(
+		&acc.ID,
+		&acc.TenantID,
+		&acc.Name,
+		&acc.Type,
+		&acc.Currency,
+		&acc.BalanceCents,
+		&stored,
+		&acc.CreatedAt,
+		&acc.UpdatedAt,
+	); err != nil {
+		return domain.Account{}, err
+	}
+	acc.Metadata = jsonToMap(stored)
+	return acc, nil
+}
+
 func (r *Repository) ListAccounts(ctx context.Context, tenantID string) ([]domain.Account, error) {
 	rows, err := r.pool.Query(ctx, `
 		SELECT id, tenant_id, name, type, currency,
@@ -118,15 +187,20 @@ func (r *Repository) applyJournal(ctx context.Context, journal domain.JournalEnt
 		return err
 	}
 
-	_, err = tx.Exec(ctx, `
+	// A journal is applied once: posting an id again is a no-op.
+	tag, err := tx.Exec(ctx, `
 		INSERT INTO ledger_journals (
 			id, tenant_id, reference_id, reference_type,
 			description, metadata, created_at
 		) VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (id) DO NOTHING
 	`, journal.ID, journal.TenantID, journal.ReferenceID, journal.ReferenceType, journal.Description, metadata, journal.CreatedAt)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
 
 	for _, entry := range entries {
 		_, err := tx.Exec(ctx, `
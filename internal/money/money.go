@@ -0,0 +1,231 @@
+// Package money provides a fixed-point decimal amount for prices and charges
+// that need precision below a currency's minor unit.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimal places kept below the minor unit (cent).
+const Scale = 6
+
+const unit = 1_000_000
+
+// ErrInvalidAmount is returned when a decimal string cannot be parsed.
+var ErrInvalidAmount = errors.New("invalid money amount")
+
+// ErrOverflow is reported by Err for amounts that left the range of Amount.
+var ErrOverflow = errors.New("money amount out of range")
+
+// Amount is a fixed-point amount of minor currency units with Scale decimal
+// places, so 1 cent is 1_000_000 and $0.0004 is 40_000. Amounts are only
+// rounded to whole cents when an invoice line is finalized.
+//
+// An Amount holds up to MaxCents in either direction. A result outside that
+// range is Invalid, which, like NaN, stays Invalid through further arithmetic;
+// check Err before storing or billing an amount.
+type Amount int64
+
+// Zero is the zero amount.
+const Zero Amount = 0
+
+// Invalid is the result of arithmetic that overflowed.
+const Invalid Amount = math.MinInt64
+
+// MaxCents is the largest number of whole minor units an Amount holds.
+const MaxCents = math.MaxInt64 / unit
+
+// FromCents converts whole minor units into an Amount, Invalid when cents
+// exceeds MaxCents in either direction.
+func FromCents(cents int64) Amount {
+	if cents > MaxCents || cents < -MaxCents {
+		return Invalid
+	}
+	return Amount(cents * unit)
+}
+
+// Err returns ErrOverflow for Invalid amounts and nil otherwise.
+func (a Amount) Err() error {
+	if a == Invalid {
+		return ErrOverflow
+	}
+	return nil
+}
+
+// Parse reads a decimal string of minor units, e.g. "0.04" for $0.0004.
+func Parse(raw string) (Amount, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return Zero, nil
+	}
+	r, ok := new(big.Rat).SetString(raw)
+	if !ok {
+		return Zero, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+	}
+	return fromRat(r)
+}
+
+// MustParse is like Parse but panics on invalid input. Intended for constants and tests.
+func MustParse(raw string) Amount {
+	a, err := Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Add returns a + b.
+func (a Amount) Add(b Amount) Amount {
+	if a == Invalid || b == Invalid {
+		return Invalid
+	}
+	sum := a + b
+	// Operands of the same sign overflowed when the sign of the sum differs.
+	if (a > 0 && b > 0 && sum <= 0) || (a < 0 && b < 0 && sum >= 0) || sum == Invalid {
+		return Invalid
+	}
+	return sum
+}
+
+// Sub returns a - b.
+func (a Amount) Sub(b Amount) Amount {
+	if b == Invalid {
+		return Invalid
+	}
+	return a.Add(-b)
+}
+
+// Neg returns -a.
+func (a Amount) Neg() Amount {
+	if a == Invalid {
+		return Invalid
+	}
+	return -a
+}
+
+// IsZero reports whether the amount is zero.
+func (a Amount) IsZero() bool { return a == 0 }
+
+// Mul multiplies the amount by an integer count, such as a number of packages.
+func (a Amount) Mul(n int64) Amount {
+	if a == Invalid {
+		return Invalid
+	}
+	if a == 0 || n == 0 {
+		return 0
+	}
+	product := a * Amount(n)
+	if product/Amount(n) != a || product == Invalid {
+		return Invalid
+	}
+	return product
+}
+
+// MulQuantity multiplies the amount by a usage quantity. The quantity is taken
+// at its shortest decimal representation so binary float error does not leak
+// into the product, which is rounded half away from zero to Scale places.
+// Quantities that are not finite give Invalid.
+func (a Amount) MulQuantity(quantity float64) Amount {
+	if math.IsNaN(quantity) || math.IsInf(quantity, 0) {
+		return Invalid
+	}
+	return a.mulRat(floatRat(quantity))
+}
+
+// MulFraction scales the amount by num/den, as when prorating a charge over the
+// remaining part of a period. The result is rounded half away from zero to Scale places.
+func (a Amount) MulFraction(num, den int64) Amount {
+	if den == 0 {
+		return 0
+	}
+	if a == Invalid {
+		return Invalid
+	}
+	return a.mulRat(big.NewRat(num, den))
+}
+
+// Percent returns rate percent of the amount, rounded to Scale places.
+func (a Amount) Percent(rate float64) Amount {
+	if a == Invalid || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return Invalid
+	}
+	r := floatRat(rate)
+	return a.mulRat(r.Quo(r, big.NewRat(100, 1)))
+}
+
+// Cents rounds the amount half away from zero to whole minor units. This is the
+// single rounding step applied when invoice lines are finalized.
+func (a Amount) Cents() int64 {
+	whole, frac := int64(a)/unit, int64(a)%unit
+	switch {
+	case frac >= unit/2:
+		whole++
+	case frac <= -unit/2:
+		whole--
+	}
+	return whole
+}
+
+// String formats the amount as a decimal number of minor units without
+// trailing zeros, suitable for NUMERIC columns and event payloads.
+func (a Amount) String() string {
+	sign := ""
+	v := int64(a)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	whole, frac := v/unit, v%unit
+	if frac == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	digits := strings.TrimRight(fmt.Sprintf("%0*d", Scale, frac), "0")
+	return fmt.Sprintf("%s%d.%s", sign, whole, digits)
+}
+
+func (a Amount) mulRat(r *big.Rat) Amount {
+	if a == Invalid {
+		return Invalid
+	}
+	r.Mul(r, new(big.Rat).SetInt64(int64(a)))
+	product, err := fromRat(r.Quo(r, big.NewRat(unit, 1)))
+	if err != nil {
+		return Invalid
+	}
+	return product
+}
+
+func floatRat(v float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(v, 'f', -1, 64))
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// fromRat converts minor units held in r to an Amount, rounding half away from zero.
+func fromRat(r *big.Rat) (Amount, error) {
+	scaled := new(big.Rat).Mul(r, big.NewRat(unit, 1))
+	num, den := scaled.Num(), scaled.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() != 0 {
+		// Compare 2*|rem| with den to round half away from zero.
+		twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+		if twice.Cmp(den) >= 0 {
+			if num.Sign() < 0 {
+				quo.Sub(quo, big.NewInt(1))
+			} else {
+				quo.Add(quo, big.NewInt(1))
+			}
+		}
+	}
+	if !quo.IsInt64() || quo.Int64() == int64(Invalid) {
+		return Zero, fmt.Errorf("%w: %s overflows", ErrInvalidAmount, r.FloatString(Scale))
+	}
+	return Amount(quo.Int64()), nil
+}
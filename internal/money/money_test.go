@@ -0,0 +1,113 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseAndString(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Amount
+		str  string
+	}{
+		{raw: "0", want: 0, str: "0"},
+		{raw: "12", want: 12_000_000, str: "12"},
+		{raw: "0.04", want: 40_000, str: "0.04"},
+		{raw: "-1.5", want: -1_500_000, str: "-1.5"},
+		{raw: "0.0000005", want: 1, str: "0.000001"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.raw, err)
+		}
+		if got != tt.want {
+			t.Fatalf("Parse(%q) = %d, want %d", tt.raw, got, tt.want)
+		}
+		if got.String() != tt.str {
+			t.Fatalf("String() = %q, want %q", got.String(), tt.str)
+		}
+	}
+	if _, err := Parse("abc"); err == nil {
+		t.Fatal("expected error for invalid amount")
+	}
+}
+
+func TestMulQuantityAvoidsFloatError(t *testing.T) {
+	perToken := MustParse("0.04") // $0.0004
+	got := perToken.MulQuantity(0.1).Add(perToken.MulQuantity(0.2))
+	if got != perToken.MulQuantity(0.3) {
+		t.Fatalf("expected %s, got %s", perToken.MulQuantity(0.3), got)
+	}
+	if cents := perToken.MulQuantity(1_234_567).Cents(); cents != 49383 {
+		t.Fatalf("expected 49383 cents, got %d", cents)
+	}
+}
+
+func TestCentsRounding(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		want   int64
+	}{
+		{amount: MustParse("1.4999"), want: 1},
+		{amount: MustParse("1.5"), want: 2},
+		{amount: MustParse("-1.5"), want: -2},
+		{amount: MustParse("-1.4"), want: -1},
+		{amount: FromCents(42), want: 42},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.Cents(); got != tt.want {
+			t.Fatalf("%s.Cents() = %d, want %d", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	if got := FromCents(1250).Percent(11); got != MustParse("137.5") {
+		t.Fatalf("expected 137.5, got %s", got)
+	}
+}
+
+func TestMulFraction(t *testing.T) {
+	// 10.00 over 15 of 30 days, and 1/3 of a cent rounded to Scale places.
+	if got := FromCents(1000).MulFraction(15, 30); got != FromCents(500) {
+		t.Fatalf("expected 500, got %s", got)
+	}
+	if got := FromCents(1).MulFraction(1, 3); got != MustParse("0.333333") {
+		t.Fatalf("expected 0.333333, got %s", got)
+	}
+	if got := FromCents(1000).MulFraction(1, 0); got != 0 {
+		t.Fatalf("expected 0 for empty period, got %s", got)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	max := FromCents(MaxCents)
+	tests := []struct {
+		name string
+		got  Amount
+	}{
+		{name: "from cents", got: FromCents(MaxCents + 1)},
+		{name: "from negative cents", got: FromCents(-MaxCents - 1)},
+		{name: "add", got: max.Add(FromCents(1))},
+		{name: "sub", got: max.Neg().Sub(FromCents(1))},
+		{name: "mul", got: FromCents(1_000_000).Mul(10_000_000)},
+		{name: "mul quantity", got: FromCents(1_000_000).MulQuantity(1e7)},
+		{name: "propagates", got: FromCents(MaxCents + 1).Add(FromCents(1)).Mul(0).Add(Zero)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.got.Err(), ErrOverflow) {
+				t.Fatalf("expected ErrOverflow, got %d", tt.got)
+			}
+		})
+	}
+
+	if err := max.Add(max.Neg()).Err(); err != nil {
+		t.Fatalf("expected amounts in range to add, got %v", err)
+	}
+	if got := FromCents(1_000).Mul(1_000); got.Err() != nil || got.Cents() != 1_000_000 {
+		t.Fatalf("expected 1000000 cents, got %d", got.Cents())
+	}
+}
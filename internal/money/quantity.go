@@ -0,0 +1,82 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Quantity is a fixed-point usage quantity with Scale decimal places, so 1.5
+// units is 1_500_000. Usage is summed as a Quantity so binary float error
+// cannot move a total across a tier boundary; it is converted with Float64
+// only to price the finished total.
+//
+// Like Amount, a result outside the range of Quantity is InvalidQuantity and
+// stays invalid through further arithmetic; check Err before pricing it.
+type Quantity int64
+
+// InvalidQuantity is the result of arithmetic that overflowed.
+const InvalidQuantity Quantity = math.MinInt64
+
+// QuantityFromFloat converts a usage value taken at its shortest decimal
+// representation, rounded half away from zero to Scale places. Values that
+// are not finite or out of range give InvalidQuantity.
+func QuantityFromFloat(v float64) Quantity {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return InvalidQuantity
+	}
+	q, err := fromRat(floatRat(v))
+	if err != nil {
+		return InvalidQuantity
+	}
+	return Quantity(q)
+}
+
+// ParseQuantity reads a decimal string, e.g. a NUMERIC column.
+func ParseQuantity(raw string) (Quantity, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	r, ok := new(big.Rat).SetString(raw)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+	}
+	q, err := fromRat(r)
+	if err != nil {
+		return 0, err
+	}
+	return Quantity(q), nil
+}
+
+// Err returns ErrOverflow for InvalidQuantity and nil otherwise.
+func (q Quantity) Err() error {
+	return Amount(q).Err()
+}
+
+// Add returns q + o.
+func (q Quantity) Add(o Quantity) Quantity {
+	return Quantity(Amount(q).Add(Amount(o)))
+}
+
+// Sub returns q - o.
+func (q Quantity) Sub(o Quantity) Quantity {
+	return Quantity(Amount(q).Sub(Amount(o)))
+}
+
+// Float64 returns the nearest float64 to the quantity, for pricing and
+// reporting a finished total. InvalidQuantity gives NaN.
+func (q Quantity) Float64() float64 {
+	if q == InvalidQuantity {
+		return math.NaN()
+	}
+	v, _ := strconv.ParseFloat(q.String(), 64)
+	return v
+}
+
+// String formats the quantity as a decimal number without trailing zeros.
+func (q Quantity) String() string {
+	return Amount(q).String()
+}
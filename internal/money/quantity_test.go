@@ -0,0 +1,53 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestQuantitySumAvoidsFloatError(t *testing.T) {
+	sum := QuantityFromFloat(0.1).Add(QuantityFromFloat(0.2))
+	if got := sum.Float64(); got != 0.3 {
+		t.Fatalf("expected 0.3, got %v", got)
+	}
+	var tenths Quantity
+	for i := 0; i < 10; i++ {
+		tenths = tenths.Add(QuantityFromFloat(0.1))
+	}
+	if tenths.String() != "1" {
+		t.Fatalf("expected 1, got %s", tenths)
+	}
+	if got := tenths.Sub(QuantityFromFloat(0.25)).String(); got != "0.75" {
+		t.Fatalf("expected 0.75, got %s", got)
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	q, err := ParseQuantity("12.3456789")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if q.String() != "12.345679" {
+		t.Fatalf("expected 12.345679, got %s", q)
+	}
+	if _, err := ParseQuantity("1e400"); err == nil {
+		t.Fatal("expected an out of range quantity to fail")
+	}
+	if _, err := ParseQuantity("abc"); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("expected ErrInvalidAmount, got %v", err)
+	}
+}
+
+func TestQuantityOverflow(t *testing.T) {
+	if err := QuantityFromFloat(math.Inf(1)).Err(); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	large := QuantityFromFloat(9e12)
+	if err := large.Add(large).Add(QuantityFromFloat(1)).Err(); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	if !math.IsNaN(InvalidQuantity.Float64()) {
+		t.Fatal("expected NaN for an invalid quantity")
+	}
+}
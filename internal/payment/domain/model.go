@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Provider identifies the processor holding a payment method.
+type Provider int16
+
+const (
+	ProviderUnspecified Provider = 0
+	ProviderManual      Provider = 1
+	ProviderStripe      Provider = 2
+	ProviderXendit      Provider = 3
+	ProviderMidtrans    Provider = 4
+)
+
+var providerNames = map[Provider]string{
+	ProviderManual:   "manual",
+	ProviderStripe:   "stripe",
+	ProviderXendit:   "xendit",
+	ProviderMidtrans: "midtrans",
+}
+
+func (p Provider) String() string {
+	return providerNames[p]
+}
+
+// MethodType is the kind of instrument a payment method charges.
+type MethodType int16
+
+const (
+	MethodTypeUnspecified  MethodType = 0
+	MethodTypeCard         MethodType = 1
+	MethodTypeBankTransfer MethodType = 2
+	MethodTypeEWallet      MethodType = 3
+)
+
+var methodTypeNames = map[MethodType]string{
+	MethodTypeCard:         "card",
+	MethodTypeBankTransfer: "bank_transfer",
+	MethodTypeEWallet:      "ewallet",
+}
+
+func (t MethodType) String() string {
+	return methodTypeNames[t]
+}
+
+// ErrInvalidPaymentMethod is returned when a payment method is missing required fields.
+var ErrInvalidPaymentMethod = errors.New("invalid payment method")
+
+// ParseProvider resolves a provider by name.
+func ParseProvider(raw string) (Provider, error) {
+	for p, name := range providerNames {
+		if name == strings.ToLower(raw) {
+			return p, nil
+		}
+	}
+	return ProviderUnspecified, fmt.Errorf("%w: unsupported provider %q", ErrInvalidPaymentMethod, raw)
+}
+
+// ParseMethodType resolves a payment method type by name.
+func ParseMethodType(raw string) (MethodType, error) {
+	for t, name := range methodTypeNames {
+		if name == strings.ToLower(raw) {
+			return t, nil
+		}
+	}
+	return MethodTypeUnspecified, fmt.Errorf("%w: unsupported type %q", ErrInvalidPaymentMethod, raw)
+}
+
+// PaymentMethod is a customer's stored instrument, tokenized by its provider.
+type PaymentMethod struct {
+	ID           string
+	TenantID     string
+	CustomerID   string
+	Provider     Provider
+	Type         MethodType
+	DisplayName  string
+	Last4        string
+	ExpMonth     string
+	ExpYear      string
+	IsDefault    bool
+	ProviderData map[string]interface{}
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// AttemptStatus is the outcome of charging an invoice.
+type AttemptStatus int16
+
+const (
+	AttemptStatusUnspecified AttemptStatus = 0
+	AttemptStatusPending     AttemptStatus = 1
+	AttemptStatusSucceeded   AttemptStatus = 2
+	AttemptStatusFailed      AttemptStatus = 3
+)
+
+var attemptStatusNames = map[AttemptStatus]string{
+	AttemptStatusPending:   "pending",
+	AttemptStatusSucceeded: "succeeded",
+	AttemptStatusFailed:    "failed",
+}
+
+func (s AttemptStatus) String() string {
+	return attemptStatusNames[s]
+}
+
+// ErrInvalidPaymentAttempt is returned when a payment attempt is missing required fields.
+var ErrInvalidPaymentAttempt = errors.New("invalid payment attempt")
+
+// ParseAttemptStatus resolves an attempt status by name.
+func ParseAttemptStatus(raw string) (AttemptStatus, error) {
+	for s, name := range attemptStatusNames {
+		if name == strings.ToLower(raw) {
+			return s, nil
+		}
+	}
+	return AttemptStatusUnspecified, fmt.Errorf("%w: unsupported status %q", ErrInvalidPaymentAttempt, raw)
+}
+
+// Attempt records one try at collecting an invoice, as reported by the
+// provider holding the payment method.
+type Attempt struct {
+	ID                    string
+	TenantID              string
+	InvoiceID             string
+	PaymentMethodID       string
+	Status                AttemptStatus
+	ProviderTransactionID string
+	FailureReason         string
+	AttemptedAt           time.Time
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+}
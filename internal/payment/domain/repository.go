@@ -0,0 +1,19 @@
+package domain
+
+import "context"
+
+// Repository persists customer payment methods and payment attempts.
+type Repository interface {
+	// Create stores a payment method. A default method replaces the
+	// customer's previous default.
+	Create(ctx context.Context, method PaymentMethod) error
+	ListByCustomer(ctx context.Context, tenantID, customerID string) ([]PaymentMethod, error)
+	// CreateAttempt stores the attempt, reporting false when an attempt with
+	// its provider transaction id is already stored for the tenant.
+	CreateAttempt(ctx context.Context, attempt Attempt) (bool, error)
+	// GetAttemptByTransaction returns the tenant's attempt recorded for the
+	// provider transaction, reporting false when there is none.
+	GetAttemptByTransaction(ctx context.Context, tenantID, providerTransactionID string) (Attempt, bool, error)
+	// ListAttempts returns the invoice's attempts, oldest first.
+	ListAttempts(ctx context.Context, tenantID, invoiceID string) ([]Attempt, error)
+}
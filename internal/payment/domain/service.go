@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+	"go.uber.org/zap"
+)
+
+// Service manages customer payment methods and records payment attempts.
+type Service struct {
+	repo   Repository
+	logger *zap.Logger
+
+	genID *snowflake.Node
+}
+
+// NewService constructs the payment method service.
+func NewService(repo Repository, logger *zap.Logger, genID *snowflake.Node) *Service {
+	return &Service{repo: repo, logger: logger.Named("payment.service"), genID: genID}
+}
+
+// AttachPaymentMethod stores a provider-tokenized method for a customer. The
+// customer's first method becomes the default.
+func (s *Service) AttachPaymentMethod(ctx context.Context, method PaymentMethod) (PaymentMethod, error) {
+	if method.TenantID == "" || method.CustomerID == "" {
+		return PaymentMethod{}, fmt.Errorf("%w: tenant_id and customer_id required", ErrInvalidPaymentMethod)
+	}
+	existing, err := s.repo.ListByCustomer(ctx, method.TenantID, method.CustomerID)
+	if err != nil {
+		return PaymentMethod{}, err
+	}
+	if len(existing) == 0 {
+		method.IsDefault = true
+	}
+
+	now := time.Now().UTC()
+	method.ID = s.genID.Generate().String()
+	method.CreatedAt = now
+	method.UpdatedAt = now
+	if err := s.repo.Create(ctx, method); err != nil {
+		s.logger.Error("create payment method", zap.Error(err))
+		return PaymentMethod{}, err
+	}
+	s.logger.Info("payment method attached", zap.String("id", method.ID), zap.String("customer_id", method.CustomerID))
+	return method, nil
+}
+
+// ListPaymentMethods returns a customer's payment methods, default first.
+func (s *Service) ListPaymentMethods(ctx context.Context, tenantID, customerID string) ([]PaymentMethod, error) {
+	return s.repo.ListByCustomer(ctx, tenantID, customerID)
+}
+
+// HasPaymentMethod reports whether the customer has a payment method on file.
+func (s *Service) HasPaymentMethod(ctx context.Context, tenantID, customerID string) (bool, error) {
+	methods, err := s.repo.ListByCustomer(ctx, tenantID, customerID)
+	if err != nil {
+		return false, err
+	}
+	return len(methods) > 0, nil
+}
+
+// FindAttempt returns the tenant's attempt recorded for a provider
+// transaction, reporting false when there is none or the id is empty.
+func (s *Service) FindAttempt(ctx context.Context, tenantID, providerTransactionID string) (Attempt, bool, error) {
+	if providerTransactionID == "" {
+		return Attempt{}, false, nil
+	}
+	return s.repo.GetAttemptByTransaction(ctx, tenantID, providerTransactionID)
+}
+
+// RecordAttempt stores the outcome of charging an invoice. AttemptedAt
+// defaults to now. An attempt is recorded once per provider transaction; a
+// repeated one returns the stored attempt and false.
+func (s *Service) RecordAttempt(ctx context.Context, attempt Attempt) (Attempt, bool, error) {
+	if attempt.TenantID == "" || attempt.InvoiceID == "" || attempt.PaymentMethodID == "" {
+		return Attempt{}, false, fmt.Errorf("%w: tenant_id, invoice_id and payment_method_id required", ErrInvalidPaymentAttempt)
+	}
+	if attempt.Status == AttemptStatusUnspecified {
+		return Attempt{}, false, fmt.Errorf("%w: status required", ErrInvalidPaymentAttempt)
+	}
+
+	now := time.Now().UTC()
+	if attempt.AttemptedAt.IsZero() {
+		attempt.AttemptedAt = now
+	}
+	attempt.ID = s.genID.Generate().String()
+	attempt.CreatedAt = now
+	attempt.UpdatedAt = now
+	created, err := s.repo.CreateAttempt(ctx, attempt)
+	if err != nil {
+		s.logger.Error("create payment attempt", zap.Error(err))
+		return Attempt{}, false, err
+	}
+	if !created {
+		existing, _, err := s.FindAttempt(ctx, attempt.TenantID, attempt.ProviderTransactionID)
+		if err != nil {
+			return Attempt{}, false, err
+		}
+		return existing, false, nil
+	}
+	s.logger.Info("payment attempt recorded",
+		zap.String("id", attempt.ID),
+		zap.String("invoice_id", attempt.InvoiceID),
+		zap.String("status", attempt.Status.String()),
+	)
+	return attempt, true, nil
+}
+
+// ListAttempts returns the attempts made to collect an invoice, oldest first.
+func (s *Service) ListAttempts(ctx context.Context, tenantID, invoiceID string) ([]Attempt, error) {
+	return s.repo.ListAttempts(ctx, tenantID, invoiceID)
+}
@@ -0,0 +1,149 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/smallbiznis/corebilling/internal/headers"
+	"github.com/smallbiznis/corebilling/internal/payment/domain"
+	"go.uber.org/fx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ModuleHTTP = fx.Invoke(RegisterHTTP)
+
+func RegisterHTTP(lc fx.Lifecycle, mux *runtime.ServeMux, svc *domain.Service) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := mux.HandlePath(http.MethodPost, "/v1/customers/{customer_id}/payment_methods", attachHandler(svc)); err != nil {
+				return err
+			}
+			return mux.HandlePath(http.MethodGet, "/v1/customers/{customer_id}/payment_methods", listHandler(svc))
+		},
+	})
+}
+
+type paymentMethodRequest struct {
+	Provider     string                 `json:"provider"`
+	Type         string                 `json:"type"`
+	DisplayName  string                 `json:"display_name"`
+	Last4        string                 `json:"last4"`
+	ExpMonth     string                 `json:"exp_month"`
+	ExpYear      string                 `json:"exp_year"`
+	IsDefault    bool                   `json:"is_default"`
+	ProviderData map[string]interface{} `json:"provider_data"`
+}
+
+type paymentMethodResponse struct {
+	ID          string    `json:"id"`
+	CustomerID  string    `json:"customer_id"`
+	Provider    string    `json:"provider"`
+	Type        string    `json:"type"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Last4       string    `json:"last4,omitempty"`
+	ExpMonth    string    `json:"exp_month,omitempty"`
+	ExpYear     string    `json:"exp_year,omitempty"`
+	IsDefault   bool      `json:"is_default"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func attachHandler(svc *domain.Service) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		tenantID := r.Header.Get(headers.HeaderTenantID)
+		if tenantID == "" {
+			writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+			return
+		}
+		var body paymentMethodRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+		provider, err := domain.ParseProvider(body.Provider)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		methodType, err := domain.ParseMethodType(body.Type)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		method, err := svc.AttachPaymentMethod(r.Context(), domain.PaymentMethod{
+			TenantID:     tenantID,
+			CustomerID:   params["customer_id"],
+			Provider:     provider,
+			Type:         methodType,
+			DisplayName:  body.DisplayName,
+			Last4:        body.Last4,
+			ExpMonth:     body.ExpMonth,
+			ExpYear:      body.ExpYear,
+			IsDefault:    body.IsDefault,
+			ProviderData: body.ProviderData,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, toResponse(method))
+	}
+}
+
+func listHandler(svc *domain.Service) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		tenantID := r.Header.Get(headers.HeaderTenantID)
+		if tenantID == "" {
+			writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+			return
+		}
+		methods, err := svc.ListPaymentMethods(r.Context(), tenantID, params["customer_id"])
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		resp := make([]paymentMethodResponse, 0, len(methods))
+		for _, m := range methods {
+			resp = append(resp, toResponse(m))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"payment_methods": resp})
+	}
+}
+
+func toResponse(m domain.PaymentMethod) paymentMethodResponse {
+	return paymentMethodResponse{
+		ID:          m.ID,
+		CustomerID:  m.CustomerID,
+		Provider:    m.Provider.String(),
+		Type:        m.Type.String(),
+		DisplayName: m.DisplayName,
+		Last4:       m.Last4,
+		ExpMonth:    m.ExpMonth,
+		ExpYear:     m.ExpYear,
+		IsDefault:   m.IsDefault,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+// toStatus maps domain errors onto gRPC status codes for the HTTP response.
+func toStatus(err error) error {
+	if errors.Is(err, domain.ErrInvalidPaymentMethod) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(toStatus(err))
+	writeJSON(w, runtime.HTTPStatusFromCode(st.Code()), map[string]string{"error": st.Message()})
+}
@@ -0,0 +1,15 @@
+package payment
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/smallbiznis/corebilling/internal/payment/domain"
+	reposqlc "github.com/smallbiznis/corebilling/internal/payment/repository/sqlc"
+)
+
+// Module wires payment method services.
+var Module = fx.Options(
+	fx.Provide(reposqlc.NewRepository),
+	fx.Provide(domain.NewService),
+	ModuleHTTP,
+)
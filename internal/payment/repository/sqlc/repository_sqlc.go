@@ -0,0 +1,241 @@
+package sqlc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/smallbiznis/corebilling/internal/payment/domain"
+)
+
+// Repository handles payment method and payment attempt persistence.
+type Repository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRepository constructs repository.
+func NewRepository(pool *pgxpool.Pool) domain.Repository {
+	return &Repository{pool: pool}
+}
+
+// Create inserts a payment method, clearing the customer's previous default
+// when the new method is the default.
+func (r *Repository) Create(ctx context.Context, method domain.PaymentMethod) error {
+	providerData, err := marshalJSON(method.ProviderData)
+	if err != nil {
+		return err
+	}
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if method.IsDefault {
+		if _, err := tx.Exec(ctx, `
+			UPDATE payment_methods SET is_default = FALSE, updated_at = $3
+			WHERE tenant_id = $1 AND customer_id = $2 AND is_default
+		`, method.TenantID, method.CustomerID, method.UpdatedAt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO payment_methods (
+			id, tenant_id, customer_id, provider, type, display_name, last4,
+			exp_month, exp_year, is_default, provider_data, created_at, updated_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+	`,
+		method.ID,
+		method.TenantID,
+		method.CustomerID,
+		int16(method.Provider),
+		int16(method.Type),
+		nullIfEmpty(method.DisplayName),
+		nullIfEmpty(method.Last4),
+		nullIfEmpty(method.ExpMonth),
+		nullIfEmpty(method.ExpYear),
+		method.IsDefault,
+		providerData,
+		method.CreatedAt,
+		method.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListByCustomer returns the customer's payment methods, default first.
+func (r *Repository) ListByCustomer(ctx context.Context, tenantID, customerID string) ([]domain.PaymentMethod, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id::TEXT, tenant_id::TEXT, customer_id::TEXT, provider, type,
+			COALESCE(display_name, ''), COALESCE(last4, ''), COALESCE(exp_month, ''),
+			COALESCE(exp_year, ''), is_default, provider_data, created_at, updated_at
+		FROM payment_methods
+		WHERE tenant_id = $1 AND customer_id = $2
+		ORDER BY is_default DESC, created_at DESC
+	`, tenantID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var methods []domain.PaymentMethod
+	for rows.Next() {
+		var m domain.PaymentMethod
+		var providerData []byte
+		if err := rows.Scan(
+			&m.ID,
+			&m.TenantID,
+			&m.CustomerID,
+			&m.Provider,
+			&m.Type,
+			&m.DisplayName,
+			&m.Last4,
+			&m.ExpMonth,
+			&m.ExpYear,
+			&m.IsDefault,
+			&providerData,
+			&m.CreatedAt,
+			&m.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		m.ProviderData = jsonToMap(providerData)
+		methods = append(methods, m)
+	}
+	return methods, rows.Err()
+}
+
+// CreateAttempt inserts a payment attempt unless its provider transaction was
+// already recorded. The failure reason is kept in the attempt's metadata.
+func (r *Repository) CreateAttempt(ctx context.Context, attempt domain.Attempt) (bool, error) {
+	var metadata map[string]interface{}
+	if attempt.FailureReason != "" {
+		metadata = map[string]interface{}{"failure_reason": attempt.FailureReason}
+	}
+	raw, err := marshalJSON(metadata)
+	if err != nil {
+		return false, err
+	}
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO payment_attempts (
+			id, tenant_id, invoice_id, payment_method_id, status,
+			provider_transaction_id, attempted_at, metadata, created_at, updated_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		ON CONFLICT (tenant_id, provider_transaction_id) WHERE provider_transaction_id IS NOT NULL DO NOTHING
+	`,
+		attempt.ID,
+		attempt.TenantID,
+		attempt.InvoiceID,
+		attempt.PaymentMethodID,
+		int16(attempt.Status),
+		nullIfEmpty(attempt.ProviderTransactionID),
+		attempt.AttemptedAt,
+		raw,
+		attempt.CreatedAt,
+		attempt.UpdatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+const attemptColumns = `
+	id::TEXT, tenant_id::TEXT, invoice_id::TEXT, payment_method_id::TEXT,
+	status, COALESCE(provider_transaction_id, ''), COALESCE(attempted_at, created_at),
+	metadata, created_at, updated_at`
+
+// GetAttemptByTransaction returns the tenant's attempt for a provider transaction.
+func (r *Repository) GetAttemptByTransaction(ctx context.Context, tenantID, providerTransactionID string) (domain.Attempt, bool, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT `+attemptColumns+`
+		FROM payment_attempts
+		WHERE tenant_id = $1 AND provider_transaction_id = $2
+	`, tenantID, providerTransactionID)
+	a, err := scanAttempt(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Attempt{}, false, nil
+	}
+	if err != nil {
+		return domain.Attempt{}, false, err
+	}
+	return a, true, nil
+}
+
+// ListAttempts returns the invoice's payment attempts, oldest first.
+func (r *Repository) ListAttempts(ctx context.Context, tenantID, invoiceID string) ([]domain.Attempt, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+attemptColumns+`
+		FROM payment_attempts
+		WHERE tenant_id = $1 AND invoice_id = $2
+		ORDER BY attempted_at, id
+	`, tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []domain.Attempt
+	for rows.Next() {
+		a, err := scanAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+func scanAttempt(row pgx.Row) (domain.Attempt, error) {
+	var a domain.Attempt
+	var metadata []byte
+	if err := row.Scan(
+		&a.ID,
+		&a.TenantID,
+		&a.InvoiceID,
+		&a.PaymentMethodID,
+		&a.Status,
+		&a.ProviderTransactionID,
+		&a.AttemptedAt,
+		&metadata,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	); err != nil {
+		return domain.Attempt{}, err
+	}
+	if reason, ok := jsonToMap(metadata)["failure_reason"].(string); ok {
+		a.FailureReason = reason
+	}
+	return a, nil
+}
+
+func nullIfEmpty(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func marshalJSON(value map[string]interface{}) ([]byte, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
+
+func jsonToMap(value []byte) map[string]interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(value, &data); err != nil {
+		return nil
+	}
+	return data
+}
+
+var _ domain.Repository = (*Repository)(nil)
@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/smallbiznis/corebilling/internal/money"
+)
+
+// ErrInvalidTiers is returned when a price's tiers do not form a contiguous range.
+var ErrInvalidTiers = errors.New("invalid price tiers")
+
+// ChargesUsage reports whether the price charges for usage. Flat prices only
+// do when they carry tiers.
+func (p Price) ChargesUsage(tiered bool) bool {
+	return p.IsMetered() || tiered
+}
+
+// UsagePriceIndex returns the index of the price billing usage of meter among
+// the prices for which rates reports true: the price rating that meter, or
+// else the first price rating every meter. It returns -1 when no price bills
+// the meter. Rating and invoicing both pick the billing price with it.
+func UsagePriceIndex(prices []Price, rates func(i int) bool, meter string) int {
+	fallback := -1
+	for i, price := range prices {
+		if !rates(i) {
+			continue
+		}
+		switch price.MeterCode() {
+		case meter:
+			return i
+		case "":
+			if fallback < 0 {
+				fallback = i
+			}
+		}
+	}
+	return fallback
+}
+
+// UsageAmount returns the unrounded amount owed for the quantity consumed over
+// a billing period under the price's pricing model. Flat prices are billed as a
+// recurring fee and only accrue usage charges when they carry tiers. Callers
+// round to cents only when finalizing an invoice line.
+func UsageAmount(price Price, tiers []PriceTier, quantity float64) money.Amount {
+	if quantity <= 0 {
+		return money.Zero
+	}
+	switch price.PricingModel {
+	case PricingModelPerUnit:
+		return price.UnitPrice().MulQuantity(quantity)
+	case PricingModelTiered, PricingModelVolume:
+		return TieredAmount(price.EffectiveTierMode(), tiers, quantity)
+	case PricingModelPackage:
+		return price.UnitPrice().Mul(PackageCount(quantity, price.PackageSize, price.PackageRounding))
+	default:
+		if len(tiers) > 0 {
+			return TieredAmount(price.EffectiveTierMode(), tiers, quantity)
+		}
+		return money.Zero
+	}
+}
+
+// TieredAmount prices a quantity against tiers ordered by start quantity. In
+// graduated mode every reached tier charges its slice plus its flat amount; in
+// volume mode the whole quantity is charged at the reached tier only.
+func TieredAmount(mode TierMode, tiers []PriceTier, quantity float64) money.Amount {
+	if quantity <= 0 || len(tiers) == 0 {
+		return money.Zero
+	}
+	if mode == TierModeVolume {
+		tier := tiers[len(tiers)-1]
+		for _, t := range tiers {
+			if t.EndQuantity <= 0 || quantity <= t.EndQuantity {
+				tier = t
+				break
+			}
+		}
+		return tier.UnitPrice().MulQuantity(quantity).Add(money.FromCents(tier.FlatAmountCents))
+	}
+
+	total := money.Zero
+	for _, tier := range tiers {
+		if quantity <= tier.StartQuantity {
+			break
+		}
+		upper := quantity
+		if tier.EndQuantity > 0 && tier.EndQuantity < upper {
+			upper = tier.EndQuantity
+		}
+		total = total.Add(tier.UnitPrice().MulQuantity(upper - tier.StartQuantity)).Add(money.FromCents(tier.FlatAmountCents))
+	}
+	return total
+}
+
+// ValidateTiers checks that tiers start at zero, are ordered, contiguous and
+// non-overlapping, and that only the last tier is unbounded (end quantity 0).
+func ValidateTiers(tiers []PriceTier) error {
+	next := 0.0
+	for i, tier := range tiers {
+		if tier.StartQuantity != next {
+			return fmt.Errorf("%w: tier %d starts at %v, expected %v", ErrInvalidTiers, i+1, tier.StartQuantity, next)
+		}
+		if tier.UnitPrice() < 0 || tier.FlatAmountCents < 0 {
+			return fmt.Errorf("%w: tier %d has a negative amount", ErrInvalidTiers, i+1)
+		}
+		if tier.EndQuantity == 0 {
+			if i != len(tiers)-1 {
+				return fmt.Errorf("%w: only the last tier may be unbounded", ErrInvalidTiers)
+			}
+			continue
+		}
+		if tier.EndQuantity <= tier.StartQuantity {
+			return fmt.Errorf("%w: tier %d ends at %v, before its start", ErrInvalidTiers, i+1, tier.EndQuantity)
+		}
+		next = tier.EndQuantity
+	}
+	return nil
+}
+
+// PackageCount returns how many packages of size units a quantity is billed
+// as. Sizes of zero or less bill every unit as its own package.
+func PackageCount(quantity, size float64, rounding PackageRounding) int64 {
+	if quantity <= 0 {
+		return 0
+	}
+	if size <= 0 {
+		size = 1
+	}
+	// Trim float noise so exact multiples never round up to an extra package.
+	blocks := math.Round(quantity/size*1e9) / 1e9
+	switch rounding {
+	case PackageRoundingDown:
+		return int64(math.Floor(blocks))
+	case PackageRoundingNearest:
+		return int64(math.Round(blocks))
+	default:
+		return int64(math.Ceil(blocks))
+	}
+}
+
+// PeriodEnd returns the end of a billing period starting at start, advancing by
+// the price's interval and count. Monthly and yearly periods land on anchorDay,
+// clamped to the length of short months, so a period anchored on the 31st ends
+// on Feb 28 and the next one on Mar 31. An anchorDay of 0 uses start's day.
+// Prices without an interval bill monthly.
+func (p Price) PeriodEnd(start time.Time, anchorDay int) time.Time {
+	return p.shiftPeriods(start, p.intervalCount(), anchorDay)
+}
+
+// PeriodStart returns the start of the full-length period ending at end. It is
+// the inverse of PeriodEnd and is used to measure partial first periods.
+func (p Price) PeriodStart(end time.Time, anchorDay int) time.Time {
+	return p.shiftPeriods(end, -p.intervalCount(), anchorDay)
+}
+
+func (p Price) intervalCount() int {
+	if p.BillingIntervalCount <= 0 {
+		return 1
+	}
+	return int(p.BillingIntervalCount)
+}
+
+func (p Price) shiftPeriods(t time.Time, count, anchorDay int) time.Time {
+	switch p.BillingInterval {
+	case BillingIntervalDay:
+		return t.AddDate(0, 0, count)
+	case BillingIntervalWeek:
+		return t.AddDate(0, 0, 7*count)
+	case BillingIntervalYear:
+		return AddMonths(t, 12*count, anchorDay)
+	default:
+		return AddMonths(t, count, anchorDay)
+	}
+}
+
+// AddMonths moves t by months and lands on anchorDay, or the last day of the
+// target month when it is shorter. The time of day is kept. An anchorDay of 0
+// uses t's day.
+func AddMonths(t time.Time, months, anchorDay int) time.Time {
+	if anchorDay <= 0 {
+		anchorDay = t.Day()
+	}
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(anchorDay, last)-1)
+}
@@ -0,0 +1,220 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/smallbiznis/corebilling/internal/money"
+)
+
+func TestTieredAmount(t *testing.T) {
+	tiers := []PriceTier{
+		{StartQuantity: 0, EndQuantity: 100, UnitAmountCents: 10},
+		{StartQuantity: 100, EndQuantity: 1000, UnitAmountCents: 5, FlatAmountCents: 200},
+		{StartQuantity: 1000, UnitAmountCents: 2, FlatAmountCents: 500},
+	}
+
+	tests := []struct {
+		name     string
+		mode     TierMode
+		quantity float64
+		want     int64
+	}{
+		{name: "graduated first tier", mode: TierModeGraduated, quantity: 50, want: 500},
+		{name: "graduated crosses tier with flat fee", mode: TierModeGraduated, quantity: 150, want: 1000 + 250 + 200},
+		{name: "graduated unbounded tier", mode: TierModeGraduated, quantity: 1500, want: 1000 + 4500 + 200 + 1000 + 500},
+		{name: "volume first tier", mode: TierModeVolume, quantity: 100, want: 1000},
+		{name: "volume second tier", mode: TierModeVolume, quantity: 150, want: 750 + 200},
+		{name: "volume unbounded tier", mode: TierModeVolume, quantity: 1500, want: 3000 + 500},
+		{name: "zero quantity", mode: TierModeVolume, quantity: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TieredAmount(tt.mode, tiers, tt.quantity); got != money.FromCents(tt.want) {
+				t.Fatalf("TieredAmount() = %s, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsageAmountVolumeModel(t *testing.T) {
+	price := Price{PricingModel: PricingModelVolume}
+	tiers := []PriceTier{
+		{StartQuantity: 0, EndQuantity: 10, UnitAmountCents: 100},
+		{StartQuantity: 10, UnitAmountCents: 50},
+	}
+	if got := UsageAmount(price, tiers, 20); got != money.FromCents(1000) {
+		t.Fatalf("UsageAmount() = %s, want 1000", got)
+	}
+}
+
+func TestUsageAmountSubCentUnitPrice(t *testing.T) {
+	price := Price{PricingModel: PricingModelPerUnit, UnitAmount: money.MustParse("0.04")}
+	got := UsageAmount(price, nil, 12_345)
+	if got != money.MustParse("493.8") {
+		t.Fatalf("UsageAmount() = %s, want 493.8", got)
+	}
+	if got.Cents() != 494 {
+		t.Fatalf("Cents() = %d, want 494", got.Cents())
+	}
+}
+
+func TestValidateTiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		tiers   []PriceTier
+		wantErr bool
+	}{
+		{name: "no tiers"},
+		{name: "contiguous", tiers: []PriceTier{{StartQuantity: 0, EndQuantity: 10}, {StartQuantity: 10}}},
+		{name: "does not start at zero", tiers: []PriceTier{{StartQuantity: 1, EndQuantity: 10}}, wantErr: true},
+		{name: "gap", tiers: []PriceTier{{StartQuantity: 0, EndQuantity: 10}, {StartQuantity: 11}}, wantErr: true},
+		{name: "overlap", tiers: []PriceTier{{StartQuantity: 0, EndQuantity: 10}, {StartQuantity: 5}}, wantErr: true},
+		{name: "unbounded in the middle", tiers: []PriceTier{{StartQuantity: 0}, {StartQuantity: 0, EndQuantity: 10}}, wantErr: true},
+		{name: "end before start", tiers: []PriceTier{{StartQuantity: 0, EndQuantity: -1}}, wantErr: true},
+		{name: "negative amount", tiers: []PriceTier{{StartQuantity: 0, UnitAmountCents: -1}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTiers(tt.tiers)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("ValidateTiers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTiers) {
+				t.Fatalf("expected ErrInvalidTiers, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPackageAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		rounding PackageRounding
+		quantity float64
+		want     int64
+	}{
+		{name: "up zero", rounding: PackageRoundingUp, quantity: 0, want: 0},
+		{name: "up single call", rounding: PackageRoundingUp, quantity: 1, want: 500},
+		{name: "up exact block", rounding: PackageRoundingUp, quantity: 1000, want: 500},
+		{name: "up one over block", rounding: PackageRoundingUp, quantity: 1001, want: 1000},
+		{name: "down below block", rounding: PackageRoundingDown, quantity: 999, want: 0},
+		{name: "down exact block", rounding: PackageRoundingDown, quantity: 2000, want: 1000},
+		{name: "down partial block", rounding: PackageRoundingDown, quantity: 2999, want: 1000},
+		{name: "nearest below half", rounding: PackageRoundingNearest, quantity: 1499, want: 500},
+		{name: "nearest at half", rounding: PackageRoundingNearest, quantity: 1500, want: 1000},
+		{name: "nearest above half", rounding: PackageRoundingNearest, quantity: 1501, want: 1000},
+		{name: "default rounds up", quantity: 1, want: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price := Price{
+				PricingModel:    PricingModelPackage,
+				UnitAmountCents: 500,
+				PackageSize:     1000,
+				PackageRounding: tt.rounding,
+			}
+			if got := UsageAmount(price, nil, tt.quantity); got != money.FromCents(tt.want) {
+				t.Fatalf("UsageAmount() = %s, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackageCountFractionalSize(t *testing.T) {
+	if got := PackageCount(0.3, 0.1, PackageRoundingUp); got != 3 {
+		t.Fatalf("PackageCount() = %d, want 3", got)
+	}
+}
+
+func TestPricePeriodEnd(t *testing.T) {
+	start := time.Date(2025, 3, 15, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		interval int32
+		count    int32
+		want     time.Time
+	}{
+		{name: "default monthly", want: time.Date(2025, 4, 15, 9, 30, 0, 0, time.UTC)},
+		{name: "every 2 weeks", interval: BillingIntervalWeek, count: 2, want: time.Date(2025, 3, 29, 9, 30, 0, 0, time.UTC)},
+		{name: "quarterly", interval: BillingIntervalMonth, count: 3, want: time.Date(2025, 6, 15, 9, 30, 0, 0, time.UTC)},
+		{name: "yearly", interval: BillingIntervalYear, count: 1, want: time.Date(2026, 3, 15, 9, 30, 0, 0, time.UTC)},
+		{name: "daily", interval: BillingIntervalDay, count: 1, want: time.Date(2025, 3, 16, 9, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price := Price{BillingInterval: tt.interval, BillingIntervalCount: tt.count}
+			got := price.PeriodEnd(start, 0)
+			if !got.Equal(tt.want) {
+				t.Fatalf("expected %s got %s", tt.want, got)
+			}
+			if back := price.PeriodStart(got, start.Day()); !back.Equal(start) {
+				t.Fatalf("expected period start %s got %s", start, back)
+			}
+		})
+	}
+}
+
+func TestAddMonthsClampsToAnchorDay(t *testing.T) {
+	jan31 := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		from      time.Time
+		months    int
+		anchorDay int
+		want      time.Time
+	}{
+		{name: "short month", from: jan31, months: 1, anchorDay: 31, want: time.Date(2025, 2, 28, 12, 0, 0, 0, time.UTC)},
+		{name: "back to anchor", from: time.Date(2025, 2, 28, 12, 0, 0, 0, time.UTC), months: 1, anchorDay: 31, want: time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)},
+		{name: "leap year", from: time.Date(2024, 1, 30, 12, 0, 0, 0, time.UTC), months: 1, anchorDay: 30, want: time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{name: "thirty day month", from: time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC), months: 1, anchorDay: 31, want: time.Date(2025, 4, 30, 12, 0, 0, 0, time.UTC)},
+		{name: "backwards", from: time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC), months: -1, anchorDay: 31, want: time.Date(2025, 2, 28, 12, 0, 0, 0, time.UTC)},
+		{name: "across year", from: time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC), months: 1, want: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AddMonths(tt.from, tt.months, tt.anchorDay); !got.Equal(tt.want) {
+				t.Fatalf("expected %s got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUsagePriceIndex(t *testing.T) {
+	withMeter := func(model int32, meter string) Price {
+		price := Price{PricingModel: model}
+		if meter != "" {
+			price.Metadata = map[string]interface{}{MetadataMeterCode: meter}
+		}
+		return price
+	}
+	prices := []Price{
+		withMeter(PricingModelFlat, ""),
+		withMeter(PricingModelPerUnit, ""),
+		withMeter(PricingModelPerUnit, "storage"),
+		withMeter(PricingModelFlat, "seats"),
+	}
+	charges := func(i int) bool { return prices[i].ChargesUsage(false) }
+
+	tests := []struct {
+		meter string
+		want  int
+	}{
+		{meter: "storage", want: 2},
+		{meter: "api_calls", want: 1},
+		// A flat price without tiers never rates usage, even for its own meter.
+		{meter: "seats", want: 1},
+	}
+	for _, tt := range tests {
+		if got := UsagePriceIndex(prices, charges, tt.meter); got != tt.want {
+			t.Fatalf("meter %s: expected %d got %d", tt.meter, tt.want, got)
+		}
+	}
+	if got := UsagePriceIndex(prices[:1], charges, "api_calls"); got != -1 {
+		t.Fatalf("expected no price for a flat fee, got %d", got)
+	}
+}
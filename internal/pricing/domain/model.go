@@ -1,6 +1,71 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"github.com/smallbiznis/corebilling/internal/money"
+)
+
+// PricingModel values stored in prices.pricing_model.
+const (
+	PricingModelUnspecified = 0
+	PricingModelFlat        = 1
+	PricingModelPerUnit     = 2
+	PricingModelTiered      = 3
+	PricingModelVolume      = 4
+	PricingModelPackage     = 5
+)
+
+// BillingInterval values stored in prices.billing_interval.
+const (
+	BillingIntervalUnspecified = 0
+	BillingIntervalDay         = 1
+	BillingIntervalWeek        = 2
+	BillingIntervalMonth       = 3
+	BillingIntervalYear        = 4
+)
+
+// TierMode controls how a tiered price applies its tiers to a quantity.
+type TierMode string
+
+const (
+	// TierModeGraduated charges each tier for the slice of quantity within it.
+	TierModeGraduated TierMode = "graduated"
+	// TierModeVolume charges the whole quantity at the rate of the tier it reaches.
+	TierModeVolume TierMode = "volume"
+)
+
+// PackageRounding controls how partial packages are counted.
+type PackageRounding string
+
+const (
+	PackageRoundingUp      PackageRounding = "up"
+	PackageRoundingDown    PackageRounding = "down"
+	PackageRoundingNearest PackageRounding = "nearest"
+)
+
+// MigrationPolicy controls what happens to subscriptions on earlier versions
+// of a price when a new version takes effect.
+type MigrationPolicy string
+
+const (
+	// MigrationPolicyGrandfather keeps existing subscriptions on their version.
+	MigrationPolicyGrandfather MigrationPolicy = "grandfather"
+	// MigrationPolicyMigrate moves subscriptions at their next period boundary.
+	MigrationPolicyMigrate MigrationPolicy = "migrate"
+)
+
+// Metadata keys understood when creating prices and tiers.
+const (
+	MetadataMeterCode       = "meter_code"
+	MetadataTierMode        = "tier_mode"
+	MetadataFlatAmountCents = "flat_amount_cents"
+	MetadataPackageSize     = "package_size"
+	MetadataPackageRounding = "package_rounding"
+	MetadataUnitAmount      = "unit_amount_decimal"
+	MetadataCurrencyOptions = "currency_options"
+)
 
 // Product represents a purchasable item.
 type Product struct {
@@ -25,14 +90,105 @@ type Price struct {
 	PricingModel         int32
 	Currency             string
 	UnitAmountCents      int64
+	UnitAmount           money.Amount
 	BillingInterval      int32
 	BillingIntervalCount int32
+	TierMode             TierMode
+	PackageSize          float64
+	PackageRounding      PackageRounding
 	Active               bool
 	Metadata             map[string]interface{}
+	CurrencyOptions      []CurrencyOption
+	RootPriceID          int64
+	Version              int32
+	EffectiveFrom        time.Time
+	MigrationPolicy      MigrationPolicy
+	ArchivedAt           *time.Time
 	CreatedAt            time.Time
 	UpdatedAt            time.Time
 }
 
+// PriceChange describes a new version of an existing price. Zero values keep
+// the previous version's setting.
+type PriceChange struct {
+	UnitAmountCents int64
+	UnitAmount      money.Amount
+	Tiers           []PriceTier
+	CurrencyOptions []CurrencyOption
+	EffectiveFrom   time.Time
+	MigrationPolicy MigrationPolicy
+	Metadata        map[string]interface{}
+}
+
+// CurrencyOption is the amount charged for a price in an additional currency.
+type CurrencyOption struct {
+	PriceID         int64
+	Currency        string
+	UnitAmountCents int64
+	UnitAmount      money.Amount
+	CreatedAt       time.Time
+}
+
+// MeterCode returns the meter rated by this price, or empty for prices that
+// apply to every meter of a subscription.
+func (p Price) MeterCode() string {
+	if code, ok := p.Metadata[MetadataMeterCode].(string); ok {
+		return code
+	}
+	return ""
+}
+
+// IsArchived reports whether the price version no longer accepts new subscriptions.
+func (p Price) IsArchived() bool {
+	return p.ArchivedAt != nil
+}
+
+// UnitPrice returns the unit amount with sub-cent precision, falling back to
+// the whole-cent amount for prices created without a decimal amount.
+func (p Price) UnitPrice() money.Amount {
+	if p.UnitAmount != 0 {
+		return p.UnitAmount
+	}
+	return money.FromCents(p.UnitAmountCents)
+}
+
+// ForCurrency returns the price as charged in currency, using the base amount
+// or a matching currency option. It reports false when the price is not sold
+// in that currency.
+func (p Price) ForCurrency(currency string) (Price, bool) {
+	if currency == "" || strings.EqualFold(p.Currency, currency) {
+		return p, true
+	}
+	for _, option := range p.CurrencyOptions {
+		if strings.EqualFold(option.Currency, currency) {
+			p.Currency = strings.ToUpper(option.Currency)
+			p.UnitAmountCents = option.UnitAmountCents
+			p.UnitAmount = option.UnitAmount
+			return p, true
+		}
+	}
+	return Price{}, false
+}
+
+// EffectiveTierMode returns the tier mode used when evaluating the price.
+func (p Price) EffectiveTierMode() TierMode {
+	if p.PricingModel == PricingModelVolume || p.TierMode == TierModeVolume {
+		return TierModeVolume
+	}
+	return TierModeGraduated
+}
+
+// IsMetered reports whether the price charges for usage rather than a
+// recurring fee.
+func (p Price) IsMetered() bool {
+	switch p.PricingModel {
+	case PricingModelPerUnit, PricingModelTiered, PricingModelVolume, PricingModelPackage:
+		return true
+	default:
+		return false
+	}
+}
+
 // PriceTier represents tiered pricing intervals for a price.
 type PriceTier struct {
 	ID              int64
@@ -40,8 +196,18 @@ type PriceTier struct {
 	StartQuantity   float64
 	EndQuantity     float64
 	UnitAmountCents int64
+	UnitAmount      money.Amount
+	FlatAmountCents int64
 	Unit            string
 	Metadata        map[string]interface{}
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 }
+
+// UnitPrice returns the tier unit amount with sub-cent precision.
+func (t PriceTier) UnitPrice() money.Amount {
+	if t.UnitAmount != 0 {
+		return t.UnitAmount
+	}
+	return money.FromCents(t.UnitAmountCents)
+}
@@ -1,15 +1,24 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Repository defines persistence for pricing entities.
 type Repository interface {
 	CreateProduct(ctx context.Context, p Product) error
 	GetProduct(ctx context.Context, id int64) (Product, error)
 	ListProducts(ctx context.Context, tenantID int64) ([]Product, error)
-	CreatePrice(ctx context.Context, p Price) error
+	// CreatePrice stores the price with its tiers and currency options in one
+	// transaction.
+	CreatePrice(ctx context.Context, p Price, tiers []PriceTier) error
 	GetPrice(ctx context.Context, tenantID, id int64) (Price, error)
 	ListPrices(ctx context.Context, tenantID, productID int64) ([]Price, error)
-	CreatePriceTier(ctx context.Context, t PriceTier) error
 	ListPriceTiersByPriceIDs(ctx context.Context, priceIDs []int64) ([]PriceTier, error)
+	GetLatestPriceVersion(ctx context.Context, tenantID, rootPriceID int64) (Price, error)
+	// FindMigrationTarget returns the newest migrate-policy version that
+	// supersedes priceID and is effective at the given time.
+	FindMigrationTarget(ctx context.Context, tenantID, priceID int64, at time.Time) (Price, bool, error)
+	ArchivePrice(ctx context.Context, tenantID, id int64, at time.Time) error
 }
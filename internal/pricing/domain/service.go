@@ -2,12 +2,16 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/snowflake"
 	"github.com/smallbiznis/corebilling/internal/headers"
+	"github.com/smallbiznis/corebilling/internal/money"
 	pricingv1 "github.com/smallbiznis/go-genproto/smallbiznis/pricing/v1"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -129,31 +133,148 @@ func (s *Service) CreatePrice(ctx context.Context, req *pricingv1.CreatePriceReq
 		BillingIntervalCount: p.GetBillingIntervalCount(),
 		Active:               p.GetActive(),
 		Metadata:             structToMap(p.GetMetadata()),
+		RootPriceID:          id.Int64(),
+		Version:              1,
+		EffectiveFrom:        now,
+		MigrationPolicy:      MigrationPolicyGrandfather,
 		CreatedAt:            now,
 		UpdatedAt:            now,
 	}
-	if err := s.repo.CreatePrice(ctx, price); err != nil {
-		return nil, err
+	price.CurrencyOptions, err = parseCurrencyOptions(price, now)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
+	tiers := make([]PriceTier, 0, len(req.GetTiers()))
 	for _, t := range req.GetTiers() {
-		tier := PriceTier{
+		md := structToMap(t.GetMetadata())
+		unitAmount, err := parseUnitAmount(md, t.GetUnitAmountCents())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		tiers = append(tiers, PriceTier{
 			ID:              s.genID.Generate().Int64(),
 			PriceID:         price.ID,
 			StartQuantity:   t.GetStartQuantity(),
 			EndQuantity:     t.GetEndQuantity(),
 			UnitAmountCents: t.GetUnitAmountCents(),
+			UnitAmount:      unitAmount,
+			FlatAmountCents: int64Value(md[MetadataFlatAmountCents]),
 			Unit:            t.GetUnit(),
-			Metadata:        structToMap(t.GetMetadata()),
+			Metadata:        md,
 			CreatedAt:       now,
 			UpdatedAt:       now,
+		})
+	}
+	if err := preparePrice(&price, tiers); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.repo.CreatePrice(ctx, price, tiers); err != nil {
+		return nil, err
+	}
+
+	return s.toPriceProto(price), nil
+}
+
+// CreatePriceVersion publishes a new immutable version of a price. Existing
+// subscriptions stay on their version unless the change uses
+// MigrationPolicyMigrate, in which case they move at their next period
+// boundary on or after EffectiveFrom.
+func (s *Service) CreatePriceVersion(ctx context.Context, tenantID, priceID int64, change PriceChange) (Price, error) {
+	current, err := s.repo.GetPrice(ctx, tenantID, priceID)
+	if err != nil {
+		return Price{}, status.Errorf(codes.NotFound, "price not found: %v", err)
+	}
+	latest, err := s.repo.GetLatestPriceVersion(ctx, tenantID, current.RootPriceID)
+	if err != nil {
+		return Price{}, err
+	}
+	if latest.IsArchived() {
+		return Price{}, status.Error(codes.FailedPrecondition, "price is archived")
+	}
+
+	now := time.Now().UTC()
+	next := latest
+	next.ID = s.genID.Generate().Int64()
+	next.Version = latest.Version + 1
+	next.Active = true
+	next.ArchivedAt = nil
+	next.CreatedAt = now
+	next.UpdatedAt = now
+	next.EffectiveFrom = change.EffectiveFrom
+	if next.EffectiveFrom.IsZero() {
+		next.EffectiveFrom = now
+	}
+	switch change.MigrationPolicy {
+	case "", MigrationPolicyGrandfather:
+		next.MigrationPolicy = MigrationPolicyGrandfather
+	case MigrationPolicyMigrate:
+		next.MigrationPolicy = MigrationPolicyMigrate
+	default:
+		return Price{}, status.Errorf(codes.InvalidArgument, "unsupported migration_policy %q", change.MigrationPolicy)
+	}
+	if change.Metadata != nil {
+		next.Metadata = change.Metadata
+	}
+	if change.UnitAmountCents != 0 || change.UnitAmount != 0 {
+		next.UnitAmountCents = change.UnitAmountCents
+		next.UnitAmount = change.UnitAmount
+		if change.Metadata == nil {
+			// The new amount replaces the one carried over in the metadata.
+			next.Metadata = withoutKey(next.Metadata, MetadataUnitAmount)
 		}
-		if err := s.repo.CreatePriceTier(ctx, tier); err != nil {
-			return nil, err
+	}
+
+	tiers := change.Tiers
+	if tiers == nil {
+		if tiers, err = s.repo.ListPriceTiersByPriceIDs(ctx, []int64{latest.ID}); err != nil {
+			return Price{}, err
 		}
 	}
+	options := change.CurrencyOptions
+	if options == nil {
+		options = latest.CurrencyOptions
+	}
 
-	return s.toPriceProto(price), nil
+	versionTiers := make([]PriceTier, 0, len(tiers))
+	for _, tier := range tiers {
+		tier.ID = s.genID.Generate().Int64()
+		tier.PriceID = next.ID
+		tier.CreatedAt = now
+		tier.UpdatedAt = now
+		versionTiers = append(versionTiers, tier)
+	}
+	next.CurrencyOptions = make([]CurrencyOption, 0, len(options))
+	for _, option := range options {
+		option.PriceID = next.ID
+		option.CreatedAt = now
+		next.CurrencyOptions = append(next.CurrencyOptions, option)
+	}
+	if err := preparePrice(&next, versionTiers); err != nil {
+		return Price{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.repo.CreatePrice(ctx, next, versionTiers); err != nil {
+		return Price{}, err
+	}
+
+	s.logger.Info("price version created",
+		zap.Int64("price_id", next.ID),
+		zap.Int64("root_price_id", next.RootPriceID),
+		zap.Int32("version", next.Version),
+		zap.String("migration_policy", string(next.MigrationPolicy)),
+	)
+	return next, nil
+}
+
+// ArchivePriceVersion stops a price version from being used by new
+// subscriptions. Subscriptions already on it keep billing unchanged.
+func (s *Service) ArchivePriceVersion(ctx context.Context, tenantID, priceID int64) error {
+	if err := s.repo.ArchivePrice(ctx, tenantID, priceID, time.Now().UTC()); err != nil {
+		return status.Errorf(codes.NotFound, "price not found or already archived: %v", err)
+	}
+	s.logger.Info("price archived", zap.Int64("price_id", priceID))
+	return nil
 }
 
 // GetPrice loads a price.
@@ -264,6 +385,165 @@ func structToMap(s *structpb.Struct) map[string]interface{} {
 	return s.AsMap()
 }
 
+// preparePrice checks a price and its tiers before a version of it is stored,
+// and sets the fields read from its metadata: the sub-cent unit amount, the
+// tier mode and the package settings. A unit amount already set is kept
+// unless the metadata carries one.
+func preparePrice(price *Price, tiers []PriceTier) error {
+	var err error
+	if _, ok := price.Metadata[MetadataUnitAmount]; ok || price.UnitAmount == 0 {
+		if price.UnitAmount, err = parseUnitAmount(price.Metadata, price.UnitAmountCents); err != nil {
+			return err
+		}
+	}
+	if price.TierMode, err = parseTierMode(price.Metadata); err != nil {
+		return err
+	}
+	price.PackageSize, price.PackageRounding = 0, ""
+	if price.PricingModel == PricingModelPackage {
+		price.PackageSize = floatValue(price.Metadata[MetadataPackageSize])
+		if price.PackageSize <= 0 {
+			return errors.New("package prices require a positive package_size")
+		}
+		if price.PackageRounding, err = parsePackageRounding(price.Metadata); err != nil {
+			return err
+		}
+	}
+
+	if (price.PricingModel == PricingModelTiered || price.PricingModel == PricingModelVolume) && len(tiers) == 0 {
+		return errors.New("tiered prices require at least one tier")
+	}
+	if err := ValidateTiers(tiers); err != nil {
+		return err
+	}
+
+	if len(price.CurrencyOptions) > 0 && len(tiers) > 0 {
+		// Tier amounts are stored in the base currency only.
+		return errors.New("currency_options are not supported on tiered prices")
+	}
+	seen := make(map[string]bool, len(price.CurrencyOptions))
+	for _, option := range price.CurrencyOptions {
+		currency := strings.ToUpper(option.Currency)
+		switch {
+		case currency == "":
+			return errors.New("currency option requires a currency")
+		case strings.EqualFold(currency, price.Currency):
+			return fmt.Errorf("currency option %s duplicates the price currency", currency)
+		case seen[currency]:
+			return fmt.Errorf("currency option %s is listed twice", currency)
+		}
+		seen[currency] = true
+	}
+	return nil
+}
+
+func withoutKey(md map[string]interface{}, key string) map[string]interface{} {
+	out := make(map[string]interface{}, len(md))
+	for k, v := range md {
+		if k != key {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// parseUnitAmount reads an optional sub-cent unit amount from metadata, e.g.
+// {"unit_amount_decimal": "0.04"} for $0.0004, defaulting to the cent amount.
+func parseUnitAmount(md map[string]interface{}, cents int64) (money.Amount, error) {
+	switch v := md[MetadataUnitAmount].(type) {
+	case string:
+		return money.Parse(v)
+	case float64:
+		return money.Parse(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		amount := money.FromCents(cents)
+		return amount, amount.Err()
+	}
+}
+
+// parseCurrencyOptions reads per-currency amounts from metadata, e.g.
+// {"currency_options": {"IDR": {"unit_amount_cents": 15000000}, "SGD": {"unit_amount_decimal": "1350"}}}.
+func parseCurrencyOptions(price Price, now time.Time) ([]CurrencyOption, error) {
+	raw, ok := price.Metadata[MetadataCurrencyOptions].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	currencies := make([]string, 0, len(raw))
+	for currency := range raw {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	options := make([]CurrencyOption, 0, len(raw))
+	for _, currency := range currencies {
+		md, ok := raw[currency].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("currency option %s must be an object", currency)
+		}
+		cents := int64Value(md["unit_amount_cents"])
+		amount, err := parseUnitAmount(md, cents)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, CurrencyOption{
+			PriceID:         price.ID,
+			Currency:        strings.ToUpper(currency),
+			UnitAmountCents: cents,
+			UnitAmount:      amount,
+			CreatedAt:       now,
+		})
+	}
+	return options, nil
+}
+
+func parseTierMode(md map[string]interface{}) (TierMode, error) {
+	raw, _ := md[MetadataTierMode].(string)
+	switch TierMode(raw) {
+	case "", TierModeGraduated:
+		return TierModeGraduated, nil
+	case TierModeVolume:
+		return TierModeVolume, nil
+	default:
+		return "", fmt.Errorf("unsupported tier_mode %q", raw)
+	}
+}
+
+func parsePackageRounding(md map[string]interface{}) (PackageRounding, error) {
+	raw, _ := md[MetadataPackageRounding].(string)
+	switch PackageRounding(raw) {
+	case "", PackageRoundingUp:
+		return PackageRoundingUp, nil
+	case PackageRoundingDown, PackageRoundingNearest:
+		return PackageRounding(raw), nil
+	default:
+		return "", fmt.Errorf("unsupported package_rounding %q", raw)
+	}
+}
+
+func floatValue(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		parsed, _ := strconv.ParseFloat(n, 64)
+		return parsed
+	default:
+		return 0
+	}
+}
+
+func int64Value(v interface{}) int64 {
+	switch n := v.(type) {
+	case float64:
+		return int64(n)
+	case string:
+		parsed, _ := strconv.ParseInt(n, 10, 64)
+		return parsed
+	default:
+		return 0
+	}
+}
+
 func parseID(raw string) int64 {
 	id, err := strconv.ParseInt(raw, 10, 64)
 	if err != nil {
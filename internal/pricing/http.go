@@ -2,12 +2,21 @@ package pricing
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/smallbiznis/corebilling/internal/headers"
+	"github.com/smallbiznis/corebilling/internal/money"
 	"github.com/smallbiznis/corebilling/internal/pricing/domain"
 	pricingv1 "github.com/smallbiznis/go-genproto/smallbiznis/pricing/v1"
 	"go.uber.org/fx"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var ModuleHTTP = fx.Invoke(RegisterHTTP)
@@ -18,7 +27,177 @@ func RegisterHTTP(lc fx.Lifecycle, s *grpc.Server, mux *runtime.ServeMux, svc *d
 			if err := pricingv1.RegisterPricingServiceHandlerServer(ctx, mux, svc); err != nil {
 				return err
 			}
-			return nil
+			if err := mux.HandlePath(http.MethodPost, "/v1/prices/{id}/versions", createPriceVersionHandler(svc)); err != nil {
+				return err
+			}
+			return mux.HandlePath(http.MethodPost, "/v1/prices/{id}/archive", archivePriceHandler(svc))
 		},
 	})
 }
+
+type priceTierRequest struct {
+	StartQuantity     float64 `json:"start_quantity"`
+	EndQuantity       float64 `json:"end_quantity"`
+	UnitAmountCents   int64   `json:"unit_amount_cents"`
+	UnitAmountDecimal string  `json:"unit_amount_decimal"`
+	FlatAmountCents   int64   `json:"flat_amount_cents"`
+	Unit              string  `json:"unit"`
+}
+
+type currencyOptionRequest struct {
+	UnitAmountCents   int64  `json:"unit_amount_cents"`
+	UnitAmountDecimal string `json:"unit_amount_decimal"`
+}
+
+type createPriceVersionRequest struct {
+	UnitAmountCents   int64                            `json:"unit_amount_cents"`
+	UnitAmountDecimal string                           `json:"unit_amount_decimal"`
+	Tiers             []priceTierRequest               `json:"tiers"`
+	CurrencyOptions   map[string]currencyOptionRequest `json:"currency_options"`
+	EffectiveFrom     *time.Time                       `json:"effective_from"`
+	MigrationPolicy   string                           `json:"migration_policy"`
+	Metadata          map[string]interface{}           `json:"metadata"`
+}
+
+type priceVersionResponse struct {
+	ID              string    `json:"id"`
+	RootPriceID     string    `json:"root_price_id"`
+	Version         int32     `json:"version"`
+	Currency        string    `json:"currency"`
+	UnitAmountCents int64     `json:"unit_amount_cents"`
+	UnitAmount      string    `json:"unit_amount_decimal"`
+	EffectiveFrom   time.Time `json:"effective_from"`
+	MigrationPolicy string    `json:"migration_policy"`
+}
+
+func createPriceVersionHandler(svc *domain.Service) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		tenantID, priceID, err := priceRequestIDs(r, params)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var body createPriceVersionRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+		change, err := body.toChange()
+		if err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, err.Error()))
+			return
+		}
+
+		price, err := svc.CreatePriceVersion(r.Context(), tenantID, priceID, change)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, priceVersionResponse{
+			ID:              strconv.FormatInt(price.ID, 10),
+			RootPriceID:     strconv.FormatInt(price.RootPriceID, 10),
+			Version:         price.Version,
+			Currency:        price.Currency,
+			UnitAmountCents: price.UnitAmountCents,
+			UnitAmount:      price.UnitPrice().String(),
+			EffectiveFrom:   price.EffectiveFrom,
+			MigrationPolicy: string(price.MigrationPolicy),
+		})
+	}
+}
+
+func archivePriceHandler(svc *domain.Service) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		tenantID, priceID, err := priceRequestIDs(r, params)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := svc.ArchivePriceVersion(r.Context(), tenantID, priceID); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (req createPriceVersionRequest) toChange() (domain.PriceChange, error) {
+	change := domain.PriceChange{
+		UnitAmountCents: req.UnitAmountCents,
+		MigrationPolicy: domain.MigrationPolicy(req.MigrationPolicy),
+		Metadata:        req.Metadata,
+	}
+	if req.EffectiveFrom != nil {
+		change.EffectiveFrom = req.EffectiveFrom.UTC()
+	}
+	amount, err := parseAmount(req.UnitAmountDecimal, req.UnitAmountCents)
+	if err != nil {
+		return domain.PriceChange{}, err
+	}
+	if req.UnitAmountDecimal != "" || req.UnitAmountCents != 0 {
+		change.UnitAmount = amount
+	}
+	if req.Tiers != nil {
+		change.Tiers = make([]domain.PriceTier, 0, len(req.Tiers))
+		for _, t := range req.Tiers {
+			unitAmount, err := parseAmount(t.UnitAmountDecimal, t.UnitAmountCents)
+			if err != nil {
+				return domain.PriceChange{}, err
+			}
+			change.Tiers = append(change.Tiers, domain.PriceTier{
+				StartQuantity:   t.StartQuantity,
+				EndQuantity:     t.EndQuantity,
+				UnitAmountCents: t.UnitAmountCents,
+				UnitAmount:      unitAmount,
+				FlatAmountCents: t.FlatAmountCents,
+				Unit:            t.Unit,
+			})
+		}
+	}
+	if req.CurrencyOptions != nil {
+		change.CurrencyOptions = make([]domain.CurrencyOption, 0, len(req.CurrencyOptions))
+		for currency, o := range req.CurrencyOptions {
+			unitAmount, err := parseAmount(o.UnitAmountDecimal, o.UnitAmountCents)
+			if err != nil {
+				return domain.PriceChange{}, err
+			}
+			change.CurrencyOptions = append(change.CurrencyOptions, domain.CurrencyOption{
+				Currency:        strings.ToUpper(currency),
+				UnitAmountCents: o.UnitAmountCents,
+				UnitAmount:      unitAmount,
+			})
+		}
+	}
+	return change, nil
+}
+
+func parseAmount(decimal string, cents int64) (money.Amount, error) {
+	if decimal != "" {
+		return money.Parse(decimal)
+	}
+	amount := money.FromCents(cents)
+	return amount, amount.Err()
+}
+
+func priceRequestIDs(r *http.Request, params map[string]string) (int64, int64, error) {
+	tenantID, err := strconv.ParseInt(r.Header.Get(headers.HeaderTenantID), 10, 64)
+	if err != nil {
+		return 0, 0, status.Error(codes.InvalidArgument, "invalid tenant_id")
+	}
+	priceID, err := strconv.ParseInt(params["id"], 10, 64)
+	if err != nil {
+		return 0, 0, status.Error(codes.InvalidArgument, "invalid price id")
+	}
+	return tenantID, priceID, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	writeJSON(w, runtime.HTTPStatusFromCode(st.Code()), map[string]string{"error": st.Message()})
+}
@@ -2,10 +2,14 @@ package sqlc
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/smallbiznis/corebilling/internal/money"
 	"github.com/smallbiznis/corebilling/internal/pricing/domain"
 )
 
@@ -53,29 +57,86 @@ func (r *Repository) ListProducts(ctx context.Context, tenantID int64) ([]domain
 	return items, nil
 }
 
-func (r *Repository) CreatePrice(ctx context.Context, p domain.Price) error {
-	_, err := r.pool.Exec(ctx, `INSERT INTO prices (id, tenant_id, product_id, code, lookup_key, pricing_model, currency, unit_amount_cents, billing_interval, billing_interval_count, active, metadata, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)`,
-		p.ID, p.TenantID, p.ProductID, p.Code, p.LookupKey, p.PricingModel, p.Currency, p.UnitAmountCents, p.BillingInterval, p.BillingIntervalCount, p.Active, p.Metadata, p.CreatedAt, p.UpdatedAt)
+const priceColumns = `id, tenant_id, product_id, code, lookup_key, pricing_model, currency, unit_amount_cents, unit_amount_decimal::TEXT, billing_interval, billing_interval_count, tier_mode, package_size, package_rounding, root_price_id, version, effective_from, migration_policy, archived_at, active, metadata, created_at, updated_at`
+
+const tierColumns = `id, price_id, start_quantity, end_quantity, unit_amount_cents, unit_amount_decimal::TEXT, flat_amount_cents, unit, metadata, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// CreatePrice stores the price with its tiers and currency options in one
+// transaction.
+func (r *Repository) CreatePrice(ctx context.Context, p domain.Price, tiers []domain.PriceTier) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := createPrice(ctx, tx, p); err != nil {
+		return err
+	}
+	for _, tier := range tiers {
+		if err := createPriceTier(ctx, tx, tier); err != nil {
+			return err
+		}
+	}
+	for _, option := range p.CurrencyOptions {
+		if err := createCurrencyOption(ctx, tx, option); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func createPrice(ctx context.Context, tx pgx.Tx, p domain.Price) error {
+	rootID, version, effectiveFrom := p.RootPriceID, p.Version, p.EffectiveFrom
+	if rootID == 0 {
+		rootID = p.ID
+	}
+	if version == 0 {
+		version = 1
+	}
+	if effectiveFrom.IsZero() {
+		effectiveFrom = p.CreatedAt
+	}
+	policy := p.MigrationPolicy
+	if policy == "" {
+		policy = domain.MigrationPolicyGrandfather
+	}
+	_, err := tx.Exec(ctx, `INSERT INTO prices (id, tenant_id, product_id, code, lookup_key, pricing_model, currency, unit_amount_cents, unit_amount_decimal, billing_interval, billing_interval_count, tier_mode, package_size, package_rounding, root_price_id, version, effective_from, migration_policy, active, metadata, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9::NUMERIC,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22)`,
+		p.ID, p.TenantID, p.ProductID, p.Code, p.LookupKey, p.PricingModel, p.Currency, p.UnitAmountCents, p.UnitPrice().String(), p.BillingInterval, p.BillingIntervalCount, tierModeOrDefault(p.TierMode), p.PackageSize, packageRoundingOrDefault(p.PackageRounding), rootID, version, effectiveFrom, policy, p.Active, p.Metadata, p.CreatedAt, p.UpdatedAt)
 	return err
 }
 
-func (r *Repository) CreatePriceTier(ctx context.Context, t domain.PriceTier) error {
-	_, err := r.pool.Exec(ctx, `INSERT INTO price_tiers (id, price_id, start_quantity, end_quantity, unit_amount_cents, unit, metadata, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
-		t.ID, t.PriceID, t.StartQuantity, t.EndQuantity, t.UnitAmountCents, t.Unit, t.Metadata, t.CreatedAt, t.UpdatedAt)
+func createPriceTier(ctx context.Context, tx pgx.Tx, t domain.PriceTier) error {
+	_, err := tx.Exec(ctx, `INSERT INTO price_tiers (id, price_id, start_quantity, end_quantity, unit_amount_cents, unit_amount_decimal, flat_amount_cents, unit, metadata, created_at, updated_at) VALUES ($1,$2,$3,$4,$5,$6::NUMERIC,$7,$8,$9,$10,$11)`,
+		t.ID, t.PriceID, t.StartQuantity, t.EndQuantity, t.UnitAmountCents, t.UnitPrice().String(), t.FlatAmountCents, t.Unit, t.Metadata, t.CreatedAt, t.UpdatedAt)
 	return err
 }
 
 func (r *Repository) GetPrice(ctx context.Context, tenantId, id int64) (domain.Price, error) {
-	row := r.pool.QueryRow(ctx, `SELECT id, tenant_id, product_id, code, lookup_key, pricing_model, currency, unit_amount_cents, billing_interval, billing_interval_count, active, metadata, created_at, updated_at FROM prices WHERE tenant_id=$1 AND id=$2`, tenantId, id)
-	var p domain.Price
-	if err := row.Scan(&p.ID, &p.TenantID, &p.ProductID, &p.Code, &p.LookupKey, &p.PricingModel, &p.Currency, &p.UnitAmountCents, &p.BillingInterval, &p.BillingIntervalCount, &p.Active, &p.Metadata, &p.CreatedAt, &p.UpdatedAt); err != nil {
+	row := r.pool.QueryRow(ctx, `SELECT `+priceColumns+` FROM prices WHERE tenant_id=$1 AND id=$2`, tenantId, id)
+	return r.withCurrencyOptions(ctx, row)
+}
+
+// withCurrencyOptions scans a single price and attaches its currency options.
+func (r *Repository) withCurrencyOptions(ctx context.Context, row rowScanner) (domain.Price, error) {
+	p, err := scanPrice(row)
+	if err != nil {
 		return domain.Price{}, err
 	}
+	options, err := r.listCurrencyOptions(ctx, []int64{p.ID})
+	if err != nil {
+		return domain.Price{}, err
+	}
+	p.CurrencyOptions = options[p.ID]
 	return p, nil
 }
 
 func (r *Repository) ListPrices(ctx context.Context, tenantID, productID int64) ([]domain.Price, error) {
-	rows, err := r.pool.Query(ctx, `SELECT id, tenant_id, product_id, code, lookup_key, pricing_model, currency, unit_amount_cents, billing_interval, billing_interval_count, active, metadata, created_at, updated_at FROM prices WHERE tenant_id=$1 AND product_id=$2 ORDER BY created_at DESC`, tenantID, productID)
+	rows, err := r.pool.Query(ctx, `SELECT `+priceColumns+` FROM prices WHERE tenant_id=$1 AND product_id=$2 ORDER BY created_at DESC`, tenantID, productID)
 	if err != nil {
 		return nil, err
 	}
@@ -83,8 +144,8 @@ func (r *Repository) ListPrices(ctx context.Context, tenantID, productID int64)
 
 	var items []domain.Price
 	for rows.Next() {
-		var p domain.Price
-		if err := rows.Scan(&p.ID, &p.TenantID, &p.ProductID, &p.Code, &p.LookupKey, &p.PricingModel, &p.Currency, &p.UnitAmountCents, &p.BillingInterval, &p.BillingIntervalCount, &p.Active, &p.Metadata, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		p, err := scanPrice(rows)
+		if err != nil {
 			return nil, err
 		}
 		items = append(items, p)
@@ -92,15 +153,100 @@ func (r *Repository) ListPrices(ctx context.Context, tenantID, productID int64)
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+
+	ids := make([]int64, 0, len(items))
+	for _, p := range items {
+		ids = append(ids, p.ID)
+	}
+	options, err := r.listCurrencyOptions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].CurrencyOptions = options[items[i].ID]
+	}
 	return items, nil
 }
 
+func (r *Repository) GetLatestPriceVersion(ctx context.Context, tenantID, rootPriceID int64) (domain.Price, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+priceColumns+` FROM prices WHERE tenant_id=$1 AND root_price_id=$2 ORDER BY version DESC LIMIT 1`, tenantID, rootPriceID)
+	return r.withCurrencyOptions(ctx, row)
+}
+
+func (r *Repository) FindMigrationTarget(ctx context.Context, tenantID, priceID int64, at time.Time) (domain.Price, bool, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+priceColumns+` FROM prices
+		WHERE tenant_id=$1
+		  AND root_price_id = (SELECT root_price_id FROM prices WHERE tenant_id=$1 AND id=$2)
+		  AND version > (SELECT version FROM prices WHERE tenant_id=$1 AND id=$2)
+		  AND migration_policy=$3
+		  AND effective_from <= $4
+		  AND archived_at IS NULL
+		ORDER BY version DESC
+		LIMIT 1`, tenantID, priceID, domain.MigrationPolicyMigrate, at)
+	p, err := r.withCurrencyOptions(ctx, row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Price{}, false, nil
+	}
+	if err != nil {
+		return domain.Price{}, false, err
+	}
+	return p, true, nil
+}
+
+func (r *Repository) ArchivePrice(ctx context.Context, tenantID, id int64, at time.Time) error {
+	tag, err := r.pool.Exec(ctx, `UPDATE prices SET archived_at=$3, active=FALSE, updated_at=$3 WHERE tenant_id=$1 AND id=$2 AND archived_at IS NULL`, tenantID, id, at)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func createCurrencyOption(ctx context.Context, tx pgx.Tx, o domain.CurrencyOption) error {
+	_, err := tx.Exec(ctx, `INSERT INTO price_currency_options (price_id, currency, unit_amount_cents, unit_amount_decimal, created_at) VALUES ($1,$2,$3,$4::NUMERIC,$5)`,
+		o.PriceID, o.Currency, o.UnitAmountCents, o.UnitAmount.String(), o.CreatedAt)
+	return err
+}
+
+func (r *Repository) listCurrencyOptions(ctx context.Context, priceIDs []int64) (map[int64][]domain.CurrencyOption, error) {
+	if len(priceIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `SELECT price_id, currency, unit_amount_cents, unit_amount_decimal::TEXT, created_at FROM price_currency_options WHERE price_id = ANY($1::BIGINT[]) ORDER BY price_id, currency`, buildInt64Array(priceIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := make(map[int64][]domain.CurrencyOption)
+	for rows.Next() {
+		var (
+			o          domain.CurrencyOption
+			unitAmount string
+		)
+		if err := rows.Scan(&o.PriceID, &o.Currency, &o.UnitAmountCents, &unitAmount, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		if o.UnitAmount, err = money.Parse(unitAmount); err != nil {
+			return nil, err
+		}
+		options[o.PriceID] = append(options[o.PriceID], o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
 func (r *Repository) ListPriceTiersByPriceIDs(ctx context.Context, priceIDs []int64) ([]domain.PriceTier, error) {
 	if len(priceIDs) == 0 {
 		return nil, nil
 	}
 
-	rows, err := r.pool.Query(ctx, `SELECT id, price_id, start_quantity, end_quantity, unit_amount_cents, unit, metadata, created_at, updated_at FROM price_tiers WHERE price_id = ANY($1::BIGINT[]) ORDER BY price_id, start_quantity`, buildInt64Array(priceIDs))
+	rows, err := r.pool.Query(ctx, `SELECT `+tierColumns+` FROM price_tiers WHERE price_id = ANY($1::BIGINT[]) ORDER BY price_id, start_quantity`, buildInt64Array(priceIDs))
 	if err != nil {
 		return nil, err
 	}
@@ -108,8 +254,14 @@ func (r *Repository) ListPriceTiersByPriceIDs(ctx context.Context, priceIDs []in
 
 	var tiers []domain.PriceTier
 	for rows.Next() {
-		var t domain.PriceTier
-		if err := rows.Scan(&t.ID, &t.PriceID, &t.StartQuantity, &t.EndQuantity, &t.UnitAmountCents, &t.Unit, &t.Metadata, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		var (
+			t          domain.PriceTier
+			unitAmount string
+		)
+		if err := rows.Scan(&t.ID, &t.PriceID, &t.StartQuantity, &t.EndQuantity, &t.UnitAmountCents, &unitAmount, &t.FlatAmountCents, &t.Unit, &t.Metadata, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if t.UnitAmount, err = money.Parse(unitAmount); err != nil {
 			return nil, err
 		}
 		tiers = append(tiers, t)
@@ -120,6 +272,36 @@ func (r *Repository) ListPriceTiersByPriceIDs(ctx context.Context, priceIDs []in
 	return tiers, nil
 }
 
+func scanPrice(row rowScanner) (domain.Price, error) {
+	var (
+		p          domain.Price
+		unitAmount string
+	)
+	if err := row.Scan(&p.ID, &p.TenantID, &p.ProductID, &p.Code, &p.LookupKey, &p.PricingModel, &p.Currency, &p.UnitAmountCents, &unitAmount, &p.BillingInterval, &p.BillingIntervalCount, &p.TierMode, &p.PackageSize, &p.PackageRounding, &p.RootPriceID, &p.Version, &p.EffectiveFrom, &p.MigrationPolicy, &p.ArchivedAt, &p.Active, &p.Metadata, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return domain.Price{}, err
+	}
+	amount, err := money.Parse(unitAmount)
+	if err != nil {
+		return domain.Price{}, err
+	}
+	p.UnitAmount = amount
+	return p, nil
+}
+
+func tierModeOrDefault(mode domain.TierMode) domain.TierMode {
+	if mode == "" {
+		return domain.TierModeGraduated
+	}
+	return mode
+}
+
+func packageRoundingOrDefault(rounding domain.PackageRounding) domain.PackageRounding {
+	if rounding == "" {
+		return domain.PackageRoundingUp
+	}
+	return rounding
+}
+
 func buildInt64Array(ids []int64) string {
 	var b strings.Builder
 	b.WriteByte('{')
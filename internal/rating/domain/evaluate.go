@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"github.com/smallbiznis/corebilling/internal/money"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+)
+
+// incrementalAmount prices a single usage event as the change in the period's
+// cumulative amount, so the sum of rated events always matches the period total.
+// The amount is negative when the event lowers the total, e.g. when volume
+// pricing moves the whole period into a cheaper tier; clamping it would make
+// the events add up to more than the period is invoiced for. Quantities are
+// added as fixed-point decimals, like the invoice engine sums a period.
+func incrementalAmount(price pricing.Price, tiers []pricing.PriceTier, before, value money.Quantity) money.Amount {
+	after := before.Add(value)
+	if after.Err() != nil {
+		return money.Invalid
+	}
+	return pricing.UsageAmount(price, tiers, after.Float64()).Sub(pricing.UsageAmount(price, tiers, before.Float64()))
+}
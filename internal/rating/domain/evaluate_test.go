@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/smallbiznis/corebilling/internal/money"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+)
+
+func TestIncrementalAmount(t *testing.T) {
+	tiers := []pricing.PriceTier{
+		{StartQuantity: 0, EndQuantity: 100, UnitAmountCents: 10},
+		{StartQuantity: 100, EndQuantity: 0, UnitAmountCents: 5},
+	}
+	fractionalTiers := []pricing.PriceTier{
+		{StartQuantity: 0, EndQuantity: 0.3, UnitAmountCents: 1000},
+		{StartQuantity: 0.3, EndQuantity: 0, UnitAmountCents: 500},
+	}
+
+	tests := []struct {
+		name   string
+		price  pricing.Price
+		tiers  []pricing.PriceTier
+		before float64
+		value  float64
+		want   int64
+	}{
+		{name: "flat", price: pricing.Price{PricingModel: pricing.PricingModelFlat, UnitAmountCents: 999}, value: 10, want: 0},
+		{name: "per unit", price: pricing.Price{PricingModel: pricing.PricingModelPerUnit, UnitAmountCents: 25}, before: 3, value: 4, want: 100},
+		{name: "graduated across tiers", price: pricing.Price{PricingModel: pricing.PricingModelTiered}, tiers: tiers, before: 90, value: 20, want: 150},
+		// Crossing into a cheaper volume tier lowers the period total, so the
+		// event is rated negative on purpose; see incrementalAmount.
+		{name: "volume reprices earlier usage at the cheaper tier", price: pricing.Price{PricingModel: pricing.PricingModelVolume}, tiers: tiers, before: 90, value: 20, want: -350},
+		// 0.1 + 0.2 exceeds 0.3 in float64 and would reach the cheaper tier.
+		{name: "fractional usage stays in its tier", price: pricing.Price{PricingModel: pricing.PricingModelVolume}, tiers: fractionalTiers, before: 0.1, value: 0.2, want: 200},
+		{name: "package rounds up", price: pricing.Price{PricingModel: pricing.PricingModelPackage, UnitAmountCents: 500, PackageSize: 10}, before: 8, value: 5, want: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := incrementalAmount(tt.price, tt.tiers, money.QuantityFromFloat(tt.before), money.QuantityFromFloat(tt.value)); got != money.FromCents(tt.want) {
+				t.Fatalf("incrementalAmount() = %s, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,21 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"github.com/smallbiznis/corebilling/internal/money"
+)
 
 // RatingResult represents calculation of usage into charges.
 type RatingResult struct {
-	ID          string
-	UsageID     string
-	AmountCents int64
-	Currency    string
-	CreatedAt   time.Time
+	ID             string
+	TenantID       string
+	UsageID        string
+	SubscriptionID string
+	PriceID        string
+	MeterCode      string
+	Quantity       float64
+	Amount         money.Amount
+	Currency       string
+	CreatedAt      time.Time
 }
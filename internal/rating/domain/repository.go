@@ -4,6 +4,8 @@ import "context"
 
 // Repository defines rating persistence.
 type Repository interface {
-	Create(ctx context.Context, rating RatingResult) error
+	// Create stores a rating result and returns the stored one, which is the
+	// earlier result when the usage was already rated against the price.
+	Create(ctx context.Context, rating RatingResult) (RatingResult, error)
 	GetByUsage(ctx context.Context, usageID string) ([]RatingResult, error)
 }
@@ -2,32 +2,203 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/bwmarrin/snowflake"
+	"github.com/smallbiznis/corebilling/internal/money"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+	subscription "github.com/smallbiznis/corebilling/internal/subscription/domain"
+	usage "github.com/smallbiznis/corebilling/internal/usage/domain"
 	"go.uber.org/zap"
 )
 
+// ErrNoPriceForMeter is returned when the subscription price does not bill the usage meter.
+var ErrNoPriceForMeter = errors.New("no price configured for meter")
+
 // Service handles rating operations.
 type Service struct {
-	repo   Repository
-	logger *zap.Logger
+	repo             Repository
+	subscriptionRepo subscription.Repository
+	pricingRepo      pricing.Repository
+	usageRepo        usage.Repository
+	logger           *zap.Logger
+
+	genID *snowflake.Node
 }
 
 // NewService constructs rating service.
-func NewService(repo Repository, logger *zap.Logger) *Service {
-	return &Service{repo: repo, logger: logger.Named("rating.service")}
+func NewService(
+	repo Repository,
+	subscriptionRepo subscription.Repository,
+	pricingRepo pricing.Repository,
+	usageRepo usage.Repository,
+	logger *zap.Logger,
+	genID *snowflake.Node,
+) *Service {
+	return &Service{
+		repo:             repo,
+		subscriptionRepo: subscriptionRepo,
+		pricingRepo:      pricingRepo,
+		usageRepo:        usageRepo,
+		logger:           logger.Named("rating.service"),
+		genID:            genID,
+	}
 }
 
-// Create stores a rating result.
-func (s *Service) Create(ctx context.Context, rating RatingResult) error {
-	if err := s.repo.Create(ctx, rating); err != nil {
+// Create stores a rating result and returns the stored one. Rating a usage
+// record against a price again returns the first result.
+func (s *Service) Create(ctx context.Context, rating RatingResult) (RatingResult, error) {
+	stored, err := s.repo.Create(ctx, rating)
+	if err != nil {
 		s.logger.Error("create rating", zap.Error(err))
-		return err
+		return RatingResult{}, err
 	}
-	s.logger.Info("rating created", zap.String("id", rating.ID))
-	return nil
+	s.logger.Info("rating created", zap.String("id", stored.ID))
+	return stored, nil
 }
 
 // GetByUsage returns rating results for a usage record.
 func (s *Service) GetByUsage(ctx context.Context, usageID string) ([]RatingResult, error) {
 	return s.repo.GetByUsage(ctx, usageID)
 }
+
+// RateUsage prices a stored usage record against its subscription's price and
+// persists the result. The record is charged the difference between the
+// period's cumulative amount with and without it, so tiered and package
+// models stay correct regardless of how usage is split across events.
+// Usage is rated on the subscription item whose price bills the meter. Usage
+// of paused subscriptions is not rated.
+func (s *Service) RateUsage(ctx context.Context, record usage.UsageRecord) (*RatingResult, error) {
+	if record.SubscriptionID == "" {
+		return nil, errors.New("subscription_id required")
+	}
+
+	sub, err := s.subscriptionRepo.GetByID(ctx, record.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Pause != nil {
+		return nil, subscription.ErrSubscriptionPaused
+	}
+	price, tiers, err := s.meterPrice(ctx, sub, record)
+	if err != nil {
+		return nil, err
+	}
+
+	// Usage recorded so far in the period, including this record. Records
+	// sharing its timestamp count as earlier up to its id.
+	periodStart := sub.CurrentPeriodStart
+	if periodStart.IsZero() || record.RecordedAt.Before(periodStart) {
+		periodStart = record.RecordedAt
+	}
+	consumed, err := s.usageRepo.SumValue(ctx, usage.ListUsageFilter{
+		TenantID:       record.TenantID,
+		SubscriptionID: record.SubscriptionID,
+		MeterCode:      record.MeterCode,
+		From:           periodStart,
+		To:             record.RecordedAt,
+		ThroughID:      record.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	value := money.QuantityFromFloat(record.Value)
+	before := consumed.Sub(value)
+	if before < 0 {
+		before = 0
+	}
+
+	amount := incrementalAmount(price, tiers, before, value)
+	if err := amount.Err(); err != nil {
+		return nil, err
+	}
+	result := RatingResult{
+		ID:             s.genID.Generate().String(),
+		TenantID:       record.TenantID,
+		UsageID:        record.ID,
+		SubscriptionID: record.SubscriptionID,
+		PriceID:        strconv.FormatInt(price.ID, 10),
+		MeterCode:      record.MeterCode,
+		Quantity:       record.Value,
+		Amount:         amount,
+		Currency:       strings.ToUpper(price.Currency),
+		CreatedAt:      time.Now().UTC(),
+	}
+	stored, err := s.Create(ctx, result)
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// meterPrice returns the price, with its tiers, that bills the record's meter
+// on the subscription, chosen with pricing.UsagePriceIndex like the invoice
+// engine does. It returns ErrNoPriceForMeter when no price charges for the
+// meter's usage, e.g. when the subscription only has flat fees.
+func (s *Service) meterPrice(ctx context.Context, sub subscription.Subscription, record usage.UsageRecord) (pricing.Price, []pricing.PriceTier, error) {
+	items, err := s.subscriptionRepo.ListItems(ctx, subscription.ItemFilter{SubscriptionID: sub.ID, RemovedAfter: record.RecordedAt})
+	if err != nil {
+		return pricing.Price{}, nil, err
+	}
+	var prices []pricing.Price
+	var priceIDs []int64
+	for _, item := range sub.BillableItems(items) {
+		if item.ID != "" && item.CreatedAt.After(record.RecordedAt) {
+			continue
+		}
+		price, err := s.loadPrice(ctx, sub, item.PriceID)
+		if err != nil {
+			return pricing.Price{}, nil, err
+		}
+		prices = append(prices, price)
+		priceIDs = append(priceIDs, price.ID)
+	}
+	if len(prices) == 0 {
+		return pricing.Price{}, nil, ErrNoPriceForMeter
+	}
+	tiers, err := s.pricingRepo.ListPriceTiersByPriceIDs(ctx, priceIDs)
+	if err != nil {
+		return pricing.Price{}, nil, err
+	}
+	tiersOf := func(price pricing.Price) []pricing.PriceTier {
+		var matched []pricing.PriceTier
+		for _, tier := range tiers {
+			if tier.PriceID == price.ID {
+				matched = append(matched, tier)
+			}
+		}
+		return matched
+	}
+
+	chosen := pricing.UsagePriceIndex(prices, func(i int) bool {
+		return prices[i].ChargesUsage(len(tiersOf(prices[i])) > 0)
+	}, record.MeterCode)
+	if chosen < 0 {
+		return pricing.Price{}, nil, ErrNoPriceForMeter
+	}
+	return prices[chosen], tiersOf(prices[chosen]), nil
+}
+
+func (s *Service) loadPrice(ctx context.Context, sub subscription.Subscription, id string) (pricing.Price, error) {
+	tenantID, err := strconv.ParseInt(sub.TenantID, 10, 64)
+	if err != nil {
+		return pricing.Price{}, errors.New("invalid tenant_id")
+	}
+	priceID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return pricing.Price{}, ErrNoPriceForMeter
+	}
+	price, err := s.pricingRepo.GetPrice(ctx, tenantID, priceID)
+	if err != nil {
+		return pricing.Price{}, err
+	}
+	resolved, ok := price.ForCurrency(sub.Currency)
+	if !ok {
+		return pricing.Price{}, fmt.Errorf("price %d has no %s amount", price.ID, sub.Currency)
+	}
+	return resolved, nil
+}
@@ -2,9 +2,12 @@ package sqlc
 
 import (
 	"context"
+	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/smallbiznis/corebilling/internal/money"
 	"github.com/smallbiznis/corebilling/internal/rating/domain"
 )
 
@@ -18,16 +21,51 @@ func NewRepository(pool *pgxpool.Pool) domain.Repository {
 	return &Repository{pool: pool}
 }
 
-// Create inserts rating result.
-func (r *Repository) Create(ctx context.Context, rating domain.RatingResult) error {
-	_, err := r.pool.Exec(ctx, `INSERT INTO rating_results (id, usage_id, amount_cents, currency, created_at) VALUES ($1,$2,$3,$4,$5)`,
-		rating.ID, rating.UsageID, rating.AmountCents, rating.Currency, rating.CreatedAt)
-	return err
+// Create inserts rating result. Re-rating the same usage against the same
+// price is a no-op that returns the stored result.
+func (r *Repository) Create(ctx context.Context, rating domain.RatingResult) (domain.RatingResult, error) {
+	var id string
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO rating_results (
+			id, tenant_id, usage_id, subscription_id, price_id, meter_code,
+			quantity, amount, amount_cents, currency, created_at, updated_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8::NUMERIC,$9,$10,$11,$11)
+		ON CONFLICT (usage_id, price_id) DO NOTHING
+		RETURNING id::TEXT
+	`,
+		rating.ID,
+		rating.TenantID,
+		rating.UsageID,
+		nullIfEmpty(rating.SubscriptionID),
+		rating.PriceID,
+		nullIfEmpty(rating.MeterCode),
+		rating.Quantity,
+		rating.Amount.String(),
+		rating.Amount.Cents(),
+		rating.Currency,
+		rating.CreatedAt,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return scanRating(r.pool.QueryRow(ctx, `
+			SELECT `+ratingColumns+`
+			FROM rating_results
+			WHERE usage_id=$1 AND price_id=$2
+		`, rating.UsageID, rating.PriceID))
+	}
+	if err != nil {
+		return domain.RatingResult{}, err
+	}
+	return rating, nil
 }
 
 // GetByUsage returns ratings for usage.
 func (r *Repository) GetByUsage(ctx context.Context, usageID string) ([]domain.RatingResult, error) {
-	rows, err := r.pool.Query(ctx, `SELECT id, usage_id, amount_cents, currency, created_at FROM rating_results WHERE usage_id=$1 ORDER BY created_at DESC`, usageID)
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+ratingColumns+`
+		FROM rating_results
+		WHERE usage_id=$1
+		ORDER BY created_at DESC
+	`, usageID)
 	if err != nil {
 		return nil, err
 	}
@@ -35,8 +73,8 @@ func (r *Repository) GetByUsage(ctx context.Context, usageID string) ([]domain.R
 
 	var ratings []domain.RatingResult
 	for rows.Next() {
-		var rRes domain.RatingResult
-		if err := rows.Scan(&rRes.ID, &rRes.UsageID, &rRes.AmountCents, &rRes.Currency, &rRes.CreatedAt); err != nil {
+		rRes, err := scanRating(rows)
+		if err != nil {
 			return nil, err
 		}
 		ratings = append(ratings, rRes)
@@ -47,4 +85,42 @@ func (r *Repository) GetByUsage(ctx context.Context, usageID string) ([]domain.R
 	return ratings, nil
 }
 
+// ratingColumns are the columns read by scanRating.
+const ratingColumns = `
+	id, tenant_id, usage_id, COALESCE(subscription_id::TEXT, ''), price_id,
+	COALESCE(meter_code, ''), quantity, amount::TEXT, currency, created_at`
+
+func scanRating(row pgx.Row) (domain.RatingResult, error) {
+	var (
+		rRes   domain.RatingResult
+		amount string
+	)
+	if err := row.Scan(
+		&rRes.ID,
+		&rRes.TenantID,
+		&rRes.UsageID,
+		&rRes.SubscriptionID,
+		&rRes.PriceID,
+		&rRes.MeterCode,
+		&rRes.Quantity,
+		&amount,
+		&rRes.Currency,
+		&rRes.CreatedAt,
+	); err != nil {
+		return domain.RatingResult{}, err
+	}
+	var err error
+	if rRes.Amount, err = money.Parse(amount); err != nil {
+		return domain.RatingResult{}, err
+	}
+	return rRes, nil
+}
+
+func nullIfEmpty(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
 var _ domain.Repository = (*Repository)(nil)
@@ -0,0 +1,64 @@
+package subscription
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	customer "github.com/smallbiznis/corebilling/internal/customer/domain"
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+	"github.com/smallbiznis/corebilling/internal/subscription/domain"
+	tenant "github.com/smallbiznis/corebilling/internal/tenant/domain"
+)
+
+// priceCatalog resolves subscription prices from the pricing, customer and tenant stores.
+type priceCatalog struct {
+	prices    pricing.Repository
+	customers customer.Repository
+	tenants   tenant.Repository
+}
+
+// NewPriceCatalog constructs the catalog used to validate subscription prices.
+func NewPriceCatalog(prices pricing.Repository, customers customer.Repository, tenants tenant.Repository) domain.PriceCatalog {
+	return &priceCatalog{prices: prices, customers: customers, tenants: tenants}
+}
+
+func (c *priceCatalog) GetPrice(ctx context.Context, tenantID, priceID string) (pricing.Price, error) {
+	tid, err := strconv.ParseInt(tenantID, 10, 64)
+	if err != nil {
+		return pricing.Price{}, err
+	}
+	pid, err := strconv.ParseInt(priceID, 10, 64)
+	if err != nil {
+		return pricing.Price{}, err
+	}
+	return c.prices.GetPrice(ctx, tid, pid)
+}
+
+func (c *priceCatalog) MigrationTarget(ctx context.Context, tenantID, priceID string, at time.Time) (pricing.Price, bool, error) {
+	tid, err := strconv.ParseInt(tenantID, 10, 64)
+	if err != nil {
+		return pricing.Price{}, false, err
+	}
+	pid, err := strconv.ParseInt(priceID, 10, 64)
+	if err != nil {
+		return pricing.Price{}, false, err
+	}
+	return c.prices.FindMigrationTarget(ctx, tid, pid, at)
+}
+
+func (c *priceCatalog) BillingCurrency(ctx context.Context, tenantID, customerID string) (string, error) {
+	cust, err := c.customers.GetByID(ctx, customerID)
+	if err != nil {
+		return "", err
+	}
+	if cust.Currency != "" {
+		return strings.ToUpper(cust.Currency), nil
+	}
+	t, err := c.tenants.GetByID(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(t.DefaultCurrency), nil
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
+	"go.uber.org/zap"
+)
+
+// Cancel ends the subscription at at. Pending price changes and pauses are
+// dropped. The returned event records the status change.
+func (s *Service) Cancel(ctx context.Context, sub Subscription, at time.Time) (Subscription, *eventv1.Event, error) {
+	evt, err := sub.ApplyLifecycle(SubscriptionLifecycleCanceled, subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_CANCELED)
+	if err != nil {
+		return Subscription{}, nil, err
+	}
+	sub.AutoRenew = false
+	sub.CancelAt = &at
+	sub.CanceledAt = &at
+	sub.ScheduledChange = nil
+	sub.Pause = nil
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.Update(ctx, sub); err != nil {
+		return Subscription{}, nil, err
+	}
+	s.logger.Info("subscription canceled", zap.String("subscription_id", sub.ID), zap.Time("canceled_at", at))
+	return sub, evt, nil
+}
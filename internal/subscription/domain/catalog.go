@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+)
+
+var (
+	// ErrCurrencyMismatch is returned when a price is not sold in the customer's billing currency.
+	ErrCurrencyMismatch = errors.New("price not available in billing currency")
+	// ErrPriceArchived is returned when subscribing to an archived price version.
+	ErrPriceArchived = errors.New("price is archived")
+)
+
+// PriceCatalog resolves the prices and billing currency a subscription is charged with.
+type PriceCatalog interface {
+	GetPrice(ctx context.Context, tenantID, priceID string) (pricing.Price, error)
+	// BillingCurrency returns the customer's currency, falling back to the tenant default.
+	BillingCurrency(ctx context.Context, tenantID, customerID string) (string, error)
+	// MigrationTarget returns the price version a subscription on priceID
+	// should move to at a period boundary, if any.
+	MigrationTarget(ctx context.Context, tenantID, priceID string, at time.Time) (pricing.Price, bool, error)
+}
+
+// resolvePrice loads a price in the customer's billing currency.
+func resolvePrice(ctx context.Context, catalog PriceCatalog, tenantID, customerID, priceID string) (pricing.Price, error) {
+	price, err := catalog.GetPrice(ctx, tenantID, priceID)
+	if err != nil {
+		return pricing.Price{}, err
+	}
+	if price.IsArchived() {
+		return pricing.Price{}, fmt.Errorf("%w: %s", ErrPriceArchived, priceID)
+	}
+	currency, err := catalog.BillingCurrency(ctx, tenantID, customerID)
+	if err != nil {
+		return pricing.Price{}, err
+	}
+	resolved, ok := price.ForCurrency(currency)
+	if !ok {
+		return pricing.Price{}, fmt.Errorf("%w: price %s has no %s amount", ErrCurrencyMismatch, priceID, currency)
+	}
+	return resolved, nil
+}
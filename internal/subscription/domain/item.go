@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidQuantity is returned for quantities below one.
+	ErrInvalidQuantity = errors.New("quantity must be at least 1")
+	// ErrDuplicateItem is returned when a subscription already bills the price.
+	ErrDuplicateItem = errors.New("subscription already bills this price")
+	// ErrIntervalMismatch is returned when an item's price renews on a different
+	// interval than the subscription's primary price.
+	ErrIntervalMismatch = errors.New("price billing interval differs from subscription")
+	// ErrItemNotFound is returned for items that do not belong to the subscription.
+	ErrItemNotFound = errors.New("subscription item not found")
+)
+
+// Item is a price billed on a subscription next to its primary price, such as
+// seats or a metered add-on. All items share the subscription's periods,
+// currency and invoice.
+type Item struct {
+	ID             string
+	TenantID       string
+	SubscriptionID string
+	PriceID        string
+	Quantity       int64
+	// ScheduledQuantity is a lower quantity taking effect at the end of the
+	// current period, or zero.
+	ScheduledQuantity int64
+	// RemovedAt is set once the item stops being billed.
+	RemovedAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// BillableItems returns what the subscription is billed for: its primary
+// price, as an item with an empty id, followed by items.
+func (s Subscription) BillableItems(items []Item) []Item {
+	primary := Item{
+		TenantID:          s.TenantID,
+		SubscriptionID:    s.ID,
+		PriceID:           s.PriceID,
+		Quantity:          s.Quantity,
+		ScheduledQuantity: s.ScheduledQuantity,
+		CreatedAt:         s.CreatedAt,
+	}
+	if primary.Quantity < 1 {
+		primary.Quantity = 1
+	}
+	return append([]Item{primary}, items...)
+}
+
+// ListItems returns the subscription's current items.
+func (s *Service) ListItems(ctx context.Context, sub Subscription) ([]Item, error) {
+	return s.repo.ListItems(ctx, ItemFilter{SubscriptionID: sub.ID, RemovedAfter: time.Now().UTC()})
+}
+
+// AddItem adds a price to the subscription. The price must be sold in the
+// subscription's currency, renew on the same interval as the primary price
+// and not be billed on the subscription already.
+func (s *Service) AddItem(ctx context.Context, sub Subscription, item Item) (Item, error) {
+	if sub.CanceledAt != nil {
+		return Item{}, ErrSubscriptionCanceled
+	}
+	if item.Quantity < 1 {
+		return Item{}, ErrInvalidQuantity
+	}
+	items, err := s.ListItems(ctx, sub)
+	if err != nil {
+		return Item{}, err
+	}
+	for _, existing := range sub.BillableItems(items) {
+		if existing.PriceID == item.PriceID {
+			return Item{}, fmt.Errorf("%w: %s", ErrDuplicateItem, item.PriceID)
+		}
+	}
+	if err := s.CheckPrice(ctx, sub, item.PriceID); err != nil {
+		return Item{}, err
+	}
+	if err := s.checkInterval(ctx, sub, item.PriceID); err != nil {
+		return Item{}, err
+	}
+
+	now := time.Now().UTC()
+	item.TenantID = sub.TenantID
+	item.SubscriptionID = sub.ID
+	item.RemovedAt = nil
+	item.CreatedAt = now
+	item.UpdatedAt = now
+	if err := s.repo.CreateItem(ctx, item); err != nil {
+		s.logger.Error("create subscription item", zap.Error(err))
+		return Item{}, err
+	}
+	s.logger.Info("subscription item added",
+		zap.String("subscription_id", sub.ID),
+		zap.String("item_id", item.ID),
+		zap.String("price_id", item.PriceID),
+	)
+	return item, nil
+}
+
+// RemoveItem stops billing an item at at. The invoice for the current period
+// still charges it for the time before at.
+func (s *Service) RemoveItem(ctx context.Context, sub Subscription, itemID string, at time.Time) (Item, error) {
+	item, err := s.GetItem(ctx, sub, itemID)
+	if err != nil {
+		return Item{}, err
+	}
+	item.RemovedAt = &at
+	item.UpdatedAt = time.Now().UTC()
+	if err := s.repo.UpdateItem(ctx, item); err != nil {
+		s.logger.Error("remove subscription item", zap.Error(err))
+		return Item{}, err
+	}
+	s.logger.Info("subscription item removed", zap.String("subscription_id", sub.ID), zap.String("item_id", item.ID))
+	return item, nil
+}
+
+// GetItem returns a current item of sub.
+func (s *Service) GetItem(ctx context.Context, sub Subscription, itemID string) (Item, error) {
+	if sub.CanceledAt != nil {
+		return Item{}, ErrSubscriptionCanceled
+	}
+	item, err := s.repo.GetItem(ctx, itemID)
+	if err != nil || item.SubscriptionID != sub.ID || item.RemovedAt != nil {
+		return Item{}, ErrItemNotFound
+	}
+	return item, nil
+}
+
+// checkInterval verifies that priceID renews on the primary price's interval,
+// so every item fits the subscription's periods.
+func (s *Service) checkInterval(ctx context.Context, sub Subscription, priceID string) error {
+	if s.catalog == nil {
+		return nil
+	}
+	primary, err := s.catalog.GetPrice(ctx, sub.TenantID, sub.PriceID)
+	if err != nil {
+		return err
+	}
+	price, err := s.catalog.GetPrice(ctx, sub.TenantID, priceID)
+	if err != nil {
+		return err
+	}
+	if price.BillingInterval != primary.BillingInterval || price.BillingIntervalCount != primary.BillingIntervalCount {
+		return fmt.Errorf("%w: %s", ErrIntervalMismatch, priceID)
+	}
+	return nil
+}
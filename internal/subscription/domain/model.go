@@ -1,6 +1,11 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Subscription represents a tenant subscription to a pricing plan.
 type Subscription struct {
@@ -15,9 +20,103 @@ type Subscription struct {
 	CurrentPeriodEnd   time.Time
 	TrialStartAt       *time.Time
 	TrialEndAt         *time.Time
-	CancelAt           *time.Time
-	CanceledAt         *time.Time
-	Metadata           map[string]interface{}
-	CreatedAt          time.Time
-	UpdatedAt          time.Time
+	// TrialReminderSentAt is set once subscription.trial_will_end was emitted.
+	TrialReminderSentAt *time.Time
+	CancelAt            *time.Time
+	CanceledAt          *time.Time
+	Currency            string
+	BillingAnchor       BillingAnchor
+	BillingAnchorDay    int
+	ScheduledChange     *ScheduledChange
+	Pause               *Pause
+	// Quantity is the number of units, e.g. seats, of the primary price.
+	Quantity int64
+	// ScheduledQuantity is a lower quantity taking effect at the end of the
+	// current period, or zero.
+	ScheduledQuantity int64
+	Metadata          map[string]interface{}
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// ScheduledChange is a price change deferred until EffectiveAt, normally the
+// end of the current period so the customer keeps what they already paid for.
+type ScheduledChange struct {
+	PriceID     string
+	EffectiveAt time.Time
+}
+
+// Due reports whether the scheduled change takes effect at or before at.
+func (c *ScheduledChange) Due(at time.Time) bool {
+	return c != nil && !at.Before(c.EffectiveAt)
+}
+
+// PauseBehavior decides how the time a subscription spends paused is billed.
+type PauseBehavior string
+
+const (
+	// PauseBehaviorVoid bills nothing for the paused time: on resume the
+	// current period is extended by the length of the pause.
+	PauseBehaviorVoid PauseBehavior = "void"
+	// PauseBehaviorKeep keeps periods running while paused; periods that
+	// ended during the pause are invoiced once the subscription resumes.
+	PauseBehaviorKeep PauseBehavior = "keep"
+)
+
+var (
+	// ErrSubscriptionPaused is returned for operations a paused subscription does not allow.
+	ErrSubscriptionPaused = errors.New("subscription is paused")
+	// ErrSubscriptionCanceled is returned when changing a subscription that has ended.
+	ErrSubscriptionCanceled = errors.New("subscription is canceled")
+	// ErrNoPendingCancellation is returned when reactivating a subscription that is not set to cancel.
+	ErrNoPendingCancellation = errors.New("subscription has no pending cancellation")
+)
+
+// ParsePauseBehavior validates a pause behavior, defaulting to void.
+func ParsePauseBehavior(raw string) (PauseBehavior, error) {
+	switch b := PauseBehavior(strings.ToLower(raw)); b {
+	case "":
+		return PauseBehaviorVoid, nil
+	case PauseBehaviorVoid, PauseBehaviorKeep:
+		return b, nil
+	}
+	return "", fmt.Errorf("unsupported pause behavior %q", raw)
+}
+
+// Pause records a paused subscription. ResumesAt, when set, is when it
+// resumes automatically.
+type Pause struct {
+	Behavior  PauseBehavior
+	PausedAt  time.Time
+	ResumesAt *time.Time
+}
+
+// ResumeDue reports whether the pause ends automatically at or before at.
+func (p *Pause) ResumeDue(at time.Time) bool {
+	return p != nil && p.ResumesAt != nil && !at.Before(*p.ResumesAt)
+}
+
+// CancellationPending reports whether the subscription is set to cancel at a later date.
+func (s Subscription) CancellationPending() bool {
+	return s.CancelAt != nil && s.CanceledAt == nil
+}
+
+// resume clears the pause at at. Void pauses push the current period, and a
+// change or cancellation scheduled for its end, back by the time spent paused.
+func (s *Subscription) resume(at time.Time) {
+	if s.Pause == nil {
+		return
+	}
+	if s.Pause.Behavior == PauseBehaviorVoid && at.After(s.Pause.PausedAt) {
+		paused := at.Sub(s.Pause.PausedAt)
+		s.CurrentPeriodEnd = s.CurrentPeriodEnd.Add(paused)
+		if s.ScheduledChange != nil {
+			s.ScheduledChange.EffectiveAt = s.ScheduledChange.EffectiveAt.Add(paused)
+		}
+		if s.CancellationPending() {
+			cancelAt := s.CancelAt.Add(paused)
+			s.CancelAt = &cancelAt
+		}
+	}
+	s.Pause = nil
 }
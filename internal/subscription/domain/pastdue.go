@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
+	"go.uber.org/zap"
+)
+
+// MarkPastDue moves an active subscription to past due after a payment for
+// one of its invoices failed. Subscriptions already in dunning are returned
+// unchanged with a nil event.
+func (s *Service) MarkPastDue(ctx context.Context, sub Subscription) (Subscription, *eventv1.Event, error) {
+	if subscriptionv1.SubscriptionStatus(sub.Status) == SubscriptionStatusPastDue {
+		return sub, nil, nil
+	}
+	return s.transition(ctx, sub, SubscriptionLifecyclePastDue, SubscriptionStatusPastDue)
+}
+
+// MarkUnpaid leaves a past due subscription unpaid once dunning is exhausted.
+func (s *Service) MarkUnpaid(ctx context.Context, sub Subscription) (Subscription, *eventv1.Event, error) {
+	return s.transition(ctx, sub, SubscriptionLifecycleUnpaid, SubscriptionStatusUnpaid)
+}
+
+// Recover reactivates a past due or unpaid subscription whose outstanding
+// invoice was paid. Other subscriptions are returned unchanged with a nil event.
+func (s *Service) Recover(ctx context.Context, sub Subscription) (Subscription, *eventv1.Event, error) {
+	switch subscriptionv1.SubscriptionStatus(sub.Status) {
+	case SubscriptionStatusPastDue, SubscriptionStatusUnpaid:
+		return s.transition(ctx, sub, SubscriptionLifecycleRecovered, subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE)
+	}
+	return sub, nil, nil
+}
+
+func (s *Service) transition(ctx context.Context, sub Subscription, lifecycle SubscriptionLifecycle, target subscriptionv1.SubscriptionStatus) (Subscription, *eventv1.Event, error) {
+	evt, err := sub.ApplyLifecycle(lifecycle, target)
+	if err != nil {
+		return Subscription{}, nil, err
+	}
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.Update(ctx, sub); err != nil {
+		return Subscription{}, nil, err
+	}
+	s.logger.Info("subscription status changed",
+		zap.String("subscription_id", sub.ID),
+		zap.String("status", StatusName(target)),
+	)
+	return sub, evt, nil
+}
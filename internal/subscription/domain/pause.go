@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
+	"go.uber.org/zap"
+)
+
+// Pause stops renewing and rating an active subscription until it is
+// resumed, automatically at resumesAt when set. The returned event records
+// the status change.
+func (s *Service) Pause(ctx context.Context, sub Subscription, behavior PauseBehavior, resumesAt *time.Time, at time.Time) (Subscription, *eventv1.Event, error) {
+	evt, err := sub.ApplyLifecycle(SubscriptionLifecyclePaused, SubscriptionStatusPaused)
+	if err != nil {
+		return Subscription{}, nil, err
+	}
+	sub.Pause = &Pause{Behavior: behavior, PausedAt: at, ResumesAt: resumesAt}
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.Update(ctx, sub); err != nil {
+		return Subscription{}, nil, err
+	}
+	s.logger.Info("subscription paused",
+		zap.String("subscription_id", sub.ID),
+		zap.String("behavior", string(behavior)),
+	)
+	return sub, evt, nil
+}
+
+// Resume reactivates a paused subscription at at. Void pauses extend the
+// current period by the paused time; with keep, periods that ended while
+// paused are picked up by the renewal worker.
+func (s *Service) Resume(ctx context.Context, sub Subscription, at time.Time) (Subscription, *eventv1.Event, error) {
+	evt, err := sub.ApplyLifecycle(SubscriptionLifecycleResumed, subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE)
+	if err != nil {
+		return Subscription{}, nil, err
+	}
+	sub.resume(at)
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.Update(ctx, sub); err != nil {
+		return Subscription{}, nil, err
+	}
+	s.logger.Info("subscription resumed",
+		zap.String("subscription_id", sub.ID),
+		zap.Time("period_end", sub.CurrentPeriodEnd),
+	)
+	return sub, evt, nil
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+)
+
+// BillingAnchor decides the day of the month periods start on.
+type BillingAnchor string
+
+const (
+	// BillingAnchorAnniversary bills on the day of month the subscription started.
+	BillingAnchorAnniversary BillingAnchor = "anniversary"
+	// BillingAnchorCalendar bills on the 1st of the month, after a partial first period.
+	BillingAnchorCalendar BillingAnchor = "calendar"
+)
+
+// ParseBillingAnchor validates an anchor, defaulting to anniversary billing.
+func ParseBillingAnchor(raw string) (BillingAnchor, error) {
+	switch a := BillingAnchor(strings.ToLower(raw)); a {
+	case "":
+		return BillingAnchorAnniversary, nil
+	case BillingAnchorAnniversary, BillingAnchorCalendar:
+		return a, nil
+	}
+	return "", fmt.Errorf("unsupported billing_anchor %q", raw)
+}
+
+// FirstPeriod returns the end of the first period for a subscription starting
+// at start and the anchor day later periods are measured from. Calendar
+// anchors end the first period at the next 1st of the month (or January 1st
+// for yearly prices), leaving a partial period that is prorated on the
+// invoice. Daily and weekly prices always bill on their anniversary.
+func FirstPeriod(anchor BillingAnchor, price pricing.Price, start time.Time) (time.Time, int) {
+	if anchor != BillingAnchorCalendar {
+		return price.PeriodEnd(start, start.Day()), start.Day()
+	}
+	switch price.BillingInterval {
+	case pricing.BillingIntervalDay, pricing.BillingIntervalWeek:
+		return price.PeriodEnd(start, start.Day()), start.Day()
+	case pricing.BillingIntervalYear:
+		return time.Date(start.Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC), 1
+	default:
+		return time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, time.UTC), 1
+	}
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+)
+
+func TestFirstPeriod(t *testing.T) {
+	monthly := pricing.Price{BillingInterval: pricing.BillingIntervalMonth, BillingIntervalCount: 1}
+	yearly := pricing.Price{BillingInterval: pricing.BillingIntervalYear, BillingIntervalCount: 1}
+	weekly := pricing.Price{BillingInterval: pricing.BillingIntervalWeek, BillingIntervalCount: 1}
+	jan31 := time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		anchor    BillingAnchor
+		price     pricing.Price
+		start     time.Time
+		wantEnd   time.Time
+		anchorDay int
+	}{
+		{name: "anniversary on the 31st", anchor: BillingAnchorAnniversary, price: monthly, start: jan31, wantEnd: time.Date(2025, 2, 28, 10, 0, 0, 0, time.UTC), anchorDay: 31},
+		{name: "calendar monthly", anchor: BillingAnchorCalendar, price: monthly, start: jan31, wantEnd: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), anchorDay: 1},
+		{name: "calendar yearly", anchor: BillingAnchorCalendar, price: yearly, start: jan31, wantEnd: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), anchorDay: 1},
+		{name: "calendar weekly keeps anniversary", anchor: BillingAnchorCalendar, price: weekly, start: jan31, wantEnd: time.Date(2025, 2, 7, 10, 0, 0, 0, time.UTC), anchorDay: 31},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, day := FirstPeriod(tt.anchor, tt.price, tt.start)
+			if !end.Equal(tt.wantEnd) || day != tt.anchorDay {
+				t.Fatalf("expected %s (day %d) got %s (day %d)", tt.wantEnd, tt.anchorDay, end, day)
+			}
+		})
+	}
+
+	// Later periods return to the anchor day after a short month.
+	end, day := FirstPeriod(BillingAnchorAnniversary, monthly, jan31)
+	if next := monthly.PeriodEnd(end, day); !next.Equal(time.Date(2025, 3, 31, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected Mar 31 renewal, got %s", next)
+	}
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// QuantityChange describes a new quantity for the primary price, when ItemID
+// is empty, or for an item of a subscription.
+type QuantityChange struct {
+	ItemID   string
+	PriceID  string
+	Previous int64
+	Quantity int64
+	// Scheduled is set for decreases, which take effect at EffectiveAt, the
+	// end of the current period.
+	Scheduled   bool
+	EffectiveAt time.Time
+}
+
+// Increase reports whether the quantity went up.
+func (c QuantityChange) Increase() bool {
+	return c.Quantity > c.Previous
+}
+
+// UpdateQuantity changes the quantity billed for the primary price, when
+// itemID is empty, or for an item. Increases apply at at; the caller prorates
+// them for the rest of the period. Decreases are scheduled for the end of the
+// current period so the customer keeps the units already billed. Setting the
+// current quantity again drops a scheduled decrease.
+func (s *Service) UpdateQuantity(ctx context.Context, sub Subscription, itemID string, quantity int64, at time.Time) (Subscription, QuantityChange, error) {
+	if quantity < 1 {
+		return Subscription{}, QuantityChange{}, ErrInvalidQuantity
+	}
+	if sub.CanceledAt != nil {
+		return Subscription{}, QuantityChange{}, ErrSubscriptionCanceled
+	}
+
+	var item Item
+	if itemID == "" {
+		item = sub.BillableItems(nil)[0]
+	} else {
+		var err error
+		if item, err = s.GetItem(ctx, sub, itemID); err != nil {
+			return Subscription{}, QuantityChange{}, err
+		}
+	}
+	change := QuantityChange{
+		ItemID:      itemID,
+		PriceID:     item.PriceID,
+		Previous:    item.Quantity,
+		Quantity:    quantity,
+		EffectiveAt: at,
+	}
+	item.ScheduledQuantity = 0
+	switch {
+	case quantity > item.Quantity:
+		item.Quantity = quantity
+	case quantity < item.Quantity:
+		item.ScheduledQuantity = quantity
+		change.Scheduled = true
+		change.EffectiveAt = sub.CurrentPeriodEnd
+	}
+
+	now := time.Now().UTC()
+	if itemID == "" {
+		sub.Quantity = item.Quantity
+		sub.ScheduledQuantity = item.ScheduledQuantity
+		sub.UpdatedAt = now
+		if err := s.Update(ctx, sub); err != nil {
+			return Subscription{}, QuantityChange{}, err
+		}
+	} else {
+		item.UpdatedAt = now
+		if err := s.repo.UpdateItem(ctx, item); err != nil {
+			s.logger.Error("update subscription item", zap.Error(err))
+			return Subscription{}, QuantityChange{}, err
+		}
+	}
+	s.logger.Info("subscription quantity updated",
+		zap.String("subscription_id", sub.ID),
+		zap.String("item_id", itemID),
+		zap.Int64("previous_quantity", change.Previous),
+		zap.Int64("quantity", quantity),
+		zap.Bool("scheduled", change.Scheduled),
+	)
+	return sub, change, nil
+}
+
+// applyScheduledQuantities lowers the quantities scheduled to decrease at the
+// end of the period that just ended. It returns the items it changed for the
+// caller to store with the subscription.
+func (s *Service) applyScheduledQuantities(ctx context.Context, sub Subscription, boundary time.Time) (Subscription, []Item, []QuantityChange, error) {
+	items, err := s.ListItems(ctx, sub)
+	if err != nil {
+		return sub, nil, nil, err
+	}
+	var changed []Item
+	var changes []QuantityChange
+	for _, item := range sub.BillableItems(items) {
+		if item.ScheduledQuantity == 0 {
+			continue
+		}
+		changes = append(changes, QuantityChange{
+			ItemID:      item.ID,
+			PriceID:     item.PriceID,
+			Previous:    item.Quantity,
+			Quantity:    item.ScheduledQuantity,
+			EffectiveAt: boundary,
+		})
+		if item.ID == "" {
+			sub.Quantity = item.ScheduledQuantity
+			sub.ScheduledQuantity = 0
+			continue
+		}
+		item.Quantity = item.ScheduledQuantity
+		item.ScheduledQuantity = 0
+		item.UpdatedAt = time.Now().UTC()
+		changed = append(changed, item)
+	}
+	return sub, changed, changes, nil
+}
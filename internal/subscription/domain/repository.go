@@ -1,6 +1,9 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // ListSubscriptionsFilter configures pagination for tenant subscriptions.
 type ListSubscriptionsFilter struct {
@@ -10,10 +13,70 @@ type ListSubscriptionsFilter struct {
 	Offset     int
 }
 
+// RenewalFilter selects subscriptions whose period ended at or before At.
+type RenewalFilter struct {
+	At       time.Time
+	Statuses []int32
+	Limit    int
+}
+
+// TrialFilter selects subscriptions in Status whose trial ends after EndsAfter
+// and at or before EndsBy. Unreminded skips trials whose reminder was sent.
+type TrialFilter struct {
+	EndsAfter  time.Time
+	EndsBy     time.Time
+	Status     int32
+	Unreminded bool
+	Limit      int
+}
+
+// ResumeFilter selects paused subscriptions due to resume at or before At.
+type ResumeFilter struct {
+	At    time.Time
+	Limit int
+}
+
+// CancellationFilter selects subscriptions in Statuses set to cancel at or before At.
+type CancellationFilter struct {
+	At       time.Time
+	Statuses []int32
+	Limit    int
+}
+
+// ItemFilter selects the items of a subscription. Items removed at or before
+// RemovedAfter are skipped.
+type ItemFilter struct {
+	SubscriptionID string
+	RemovedAfter   time.Time
+}
+
 // Repository provides subscription persistence.
 type Repository interface {
 	Create(ctx context.Context, sub Subscription) error
 	GetByID(ctx context.Context, id string) (Subscription, error)
 	List(ctx context.Context, filter ListSubscriptionsFilter) ([]Subscription, bool, error)
 	Update(ctx context.Context, sub Subscription) error
+	// SaveRenewal stores the renewed subscription with its changed items and,
+	// when set, its schedule in one transaction.
+	SaveRenewal(ctx context.Context, sub Subscription, items []Item, schedule *Schedule) error
+	// ListRenewalsDue returns auto-renewing subscriptions whose current period has ended.
+	ListRenewalsDue(ctx context.Context, filter RenewalFilter) ([]Subscription, error)
+	// ListTrialsEnding returns subscriptions whose trial ends within the filter window.
+	ListTrialsEnding(ctx context.Context, filter TrialFilter) ([]Subscription, error)
+	// ListResumesDue returns paused subscriptions whose resume date has passed.
+	ListResumesDue(ctx context.Context, filter ResumeFilter) ([]Subscription, error)
+	// ListCancellationsDue returns subscriptions whose pending cancellation date has passed.
+	ListCancellationsDue(ctx context.Context, filter CancellationFilter) ([]Subscription, error)
+
+	CreateItem(ctx context.Context, item Item) error
+	GetItem(ctx context.Context, id string) (Item, error)
+	UpdateItem(ctx context.Context, item Item) error
+	// ListItems returns a subscription's items in the order they were added.
+	ListItems(ctx context.Context, filter ItemFilter) ([]Item, error)
+
+	CreateSchedule(ctx context.Context, schedule Schedule) error
+	GetSchedule(ctx context.Context, id string) (Schedule, error)
+	UpdateSchedule(ctx context.Context, schedule Schedule) error
+	// GetActiveSchedule returns the active schedule driving the subscription.
+	GetActiveSchedule(ctx context.Context, subscriptionID string) (Schedule, bool, error)
 }
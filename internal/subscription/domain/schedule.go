@@ -0,0 +1,285 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidSchedule is returned when a schedule's phases are inconsistent.
+	ErrInvalidSchedule = errors.New("invalid subscription schedule")
+	// ErrScheduleNotFound is returned for schedules that do not belong to the tenant.
+	ErrScheduleNotFound = errors.New("subscription schedule not found")
+	// ErrScheduleNotActive is returned when changing a schedule that completed or was released.
+	ErrScheduleNotActive = errors.New("subscription schedule is not active")
+)
+
+// ScheduleStatus tracks whether a schedule still drives its subscription.
+type ScheduleStatus string
+
+const (
+	ScheduleStatusActive ScheduleStatus = "active"
+	// ScheduleStatusCompleted schedules have started the last period of their last phase.
+	ScheduleStatusCompleted ScheduleStatus = "completed"
+	// ScheduleStatusReleased schedules were detached; the subscription keeps
+	// the terms of the phase it was in.
+	ScheduleStatusReleased ScheduleStatus = "released"
+)
+
+// ScheduleEndBehavior decides what happens to the subscription after the
+// last phase.
+type ScheduleEndBehavior string
+
+const (
+	// ScheduleEndRelease keeps renewing the subscription on the last phase's terms.
+	ScheduleEndRelease ScheduleEndBehavior = "release"
+	// ScheduleEndCancel cancels the subscription when the last phase ends.
+	ScheduleEndCancel ScheduleEndBehavior = "cancel"
+)
+
+// ParseScheduleEndBehavior validates an end behavior, defaulting to release.
+func ParseScheduleEndBehavior(raw string) (ScheduleEndBehavior, error) {
+	switch b := ScheduleEndBehavior(strings.ToLower(raw)); b {
+	case "":
+		return ScheduleEndRelease, nil
+	case ScheduleEndRelease, ScheduleEndCancel:
+		return b, nil
+	}
+	return "", fmt.Errorf("%w: unsupported end_behavior %q", ErrInvalidSchedule, raw)
+}
+
+// Phase sets the terms a subscription is billed on for a number of periods.
+type Phase struct {
+	PriceID  string
+	Quantity int64
+	// CouponCode is redeemed on the subscription when the phase starts and
+	// its discount ended when the phase ends.
+	CouponCode string
+	// Iterations is the number of billing periods the phase lasts. Zero,
+	// allowed on the last phase only, lasts until the subscription ends.
+	Iterations int
+}
+
+// Schedule moves a subscription through ordered phases, e.g. three discounted
+// months, then nine at full price, then an annual price.
+type Schedule struct {
+	ID             string
+	TenantID       string
+	SubscriptionID string
+	Phases         []Phase
+	EndBehavior    ScheduleEndBehavior
+	Status         ScheduleStatus
+	CurrentPhase   int
+	// PeriodsInPhase counts the billing periods of the current phase that
+	// have started.
+	PeriodsInPhase int
+	// DiscountID is the discount redeemed for the current phase's coupon.
+	DiscountID string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// ScheduleProgress describes how a renewal moved the subscription's schedule.
+type ScheduleProgress struct {
+	Schedule Schedule
+	// PhaseStarted is set when the new period begins the next phase.
+	PhaseStarted bool
+	// Completed is set when the new period is the last the schedule covers.
+	Completed bool
+}
+
+// Validate checks the phases and end behavior.
+func (s Schedule) Validate() error {
+	if len(s.Phases) == 0 {
+		return fmt.Errorf("%w: at least one phase required", ErrInvalidSchedule)
+	}
+	for i, phase := range s.Phases {
+		last := i == len(s.Phases)-1
+		switch {
+		case phase.PriceID == "":
+			return fmt.Errorf("%w: phase %d: price_id required", ErrInvalidSchedule, i)
+		case phase.Quantity < 0:
+			return fmt.Errorf("%w: phase %d: %s", ErrInvalidSchedule, i, ErrInvalidQuantity)
+		case phase.Iterations < 0:
+			return fmt.Errorf("%w: phase %d: iterations must not be negative", ErrInvalidSchedule, i)
+		case phase.Iterations == 0 && !last:
+			return fmt.Errorf("%w: phase %d: only the last phase may run indefinitely", ErrInvalidSchedule, i)
+		case phase.Iterations == 0 && s.EndBehavior == ScheduleEndCancel:
+			return fmt.Errorf("%w: the last phase needs iterations to end with cancel", ErrInvalidSchedule)
+		}
+	}
+	if _, err := ParseScheduleEndBehavior(string(s.EndBehavior)); err != nil || s.EndBehavior == "" {
+		return fmt.Errorf("%w: unsupported end_behavior %q", ErrInvalidSchedule, s.EndBehavior)
+	}
+	return nil
+}
+
+// Phase returns the current phase.
+func (s Schedule) Phase() Phase {
+	return s.Phases[s.CurrentPhase]
+}
+
+// advance moves the schedule into the next billing period, starting the next
+// phase once the current one has run all its periods. It reports whether a
+// phase started.
+func (s *Schedule) advance() bool {
+	started := false
+	if n := s.Phase().Iterations; n > 0 && s.PeriodsInPhase >= n && s.CurrentPhase+1 < len(s.Phases) {
+		s.CurrentPhase++
+		s.PeriodsInPhase = 0
+		started = true
+	}
+	s.PeriodsInPhase++
+	return started
+}
+
+// finalPeriod reports whether the current period is the last one the
+// schedule covers.
+func (s Schedule) finalPeriod() bool {
+	n := s.Phase().Iterations
+	return s.CurrentPhase == len(s.Phases)-1 && n > 0 && s.PeriodsInPhase >= n
+}
+
+// quantity returns the phase's quantity, one when unset.
+func (p Phase) quantity() int64 {
+	if p.Quantity < 1 {
+		return 1
+	}
+	return p.Quantity
+}
+
+// CheckSchedule validates the schedule and verifies that the customer can be
+// billed every phase's price.
+func (s *Service) CheckSchedule(ctx context.Context, tenantID, customerID string, schedule Schedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	if s.catalog == nil {
+		return nil
+	}
+	for _, phase := range schedule.Phases {
+		if _, err := resolvePrice(ctx, s.catalog, tenantID, customerID, phase.PriceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateSchedule creates sub on the terms of the schedule's first phase and
+// stores the schedule driving it. A discount already redeemed for the first
+// phase's coupon is passed in schedule.DiscountID.
+func (s *Service) CreateSchedule(ctx context.Context, sub Subscription, schedule Schedule) (Subscription, Schedule, error) {
+	if err := s.CheckSchedule(ctx, sub.TenantID, sub.CustomerID, schedule); err != nil {
+		return Subscription{}, Schedule{}, err
+	}
+	first := schedule.Phases[0]
+	sub.PriceID = first.PriceID
+	sub.Quantity = first.quantity()
+	sub.AutoRenew = true
+	sub, err := s.Create(ctx, sub)
+	if err != nil {
+		return Subscription{}, Schedule{}, err
+	}
+
+	now := time.Now().UTC()
+	schedule.TenantID = sub.TenantID
+	schedule.SubscriptionID = sub.ID
+	schedule.Status = ScheduleStatusActive
+	schedule.CurrentPhase = 0
+	schedule.PeriodsInPhase = 1
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	if schedule.finalPeriod() {
+		if sub, err = s.completeSchedule(ctx, sub, &schedule); err != nil {
+			return Subscription{}, Schedule{}, err
+		}
+	}
+	if err := s.repo.CreateSchedule(ctx, schedule); err != nil {
+		s.logger.Error("create subscription schedule", zap.Error(err))
+		return Subscription{}, Schedule{}, err
+	}
+	s.logger.Info("subscription schedule created",
+		zap.String("schedule_id", schedule.ID),
+		zap.String("subscription_id", sub.ID),
+		zap.Int("phases", len(schedule.Phases)),
+	)
+	return sub, schedule, nil
+}
+
+// GetSchedule returns a schedule of the tenant by id.
+func (s *Service) GetSchedule(ctx context.Context, tenantID, id string) (Schedule, error) {
+	schedule, err := s.repo.GetSchedule(ctx, id)
+	if err != nil || schedule.TenantID != tenantID {
+		return Schedule{}, ErrScheduleNotFound
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule persists schedule changes.
+func (s *Service) UpdateSchedule(ctx context.Context, schedule Schedule) error {
+	schedule.UpdatedAt = time.Now().UTC()
+	if err := s.repo.UpdateSchedule(ctx, schedule); err != nil {
+		s.logger.Error("update subscription schedule", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ReleaseSchedule detaches an active schedule. The subscription keeps the
+// price, quantity and discount of the current phase and renews as usual.
+func (s *Service) ReleaseSchedule(ctx context.Context, schedule Schedule) (Schedule, error) {
+	if schedule.Status != ScheduleStatusActive {
+		return Schedule{}, ErrScheduleNotActive
+	}
+	schedule.Status = ScheduleStatusReleased
+	if err := s.UpdateSchedule(ctx, schedule); err != nil {
+		return Schedule{}, err
+	}
+	s.logger.Info("subscription schedule released", zap.String("schedule_id", schedule.ID))
+	return schedule, nil
+}
+
+// advanceSchedule counts the period starting at the renewal boundary against
+// the subscription's active schedule and switches the subscription to the
+// next phase's price and quantity when one starts. It returns nil progress
+// for subscriptions without an active schedule.
+func (s *Service) advanceSchedule(ctx context.Context, sub Subscription) (Subscription, *ScheduleProgress, error) {
+	schedule, ok, err := s.repo.GetActiveSchedule(ctx, sub.ID)
+	if err != nil || !ok {
+		return sub, nil, err
+	}
+	progress := &ScheduleProgress{PhaseStarted: schedule.advance()}
+	if progress.PhaseStarted {
+		phase := schedule.Phase()
+		if err := s.CheckPrice(ctx, sub, phase.PriceID); err != nil {
+			return sub, nil, err
+		}
+		sub.PriceID = phase.PriceID
+		sub.Quantity = phase.quantity()
+		sub.ScheduledChange = nil
+		sub.ScheduledQuantity = 0
+		s.logger.Info("subscription schedule phase started",
+			zap.String("schedule_id", schedule.ID),
+			zap.String("subscription_id", sub.ID),
+			zap.Int("phase", schedule.CurrentPhase),
+		)
+	}
+	progress.Completed = schedule.finalPeriod()
+	progress.Schedule = schedule
+	return sub, progress, nil
+}
+
+// completeSchedule marks the schedule completed once its final period has
+// started and, when it ends with cancel, cancels sub at that period's end.
+func (s *Service) completeSchedule(ctx context.Context, sub Subscription, schedule *Schedule) (Subscription, error) {
+	schedule.Status = ScheduleStatusCompleted
+	if schedule.EndBehavior != ScheduleEndCancel {
+		return sub, nil
+	}
+	return s.ScheduleCancellation(ctx, sub)
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScheduleValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		wantErr  bool
+	}{
+		{name: "single open phase", schedule: Schedule{EndBehavior: ScheduleEndRelease, Phases: []Phase{{PriceID: "p1"}}}},
+		{name: "phases then cancel", schedule: Schedule{EndBehavior: ScheduleEndCancel, Phases: []Phase{{PriceID: "p1", Iterations: 3}, {PriceID: "p2", Iterations: 9}}}},
+		{name: "no phases", schedule: Schedule{EndBehavior: ScheduleEndRelease}, wantErr: true},
+		{name: "missing price", schedule: Schedule{EndBehavior: ScheduleEndRelease, Phases: []Phase{{Iterations: 1}}}, wantErr: true},
+		{name: "negative quantity", schedule: Schedule{EndBehavior: ScheduleEndRelease, Phases: []Phase{{PriceID: "p1", Quantity: -1}}}, wantErr: true},
+		{name: "open middle phase", schedule: Schedule{EndBehavior: ScheduleEndRelease, Phases: []Phase{{PriceID: "p1"}, {PriceID: "p2"}}}, wantErr: true},
+		{name: "cancel without end", schedule: Schedule{EndBehavior: ScheduleEndCancel, Phases: []Phase{{PriceID: "p1"}}}, wantErr: true},
+		{name: "unknown end behavior", schedule: Schedule{EndBehavior: "renew", Phases: []Phase{{PriceID: "p1"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schedule.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSchedule) {
+				t.Fatalf("expected ErrInvalidSchedule got %v", err)
+			}
+		})
+	}
+}
+
+func TestScheduleAdvance(t *testing.T) {
+	phases := []Phase{{PriceID: "intro", Iterations: 2}, {PriceID: "standard", Iterations: 1}, {PriceID: "annual"}}
+
+	tests := []struct {
+		name        string
+		phase       int
+		periods     int
+		wantPhase   int
+		wantPeriods int
+		wantStarted bool
+		wantFinal   bool
+	}{
+		{name: "within phase", phase: 0, periods: 1, wantPhase: 0, wantPeriods: 2},
+		{name: "phase boundary", phase: 0, periods: 2, wantPhase: 1, wantPeriods: 1, wantStarted: true},
+		{name: "single period phase", phase: 1, periods: 1, wantPhase: 2, wantPeriods: 1, wantStarted: true},
+		{name: "open last phase", phase: 2, periods: 5, wantPhase: 2, wantPeriods: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Schedule{Phases: phases, CurrentPhase: tt.phase, PeriodsInPhase: tt.periods}
+			started := s.advance()
+			if started != tt.wantStarted || s.CurrentPhase != tt.wantPhase || s.PeriodsInPhase != tt.wantPeriods {
+				t.Fatalf("expected phase %d period %d started %v, got phase %d period %d started %v",
+					tt.wantPhase, tt.wantPeriods, tt.wantStarted, s.CurrentPhase, s.PeriodsInPhase, started)
+			}
+			if s.finalPeriod() != tt.wantFinal {
+				t.Fatalf("expected final period %v", tt.wantFinal)
+			}
+		})
+	}
+
+	last := Schedule{Phases: []Phase{{PriceID: "intro", Iterations: 1}, {PriceID: "standard", Iterations: 2}}, CurrentPhase: 1, PeriodsInPhase: 1}
+	if last.advance() || !last.finalPeriod() {
+		t.Fatalf("expected the second period of the last phase to be final, got %+v", last)
+	}
+}
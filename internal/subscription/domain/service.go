@@ -2,29 +2,56 @@ package domain
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 // Service handles subscription workflows.
 type Service struct {
-	repo   Repository
-	logger *zap.Logger
+	repo    Repository
+	catalog PriceCatalog
+	logger  *zap.Logger
 }
 
-// NewService constructs Service.
-func NewService(repo Repository, logger *zap.Logger) *Service {
-	return &Service{repo: repo, logger: logger.Named("subscription.service")}
+// NewService constructs Service. A nil catalog skips price and currency checks.
+func NewService(repo Repository, catalog PriceCatalog, logger *zap.Logger) *Service {
+	return &Service{repo: repo, catalog: catalog, logger: logger.Named("subscription.service")}
 }
 
-// Create registers a subscription.
-func (s *Service) Create(ctx context.Context, sub Subscription) error {
+// Create registers a subscription, billing it in the customer's currency.
+// It fails with ErrCurrencyMismatch when the price is not sold in that currency.
+// The first period is measured from CurrentPeriodStart by the price's interval
+// and the subscription's billing anchor. A trial is the first period; paid
+// periods start when it ends.
+func (s *Service) Create(ctx context.Context, sub Subscription) (Subscription, error) {
+	if sub.BillingAnchor == "" {
+		sub.BillingAnchor = BillingAnchorAnniversary
+	}
+	if sub.Quantity < 1 {
+		sub.Quantity = 1
+	}
+	if s.catalog != nil {
+		price, err := resolvePrice(ctx, s.catalog, sub.TenantID, sub.CustomerID, sub.PriceID)
+		if err != nil {
+			s.logger.Warn("resolve subscription price", zap.Error(err), zap.String("price_id", sub.PriceID))
+			return Subscription{}, err
+		}
+		sub.Currency = strings.ToUpper(price.Currency)
+		sub.CurrentPeriodEnd, sub.BillingAnchorDay = FirstPeriod(sub.BillingAnchor, price, sub.CurrentPeriodStart)
+	}
+	if sub.TrialEndAt != nil && sub.TrialEndAt.After(sub.CurrentPeriodStart) {
+		sub.CurrentPeriodEnd = *sub.TrialEndAt
+	}
 	if err := s.repo.Create(ctx, sub); err != nil {
 		s.logger.Error("create subscription", zap.Error(err))
-		return err
+		return Subscription{}, err
 	}
 	s.logger.Info("subscription created", zap.String("id", sub.ID))
-	return nil
+	return sub, nil
 }
 
 // Get returns a subscription by id.
@@ -45,3 +72,278 @@ func (s *Service) Update(ctx context.Context, sub Subscription) error {
 	}
 	return nil
 }
+
+// CheckPrice verifies that the subscription may switch to priceID: the price
+// must not be archived and must be sold in the subscription's currency.
+func (s *Service) CheckPrice(ctx context.Context, sub Subscription, priceID string) error {
+	if s.catalog == nil {
+		return nil
+	}
+	price, err := s.catalog.GetPrice(ctx, sub.TenantID, priceID)
+	if err != nil {
+		return err
+	}
+	if price.IsArchived() {
+		return fmt.Errorf("%w: %s", ErrPriceArchived, priceID)
+	}
+	if _, ok := price.ForCurrency(sub.Currency); !ok {
+		return fmt.Errorf("%w: price %s has no %s amount", ErrCurrencyMismatch, priceID, sub.Currency)
+	}
+	return nil
+}
+
+// SchedulePriceChange defers a switch to priceID until the end of the current
+// period, replacing any change already scheduled. Downgrades use this so the
+// customer keeps the paid plan for the period they were billed for.
+func (s *Service) SchedulePriceChange(ctx context.Context, id, priceID string) (Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if err := s.CheckPrice(ctx, sub, priceID); err != nil {
+		return Subscription{}, err
+	}
+	sub.ScheduledChange = &ScheduledChange{PriceID: priceID, EffectiveAt: sub.CurrentPeriodEnd}
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.Update(ctx, sub); err != nil {
+		return Subscription{}, err
+	}
+	s.logger.Info("subscription price change scheduled",
+		zap.String("subscription_id", sub.ID),
+		zap.String("price_id", priceID),
+		zap.Time("effective_at", sub.CurrentPeriodEnd),
+	)
+	return sub, nil
+}
+
+// CancelScheduledChange drops a pending price change.
+func (s *Service) CancelScheduledChange(ctx context.Context, id string) (Subscription, error) {
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if sub.ScheduledChange == nil {
+		return sub, nil
+	}
+	sub.ScheduledChange = nil
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.Update(ctx, sub); err != nil {
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+// applyScheduledChange switches the subscription to its scheduled price once
+// the change is due at at. It reports whether the price changed; the caller
+// stores the subscription.
+func (s *Service) applyScheduledChange(ctx context.Context, sub Subscription, at time.Time) (Subscription, bool, error) {
+	if !sub.ScheduledChange.Due(at) {
+		return sub, false, nil
+	}
+	change := sub.ScheduledChange
+	if err := s.CheckPrice(ctx, sub, change.PriceID); err != nil {
+		return sub, false, err
+	}
+	previous := sub.PriceID
+	sub.PriceID = change.PriceID
+	sub.ScheduledChange = nil
+	s.logger.Info("scheduled price change applied",
+		zap.String("subscription_id", sub.ID),
+		zap.String("from_price_id", previous),
+		zap.String("to_price_id", sub.PriceID),
+	)
+	return sub, previous != sub.PriceID, nil
+}
+
+// ListRenewalsDue returns auto-renewing subscriptions whose period has ended.
+func (s *Service) ListRenewalsDue(ctx context.Context, filter RenewalFilter) ([]Subscription, error) {
+	return s.repo.ListRenewalsDue(ctx, filter)
+}
+
+// ListTrialsEnding returns subscriptions whose trial ends within the filter window.
+func (s *Service) ListTrialsEnding(ctx context.Context, filter TrialFilter) ([]Subscription, error) {
+	return s.repo.ListTrialsEnding(ctx, filter)
+}
+
+// ListResumesDue returns paused subscriptions whose resume date has passed.
+func (s *Service) ListResumesDue(ctx context.Context, filter ResumeFilter) ([]Subscription, error) {
+	return s.repo.ListResumesDue(ctx, filter)
+}
+
+// ScheduleCancellation cancels the subscription at the end of its current
+// period. It keeps its status until then and stops renewing.
+func (s *Service) ScheduleCancellation(ctx context.Context, sub Subscription) (Subscription, error) {
+	if sub.CanceledAt != nil {
+		return Subscription{}, ErrSubscriptionCanceled
+	}
+	cancelAt := sub.CurrentPeriodEnd
+	sub.CancelAt = &cancelAt
+	sub.AutoRenew = false
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.Update(ctx, sub); err != nil {
+		return Subscription{}, err
+	}
+	s.logger.Info("subscription cancellation scheduled", zap.String("subscription_id", sub.ID), zap.Time("cancel_at", cancelAt))
+	return sub, nil
+}
+
+// Reactivate drops a pending cancellation so the subscription renews again.
+func (s *Service) Reactivate(ctx context.Context, sub Subscription) (Subscription, error) {
+	if !sub.CancellationPending() {
+		return Subscription{}, ErrNoPendingCancellation
+	}
+	sub.CancelAt = nil
+	sub.AutoRenew = true
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.Update(ctx, sub); err != nil {
+		return Subscription{}, err
+	}
+	s.logger.Info("subscription reactivated", zap.String("subscription_id", sub.ID))
+	return sub, nil
+}
+
+// ListCancellationsDue returns subscriptions whose pending cancellation date has passed.
+func (s *Service) ListCancellationsDue(ctx context.Context, filter CancellationFilter) ([]Subscription, error) {
+	return s.repo.ListCancellationsDue(ctx, filter)
+}
+
+// MarkTrialReminderSent records that the trial_will_end reminder went out.
+func (s *Service) MarkTrialReminderSent(ctx context.Context, sub Subscription, at time.Time) (Subscription, error) {
+	sub.TrialReminderSentAt = &at
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.Update(ctx, sub); err != nil {
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+// startFirstPaidPeriod begins the first billed period at start, measured by
+// the subscription's price and billing anchor.
+func (s *Service) startFirstPaidPeriod(ctx context.Context, sub Subscription, start time.Time) (Subscription, error) {
+	sub.CurrentPeriodStart = start
+	sub.CurrentPeriodEnd = start.AddDate(0, 1, 0)
+	sub.BillingAnchorDay = start.Day()
+	if s.catalog != nil {
+		price, err := s.catalog.GetPrice(ctx, sub.TenantID, sub.PriceID)
+		if err != nil {
+			return Subscription{}, err
+		}
+		sub.CurrentPeriodEnd, sub.BillingAnchorDay = FirstPeriod(sub.BillingAnchor, price, start)
+	}
+	return sub, nil
+}
+
+// Renewal describes a subscription advanced into its next period.
+type Renewal struct {
+	Subscription    Subscription
+	PreviousPriceID string
+	// Downgraded is set when a scheduled change was applied at the boundary.
+	Downgraded bool
+	// Migrated is set when the subscription moved to a newer price version.
+	Migrated bool
+	// QuantityChanges lists the scheduled quantity decreases applied at the boundary.
+	QuantityChanges []QuantityChange
+	// Schedule is set when the subscription is driven by a schedule.
+	Schedule *ScheduleProgress
+}
+
+// Renew advances the subscription by one billing period of its price. The
+// next phase of its schedule, the scheduled change, or else a price version
+// migration, and scheduled quantity decreases are applied at the boundary
+// first so the new period is measured and billed on the new price and
+// quantities. The subscription, its items and its schedule are stored
+// together, so a failed renewal leaves all of them in the ended period.
+func (s *Service) Renew(ctx context.Context, sub Subscription) (Renewal, error) {
+	boundary := sub.CurrentPeriodEnd
+	renewal := Renewal{PreviousPriceID: sub.PriceID}
+
+	var err error
+	sub, renewal.Schedule, err = s.advanceSchedule(ctx, sub)
+	if err != nil {
+		return Renewal{}, err
+	}
+	sub, renewal.Downgraded, err = s.applyScheduledChange(ctx, sub, boundary)
+	if err != nil {
+		return Renewal{}, err
+	}
+	if !renewal.Downgraded {
+		sub, renewal.Migrated, err = s.migratePrice(ctx, sub, boundary)
+		if err != nil {
+			return Renewal{}, err
+		}
+	}
+
+	var items []Item
+	sub, items, renewal.QuantityChanges, err = s.applyScheduledQuantities(ctx, sub, boundary)
+	if err != nil {
+		return Renewal{}, err
+	}
+
+	end := boundary.AddDate(0, 1, 0)
+	if s.catalog != nil {
+		price, err := s.catalog.GetPrice(ctx, sub.TenantID, sub.PriceID)
+		if err != nil {
+			return Renewal{}, err
+		}
+		end = price.PeriodEnd(boundary, sub.BillingAnchorDay)
+	}
+	sub.CurrentPeriodStart = boundary
+	sub.CurrentPeriodEnd = end
+	if progress := renewal.Schedule; progress != nil && progress.Completed {
+		progress.Schedule.Status = ScheduleStatusCompleted
+		if progress.Schedule.EndBehavior == ScheduleEndCancel {
+			sub.CancelAt = &end
+			sub.AutoRenew = false
+		}
+	}
+	now := time.Now().UTC()
+	sub.UpdatedAt = now
+	var schedule *Schedule
+	if renewal.Schedule != nil {
+		renewal.Schedule.Schedule.UpdatedAt = now
+		schedule = &renewal.Schedule.Schedule
+	}
+	if err := s.repo.SaveRenewal(ctx, sub, items, schedule); err != nil {
+		s.logger.Error("save subscription renewal", zap.Error(err))
+		return Renewal{}, err
+	}
+	s.logger.Info("subscription renewed",
+		zap.String("subscription_id", sub.ID),
+		zap.Time("period_start", sub.CurrentPeriodStart),
+		zap.Time("period_end", sub.CurrentPeriodEnd),
+	)
+	renewal.Subscription = sub
+	return renewal, nil
+}
+
+// migratePrice moves a subscription to the newest price version published with
+// MigrationPolicyMigrate and effective at boundary, the start of its next
+// period. Grandfathered subscriptions, or versions not sold in the
+// subscription's currency, are left unchanged. It reports whether the price
+// changed; the caller stores the subscription.
+func (s *Service) migratePrice(ctx context.Context, sub Subscription, boundary time.Time) (Subscription, bool, error) {
+	if s.catalog == nil {
+		return sub, false, nil
+	}
+	target, ok, err := s.catalog.MigrationTarget(ctx, sub.TenantID, sub.PriceID, boundary)
+	if err != nil || !ok {
+		return sub, false, err
+	}
+	if _, available := target.ForCurrency(sub.Currency); !available {
+		s.logger.Warn("price version not available in subscription currency",
+			zap.String("subscription_id", sub.ID),
+			zap.Int64("price_id", target.ID),
+			zap.String("currency", sub.Currency),
+		)
+		return sub, false, nil
+	}
+
+	previous := sub.PriceID
+	sub.PriceID = strconv.FormatInt(target.ID, 10)
+	s.logger.Info("subscription migrated to new price version",
+		zap.String("subscription_id", sub.ID),
+		zap.String("from_price_id", previous),
+		zap.String("to_price_id", sub.PriceID),
+	)
+	return sub, true, nil
+}
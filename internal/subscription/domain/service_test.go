@@ -2,19 +2,22 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
+	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
 	"go.uber.org/zap"
 )
 
 func TestServiceCreateErrors(t *testing.T) {
 	repo := NewTestRepository()
 	repo.FailCreate = true
-	svc := NewService(repo, zap.NewNop())
+	svc := NewService(repo, nil, zap.NewNop())
 
-	err := svc.Create(context.Background(), Subscription{ID: "sub-1"})
+	_, err := svc.Create(context.Background(), Subscription{ID: "sub-1"})
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -29,7 +32,7 @@ func TestServiceListPagination(t *testing.T) {
 			CreatedAt: time.Now().Add(time.Duration(i) * time.Minute),
 		}
 	}
-	svc := NewService(repo, zap.NewNop())
+	svc := NewService(repo, nil, zap.NewNop())
 
 	items, hasMore, err := svc.List(context.Background(), ListSubscriptionsFilter{
 		TenantID: "tenant",
@@ -45,3 +48,437 @@ func TestServiceListPagination(t *testing.T) {
 		t.Fatal("expected next page")
 	}
 }
+
+func TestServiceCreateResolvesCurrency(t *testing.T) {
+	catalog := NewTestCatalog("IDR")
+	catalog.Prices["price-1"] = pricing.Price{
+		Currency:        "usd",
+		UnitAmountCents: 1000,
+		CurrencyOptions: []pricing.CurrencyOption{{Currency: "IDR", UnitAmountCents: 15000000}},
+	}
+	catalog.Prices["price-2"] = pricing.Price{Currency: "usd", UnitAmountCents: 1000}
+	svc := NewService(NewTestRepository(), catalog, zap.NewNop())
+
+	sub, err := svc.Create(context.Background(), Subscription{ID: "sub-1", PriceID: "price-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Currency != "IDR" {
+		t.Fatalf("expected IDR currency, got %q", sub.Currency)
+	}
+
+	_, err = svc.Create(context.Background(), Subscription{ID: "sub-2", PriceID: "price-2"})
+	if !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("expected ErrCurrencyMismatch, got %v", err)
+	}
+}
+
+func TestServiceMigratePrice(t *testing.T) {
+	boundary := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	catalog := NewTestCatalog("USD")
+	catalog.Migrations["100"] = pricing.Price{ID: 200, Currency: "USD", EffectiveFrom: boundary, MigrationPolicy: pricing.MigrationPolicyMigrate}
+	catalog.Migrations["300"] = pricing.Price{ID: 400, Currency: "USD", EffectiveFrom: boundary, MigrationPolicy: pricing.MigrationPolicyGrandfather}
+	repo := NewTestRepository()
+	svc := NewService(repo, catalog, zap.NewNop())
+
+	sub := Subscription{ID: "sub-1", PriceID: "100", Currency: "USD"}
+	repo.Subs[sub.ID] = sub
+
+	if _, changed, err := svc.migratePrice(context.Background(), sub, boundary.Add(-time.Hour)); err != nil || changed {
+		t.Fatalf("expected no migration before effective date, changed=%v err=%v", changed, err)
+	}
+
+	migrated, changed, err := svc.migratePrice(context.Background(), sub, boundary)
+	if err != nil || !changed {
+		t.Fatalf("expected migration, changed=%v err=%v", changed, err)
+	}
+	if migrated.PriceID != "200" {
+		t.Fatalf("expected subscription on price 200, got %q", migrated.PriceID)
+	}
+
+	grandfathered := Subscription{ID: "sub-2", PriceID: "300", Currency: "USD"}
+	repo.Subs[grandfathered.ID] = grandfathered
+	if _, changed, err := svc.migratePrice(context.Background(), grandfathered, boundary); err != nil || changed {
+		t.Fatalf("expected grandfathered subscription to keep its price, changed=%v err=%v", changed, err)
+	}
+	if repo.Subs[grandfathered.ID].PriceID != "300" {
+		t.Fatalf("expected subscription on price 300, got %q", repo.Subs[grandfathered.ID].PriceID)
+	}
+
+	unversioned := Subscription{ID: "sub-3", PriceID: "500", Currency: "USD"}
+	if _, changed, _ := svc.migratePrice(context.Background(), unversioned, boundary); changed {
+		t.Fatal("expected subscription without a newer version to keep its price")
+	}
+}
+
+func TestServiceScheduledPriceChange(t *testing.T) {
+	periodEnd := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	catalog := NewTestCatalog("USD")
+	catalog.Prices["basic"] = pricing.Price{Currency: "USD", UnitAmountCents: 1000}
+	repo := NewTestRepository()
+	repo.Subs["sub-1"] = Subscription{ID: "sub-1", PriceID: "pro", Currency: "USD", CurrentPeriodEnd: periodEnd}
+	svc := NewService(repo, catalog, zap.NewNop())
+	ctx := context.Background()
+
+	sub, err := svc.SchedulePriceChange(ctx, "sub-1", "basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.PriceID != "pro" || sub.ScheduledChange == nil || !sub.ScheduledChange.EffectiveAt.Equal(periodEnd) {
+		t.Fatalf("expected change to basic scheduled at period end, got %+v", sub)
+	}
+
+	if _, changed, err := svc.applyScheduledChange(ctx, sub, periodEnd.Add(-time.Second)); err != nil || changed {
+		t.Fatalf("change applied before period end: changed=%v err=%v", changed, err)
+	}
+	sub, changed, err := svc.applyScheduledChange(ctx, sub, periodEnd)
+	if err != nil || !changed {
+		t.Fatalf("expected change applied, changed=%v err=%v", changed, err)
+	}
+	if sub.PriceID != "basic" || sub.ScheduledChange != nil {
+		t.Fatalf("unexpected subscription after change: %+v", sub)
+	}
+}
+
+func TestServiceRenew(t *testing.T) {
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	catalog := NewTestCatalog("USD")
+	catalog.Prices["monthly"] = pricing.Price{Currency: "USD", BillingInterval: pricing.BillingIntervalMonth, BillingIntervalCount: 1}
+	catalog.Prices["weekly"] = pricing.Price{Currency: "USD", BillingInterval: pricing.BillingIntervalWeek, BillingIntervalCount: 1}
+	repo := NewTestRepository()
+	repo.Subs["sub-1"] = Subscription{ID: "sub-1", PriceID: "monthly", Currency: "USD", AutoRenew: true, CurrentPeriodStart: start, CurrentPeriodEnd: end}
+	repo.Subs["sub-2"] = Subscription{
+		ID: "sub-2", PriceID: "monthly", Currency: "USD", AutoRenew: true, CurrentPeriodStart: start, CurrentPeriodEnd: end,
+		ScheduledChange: &ScheduledChange{PriceID: "weekly", EffectiveAt: end},
+	}
+	repo.Subs["sub-3"] = Subscription{ID: "sub-3", PriceID: "monthly", Currency: "USD", CurrentPeriodStart: start, CurrentPeriodEnd: end}
+	svc := NewService(repo, catalog, zap.NewNop())
+	ctx := context.Background()
+
+	due, err := svc.ListRenewalsDue(ctx, RenewalFilter{At: end})
+	if err != nil || len(due) != 2 {
+		t.Fatalf("expected 2 auto-renewing subscriptions due, got %d (%v)", len(due), err)
+	}
+
+	renewal, err := svc.Renew(ctx, repo.Subs["sub-1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renewal.Subscription; !got.CurrentPeriodStart.Equal(end) || !got.CurrentPeriodEnd.Equal(end.AddDate(0, 1, 0)) {
+		t.Fatalf("unexpected period %s - %s", got.CurrentPeriodStart, got.CurrentPeriodEnd)
+	}
+
+	renewal, err = svc.Renew(ctx, repo.Subs["sub-2"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !renewal.Downgraded || renewal.PreviousPriceID != "monthly" || renewal.Subscription.PriceID != "weekly" {
+		t.Fatalf("expected scheduled downgrade at renewal, got %+v", renewal)
+	}
+	if got := repo.Subs["sub-2"].CurrentPeriodEnd; !got.Equal(end.AddDate(0, 0, 7)) {
+		t.Fatalf("expected weekly period after downgrade, got end %s", got)
+	}
+}
+
+func TestServiceTrials(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	trialEnd := now.Add(48 * time.Hour)
+	catalog := NewTestCatalog("USD")
+	catalog.Prices["monthly"] = pricing.Price{Currency: "USD", BillingInterval: pricing.BillingIntervalMonth, BillingIntervalCount: 1}
+	repo := NewTestRepository()
+	svc := NewService(repo, catalog, zap.NewNop())
+	ctx := context.Background()
+
+	trialStart := now.AddDate(0, 0, -12)
+	sub, err := svc.Create(ctx, Subscription{
+		ID: "sub-1", PriceID: "monthly", Status: 1, CurrentPeriodStart: trialStart,
+		TrialStartAt: &trialStart, TrialEndAt: &trialEnd,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sub.CurrentPeriodEnd.Equal(trialEnd) {
+		t.Fatalf("expected trial to be the first period, got end %s", sub.CurrentPeriodEnd)
+	}
+
+	reminders := TrialFilter{EndsAfter: now, EndsBy: now.Add(72 * time.Hour), Status: 1, Unreminded: true}
+	due, err := svc.ListTrialsEnding(ctx, reminders)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("expected 1 trial to remind, got %d (%v)", len(due), err)
+	}
+	if _, err := svc.MarkTrialReminderSent(ctx, due[0], now); err != nil {
+		t.Fatal(err)
+	}
+	if due, _ = svc.ListTrialsEnding(ctx, reminders); len(due) != 0 {
+		t.Fatalf("expected reminder to be sent once, got %d", len(due))
+	}
+	if due, _ = svc.ListTrialsEnding(ctx, TrialFilter{EndsBy: now, Status: 1}); len(due) != 0 {
+		t.Fatalf("expected no expired trials, got %d", len(due))
+	}
+
+	paid, err := svc.startFirstPaidPeriod(ctx, repo.Subs["sub-1"], trialEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !paid.CurrentPeriodStart.Equal(trialEnd) || !paid.CurrentPeriodEnd.Equal(trialEnd.AddDate(0, 1, 0)) || paid.BillingAnchorDay != trialEnd.Day() {
+		t.Fatalf("unexpected first paid period %s - %s (anchor %d)", paid.CurrentPeriodStart, paid.CurrentPeriodEnd, paid.BillingAnchorDay)
+	}
+}
+
+func TestSubscriptionResume(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	pausedAt := start.AddDate(0, 0, 10)
+	resumeAt := pausedAt.AddDate(0, 0, 5)
+
+	tests := []struct {
+		name     string
+		behavior PauseBehavior
+		wantEnd  time.Time
+	}{
+		{name: "void extends the period", behavior: PauseBehaviorVoid, wantEnd: end.AddDate(0, 0, 5)},
+		{name: "keep leaves the period", behavior: PauseBehaviorKeep, wantEnd: end},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{
+				CurrentPeriodStart: start,
+				CurrentPeriodEnd:   end,
+				ScheduledChange:    &ScheduledChange{PriceID: "basic", EffectiveAt: end},
+				Pause:              &Pause{Behavior: tt.behavior, PausedAt: pausedAt, ResumesAt: &resumeAt},
+			}
+			if sub.Pause.ResumeDue(resumeAt.Add(-time.Second)) || !sub.Pause.ResumeDue(resumeAt) {
+				t.Fatal("unexpected resume due check")
+			}
+			sub.resume(resumeAt)
+			if sub.Pause != nil || !sub.CurrentPeriodEnd.Equal(tt.wantEnd) || !sub.ScheduledChange.EffectiveAt.Equal(tt.wantEnd) {
+				t.Fatalf("unexpected subscription after resume: end %s change %s", sub.CurrentPeriodEnd, sub.ScheduledChange.EffectiveAt)
+			}
+		})
+	}
+
+	if _, err := ParsePauseBehavior("skip"); err == nil {
+		t.Fatal("expected unsupported behavior error")
+	}
+}
+
+func TestServiceCancellationAtPeriodEnd(t *testing.T) {
+	start := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	repo := NewTestRepository()
+	repo.Subs["sub-1"] = Subscription{ID: "sub-1", AutoRenew: true, CurrentPeriodStart: start, CurrentPeriodEnd: end}
+	svc := NewService(repo, nil, zap.NewNop())
+	ctx := context.Background()
+
+	if _, err := svc.Reactivate(ctx, repo.Subs["sub-1"]); !errors.Is(err, ErrNoPendingCancellation) {
+		t.Fatalf("expected ErrNoPendingCancellation, got %v", err)
+	}
+	sub, err := svc.ScheduleCancellation(ctx, repo.Subs["sub-1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sub.CancellationPending() || !sub.CancelAt.Equal(end) || sub.AutoRenew {
+		t.Fatalf("expected cancellation pending at period end, got %+v", sub)
+	}
+	if due, _ := svc.ListCancellationsDue(ctx, CancellationFilter{At: end.Add(-time.Second)}); len(due) != 0 {
+		t.Fatalf("expected no cancellation due before period end, got %d", len(due))
+	}
+	if due, _ := svc.ListCancellationsDue(ctx, CancellationFilter{At: end}); len(due) != 1 {
+		t.Fatalf("expected cancellation due at period end, got %d", len(due))
+	}
+
+	sub, err = svc.Reactivate(ctx, sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.CancelAt != nil || !sub.AutoRenew || repo.Subs["sub-1"].CancellationPending() {
+		t.Fatalf("expected reactivated subscription, got %+v", sub)
+	}
+
+	canceledAt := start.AddDate(0, 0, 3)
+	repo.Subs["sub-2"] = Subscription{ID: "sub-2", CancelAt: &canceledAt, CanceledAt: &canceledAt}
+	if _, err := svc.ScheduleCancellation(ctx, repo.Subs["sub-2"]); !errors.Is(err, ErrSubscriptionCanceled) {
+		t.Fatalf("expected ErrSubscriptionCanceled, got %v", err)
+	}
+}
+
+func TestServiceItems(t *testing.T) {
+	repo := NewTestRepository()
+	catalog := NewTestCatalog("USD")
+	catalog.Prices["1"] = pricing.Price{ID: 1, Currency: "USD", BillingInterval: 1, BillingIntervalCount: 1}
+	catalog.Prices["2"] = pricing.Price{ID: 2, Currency: "USD", BillingInterval: 1, BillingIntervalCount: 1}
+	catalog.Prices["3"] = pricing.Price{ID: 3, Currency: "USD", BillingInterval: 2, BillingIntervalCount: 1}
+	catalog.Prices["4"] = pricing.Price{ID: 4, Currency: "EUR", BillingInterval: 1, BillingIntervalCount: 1}
+	svc := NewService(repo, catalog, zap.NewNop())
+	ctx := context.Background()
+	sub := Subscription{ID: "sub-1", TenantID: "t1", PriceID: "1", Currency: "USD"}
+	repo.Subs[sub.ID] = sub
+
+	errorCases := []struct {
+		name string
+		item Item
+		err  error
+	}{
+		{name: "zero quantity", item: Item{ID: "x", PriceID: "2"}, err: ErrInvalidQuantity},
+		{name: "primary price", item: Item{ID: "x", PriceID: "1", Quantity: 1}, err: ErrDuplicateItem},
+		{name: "other interval", item: Item{ID: "x", PriceID: "3", Quantity: 1}, err: ErrIntervalMismatch},
+		{name: "other currency", item: Item{ID: "x", PriceID: "4", Quantity: 1}, err: ErrCurrencyMismatch},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.AddItem(ctx, sub, tt.item); !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	seats, err := svc.AddItem(ctx, sub, Item{ID: "item-1", PriceID: "2", Quantity: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AddItem(ctx, sub, Item{ID: "item-2", PriceID: "2", Quantity: 1}); !errors.Is(err, ErrDuplicateItem) {
+		t.Fatalf("expected ErrDuplicateItem, got %v", err)
+	}
+	if billable := sub.BillableItems([]Item{seats}); len(billable) != 2 || billable[0].PriceID != "1" || billable[0].ID != "" {
+		t.Fatalf("expected primary price first, got %+v", billable)
+	}
+
+	removedAt := time.Now().UTC().Add(-time.Minute)
+	if _, err := svc.RemoveItem(ctx, sub, seats.ID, removedAt); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := svc.ListItems(ctx, sub); len(items) != 0 {
+		t.Fatalf("expected no current items, got %d", len(items))
+	}
+	if items, _ := repo.ListItems(ctx, ItemFilter{SubscriptionID: sub.ID, RemovedAfter: removedAt.Add(-time.Hour)}); len(items) != 1 {
+		t.Fatalf("expected removed item in earlier period, got %d", len(items))
+	}
+	if _, _, err := svc.UpdateQuantity(ctx, sub, seats.ID, 2, time.Now()); !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("expected ErrItemNotFound, got %v", err)
+	}
+}
+
+func TestServiceUpdateQuantity(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	catalog := NewTestCatalog("USD")
+	catalog.Prices["seat"] = pricing.Price{Currency: "USD", BillingInterval: pricing.BillingIntervalMonth, BillingIntervalCount: 1}
+	repo := NewTestRepository()
+	repo.Subs["sub-1"] = Subscription{ID: "sub-1", PriceID: "seat", Currency: "USD", Quantity: 5, AutoRenew: true, CurrentPeriodStart: start, CurrentPeriodEnd: end}
+	repo.Items["item-1"] = Item{ID: "item-1", SubscriptionID: "sub-1", PriceID: "seat", Quantity: 10}
+	svc := NewService(repo, catalog, zap.NewNop())
+	ctx := context.Background()
+	at := start.AddDate(0, 0, 10)
+
+	sub, change, err := svc.UpdateQuantity(ctx, repo.Subs["sub-1"], "", 8, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !change.Increase() || change.Scheduled || sub.Quantity != 8 || !change.EffectiveAt.Equal(at) {
+		t.Fatalf("expected immediate increase to 8, got %+v (quantity %d)", change, sub.Quantity)
+	}
+
+	sub, change, err = svc.UpdateQuantity(ctx, sub, "", 3, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !change.Scheduled || sub.Quantity != 8 || sub.ScheduledQuantity != 3 || !change.EffectiveAt.Equal(end) {
+		t.Fatalf("expected decrease to 3 scheduled at period end, got %+v (quantity %d)", change, sub.Quantity)
+	}
+	if _, change, err = svc.UpdateQuantity(ctx, sub, "item-1", 4, at); err != nil || !change.Scheduled {
+		t.Fatalf("expected scheduled item decrease, got %+v (%v)", change, err)
+	}
+	if _, _, err := svc.UpdateQuantity(ctx, sub, "", 0, at); !errors.Is(err, ErrInvalidQuantity) {
+		t.Fatalf("expected ErrInvalidQuantity, got %v", err)
+	}
+
+	renewal, err := svc.Renew(ctx, repo.Subs["sub-1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(renewal.QuantityChanges) != 2 || renewal.Subscription.Quantity != 3 || renewal.Subscription.ScheduledQuantity != 0 {
+		t.Fatalf("expected both decreases applied at renewal, got %+v", renewal.QuantityChanges)
+	}
+	if item := repo.Items["item-1"]; item.Quantity != 4 || item.ScheduledQuantity != 0 {
+		t.Fatalf("expected item quantity 4, got %+v", item)
+	}
+}
+
+func TestServicePastDue(t *testing.T) {
+	repo := NewTestRepository()
+	repo.Subs["sub-1"] = Subscription{ID: "sub-1", Status: int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE)}
+	svc := NewService(repo, nil, zap.NewNop())
+	ctx := context.Background()
+
+	sub, evt, err := svc.MarkPastDue(ctx, repo.Subs["sub-1"])
+	if err != nil || evt == nil || subscriptionv1.SubscriptionStatus(sub.Status) != SubscriptionStatusPastDue {
+		t.Fatalf("unexpected past due result: status %d err %v", sub.Status, err)
+	}
+	if _, evt, err := svc.MarkPastDue(ctx, sub); err != nil || evt != nil {
+		t.Fatalf("expected repeated failure to keep the subscription past due, got %v %v", evt, err)
+	}
+	if sub, _, err = svc.MarkUnpaid(ctx, sub); err != nil || repo.Subs["sub-1"].Status != int32(SubscriptionStatusUnpaid) {
+		t.Fatalf("expected unpaid, got %d %v", repo.Subs["sub-1"].Status, err)
+	}
+	if _, _, err := svc.Pause(ctx, sub, PauseBehaviorVoid, nil, time.Now()); !errors.Is(err, ErrInvalidSubscriptionTransition) {
+		t.Fatalf("expected unpaid subscription not to pause, got %v", err)
+	}
+	if sub, _, err = svc.Recover(ctx, sub); err != nil || subscriptionv1.SubscriptionStatus(sub.Status) != subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE {
+		t.Fatalf("expected recovery to activate, got %d %v", sub.Status, err)
+	}
+	if _, evt, err := svc.Recover(ctx, sub); err != nil || evt != nil {
+		t.Fatalf("expected active subscription to stay unchanged, got %v %v", evt, err)
+	}
+}
+
+func TestServiceSchedule(t *testing.T) {
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	catalog := NewTestCatalog("USD")
+	catalog.Prices["intro"] = pricing.Price{Currency: "USD", BillingInterval: pricing.BillingIntervalMonth, BillingIntervalCount: 1}
+	catalog.Prices["standard"] = pricing.Price{Currency: "USD", BillingInterval: pricing.BillingIntervalMonth, BillingIntervalCount: 1}
+	repo := NewTestRepository()
+	svc := NewService(repo, catalog, zap.NewNop())
+	ctx := context.Background()
+
+	sub, schedule, err := svc.CreateSchedule(ctx, Subscription{ID: "sub-1", CurrentPeriodStart: start}, Schedule{
+		ID:          "sched-1",
+		EndBehavior: ScheduleEndCancel,
+		Phases:      []Phase{{PriceID: "intro", Iterations: 2}, {PriceID: "standard", Quantity: 5, Iterations: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.PriceID != "intro" || sub.Quantity != 1 || !sub.AutoRenew || schedule.PeriodsInPhase != 1 {
+		t.Fatalf("expected subscription on the first phase, got %+v %+v", sub, schedule)
+	}
+
+	renewal, err := svc.Renew(ctx, sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress := renewal.Schedule; progress == nil || progress.PhaseStarted || progress.Completed || renewal.Subscription.PriceID != "intro" {
+		t.Fatalf("expected second intro period, got %+v", renewal)
+	}
+
+	renewal, err = svc.Renew(ctx, renewal.Subscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub = renewal.Subscription
+	if progress := renewal.Schedule; progress == nil || !progress.PhaseStarted || !progress.Completed {
+		t.Fatalf("expected the last phase to start and complete the schedule, got %+v", renewal.Schedule)
+	}
+	if sub.PriceID != "standard" || sub.Quantity != 5 {
+		t.Fatalf("expected standard price with quantity 5, got %s x%d", sub.PriceID, sub.Quantity)
+	}
+	if sub.AutoRenew || sub.CancelAt == nil || !sub.CancelAt.Equal(sub.CurrentPeriodEnd) {
+		t.Fatalf("expected cancellation at the end of the last phase, got %+v", sub)
+	}
+	if got := repo.Schedules["sched-1"]; got.Status != ScheduleStatusCompleted || got.CurrentPhase != 1 {
+		t.Fatalf("expected completed schedule, got %+v", got)
+	}
+	if _, err := svc.ReleaseSchedule(ctx, repo.Schedules["sched-1"]); !errors.Is(err, ErrScheduleNotActive) {
+		t.Fatalf("expected completed schedule not to release, got %v", err)
+	}
+}
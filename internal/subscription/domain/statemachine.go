@@ -3,6 +3,7 @@ package domain
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
 	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
@@ -17,13 +18,35 @@ const (
 	SubscriptionLifecycleTrialStarted SubscriptionLifecycle = "subscription.trial_started"
 	SubscriptionLifecycleActivated    SubscriptionLifecycle = "subscription.activated"
 	SubscriptionLifecycleCanceled     SubscriptionLifecycle = "subscription.canceled"
+	SubscriptionLifecyclePaused       SubscriptionLifecycle = "subscription.paused"
+	SubscriptionLifecycleResumed      SubscriptionLifecycle = "subscription.resumed"
+	SubscriptionLifecyclePastDue      SubscriptionLifecycle = "subscription.past_due"
+	SubscriptionLifecycleUnpaid       SubscriptionLifecycle = "subscription.unpaid"
+	SubscriptionLifecycleRecovered    SubscriptionLifecycle = "subscription.recovered"
 )
 
+// SubscriptionStatusPaused has no value in the published proto enum; it is
+// stored in the status column next to the proto statuses.
+const SubscriptionStatusPaused = subscriptionv1.SubscriptionStatus(101)
+
+// SubscriptionStatusPastDue marks a subscription whose latest invoice failed
+// to collect and is being retried by dunning.
+const SubscriptionStatusPastDue = subscriptionv1.SubscriptionStatus(102)
+
+// SubscriptionStatusUnpaid marks a subscription left unpaid after dunning gave
+// up. It no longer renews until the outstanding invoice is paid.
+const SubscriptionStatusUnpaid = subscriptionv1.SubscriptionStatus(103)
+
 var subscriptionTransitions = map[SubscriptionLifecycle]transitionRule{
 	SubscriptionLifecycleCreated:      {from: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_UNSPECIFIED)}, to: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING), subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE)}},
 	SubscriptionLifecycleTrialStarted: {from: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING)}, to: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING)}},
 	SubscriptionLifecycleActivated:    {from: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING), subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE)}, to: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE)}},
-	SubscriptionLifecycleCanceled:     {from: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE), subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING)}, to: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_CANCELED)}},
+	SubscriptionLifecycleCanceled:     {from: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE), subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING), SubscriptionStatusPaused, SubscriptionStatusPastDue, SubscriptionStatusUnpaid}, to: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_CANCELED)}},
+	SubscriptionLifecyclePaused:       {from: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE), SubscriptionStatusPastDue}, to: []subscriptionv1.SubscriptionStatus{SubscriptionStatusPaused}},
+	SubscriptionLifecycleResumed:      {from: []subscriptionv1.SubscriptionStatus{SubscriptionStatusPaused}, to: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE)}},
+	SubscriptionLifecyclePastDue:      {from: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE)}, to: []subscriptionv1.SubscriptionStatus{SubscriptionStatusPastDue}},
+	SubscriptionLifecycleUnpaid:       {from: []subscriptionv1.SubscriptionStatus{SubscriptionStatusPastDue}, to: []subscriptionv1.SubscriptionStatus{SubscriptionStatusUnpaid}},
+	SubscriptionLifecycleRecovered:    {from: []subscriptionv1.SubscriptionStatus{SubscriptionStatusPastDue, SubscriptionStatusUnpaid}, to: []subscriptionv1.SubscriptionStatus{subsStatus(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE)}},
 }
 
 type transitionRule struct {
@@ -54,13 +77,26 @@ func (s *Subscription) ApplyLifecycle(event SubscriptionLifecycle, target subscr
 	}
 	current := subscriptionv1.SubscriptionStatus(s.Status)
 	if !containsStatus(rule.from, current) && current != subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_UNSPECIFIED {
-		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidSubscriptionTransition, current.String(), target.String())
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidSubscriptionTransition, StatusName(current), StatusName(target))
 	}
 	if !containsStatus(rule.to, target) {
-		return nil, fmt.Errorf("%w: invalid target %s for %s", ErrInvalidSubscriptionTransition, target.String(), event)
+		return nil, fmt.Errorf("%w: invalid target %s for %s", ErrInvalidSubscriptionTransition, StatusName(target), event)
 	}
 	s.Status = int32(target)
-	return buildSubscriptionEvent(s, target.String())
+	return buildSubscriptionEvent(s, StatusName(target))
+}
+
+// StatusName returns the enum name of a status, including domain-only statuses.
+func StatusName(status subscriptionv1.SubscriptionStatus) string {
+	switch status {
+	case SubscriptionStatusPaused:
+		return "SUBSCRIPTION_STATUS_PAUSED"
+	case SubscriptionStatusPastDue:
+		return "SUBSCRIPTION_STATUS_PAST_DUE"
+	case SubscriptionStatusUnpaid:
+		return "SUBSCRIPTION_STATUS_UNPAID"
+	}
+	return status.String()
 }
 
 func buildSubscriptionEvent(sub *Subscription, status string) (*eventv1.Event, error) {
@@ -77,3 +113,64 @@ func buildSubscriptionEvent(sub *Subscription, status string) (*eventv1.Event, e
 		Data:     payload,
 	}, nil
 }
+
+// QuantityUpdatedEvent builds subscription.quantity.updated for a quantity
+// change that took effect. invoiceID is the invoice prorating an increase, if any.
+func QuantityUpdatedEvent(sub Subscription, change QuantityChange, invoiceID string) *eventv1.Event {
+	return &eventv1.Event{
+		Subject:  "subscription.quantity.updated",
+		TenantId: sub.TenantID,
+		Data: &structpb.Struct{Fields: map[string]*structpb.Value{
+			"subscription_id":   structpb.NewStringValue(sub.ID),
+			"customer_id":       structpb.NewStringValue(sub.CustomerID),
+			"item_id":           structpb.NewStringValue(change.ItemID),
+			"price_id":          structpb.NewStringValue(change.PriceID),
+			"previous_quantity": structpb.NewNumberValue(float64(change.Previous)),
+			"quantity":          structpb.NewNumberValue(float64(change.Quantity)),
+			"effective_at":      structpb.NewStringValue(change.EffectiveAt.Format(time.RFC3339)),
+			"invoice_id":        structpb.NewStringValue(invoiceID),
+		}},
+	}
+}
+
+// PhaseStartedEvent builds subscription.schedule.phase_started for the
+// schedule's current phase, which began with sub's current period.
+func PhaseStartedEvent(sub Subscription, schedule Schedule) *eventv1.Event {
+	phase := schedule.Phase()
+	return &eventv1.Event{
+		Subject:  "subscription.schedule.phase_started",
+		TenantId: sub.TenantID,
+		Data: &structpb.Struct{Fields: map[string]*structpb.Value{
+			"schedule_id":     structpb.NewStringValue(schedule.ID),
+			"subscription_id": structpb.NewStringValue(sub.ID),
+			"customer_id":     structpb.NewStringValue(sub.CustomerID),
+			"phase_index":     structpb.NewNumberValue(float64(schedule.CurrentPhase)),
+			"price_id":        structpb.NewStringValue(phase.PriceID),
+			"quantity":        structpb.NewNumberValue(float64(sub.Quantity)),
+			"coupon_code":     structpb.NewStringValue(phase.CouponCode),
+			"discount_id":     structpb.NewStringValue(schedule.DiscountID),
+			"period_start":    structpb.NewStringValue(sub.CurrentPeriodStart.Format(time.RFC3339)),
+			"period_end":      structpb.NewStringValue(sub.CurrentPeriodEnd.Format(time.RFC3339)),
+		}},
+	}
+}
+
+// ScheduleCompletedEvent builds subscription.schedule.completed once the last
+// period covered by the schedule has started. cancel_at is set when the
+// subscription ends with it.
+func ScheduleCompletedEvent(sub Subscription, schedule Schedule) *eventv1.Event {
+	cancelAt := ""
+	if sub.CancelAt != nil {
+		cancelAt = sub.CancelAt.Format(time.RFC3339)
+	}
+	return &eventv1.Event{
+		Subject:  "subscription.schedule.completed",
+		TenantId: sub.TenantID,
+		Data: &structpb.Struct{Fields: map[string]*structpb.Value{
+			"schedule_id":     structpb.NewStringValue(schedule.ID),
+			"subscription_id": structpb.NewStringValue(sub.ID),
+			"end_behavior":    structpb.NewStringValue(string(schedule.EndBehavior)),
+			"cancel_at":       structpb.NewStringValue(cancelAt),
+		}},
+	}
+}
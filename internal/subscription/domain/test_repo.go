@@ -3,11 +3,17 @@ package domain
 import (
 	"context"
 	"errors"
+	"slices"
+	"time"
+
+	pricing "github.com/smallbiznis/corebilling/internal/pricing/domain"
 )
 
 // TestRepository is an in-memory repo for tests.
 type TestRepository struct {
 	Subs       map[string]Subscription
+	Items      map[string]Item
+	Schedules  map[string]Schedule
 	FailCreate bool
 	FailGet    bool
 	FailList   bool
@@ -17,7 +23,9 @@ type TestRepository struct {
 // NewTestRepository creates a fresh test repo.
 func NewTestRepository() *TestRepository {
 	return &TestRepository{
-		Subs: make(map[string]Subscription),
+		Subs:      make(map[string]Subscription),
+		Items:     make(map[string]Item),
+		Schedules: make(map[string]Schedule),
 	}
 }
 
@@ -67,3 +75,219 @@ func (r *TestRepository) Update(ctx context.Context, sub Subscription) error {
 	r.Subs[sub.ID] = sub
 	return nil
 }
+
+func (r *TestRepository) SaveRenewal(ctx context.Context, sub Subscription, items []Item, schedule *Schedule) error {
+	if r.FailUpdate {
+		return errors.New("update error")
+	}
+	r.Subs[sub.ID] = sub
+	for _, item := range items {
+		r.Items[item.ID] = item
+	}
+	if schedule != nil {
+		r.Schedules[schedule.ID] = *schedule
+	}
+	return nil
+}
+
+func (r *TestRepository) ListRenewalsDue(ctx context.Context, filter RenewalFilter) ([]Subscription, error) {
+	if r.FailList {
+		return nil, errors.New("list error")
+	}
+	var due []Subscription
+	for _, sub := range r.Subs {
+		if !sub.AutoRenew || sub.CurrentPeriodEnd.After(filter.At) {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, sub.Status) {
+			continue
+		}
+		due = append(due, sub)
+	}
+	if filter.Limit > 0 && len(due) > filter.Limit {
+		due = due[:filter.Limit]
+	}
+	return due, nil
+}
+
+func (r *TestRepository) ListTrialsEnding(ctx context.Context, filter TrialFilter) ([]Subscription, error) {
+	if r.FailList {
+		return nil, errors.New("list error")
+	}
+	var ending []Subscription
+	for _, sub := range r.Subs {
+		if sub.Status != filter.Status || sub.TrialEndAt == nil {
+			continue
+		}
+		if !sub.TrialEndAt.After(filter.EndsAfter) || sub.TrialEndAt.After(filter.EndsBy) {
+			continue
+		}
+		if filter.Unreminded && sub.TrialReminderSentAt != nil {
+			continue
+		}
+		ending = append(ending, sub)
+	}
+	if filter.Limit > 0 && len(ending) > filter.Limit {
+		ending = ending[:filter.Limit]
+	}
+	return ending, nil
+}
+
+func (r *TestRepository) ListResumesDue(ctx context.Context, filter ResumeFilter) ([]Subscription, error) {
+	if r.FailList {
+		return nil, errors.New("list error")
+	}
+	var due []Subscription
+	for _, sub := range r.Subs {
+		if sub.Pause.ResumeDue(filter.At) {
+			due = append(due, sub)
+		}
+	}
+	if filter.Limit > 0 && len(due) > filter.Limit {
+		due = due[:filter.Limit]
+	}
+	return due, nil
+}
+
+func (r *TestRepository) ListCancellationsDue(ctx context.Context, filter CancellationFilter) ([]Subscription, error) {
+	if r.FailList {
+		return nil, errors.New("list error")
+	}
+	var due []Subscription
+	for _, sub := range r.Subs {
+		if !sub.CancellationPending() || sub.CancelAt.After(filter.At) {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, sub.Status) {
+			continue
+		}
+		due = append(due, sub)
+	}
+	if filter.Limit > 0 && len(due) > filter.Limit {
+		due = due[:filter.Limit]
+	}
+	return due, nil
+}
+
+func (r *TestRepository) CreateItem(ctx context.Context, item Item) error {
+	if r.FailCreate {
+		return errors.New("create error")
+	}
+	r.Items[item.ID] = item
+	return nil
+}
+
+func (r *TestRepository) GetItem(ctx context.Context, id string) (Item, error) {
+	if r.FailGet {
+		return Item{}, errors.New("get error")
+	}
+	item, ok := r.Items[id]
+	if !ok {
+		return Item{}, errors.New("not found")
+	}
+	return item, nil
+}
+
+func (r *TestRepository) UpdateItem(ctx context.Context, item Item) error {
+	if r.FailUpdate {
+		return errors.New("update error")
+	}
+	r.Items[item.ID] = item
+	return nil
+}
+
+func (r *TestRepository) ListItems(ctx context.Context, filter ItemFilter) ([]Item, error) {
+	if r.FailList {
+		return nil, errors.New("list error")
+	}
+	var items []Item
+	for _, item := range r.Items {
+		if item.SubscriptionID != filter.SubscriptionID {
+			continue
+		}
+		if item.RemovedAt != nil && !item.RemovedAt.After(filter.RemovedAfter) {
+			continue
+		}
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b Item) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return items, nil
+}
+
+func (r *TestRepository) CreateSchedule(ctx context.Context, schedule Schedule) error {
+	if r.FailCreate {
+		return errors.New("create error")
+	}
+	r.Schedules[schedule.ID] = schedule
+	return nil
+}
+
+func (r *TestRepository) GetSchedule(ctx context.Context, id string) (Schedule, error) {
+	if r.FailGet {
+		return Schedule{}, errors.New("get error")
+	}
+	schedule, ok := r.Schedules[id]
+	if !ok {
+		return Schedule{}, errors.New("not found")
+	}
+	return schedule, nil
+}
+
+func (r *TestRepository) UpdateSchedule(ctx context.Context, schedule Schedule) error {
+	if r.FailUpdate {
+		return errors.New("update error")
+	}
+	r.Schedules[schedule.ID] = schedule
+	return nil
+}
+
+func (r *TestRepository) GetActiveSchedule(ctx context.Context, subscriptionID string) (Schedule, bool, error) {
+	if r.FailGet {
+		return Schedule{}, false, errors.New("get error")
+	}
+	for _, schedule := range r.Schedules {
+		if schedule.SubscriptionID == subscriptionID && schedule.Status == ScheduleStatusActive {
+			return schedule, true, nil
+		}
+	}
+	return Schedule{}, false, nil
+}
+
+// TestCatalog is an in-memory price catalog for tests.
+type TestCatalog struct {
+	Prices   map[string]pricing.Price
+	Currency string
+	// Migrations maps a price id to the version published after it. Like
+	// the pricing repository, MigrationTarget only returns versions with
+	// the migrate policy.
+	Migrations map[string]pricing.Price
+}
+
+// NewTestCatalog creates a catalog billing customers in currency.
+func NewTestCatalog(currency string) *TestCatalog {
+	return &TestCatalog{
+		Prices:     make(map[string]pricing.Price),
+		Currency:   currency,
+		Migrations: make(map[string]pricing.Price),
+	}
+}
+
+func (c *TestCatalog) GetPrice(ctx context.Context, tenantID, priceID string) (pricing.Price, error) {
+	price, ok := c.Prices[priceID]
+	if !ok {
+		return pricing.Price{}, errors.New("price not found")
+	}
+	return price, nil
+}
+
+func (c *TestCatalog) BillingCurrency(ctx context.Context, tenantID, customerID string) (string, error) {
+	return c.Currency, nil
+}
+
+func (c *TestCatalog) MigrationTarget(ctx context.Context, tenantID, priceID string, at time.Time) (pricing.Price, bool, error) {
+	target, ok := c.Migrations[priceID]
+	if !ok || target.MigrationPolicy != pricing.MigrationPolicyMigrate || target.EffectiveFrom.After(at) {
+		return pricing.Price{}, false, nil
+	}
+	return target, true, nil
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
+	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
+	"go.uber.org/zap"
+)
+
+// TrialOutcome records how a trial ended.
+type TrialOutcome string
+
+const (
+	// TrialConverted activates the subscription and starts billing.
+	TrialConverted TrialOutcome = "converted"
+	// TrialCanceled cancels the subscription, e.g. when no payment method is on file.
+	TrialCanceled TrialOutcome = "canceled"
+)
+
+// ErrNotTrialing is returned when ending the trial of a subscription that is not trialing.
+var ErrNotTrialing = errors.New("subscription is not trialing")
+
+// EndTrial closes the trial of a trialing subscription at its trial end.
+// Converted subscriptions become active with their first paid period starting
+// when the trial ended; canceled ones stop renewing. The returned event
+// records the status change.
+func (s *Service) EndTrial(ctx context.Context, sub Subscription, outcome TrialOutcome) (Subscription, *eventv1.Event, error) {
+	if sub.Status != int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING) || sub.TrialEndAt == nil {
+		return Subscription{}, nil, ErrNotTrialing
+	}
+	end := *sub.TrialEndAt
+
+	var evt *eventv1.Event
+	var err error
+	switch outcome {
+	case TrialConverted:
+		if sub, err = s.startFirstPaidPeriod(ctx, sub, end); err != nil {
+			return Subscription{}, nil, err
+		}
+		evt, err = sub.ApplyLifecycle(SubscriptionLifecycleActivated, subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE)
+	case TrialCanceled:
+		evt, err = sub.ApplyLifecycle(SubscriptionLifecycleCanceled, subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_CANCELED)
+		sub.AutoRenew = false
+		sub.CancelAt = &end
+		sub.CanceledAt = &end
+		sub.CurrentPeriodEnd = end
+		sub.ScheduledChange = nil
+	default:
+		return Subscription{}, nil, fmt.Errorf("unknown trial outcome %q", outcome)
+	}
+	if err != nil {
+		return Subscription{}, nil, err
+	}
+
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.Update(ctx, sub); err != nil {
+		return Subscription{}, nil, err
+	}
+	s.logger.Info("subscription trial ended",
+		zap.String("subscription_id", sub.ID),
+		zap.String("outcome", string(outcome)),
+		zap.Time("trial_end_at", end),
+	)
+	return sub, evt, nil
+}
@@ -2,10 +2,14 @@ package subscription
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"time"
 
 	"github.com/bwmarrin/snowflake"
+	coupondomain "github.com/smallbiznis/corebilling/internal/coupon/domain"
+	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	invoiceengine "github.com/smallbiznis/corebilling/internal/invoice_engine/domain"
 	"github.com/smallbiznis/corebilling/internal/subscription/domain"
 	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
 	"go.uber.org/fx"
@@ -19,8 +23,8 @@ import (
 // ModuleGRPC registers the subscription service.
 var ModuleGRPC = fx.Invoke(RegisterGRPC)
 
-func RegisterService(svc *domain.Service, genID *snowflake.Node) *grpcService {
-	return NewGrpcService(svc, genID)
+func RegisterService(svc *domain.Service, engine *invoiceengine.Service, coupons *coupondomain.Service, outboxRepo outbox.OutboxRepository, genID *snowflake.Node) *grpcService {
+	return NewGrpcService(svc, engine, coupons, outboxRepo, genID)
 }
 
 // RegisterGRPC attaches the subscription handler.
@@ -30,7 +34,10 @@ func RegisterGRPC(server *grpc.Server, svc *grpcService) {
 
 type grpcService struct {
 	subscriptionv1.UnimplementedSubscriptionServiceServer
-	svc *domain.Service
+	svc     *domain.Service
+	engine  *invoiceengine.Service
+	coupons *coupondomain.Service
+	outbox  outbox.OutboxRepository
 
 	genID *snowflake.Node
 }
@@ -40,8 +47,8 @@ const (
 	maxSubscriptionPageSize     = 200
 )
 
-func NewGrpcService(svc *domain.Service, genID *snowflake.Node) *grpcService {
-	return &grpcService{svc: svc, genID: genID}
+func NewGrpcService(svc *domain.Service, engine *invoiceengine.Service, coupons *coupondomain.Service, outboxRepo outbox.OutboxRepository, genID *snowflake.Node) *grpcService {
+	return &grpcService{svc: svc, engine: engine, coupons: coupons, outbox: outboxRepo, genID: genID}
 }
 
 func (g *grpcService) CreateSubscription(ctx context.Context, req *subscriptionv1.CreateSubscriptionRequest) (*subscriptionv1.Subscription, error) {
@@ -57,9 +64,23 @@ func (g *grpcService) CreateSubscription(ctx context.Context, req *subscriptionv
 	currentPeriodStart := startAt
 	currentPeriodEnd := startAt.AddDate(0, 1, 0)
 
-	status := subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE
+	metadata := structToMap(req.GetMetadata())
+	anchor, err := domain.ParseBillingAnchor(stringValue(metadata, "billing_anchor"))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	quantity := int64(1)
+	if v, ok := metadata["quantity"].(float64); ok {
+		if v < 1 || v != float64(int64(v)) {
+			return nil, status.Error(codes.InvalidArgument, domain.ErrInvalidQuantity.Error())
+		}
+		quantity = int64(v)
+		delete(metadata, "quantity")
+	}
+
+	initialStatus := subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE
 	if req.GetTrialStartAt() != nil && req.GetTrialEndAt() != nil {
-		status = subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING
+		initialStatus = subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING
 	}
 
 	sub := domain.Subscription{
@@ -67,22 +88,28 @@ func (g *grpcService) CreateSubscription(ctx context.Context, req *subscriptionv
 		TenantID:           req.GetTenantId(),
 		CustomerID:         req.GetCustomerId(),
 		PriceID:            req.GetPriceId(),
-		Status:             int32(status),
+		Status:             int32(initialStatus),
 		AutoRenew:          req.GetAutoRenew(),
 		StartAt:            startAt,
 		CurrentPeriodStart: currentPeriodStart,
 		CurrentPeriodEnd:   currentPeriodEnd,
 		TrialStartAt:       toTimePtr(req.GetTrialStartAt()),
 		TrialEndAt:         toTimePtr(req.GetTrialEndAt()),
-		Metadata:           structToMap(req.GetMetadata()),
+		BillingAnchor:      anchor,
+		Quantity:           quantity,
+		Metadata:           metadata,
 		CreatedAt:          now,
 		UpdatedAt:          now,
 	}
 
-	if err := g.svc.Create(ctx, sub); err != nil {
+	created, err := g.svc.Create(ctx, sub)
+	if errors.Is(err, domain.ErrCurrencyMismatch) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
 		return nil, err
 	}
-	return g.toProto(sub), nil
+	return g.toProto(created), nil
 }
 
 func (g *grpcService) GetSubscription(ctx context.Context, req *subscriptionv1.GetSubscriptionRequest) (*subscriptionv1.Subscription, error) {
@@ -146,24 +173,81 @@ func (g *grpcService) CancelSubscription(ctx context.Context, req *subscriptionv
 		return nil, status.Error(codes.NotFound, "subscription not found")
 	}
 
+	canceled, _, err := g.cancel(ctx, sub, req.GetCancelAtPeriodEnd(), false)
+	if err != nil {
+		return nil, lifecycleError(err)
+	}
+	return g.toProto(canceled), nil
+}
+
+// cancel schedules the cancellation for the end of the current period, or
+// ends the subscription now and invoices what is outstanding, prorating the
+// recurring fee when requested. It returns the cancellation invoice id, if any.
+func (g *grpcService) cancel(ctx context.Context, sub domain.Subscription, atPeriodEnd, prorate bool) (domain.Subscription, string, error) {
+	if sub.Status == int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_CANCELED) {
+		return domain.Subscription{}, "", domain.ErrSubscriptionCanceled
+	}
+	if atPeriodEnd {
+		sub, err := g.svc.ScheduleCancellation(ctx, sub)
+		return sub, "", err
+	}
+
 	now := time.Now().UTC()
-	sub.AutoRenew = false
-	sub.UpdatedAt = now
-	sub.Status = int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_CANCELED)
-	if req.GetCancelAtPeriodEnd() {
-		cancelAt := sub.CurrentPeriodEnd
-		sub.CancelAt = &cancelAt
-		sub.CanceledAt = nil
-	} else {
-		cancelAt := now
-		sub.CancelAt = &cancelAt
-		sub.CanceledAt = &cancelAt
-	}
-
-	if err := g.svc.Update(ctx, sub); err != nil {
-		return nil, err
+	var invoiceID string
+	if g.engine != nil && sub.Status != int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING) {
+		var err error
+		invoiceID, err = g.engine.InvoiceCancellation(ctx, invoiceengine.CancellationRequest{Subscription: sub, At: now, Prorate: prorate})
+		if err != nil {
+			return domain.Subscription{}, "", err
+		}
+	}
+	canceled, evt, err := g.svc.Cancel(ctx, sub, now)
+	if err != nil {
+		return domain.Subscription{}, "", err
 	}
-	return g.toProto(sub), nil
+	g.emit(ctx, evt)
+	return canceled, invoiceID, nil
+}
+
+// updateQuantity sets the quantity of the primary price, or of an item. An
+// increase is prorated for the rest of the period on an immediate invoice and
+// emits subscription.quantity.updated; decreases wait for the renewal worker.
+// sourceKey makes the proration idempotent across retries.
+func (g *grpcService) updateQuantity(ctx context.Context, sub domain.Subscription, itemID string, quantity int64, sourceKey string) (domain.Subscription, domain.QuantityChange, string, error) {
+	current := sub.BillableItems(nil)[0]
+	if itemID != "" {
+		var err error
+		if current, err = g.svc.GetItem(ctx, sub, itemID); err != nil {
+			return domain.Subscription{}, domain.QuantityChange{}, "", err
+		}
+	}
+
+	now := time.Now().UTC()
+	var invoiceID string
+	if quantity > current.Quantity && g.engine != nil && sub.Status != int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_TRIALING) {
+		result, err := g.engine.ProrateQuantity(ctx, invoiceengine.QuantityProrationRequest{
+			Subscription: sub,
+			ItemID:       itemID,
+			PriceID:      current.PriceID,
+			Previous:     current.Quantity,
+			Quantity:     quantity,
+			At:           now,
+			SourceKey:    sourceKey,
+		})
+		if err != nil {
+			return domain.Subscription{}, domain.QuantityChange{}, "", err
+		}
+		invoiceID = result.InvoiceID
+	}
+
+	sub, change, err := g.svc.UpdateQuantity(ctx, sub, itemID, quantity, now)
+	if err != nil {
+		return domain.Subscription{}, domain.QuantityChange{}, "", err
+	}
+	if change.Increase() {
+		g.emit(ctx, domain.QuantityUpdatedEvent(sub, change, invoiceID))
+	}
+	return sub, change, invoiceID, nil
 }
 
 func (g *grpcService) toProto(sub domain.Subscription) *subscriptionv1.Subscription {
@@ -204,8 +288,76 @@ func (g *grpcService) toProto(sub domain.Subscription) *subscriptionv1.Subscript
 		TrialEndAt:         trialEnd,
 		CancelAt:           cancelAt,
 		CanceledAt:         canceledAt,
-		Metadata:           mapToStruct(sub.Metadata),
+		Metadata:           mapToStruct(withQuantity(withPause(withScheduledChange(withCurrency(sub.Metadata, sub.Currency), sub.ScheduledChange), sub.Pause), sub)),
+	}
+}
+
+// The with* helpers copy subscription state that the Subscription message has
+// no field for into a copy of its metadata, so gRPC clients can read it
+// without a proto change. Stored metadata is never modified.
+
+// withCurrency exposes the billing currency.
+func withCurrency(metadata map[string]interface{}, currency string) map[string]interface{} {
+	if currency == "" {
+		return metadata
+	}
+	out := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	out["currency"] = currency
+	return out
+}
+
+// withScheduledChange exposes a pending price change.
+func withScheduledChange(metadata map[string]interface{}, change *domain.ScheduledChange) map[string]interface{} {
+	if change == nil {
+		return metadata
+	}
+	out := make(map[string]interface{}, len(metadata)+2)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	out["scheduled_price_id"] = change.PriceID
+	out["scheduled_change_at"] = change.EffectiveAt.Format(time.RFC3339)
+	return out
+}
+
+// withPause exposes an active pause.
+func withPause(metadata map[string]interface{}, pause *domain.Pause) map[string]interface{} {
+	if pause == nil {
+		return metadata
+	}
+	out := make(map[string]interface{}, len(metadata)+3)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	out["paused_at"] = pause.PausedAt.Format(time.RFC3339)
+	out["pause_behavior"] = string(pause.Behavior)
+	if pause.ResumesAt != nil {
+		out["resumes_at"] = pause.ResumesAt.Format(time.RFC3339)
+	}
+	return out
+}
+
+// withQuantity exposes the primary price's quantity.
+func withQuantity(metadata map[string]interface{}, sub domain.Subscription) map[string]interface{} {
+	out := make(map[string]interface{}, len(metadata)+2)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	out["quantity"] = sub.BillableItems(nil)[0].Quantity
+	if sub.ScheduledQuantity > 0 {
+		out["scheduled_quantity"] = sub.ScheduledQuantity
+	}
+	return out
+}
+
+func stringValue(metadata map[string]interface{}, key string) string {
+	if v, ok := metadata[key].(string); ok {
+		return v
 	}
+	return ""
 }
 
 func parsePageToken(token string) int {
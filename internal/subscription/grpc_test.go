@@ -16,7 +16,7 @@ import (
 
 func setupService() (*grpcService, *domain.TestRepository) {
 	repo := domain.NewTestRepository()
-	svc := domain.NewService(repo, zap.NewNop())
+	svc := domain.NewService(repo, nil, zap.NewNop())
 	return &grpcService{svc: svc}, repo
 }
 
@@ -101,8 +101,8 @@ func TestCancelSubscription(t *testing.T) {
 		t.Fatal(err)
 	}
 	stored, _ := repo.GetByID(context.Background(), sub.ID)
-	if stored.Status != int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_CANCELED) {
-		t.Fatalf("expected canceled status, got %d", stored.Status)
+	if stored.Status != int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE) || stored.AutoRenew {
+		t.Fatalf("expected active, non-renewing subscription until period end, got status %d", stored.Status)
 	}
 	if stored.CanceledAt != nil {
 		t.Fatal("expected canceled_at nil when canceling at period end")
@@ -120,7 +120,7 @@ func TestCancelSubscription(t *testing.T) {
 		t.Fatal(err)
 	}
 	stored, _ = repo.GetByID(context.Background(), sub.ID)
-	if stored.CanceledAt == nil {
+	if stored.CanceledAt == nil || stored.Status != int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_CANCELED) {
 		t.Fatal("expected canceled_at set for immediate cancel")
 	}
 }
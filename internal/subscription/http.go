@@ -2,11 +2,23 @@ package subscription
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	coupondomain "github.com/smallbiznis/corebilling/internal/coupon/domain"
+	"github.com/smallbiznis/corebilling/internal/events/outbox"
+	"github.com/smallbiznis/corebilling/internal/headers"
+	"github.com/smallbiznis/corebilling/internal/subscription/domain"
+	eventv1 "github.com/smallbiznis/go-genproto/smallbiznis/event/v1"
 	subscriptionv1 "github.com/smallbiznis/go-genproto/smallbiznis/subscription/v1"
 	"go.uber.org/fx"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 var ModuleHTTP = fx.Invoke(RegisterHTTP)
@@ -17,7 +29,623 @@ func RegisterHTTP(lc fx.Lifecycle, s *grpc.Server, mux *runtime.ServeMux, svc *g
 			if err := subscriptionv1.RegisterSubscriptionServiceHandlerServer(ctx, mux, svc); err != nil {
 				return err
 			}
-			return nil
+			if err := mux.HandlePath(http.MethodPost, "/v1/subscriptions/{id}/scheduled_change", svc.scheduleChangeHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodDelete, "/v1/subscriptions/{id}/scheduled_change", svc.cancelScheduledChangeHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPost, "/v1/subscriptions/{id}/pause", svc.pauseHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPost, "/v1/subscriptions/{id}/resume", svc.resumeHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPost, "/v1/subscriptions/{id}/cancel", svc.cancelHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPost, "/v1/subscriptions/{id}/reactivate", svc.reactivateHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodGet, "/v1/subscriptions/{id}/items", svc.listItemsHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPost, "/v1/subscriptions/{id}/items", svc.addItemHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPatch, "/v1/subscriptions/{id}/items/{item_id}", svc.updateItemHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodDelete, "/v1/subscriptions/{id}/items/{item_id}", svc.removeItemHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPost, "/v1/subscriptions/{id}/quantity", svc.updateQuantityHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodPost, "/v1/subscription_schedules", svc.createScheduleHandler); err != nil {
+				return err
+			}
+			if err := mux.HandlePath(http.MethodGet, "/v1/subscription_schedules/{id}", svc.getScheduleHandler); err != nil {
+				return err
+			}
+			return mux.HandlePath(http.MethodPost, "/v1/subscription_schedules/{id}/release", svc.releaseScheduleHandler)
 		},
 	})
 }
+
+type scheduleChangeRequest struct {
+	PriceID string `json:"price_id"`
+}
+
+// scheduleChangeHandler defers a price change, typically a downgrade, to the end of the current period.
+func (g *grpcService) scheduleChangeHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if _, err := g.tenantSubscription(r, params["id"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	var body scheduleChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.PriceID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "price_id required"))
+		return
+	}
+	sub, err := g.svc.SchedulePriceChange(r.Context(), params["id"], body.PriceID)
+	switch {
+	case errors.Is(err, domain.ErrCurrencyMismatch), errors.Is(err, domain.ErrPriceArchived):
+		writeError(w, status.Error(codes.FailedPrecondition, err.Error()))
+		return
+	case err != nil:
+		writeError(w, err)
+		return
+	}
+	writeProto(w, g.toProto(sub))
+}
+
+func (g *grpcService) cancelScheduledChangeHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if _, err := g.tenantSubscription(r, params["id"]); err != nil {
+		writeError(w, err)
+		return
+	}
+	sub, err := g.svc.CancelScheduledChange(r.Context(), params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeProto(w, g.toProto(sub))
+}
+
+type pauseRequest struct {
+	Behavior  string     `json:"behavior"`
+	ResumesAt *time.Time `json:"resumes_at"`
+}
+
+// pauseHandler pauses an active subscription, optionally until resumes_at.
+func (g *grpcService) pauseHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	sub, err := g.tenantSubscription(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var body pauseRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+	}
+	behavior, err := domain.ParsePauseBehavior(body.Behavior)
+	if err != nil {
+		writeError(w, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+	now := time.Now().UTC()
+	if body.ResumesAt != nil && !body.ResumesAt.After(now) {
+		writeError(w, status.Error(codes.InvalidArgument, "resumes_at must be in the future"))
+		return
+	}
+
+	sub, evt, err := g.svc.Pause(r.Context(), sub, behavior, body.ResumesAt, now)
+	if err != nil {
+		writeError(w, lifecycleError(err))
+		return
+	}
+	g.emit(r.Context(), evt)
+	writeProto(w, g.toProto(sub))
+}
+
+// resumeHandler reactivates a paused subscription.
+func (g *grpcService) resumeHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	sub, err := g.tenantSubscription(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sub, evt, err := g.svc.Resume(r.Context(), sub, time.Now().UTC())
+	if err != nil {
+		writeError(w, lifecycleError(err))
+		return
+	}
+	g.emit(r.Context(), evt)
+	writeProto(w, g.toProto(sub))
+}
+
+type cancelRequest struct {
+	AtPeriodEnd bool `json:"at_period_end"`
+	Prorate     bool `json:"prorate"`
+}
+
+type cancelResponse struct {
+	Subscription json.RawMessage `json:"subscription"`
+	InvoiceID    string          `json:"invoice_id,omitempty"`
+}
+
+// cancelHandler cancels a subscription now, invoicing what is outstanding, or
+// at the end of its current period.
+func (g *grpcService) cancelHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	sub, err := g.tenantSubscription(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var body cancelRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+	}
+	sub, invoiceID, err := g.cancel(r.Context(), sub, body.AtPeriodEnd, body.Prorate)
+	if err != nil {
+		writeError(w, lifecycleError(err))
+		return
+	}
+	payload, err := protojson.Marshal(g.toProto(sub))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, cancelResponse{Subscription: payload, InvoiceID: invoiceID})
+}
+
+// reactivateHandler drops a pending cancellation.
+func (g *grpcService) reactivateHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	sub, err := g.tenantSubscription(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sub, err = g.svc.Reactivate(r.Context(), sub)
+	if err != nil {
+		writeError(w, lifecycleError(err))
+		return
+	}
+	writeProto(w, g.toProto(sub))
+}
+
+type itemRequest struct {
+	PriceID  string `json:"price_id"`
+	Quantity int64  `json:"quantity"`
+}
+
+type itemResponse struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	PriceID        string     `json:"price_id"`
+	Quantity       int64      `json:"quantity"`
+	RemovedAt      *time.Time `json:"removed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func toItemResponse(item domain.Item) itemResponse {
+	return itemResponse{
+		ID:             item.ID,
+		SubscriptionID: item.SubscriptionID,
+		PriceID:        item.PriceID,
+		Quantity:       item.Quantity,
+		RemovedAt:      item.RemovedAt,
+		CreatedAt:      item.CreatedAt,
+		UpdatedAt:      item.UpdatedAt,
+	}
+}
+
+// listItemsHandler returns the subscription's items, not including its primary price.
+func (g *grpcService) listItemsHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	sub, err := g.tenantSubscription(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	items, err := g.svc.ListItems(r.Context(), sub)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := make([]itemResponse, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, toItemResponse(item))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": resp})
+}
+
+// addItemHandler bills an additional price on the subscription. The quantity defaults to 1.
+func (g *grpcService) addItemHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	sub, err := g.tenantSubscription(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var body itemRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.PriceID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "price_id required"))
+		return
+	}
+	if body.Quantity == 0 {
+		body.Quantity = 1
+	}
+	item, err := g.svc.AddItem(r.Context(), sub, domain.Item{
+		ID:       g.genID.Generate().String(),
+		PriceID:  body.PriceID,
+		Quantity: body.Quantity,
+	})
+	if err != nil {
+		writeError(w, itemError(err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, toItemResponse(item))
+}
+
+// updateItemHandler changes an item's quantity like updateQuantityHandler.
+func (g *grpcService) updateItemHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	sub, err := g.tenantSubscription(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var body itemRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+		return
+	}
+	_, _, _, err = g.updateQuantity(r.Context(), sub, params["item_id"], body.Quantity, g.sourceKey(r))
+	if err != nil {
+		writeError(w, itemError(err))
+		return
+	}
+	item, err := g.svc.GetItem(r.Context(), sub, params["item_id"])
+	if err != nil {
+		writeError(w, itemError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, toItemResponse(item))
+}
+
+// removeItemHandler stops billing an item. Its time on the current period is still invoiced.
+func (g *grpcService) removeItemHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	sub, err := g.tenantSubscription(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	item, err := g.svc.RemoveItem(r.Context(), sub, params["item_id"], time.Now().UTC())
+	if err != nil {
+		writeError(w, itemError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, toItemResponse(item))
+}
+
+type quantityRequest struct {
+	ItemID   string `json:"item_id"`
+	Quantity int64  `json:"quantity"`
+}
+
+type quantityResponse struct {
+	Subscription     json.RawMessage `json:"subscription"`
+	ItemID           string          `json:"item_id,omitempty"`
+	PreviousQuantity int64           `json:"previous_quantity"`
+	Quantity         int64           `json:"quantity"`
+	Scheduled        bool            `json:"scheduled"`
+	EffectiveAt      time.Time       `json:"effective_at"`
+	InvoiceID        string          `json:"invoice_id,omitempty"`
+}
+
+// updateQuantityHandler changes the quantity of the primary price, or of the
+// item_id given. Increases are prorated on an immediate invoice; decreases
+// take effect at the end of the current period.
+func (g *grpcService) updateQuantityHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	sub, err := g.tenantSubscription(r, params["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var body quantityRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, status.Error(codes.InvalidArgument, "invalid request body"))
+		return
+	}
+	sub, change, invoiceID, err := g.updateQuantity(r.Context(), sub, body.ItemID, body.Quantity, g.sourceKey(r))
+	if err != nil {
+		writeError(w, itemError(err))
+		return
+	}
+	payload, err := protojson.Marshal(g.toProto(sub))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, quantityResponse{
+		Subscription:     payload,
+		ItemID:           change.ItemID,
+		PreviousQuantity: change.Previous,
+		Quantity:         change.Quantity,
+		Scheduled:        change.Scheduled,
+		EffectiveAt:      change.EffectiveAt,
+		InvoiceID:        invoiceID,
+	})
+}
+
+type phaseRequest struct {
+	PriceID    string `json:"price_id"`
+	Quantity   int64  `json:"quantity"`
+	CouponCode string `json:"coupon_code"`
+	Iterations int    `json:"iterations"`
+}
+
+type scheduleRequest struct {
+	CustomerID    string         `json:"customer_id"`
+	BillingAnchor string         `json:"billing_anchor"`
+	EndBehavior   string         `json:"end_behavior"`
+	Phases        []phaseRequest `json:"phases"`
+}
+
+type phaseResponse struct {
+	PriceID    string `json:"price_id"`
+	Quantity   int64  `json:"quantity"`
+	CouponCode string `json:"coupon_code,omitempty"`
+	Iterations int    `json:"iterations"`
+}
+
+type scheduleResponse struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	Status         string          `json:"status"`
+	EndBehavior    string          `json:"end_behavior"`
+	CurrentPhase   int             `json:"current_phase"`
+	PeriodsInPhase int             `json:"periods_in_phase"`
+	DiscountID     string          `json:"discount_id,omitempty"`
+	Phases         []phaseResponse `json:"phases"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+func toScheduleResponse(schedule domain.Schedule) scheduleResponse {
+	phases := make([]phaseResponse, 0, len(schedule.Phases))
+	for _, p := range schedule.Phases {
+		phases = append(phases, phaseResponse{PriceID: p.PriceID, Quantity: p.Quantity, CouponCode: p.CouponCode, Iterations: p.Iterations})
+	}
+	return scheduleResponse{
+		ID:             schedule.ID,
+		SubscriptionID: schedule.SubscriptionID,
+		Status:         string(schedule.Status),
+		EndBehavior:    string(schedule.EndBehavior),
+		CurrentPhase:   schedule.CurrentPhase,
+		PeriodsInPhase: schedule.PeriodsInPhase,
+		DiscountID:     schedule.DiscountID,
+		Phases:         phases,
+		CreatedAt:      schedule.CreatedAt,
+		UpdatedAt:      schedule.UpdatedAt,
+	}
+}
+
+// createScheduleHandler starts a subscription on the first of the schedule's
+// phases. Later phases are applied by the renewal worker at period ends.
+func (g *grpcService) createScheduleHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "tenant_id required"))
+		return
+	}
+	var body scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.CustomerID == "" {
+		writeError(w, status.Error(codes.InvalidArgument, "customer_id required"))
+		return
+	}
+	anchor, err := domain.ParseBillingAnchor(body.BillingAnchor)
+	if err != nil {
+		writeError(w, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+	endBehavior, err := domain.ParseScheduleEndBehavior(body.EndBehavior)
+	if err != nil {
+		writeError(w, scheduleError(err))
+		return
+	}
+	schedule := domain.Schedule{ID: g.genID.Generate().String(), EndBehavior: endBehavior}
+	for _, p := range body.Phases {
+		schedule.Phases = append(schedule.Phases, domain.Phase{
+			PriceID:    p.PriceID,
+			Quantity:   p.Quantity,
+			CouponCode: p.CouponCode,
+			Iterations: p.Iterations,
+		})
+	}
+	if err := g.svc.CheckSchedule(r.Context(), tenantID, body.CustomerID, schedule); err != nil {
+		writeError(w, scheduleError(err))
+		return
+	}
+
+	now := time.Now().UTC()
+	sub := domain.Subscription{
+		ID:                 g.genID.Generate().String(),
+		TenantID:           tenantID,
+		CustomerID:         body.CustomerID,
+		Status:             int32(subscriptionv1.SubscriptionStatus_SUBSCRIPTION_STATUS_ACTIVE),
+		StartAt:            now,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   now.AddDate(0, 1, 0),
+		BillingAnchor:      anchor,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if code := schedule.Phases[0].CouponCode; code != "" && g.coupons != nil {
+		discount, err := g.coupons.Redeem(r.Context(), coupondomain.RedeemRequest{
+			TenantID:       tenantID,
+			CouponCode:     code,
+			SubscriptionID: sub.ID,
+		})
+		if err != nil {
+			writeError(w, status.Error(codes.FailedPrecondition, err.Error()))
+			return
+		}
+		schedule.DiscountID = discount.ID
+	}
+	created, stored, err := g.svc.CreateSchedule(r.Context(), sub, schedule)
+	if err != nil {
+		// The subscription was never created, so its redemption is undone.
+		if schedule.DiscountID != "" {
+			_ = g.coupons.RevokeDiscount(r.Context(), tenantID, schedule.DiscountID)
+		}
+		writeError(w, scheduleError(err))
+		return
+	}
+	sub, schedule = created, stored
+	g.emit(r.Context(), domain.PhaseStartedEvent(sub, schedule))
+	if schedule.Status == domain.ScheduleStatusCompleted {
+		g.emit(r.Context(), domain.ScheduleCompletedEvent(sub, schedule))
+	}
+
+	payload, err := protojson.Marshal(g.toProto(sub))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"schedule":     toScheduleResponse(schedule),
+		"subscription": json.RawMessage(payload),
+	})
+}
+
+func (g *grpcService) getScheduleHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	schedule, err := g.tenantSchedule(r, params["id"])
+	if err != nil {
+		writeError(w, scheduleError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, toScheduleResponse(schedule))
+}
+
+// releaseScheduleHandler detaches the schedule; the subscription keeps the
+// terms of its current phase.
+func (g *grpcService) releaseScheduleHandler(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	schedule, err := g.tenantSchedule(r, params["id"])
+	if err != nil {
+		writeError(w, scheduleError(err))
+		return
+	}
+	schedule, err = g.svc.ReleaseSchedule(r.Context(), schedule)
+	if err != nil {
+		writeError(w, scheduleError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, toScheduleResponse(schedule))
+}
+
+// tenantSchedule loads a schedule owned by the request's tenant.
+func (g *grpcService) tenantSchedule(r *http.Request, id string) (domain.Schedule, error) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" || id == "" {
+		return domain.Schedule{}, status.Error(codes.InvalidArgument, "tenant_id and schedule id required")
+	}
+	return g.svc.GetSchedule(r.Context(), tenantID, id)
+}
+
+func scheduleError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidSchedule):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrScheduleNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrScheduleNotActive),
+		errors.Is(err, domain.ErrCurrencyMismatch),
+		errors.Is(err, domain.ErrPriceArchived):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return lifecycleError(err)
+}
+
+// sourceKey identifies a write for idempotent proration, preferring the
+// caller's idempotency key.
+func (g *grpcService) sourceKey(r *http.Request) string {
+	if key := r.Header.Get(headers.HeaderIdempotency); key != "" {
+		return key
+	}
+	return g.genID.Generate().String()
+}
+
+func itemError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidQuantity):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrItemNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrDuplicateItem),
+		errors.Is(err, domain.ErrIntervalMismatch),
+		errors.Is(err, domain.ErrCurrencyMismatch),
+		errors.Is(err, domain.ErrPriceArchived):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return lifecycleError(err)
+}
+
+// emit stores a lifecycle event in the outbox.
+func (g *grpcService) emit(ctx context.Context, evt *eventv1.Event) {
+	if g.outbox == nil || evt == nil {
+		return
+	}
+	_ = g.outbox.InsertOutboxEvent(ctx, &outbox.OutboxEvent{Subject: evt.GetSubject(), TenantID: evt.GetTenantId(), Event: evt})
+}
+
+func lifecycleError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidSubscriptionTransition),
+		errors.Is(err, domain.ErrSubscriptionCanceled),
+		errors.Is(err, domain.ErrNoPendingCancellation):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return err
+}
+
+// tenantSubscription loads a subscription owned by the request's tenant.
+func (g *grpcService) tenantSubscription(r *http.Request, id string) (domain.Subscription, error) {
+	tenantID := r.Header.Get(headers.HeaderTenantID)
+	if tenantID == "" || id == "" {
+		return domain.Subscription{}, status.Error(codes.InvalidArgument, "tenant_id and subscription id required")
+	}
+	sub, err := g.svc.Get(r.Context(), id)
+	if err != nil || sub.TenantID != tenantID {
+		return domain.Subscription{}, status.Error(codes.NotFound, "subscription not found")
+	}
+	return sub, nil
+}
+
+func writeProto(w http.ResponseWriter, sub *subscriptionv1.Subscription) {
+	body, err := protojson.Marshal(sub)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	writeJSON(w, runtime.HTTPStatusFromCode(st.Code()), map[string]string{"error": st.Message()})
+}
@@ -10,6 +10,7 @@ import (
 // Module wires subscription services.
 var Module = fx.Options(
 	fx.Provide(reposqlc.NewRepository),
+	fx.Provide(NewPriceCatalog),
 	fx.Provide(domain.NewService),
 	fx.Provide(RegisterService),
 	ModuleGRPC,
@@ -3,10 +3,13 @@ package sqlc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/smallbiznis/corebilling/internal/subscription/domain"
@@ -24,6 +27,20 @@ func NewRepository(pool *pgxpool.Pool) domain.Repository {
 	return &Repository{pool: pool}
 }
 
+const subscriptionColumns = `
+	id, tenant_id, customer_id, price_id, status, auto_renew,
+	start_at, current_period_start, current_period_end,
+	trial_start_at, trial_end_at, trial_reminder_sent_at, cancel_at, canceled_at,
+	COALESCE(currency, ''), billing_anchor, billing_anchor_day,
+	COALESCE(scheduled_price_id::TEXT, ''), scheduled_change_at,
+	paused_at, COALESCE(pause_behavior, ''), resumes_at,
+	quantity, COALESCE(scheduled_quantity, 0),
+	metadata, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 // Create inserts subscription.
 func (r *Repository) Create(ctx context.Context, sub domain.Subscription) error {
 	metadata, err := marshalJSON(sub.Metadata)
@@ -34,9 +51,13 @@ func (r *Repository) Create(ctx context.Context, sub domain.Subscription) error
 		INSERT INTO subscriptions (
 			id, tenant_id, customer_id, price_id, status, auto_renew,
 			start_at, current_period_start, current_period_end,
-			trial_start_at, trial_end_at, cancel_at, canceled_at,
+			trial_start_at, trial_end_at, trial_reminder_sent_at, cancel_at, canceled_at,
+			currency, billing_anchor, billing_anchor_day,
+			scheduled_price_id, scheduled_change_at,
+			paused_at, pause_behavior, resumes_at,
+			quantity, scheduled_quantity,
 			metadata, created_at, updated_at
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27)
 	`,
 		sub.ID,
 		sub.TenantID,
@@ -49,8 +70,19 @@ func (r *Repository) Create(ctx context.Context, sub domain.Subscription) error
 		sub.CurrentPeriodEnd,
 		sub.TrialStartAt,
 		sub.TrialEndAt,
+		sub.TrialReminderSentAt,
 		sub.CancelAt,
 		sub.CanceledAt,
+		nullIfEmpty(sub.Currency),
+		billingAnchor(sub.BillingAnchor),
+		sub.BillingAnchorDay,
+		scheduledPriceID(sub.ScheduledChange),
+		scheduledChangeAt(sub.ScheduledChange),
+		pausedAt(sub.Pause),
+		pauseBehavior(sub.Pause),
+		resumesAt(sub.Pause),
+		quantity(sub.Quantity),
+		nullIfZero(sub.ScheduledQuantity),
 		metadata,
 		sub.CreatedAt,
 		sub.UpdatedAt,
@@ -60,44 +92,8 @@ func (r *Repository) Create(ctx context.Context, sub domain.Subscription) error
 
 // GetByID fetches subscription by id.
 func (r *Repository) GetByID(ctx context.Context, id string) (domain.Subscription, error) {
-	row := r.pool.QueryRow(ctx, `
-		SELECT id, tenant_id, customer_id, price_id, status, auto_renew,
-		       start_at, current_period_start, current_period_end,
-		       trial_start_at, trial_end_at, cancel_at, canceled_at,
-		       metadata, created_at, updated_at
-		FROM subscriptions
-		WHERE id=$1
-	`, id)
-
-	var sub domain.Subscription
-	var metadata []byte
-	var trialStart, trialEnd, cancelAt, canceledAt *time.Time
-	if err := row.Scan(
-		&sub.ID,
-		&sub.TenantID,
-		&sub.CustomerID,
-		&sub.PriceID,
-		&sub.Status,
-		&sub.AutoRenew,
-		&sub.StartAt,
-		&sub.CurrentPeriodStart,
-		&sub.CurrentPeriodEnd,
-		&trialStart,
-		&trialEnd,
-		&cancelAt,
-		&canceledAt,
-		&metadata,
-		&sub.CreatedAt,
-		&sub.UpdatedAt,
-	); err != nil {
-		return domain.Subscription{}, err
-	}
-	sub.TrialStartAt = trialStart
-	sub.TrialEndAt = trialEnd
-	sub.CancelAt = cancelAt
-	sub.CanceledAt = canceledAt
-	sub.Metadata = jsonToMap(metadata)
-	return sub, nil
+	row := r.pool.QueryRow(ctx, `SELECT `+subscriptionColumns+` FROM subscriptions WHERE id=$1`, id)
+	return scanSubscription(row)
 }
 
 // List returns subscriptions matching the filter.
@@ -117,13 +113,7 @@ func (r *Repository) List(ctx context.Context, filter domain.ListSubscriptionsFi
 		addClause("customer_id", filter.CustomerID)
 	}
 
-	query := `
-		SELECT id, tenant_id, customer_id, price_id, status, auto_renew,
-		       start_at, current_period_start, current_period_end,
-		       trial_start_at, trial_end_at, cancel_at, canceled_at,
-		       metadata, created_at, updated_at
-		FROM subscriptions
-	`
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions`
 	if len(clauses) > 0 {
 		query += " WHERE " + strings.Join(clauses, " AND ")
 	}
@@ -153,34 +143,10 @@ func (r *Repository) List(ctx context.Context, filter domain.ListSubscriptionsFi
 
 	var subs []domain.Subscription
 	for rows.Next() {
-		var sub domain.Subscription
-		var metadata []byte
-		var trialStart, trialEnd, cancelAt, canceledAt *time.Time
-		if err := rows.Scan(
-			&sub.ID,
-			&sub.TenantID,
-			&sub.CustomerID,
-			&sub.PriceID,
-			&sub.Status,
-			&sub.AutoRenew,
-			&sub.StartAt,
-			&sub.CurrentPeriodStart,
-			&sub.CurrentPeriodEnd,
-			&trialStart,
-			&trialEnd,
-			&cancelAt,
-			&canceledAt,
-			&metadata,
-			&sub.CreatedAt,
-			&sub.UpdatedAt,
-		); err != nil {
+		sub, err := scanSubscription(rows)
+		if err != nil {
 			return nil, false, err
 		}
-		sub.TrialStartAt = trialStart
-		sub.TrialEndAt = trialEnd
-		sub.CancelAt = cancelAt
-		sub.CanceledAt = canceledAt
-		sub.Metadata = jsonToMap(metadata)
 		subs = append(subs, sub)
 	}
 	if err := rows.Err(); err != nil {
@@ -197,16 +163,50 @@ func (r *Repository) List(ctx context.Context, filter domain.ListSubscriptionsFi
 
 // Update persists subscription changes.
 func (r *Repository) Update(ctx context.Context, sub domain.Subscription) error {
+	return updateSubscription(ctx, r.pool, sub)
+}
+
+// SaveRenewal stores the renewed subscription, its changed items and its
+// schedule in one transaction.
+func (r *Repository) SaveRenewal(ctx context.Context, sub domain.Subscription, items []domain.Item, schedule *domain.Schedule) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateSubscription(ctx, tx, sub); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := updateItem(ctx, tx, item); err != nil {
+			return err
+		}
+	}
+	if schedule != nil {
+		if err := updateSchedule(ctx, tx, *schedule); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func updateSubscription(ctx context.Context, db DBTX, sub domain.Subscription) error {
 	metadata, err := marshalJSON(sub.Metadata)
 	if err != nil {
 		return err
 	}
-	_, err = r.pool.Exec(ctx, `
+	_, err = db.Exec(ctx, `
 		UPDATE subscriptions SET
 			customer_id=$2, price_id=$3, status=$4, auto_renew=$5,
 			current_period_start=$6, current_period_end=$7,
 			trial_start_at=$8, trial_end_at=$9, cancel_at=$10,
-			canceled_at=$11, metadata=$12, updated_at=$13
+			canceled_at=$11, currency=$12, billing_anchor=$13,
+			billing_anchor_day=$14, scheduled_price_id=$15,
+			scheduled_change_at=$16, metadata=$17, updated_at=$18,
+			trial_reminder_sent_at=$19, paused_at=$20,
+			pause_behavior=$21, resumes_at=$22,
+			quantity=$23, scheduled_quantity=$24
 		WHERE id=$1
 	`,
 		sub.ID,
@@ -220,12 +220,472 @@ func (r *Repository) Update(ctx context.Context, sub domain.Subscription) error
 		sub.TrialEndAt,
 		sub.CancelAt,
 		sub.CanceledAt,
+		nullIfEmpty(sub.Currency),
+		billingAnchor(sub.BillingAnchor),
+		sub.BillingAnchorDay,
+		scheduledPriceID(sub.ScheduledChange),
+		scheduledChangeAt(sub.ScheduledChange),
 		metadata,
 		sub.UpdatedAt,
+		sub.TrialReminderSentAt,
+		pausedAt(sub.Pause),
+		pauseBehavior(sub.Pause),
+		resumesAt(sub.Pause),
+		quantity(sub.Quantity),
+		nullIfZero(sub.ScheduledQuantity),
+	)
+	return err
+}
+
+// ListRenewalsDue returns auto-renewing subscriptions whose period has ended.
+func (r *Repository) ListRenewalsDue(ctx context.Context, filter domain.RenewalFilter) ([]domain.Subscription, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultSubscriptionPageSize
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+subscriptionColumns+` FROM subscriptions
+		WHERE auto_renew AND current_period_end <= $1 AND status = ANY($2::SMALLINT[])
+		ORDER BY current_period_end
+		LIMIT $3
+	`, filter.At, buildStatusArray(filter.Statuses), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []domain.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// ListTrialsEnding returns subscriptions whose trial ends within the filter window.
+func (r *Repository) ListTrialsEnding(ctx context.Context, filter domain.TrialFilter) ([]domain.Subscription, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultSubscriptionPageSize
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+subscriptionColumns+` FROM subscriptions
+		WHERE status = $1 AND trial_end_at > $2 AND trial_end_at <= $3
+			AND (NOT $4 OR trial_reminder_sent_at IS NULL)
+		ORDER BY trial_end_at
+		LIMIT $5
+	`, filter.Status, filter.EndsAfter, filter.EndsBy, filter.Unreminded, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []domain.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// ListResumesDue returns paused subscriptions whose resume date has passed.
+func (r *Repository) ListResumesDue(ctx context.Context, filter domain.ResumeFilter) ([]domain.Subscription, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultSubscriptionPageSize
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+subscriptionColumns+` FROM subscriptions
+		WHERE paused_at IS NOT NULL AND resumes_at <= $1
+		ORDER BY resumes_at
+		LIMIT $2
+	`, filter.At, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []domain.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// ListCancellationsDue returns subscriptions whose pending cancellation date has passed.
+func (r *Repository) ListCancellationsDue(ctx context.Context, filter domain.CancellationFilter) ([]domain.Subscription, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultSubscriptionPageSize
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+subscriptionColumns+` FROM subscriptions
+		WHERE cancel_at <= $1 AND canceled_at IS NULL AND status = ANY($2::SMALLINT[])
+		ORDER BY cancel_at
+		LIMIT $3
+	`, filter.At, buildStatusArray(filter.Statuses), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []domain.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+const itemColumns = `id, tenant_id, subscription_id, price_id, quantity, COALESCE(scheduled_quantity, 0), removed_at, created_at, updated_at`
+
+// CreateItem inserts a subscription item.
+func (r *Repository) CreateItem(ctx context.Context, item domain.Item) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO subscription_items (
+			id, tenant_id, subscription_id, price_id, quantity, scheduled_quantity,
+			removed_at, created_at, updated_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`,
+		item.ID,
+		item.TenantID,
+		item.SubscriptionID,
+		item.PriceID,
+		item.Quantity,
+		nullIfZero(item.ScheduledQuantity),
+		item.RemovedAt,
+		item.CreatedAt,
+		item.UpdatedAt,
+	)
+	return err
+}
+
+// GetItem fetches a subscription item by id.
+func (r *Repository) GetItem(ctx context.Context, id string) (domain.Item, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+itemColumns+` FROM subscription_items WHERE id=$1`, id)
+	return scanItem(row)
+}
+
+// UpdateItem persists a subscription item's quantities and removal.
+func (r *Repository) UpdateItem(ctx context.Context, item domain.Item) error {
+	return updateItem(ctx, r.pool, item)
+}
+
+func updateItem(ctx context.Context, db DBTX, item domain.Item) error {
+	_, err := db.Exec(ctx, `
+		UPDATE subscription_items SET quantity=$2, scheduled_quantity=$3, removed_at=$4, updated_at=$5
+		WHERE id=$1
+	`, item.ID, item.Quantity, nullIfZero(item.ScheduledQuantity), item.RemovedAt, item.UpdatedAt)
+	return err
+}
+
+// ListItems returns a subscription's items in the order they were added.
+func (r *Repository) ListItems(ctx context.Context, filter domain.ItemFilter) ([]domain.Item, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+itemColumns+` FROM subscription_items
+		WHERE subscription_id=$1 AND (removed_at IS NULL OR removed_at > $2)
+		ORDER BY created_at, id
+	`, filter.SubscriptionID, filter.RemovedAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []domain.Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func scanItem(row rowScanner) (domain.Item, error) {
+	var item domain.Item
+	if err := row.Scan(
+		&item.ID,
+		&item.TenantID,
+		&item.SubscriptionID,
+		&item.PriceID,
+		&item.Quantity,
+		&item.ScheduledQuantity,
+		&item.RemovedAt,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	); err != nil {
+		return domain.Item{}, err
+	}
+	return item, nil
+}
+
+const scheduleColumns = `id::TEXT, tenant_id::TEXT, subscription_id::TEXT, phases, end_behavior, status,
+	current_phase, periods_in_phase, COALESCE(discount_id::TEXT, ''), created_at, updated_at`
+
+// phaseRecord is the JSON form of a schedule phase in the phases column.
+type phaseRecord struct {
+	PriceID    string `json:"price_id"`
+	Quantity   int64  `json:"quantity,omitempty"`
+	CouponCode string `json:"coupon_code,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+}
+
+// CreateSchedule inserts a subscription schedule.
+func (r *Repository) CreateSchedule(ctx context.Context, schedule domain.Schedule) error {
+	phases, err := marshalPhases(schedule.Phases)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO subscription_schedules (
+			id, tenant_id, subscription_id, phases, end_behavior, status,
+			current_phase, periods_in_phase, discount_id, created_at, updated_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+	`,
+		schedule.ID,
+		schedule.TenantID,
+		schedule.SubscriptionID,
+		phases,
+		string(schedule.EndBehavior),
+		string(schedule.Status),
+		schedule.CurrentPhase,
+		schedule.PeriodsInPhase,
+		nullIfEmpty(schedule.DiscountID),
+		schedule.CreatedAt,
+		schedule.UpdatedAt,
 	)
 	return err
 }
 
+// GetSchedule fetches a subscription schedule by id.
+func (r *Repository) GetSchedule(ctx context.Context, id string) (domain.Schedule, error) {
+	row := r.pool.QueryRow(ctx, `SELECT `+scheduleColumns+` FROM subscription_schedules WHERE id=$1`, id)
+	return scanSchedule(row)
+}
+
+// UpdateSchedule persists a schedule's progress and status.
+func (r *Repository) UpdateSchedule(ctx context.Context, schedule domain.Schedule) error {
+	return updateSchedule(ctx, r.pool, schedule)
+}
+
+func updateSchedule(ctx context.Context, db DBTX, schedule domain.Schedule) error {
+	_, err := db.Exec(ctx, `
+		UPDATE subscription_schedules SET
+			status=$2, current_phase=$3, periods_in_phase=$4, discount_id=$5, updated_at=$6
+		WHERE id=$1
+	`,
+		schedule.ID,
+		string(schedule.Status),
+		schedule.CurrentPhase,
+		schedule.PeriodsInPhase,
+		nullIfEmpty(schedule.DiscountID),
+		schedule.UpdatedAt,
+	)
+	return err
+}
+
+// GetActiveSchedule returns the active schedule driving the subscription.
+func (r *Repository) GetActiveSchedule(ctx context.Context, subscriptionID string) (domain.Schedule, bool, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT `+scheduleColumns+` FROM subscription_schedules
+		WHERE subscription_id=$1 AND status=$2
+	`, subscriptionID, string(domain.ScheduleStatusActive))
+	schedule, err := scanSchedule(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Schedule{}, false, nil
+	}
+	if err != nil {
+		return domain.Schedule{}, false, err
+	}
+	return schedule, true, nil
+}
+
+func scanSchedule(row rowScanner) (domain.Schedule, error) {
+	var schedule domain.Schedule
+	var phases []byte
+	var endBehavior, status string
+	if err := row.Scan(
+		&schedule.ID,
+		&schedule.TenantID,
+		&schedule.SubscriptionID,
+		&phases,
+		&endBehavior,
+		&status,
+		&schedule.CurrentPhase,
+		&schedule.PeriodsInPhase,
+		&schedule.DiscountID,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	); err != nil {
+		return domain.Schedule{}, err
+	}
+	var records []phaseRecord
+	if err := json.Unmarshal(phases, &records); err != nil {
+		return domain.Schedule{}, err
+	}
+	for _, p := range records {
+		schedule.Phases = append(schedule.Phases, domain.Phase{
+			PriceID:    p.PriceID,
+			Quantity:   p.Quantity,
+			CouponCode: p.CouponCode,
+			Iterations: p.Iterations,
+		})
+	}
+	schedule.EndBehavior = domain.ScheduleEndBehavior(endBehavior)
+	schedule.Status = domain.ScheduleStatus(status)
+	return schedule, nil
+}
+
+func marshalPhases(phases []domain.Phase) ([]byte, error) {
+	records := make([]phaseRecord, 0, len(phases))
+	for _, p := range phases {
+		records = append(records, phaseRecord{
+			PriceID:    p.PriceID,
+			Quantity:   p.Quantity,
+			CouponCode: p.CouponCode,
+			Iterations: p.Iterations,
+		})
+	}
+	return json.Marshal(records)
+}
+
+func scanSubscription(row rowScanner) (domain.Subscription, error) {
+	var sub domain.Subscription
+	var metadata []byte
+	var scheduledPrice, behavior string
+	var trialStart, trialEnd, trialReminder, cancelAt, canceledAt, scheduledAt, pauseStart, resumeAt *time.Time
+	if err := row.Scan(
+		&sub.ID,
+		&sub.TenantID,
+		&sub.CustomerID,
+		&sub.PriceID,
+		&sub.Status,
+		&sub.AutoRenew,
+		&sub.StartAt,
+		&sub.CurrentPeriodStart,
+		&sub.CurrentPeriodEnd,
+		&trialStart,
+		&trialEnd,
+		&trialReminder,
+		&cancelAt,
+		&canceledAt,
+		&sub.Currency,
+		&sub.BillingAnchor,
+		&sub.BillingAnchorDay,
+		&scheduledPrice,
+		&scheduledAt,
+		&pauseStart,
+		&behavior,
+		&resumeAt,
+		&sub.Quantity,
+		&sub.ScheduledQuantity,
+		&metadata,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+	); err != nil {
+		return domain.Subscription{}, err
+	}
+	sub.TrialStartAt = trialStart
+	sub.TrialEndAt = trialEnd
+	sub.TrialReminderSentAt = trialReminder
+	sub.CancelAt = cancelAt
+	sub.CanceledAt = canceledAt
+	if scheduledPrice != "" && scheduledAt != nil {
+		sub.ScheduledChange = &domain.ScheduledChange{PriceID: scheduledPrice, EffectiveAt: *scheduledAt}
+	}
+	if pauseStart != nil {
+		sub.Pause = &domain.Pause{Behavior: domain.PauseBehavior(behavior), PausedAt: *pauseStart, ResumesAt: resumeAt}
+	}
+	sub.Metadata = jsonToMap(metadata)
+	return sub, nil
+}
+
+func billingAnchor(anchor domain.BillingAnchor) string {
+	if anchor == "" {
+		return string(domain.BillingAnchorAnniversary)
+	}
+	return string(anchor)
+}
+
+func scheduledPriceID(change *domain.ScheduledChange) any {
+	if change == nil {
+		return nil
+	}
+	return change.PriceID
+}
+
+func scheduledChangeAt(change *domain.ScheduledChange) *time.Time {
+	if change == nil {
+		return nil
+	}
+	return &change.EffectiveAt
+}
+
+func pausedAt(pause *domain.Pause) *time.Time {
+	if pause == nil {
+		return nil
+	}
+	return &pause.PausedAt
+}
+
+func pauseBehavior(pause *domain.Pause) any {
+	if pause == nil {
+		return nil
+	}
+	return string(pause.Behavior)
+}
+
+func resumesAt(pause *domain.Pause) *time.Time {
+	if pause == nil {
+		return nil
+	}
+	return pause.ResumesAt
+}
+
+// quantity stores subscriptions created without a quantity as one unit.
+func quantity(value int64) int64 {
+	if value < 1 {
+		return 1
+	}
+	return value
+}
+
+func nullIfZero(value int64) any {
+	if value == 0 {
+		return nil
+	}
+	return value
+}
+
+func buildStatusArray(statuses []int32) string {
+	parts := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		parts = append(parts, strconv.FormatInt(int64(status), 10))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func nullIfEmpty(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
 func marshalJSON(value map[string]interface{}) ([]byte, error) {
 	if len(value) == 0 {
 		return nil, nil
@@ -3,6 +3,8 @@ package domain
 import (
 	"context"
 	"time"
+
+	"github.com/smallbiznis/corebilling/internal/money"
 )
 
 // ListUsageFilter configures filters for listing usage records.
@@ -13,12 +15,18 @@ type ListUsageFilter struct {
 	MeterCode      string
 	From           time.Time
 	To             time.Time
-	Limit          int
-	Offset         int
+	// ThroughID limits the records recorded exactly at To to those with an
+	// id up to it, so records sharing a timestamp are ordered by id.
+	ThroughID string
+	Limit     int
+	Offset    int
 }
 
 // Repository for usage records.
 type Repository interface {
 	Create(ctx context.Context, usage UsageRecord) error
 	List(ctx context.Context, filter ListUsageFilter) ([]UsageRecord, bool, error)
+	// SumValue totals the values of matching records as a fixed-point
+	// decimal, ignoring pagination.
+	SumValue(ctx context.Context, filter ListUsageFilter) (money.Quantity, error)
 }
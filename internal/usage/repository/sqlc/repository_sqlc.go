@@ -8,6 +8,7 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/smallbiznis/corebilling/internal/money"
 	"github.com/smallbiznis/corebilling/internal/usage/domain"
 )
 
@@ -53,32 +54,7 @@ func (r *Repository) Create(ctx context.Context, usage domain.UsageRecord) error
 
 // List returns usage records matching the filter.
 func (r *Repository) List(ctx context.Context, filter domain.ListUsageFilter) ([]domain.UsageRecord, bool, error) {
-	clauses := []string{}
-	args := []any{}
-
-	addClause := func(expr string, value any) {
-		clauses = append(clauses, fmt.Sprintf("%s $%d", expr, len(args)+1))
-		args = append(args, value)
-	}
-
-	if filter.TenantID != "" {
-		addClause("tenant_id =", filter.TenantID)
-	}
-	if filter.SubscriptionID != "" {
-		addClause("subscription_id =", filter.SubscriptionID)
-	}
-	if filter.CustomerID != "" {
-		addClause("customer_id =", filter.CustomerID)
-	}
-	if filter.MeterCode != "" {
-		addClause("meter_code =", filter.MeterCode)
-	}
-	if !filter.From.IsZero() {
-		addClause("recorded_at >=", filter.From)
-	}
-	if !filter.To.IsZero() {
-		addClause("recorded_at <=", filter.To)
-	}
+	clauses, args := buildFilter(filter)
 
 	query := `
 		SELECT id, tenant_id, customer_id, subscription_id,
@@ -147,6 +123,56 @@ func (r *Repository) List(ctx context.Context, filter domain.ListUsageFilter) ([
 	return records, hasMore, nil
 }
 
+// SumValue totals usage values matching the filter, ignoring pagination.
+// Values are summed as NUMERIC so the total carries no float error.
+func (r *Repository) SumValue(ctx context.Context, filter domain.ListUsageFilter) (money.Quantity, error) {
+	clauses, args := buildFilter(filter)
+
+	query := `SELECT COALESCE(SUM(value::NUMERIC), 0)::TEXT FROM usage_records`
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+
+	var total string
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(&total); err != nil {
+		return 0, err
+	}
+	return money.ParseQuantity(total)
+}
+
+func buildFilter(filter domain.ListUsageFilter) ([]string, []any) {
+	clauses := []string{}
+	args := []any{}
+
+	addClause := func(expr string, value any) {
+		clauses = append(clauses, fmt.Sprintf("%s $%d", expr, len(args)+1))
+		args = append(args, value)
+	}
+
+	if filter.TenantID != "" {
+		addClause("tenant_id =", filter.TenantID)
+	}
+	if filter.SubscriptionID != "" {
+		addClause("subscription_id =", filter.SubscriptionID)
+	}
+	if filter.CustomerID != "" {
+		addClause("customer_id =", filter.CustomerID)
+	}
+	if filter.MeterCode != "" {
+		addClause("meter_code =", filter.MeterCode)
+	}
+	if !filter.From.IsZero() {
+		addClause("recorded_at >=", filter.From)
+	}
+	if !filter.To.IsZero() && filter.ThroughID != "" {
+		args = append(args, filter.To, filter.ThroughID)
+		clauses = append(clauses, fmt.Sprintf("(recorded_at < $%d OR (recorded_at = $%d AND id <= $%d))", len(args)-1, len(args)-1, len(args)))
+	} else if !filter.To.IsZero() {
+		addClause("recorded_at <=", filter.To)
+	}
+	return clauses, args
+}
+
 func marshalJSON(value map[string]interface{}) ([]byte, error) {
 	if len(value) == 0 {
 		return nil, nil